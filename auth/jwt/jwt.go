@@ -2,6 +2,8 @@
 
 // Package jwt provides JWT token handling for the auth module.
 // It includes functionality for generating and validating JWT tokens.
+// Config.Clock sets the clock used for issuing, expiry and revocation cleanup,
+// so that tests can advance time instead of waiting.
 package jwt
 
 import (
@@ -14,6 +16,7 @@
 	"time"
 
 	"github.com/abitofhelp/servicelib/auth/errors"
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/golang-jwt/jwt/v5"
 	"go.opentelemetry.io/otel"
@@ -64,6 +67,10 @@ type Config struct {
 	// MinSecretKeyLength is the minimum length required for the secret key
 	// Default is 32 if not specified
 	MinSecretKeyLength int
+
+	// Clock is used to issue tokens, check their expiration and clean up
+	// revoked tokens. If nil, the system clock is used.
+	Clock clock.Clock
 }
 
 // Service handles JWT token operations including generation and validation.
@@ -111,6 +118,8 @@ func NewService(config Config, logger *zap.Logger) (*Service, error) {
 		config.MinSecretKeyLength = 32
 	}
 
+	config.Clock = clock.OrReal(config.Clock)
+
 	// Validate secret key length
 	if len(config.SecretKey) < config.MinSecretKeyLength {
 		err := errors.WithContext(errors.ErrInvalidConfig, "secret_key_length", len(config.SecretKey))
@@ -215,7 +224,7 @@ func (s *Service) GenerateToken(ctx context.Context, userID string, roles []stri
 		return "", err
 	}
 
-	now := time.Now()
+	now := s.config.Clock.Now()
 	expiresAt := now.Add(s.config.TokenDuration)
 
 	// Generate a unique token ID
@@ -404,7 +413,7 @@ func (s *Service) cleanupRevokedTokens() {
 	// Get a context logger for this operation
 	logger := s.getContextLogger(ctx)
 
-	now := time.Now()
+	now := s.config.Clock.Now()
 	for tokenID, expiresAt := range s.revokedTokens {
 		if now.After(expiresAt) {
 			delete(s.revokedTokens, tokenID)
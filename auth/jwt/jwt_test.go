@@ -10,6 +10,7 @@
 
 	autherrors "github.com/abitofhelp/servicelib/auth/errors"
 	"github.com/abitofhelp/servicelib/auth/jwt"
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -116,11 +117,12 @@ func TestValidateToken(t *testing.T) {
 	assert.Nil(t, claims)
 	assert.True(t, errors.Is(err, autherrors.ErrInvalidToken))
 
-	// Test with expired token
+	// Test with expired token, issued two hours ago by a service whose clock is behind
 	expiredConfig := jwt.Config{
 		SecretKey:     "test-secret-that-is-at-least-32-chars",
-		TokenDuration: -1 * time.Hour, // Negative duration to create expired token
+		TokenDuration: 1 * time.Hour,
 		Issuer:        "test-issuer",
+		Clock:         clock.NewFake(time.Now().Add(-2 * time.Hour)),
 	}
 	expiredService, err := jwt.NewService(expiredConfig, logger)
 	require.NoError(t, err)
@@ -250,3 +252,34 @@ func TestTokenWithMalformedClaims(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, claims)
 }
+
+func TestClock(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	config := jwt.Config{
+		SecretKey:     "test-secret-that-is-at-least-32-chars",
+		TokenDuration: 1 * time.Hour,
+		Issuer:        "test-issuer",
+		Clock:         fake,
+	}
+	service, err := jwt.NewService(config, zap.NewNop())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	token, err := service.GenerateToken(ctx, "user123", []string{"user"}, []string{}, []string{})
+	require.NoError(t, err)
+
+	// The token is issued and validated at the time of the fake clock
+	claims, err := service.ValidateToken(ctx, token)
+	require.NoError(t, err)
+	assert.True(t, fake.Now().Add(time.Hour).Equal(claims.ExpiresAt.Time))
+
+	// A revoked token is rejected until it expires
+	require.NoError(t, service.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time))
+	_, err = service.ValidateToken(ctx, token)
+	assert.True(t, errors.Is(err, autherrors.ErrInvalidToken))
+
+	// Once the clock passes the expiration, the token is expired
+	fake.Advance(time.Hour + time.Second)
+	_, err = service.ValidateToken(ctx, token)
+	assert.True(t, errors.Is(err, autherrors.ErrExpiredToken))
+}
@@ -13,6 +13,7 @@
 	"time"
 
 	"github.com/abitofhelp/servicelib/auth/errors"
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/golang-jwt/jwt/v5"
 	"go.opentelemetry.io/otel"
@@ -28,8 +29,8 @@ type TokenValidator interface {
 	ValidateToken(ctx context.Context, tokenString string) (*Claims, error)
 }
 
-// parseToken parses and validates a JWT token.
-func parseToken(tokenString string, secretKey string, signingMethod SigningMethod) (*jwt.Token, error) {
+// parseToken parses and validates a JWT token, checking its time claims against the clock.
+func parseToken(tokenString string, secretKey string, signingMethod SigningMethod, c clock.Clock) (*jwt.Token, error) {
 	if tokenString == "" {
 		return nil, errors.WithOp(errors.ErrMissingToken, "jwt.parseToken")
 	}
@@ -74,7 +75,7 @@ func parseToken(tokenString string, secretKey string, signingMethod SigningMetho
 		}
 
 		return []byte(secretKey), nil
-	})
+	}, jwt.WithTimeFunc(clock.OrReal(c).Now))
 
 	if err != nil {
 		// Check for specific error types
@@ -150,7 +151,7 @@ func (v *LocalValidator) ValidateToken(ctx context.Context, tokenString string)
 	}
 
 	// Use the existing validation logic from the JWT service
-	token, err := parseToken(tokenString, v.config.SecretKey, v.config.SigningMethod)
+	token, err := parseToken(tokenString, v.config.SecretKey, v.config.SigningMethod, v.config.Clock)
 	if err != nil {
 		v.logger.Debug(ctx, "Failed to parse token", zap.Error(err))
 		return nil, err
@@ -2,17 +2,22 @@
 
 // Package cache provides functionality for caching frequently accessed data.
 //
-// This package implements a simple in-memory cache with expiration.
+// This package implements a cache with expiration on top of a pluggable store.
 package cache
 
 import (
 	"context"
+	"math"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/abitofhelp/servicelib/telemetry"
+	"github.com/abitofhelp/servicelib/valueobject/measurement"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
@@ -24,6 +29,27 @@ type Item[T any] struct {
 	Value T
 	// Expiration is the Unix timestamp in nanoseconds when this item expires
 	Expiration int64
+	// Created is the Unix timestamp in nanoseconds when this item was stored.
+	// It is used to compute the refresh-ahead window and may be zero.
+	Created int64
+	// StaleUntil is the Unix timestamp in nanoseconds until which this item may be
+	// served stale while it is refreshed. If it is not after Expiration, the item
+	// is never served stale.
+	StaleUntil int64
+	// Tags are the tags the item was stored with, used by InvalidateTag.
+	Tags []string
+	// Cost is the size of the item as reported by the cache's cost function,
+	// usually in bytes. It is zero when no cost function is set.
+	Cost int64
+}
+
+// retainUntil returns the Unix timestamp in nanoseconds after which a store may
+// discard the item.
+func (i Item[T]) retainUntil() int64 {
+	if i.StaleUntil > i.Expiration {
+		return i.StaleUntil
+	}
+	return i.Expiration
 }
 
 // Config contains cache configuration parameters.
@@ -42,9 +68,38 @@ type Config struct {
 	// When this limit is reached, new items will cause old items to be evicted.
 	MaxSize int
 
+	// MaxBytes is the maximum total cost of the items in the cache, usually in bytes.
+	// Item costs are computed by the function registered with SetCost. When this
+	// limit is reached, new items cause old items to be evicted, independently
+	// of MaxSize. The zero value disables the limit.
+	MaxBytes int64
+
 	// PurgeInterval is the interval at which expired items are purged.
 	// A background goroutine runs at this interval to remove expired items.
 	PurgeInterval time.Duration
+
+	// EvictionStrategy determines which item is evicted when the cache is full.
+	// The zero value is LRU.
+	EvictionStrategy EvictionStrategy
+
+	// NegativeTTL is how long an error returned by a WithCache loader is cached.
+	// While an error is cached, WithCache and WithCacheTTL return it without calling
+	// the loader again. Errors are kept in process, even when a shared store is used.
+	// The zero value disables negative caching.
+	NegativeTTL time.Duration
+
+	// StaleTTL is the grace period after expiration during which an item is still
+	// served while a background refresh runs (stale-while-revalidate). Stale items
+	// are only served when a refresh is possible: by WithCache and WithCacheTTL, or
+	// by Get when a loader has been registered with SetLoader.
+	// The zero value disables stale serving.
+	StaleTTL time.Duration
+
+	// RefreshAhead is the fraction of an item's lifetime (0.0-1.0) before its
+	// expiration during which a read triggers a background refresh through the
+	// registered loader. For example, 0.2 refreshes items read within the last
+	// 20% of their TTL. The zero value disables refresh-ahead.
+	RefreshAhead float64
 }
 
 // DefaultConfig returns a default cache configuration with reasonable values.
@@ -52,16 +107,26 @@ type Config struct {
 //   - Enabled: true (cache is enabled)
 //   - TTL: 5 minutes (items expire after 5 minutes)
 //   - MaxSize: 1000 (maximum of 1000 items in the cache)
+//   - MaxBytes: 0 (the total cost of the items is not limited)
 //   - PurgeInterval: 1 minute (expired items are purged every minute)
+//   - EvictionStrategy: LRU (the least recently used item is evicted first)
+//   - NegativeTTL: 0 (loader errors are not cached)
+//   - StaleTTL: 0 (expired items are never served)
+//   - RefreshAhead: 0 (items are not refreshed before they expire)
 //
 // Returns:
 //   - A Config instance with default values.
 func DefaultConfig() Config {
 	return Config{
-		Enabled:       true,
-		TTL:           5 * time.Minute,
-		MaxSize:       1000,
-		PurgeInterval: 1 * time.Minute,
+		Enabled:          true,
+		TTL:              5 * time.Minute,
+		MaxSize:          1000,
+		MaxBytes:         0,
+		PurgeInterval:    1 * time.Minute,
+		EvictionStrategy: LRU,
+		NegativeTTL:      0,
+		StaleTTL:         0,
+		RefreshAhead:     0,
 	}
 }
 
@@ -112,6 +177,38 @@ func (c Config) WithMaxSize(maxSize int) Config {
 	return c
 }
 
+// WithMaxBytes sets the maximum total cost of the items in the cache, usually in bytes.
+// If a non-positive value is provided, the limit is disabled.
+//
+// Parameters:
+//   - maxBytes: The maximum total cost of the items in the cache.
+//
+// Returns:
+//   - A new Config instance with the updated MaxBytes value.
+func (c Config) WithMaxBytes(maxBytes int64) Config {
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	c.MaxBytes = maxBytes
+	return c
+}
+
+// WithMaxMemory sets the maximum total cost of the items in the cache from a
+// memory size, such as one parsed with measurement.ParseMemSize("256MB").
+//
+// Parameters:
+//   - size: The maximum total size of the items in the cache.
+//
+// Returns:
+//   - A new Config instance with the updated MaxBytes value.
+func (c Config) WithMaxMemory(size measurement.MemSize) Config {
+	maxBytes := size.Bytes()
+	if maxBytes > math.MaxInt64 {
+		maxBytes = math.MaxInt64
+	}
+	return c.WithMaxBytes(int64(maxBytes))
+}
+
 // WithPurgeInterval sets the interval at which expired items are purged.
 // A background goroutine runs at this interval to remove expired items.
 // If a non-positive value is provided, it will be set to 1 millisecond.
@@ -129,6 +226,76 @@ func (c Config) WithPurgeInterval(purgeInterval time.Duration) Config {
 	return c
 }
 
+// WithEvictionStrategy sets the strategy used to evict items when the cache is full.
+// If an unknown strategy is provided, it will be set to LRU.
+//
+// Parameters:
+//   - strategy: The eviction strategy to use.
+//
+// Returns:
+//   - A new Config instance with the updated EvictionStrategy value.
+func (c Config) WithEvictionStrategy(strategy EvictionStrategy) Config {
+	if strategy < LRU || strategy > Random {
+		strategy = LRU
+	}
+	c.EvictionStrategy = strategy
+	return c
+}
+
+// WithNegativeTTL sets how long errors returned by WithCache loaders are cached.
+// Negative caching protects a failing backend from being called by every request
+// for the same key. If a non-positive value is provided, negative caching is disabled.
+//
+// Parameters:
+//   - negativeTTL: The time-to-live for cached loader errors.
+//
+// Returns:
+//   - A new Config instance with the updated NegativeTTL value.
+func (c Config) WithNegativeTTL(negativeTTL time.Duration) Config {
+	if negativeTTL < 0 {
+		negativeTTL = 0
+	}
+	c.NegativeTTL = negativeTTL
+	return c
+}
+
+// WithStaleTTL sets the grace period during which expired items are served while
+// they are refreshed in the background. If a non-positive value is provided,
+// stale serving is disabled.
+//
+// Parameters:
+//   - staleTTL: The grace period after expiration.
+//
+// Returns:
+//   - A new Config instance with the updated StaleTTL value.
+func (c Config) WithStaleTTL(staleTTL time.Duration) Config {
+	if staleTTL < 0 {
+		staleTTL = 0
+	}
+	c.StaleTTL = staleTTL
+	return c
+}
+
+// WithRefreshAhead sets the fraction of an item's lifetime before its expiration
+// during which reads trigger a background refresh through the registered loader.
+// The value should be between 0 and 1, where 0 disables refresh-ahead. If a value
+// outside this range is provided, it will be clamped to the valid range.
+//
+// Parameters:
+//   - refreshAhead: The refresh-ahead window as a fraction of the TTL (0-1).
+//
+// Returns:
+//   - A new Config instance with the updated RefreshAhead value.
+func (c Config) WithRefreshAhead(refreshAhead float64) Config {
+	if refreshAhead < 0 {
+		refreshAhead = 0
+	} else if refreshAhead > 1 {
+		refreshAhead = 1
+	}
+	c.RefreshAhead = refreshAhead
+	return c
+}
+
 // Options contains additional options for the cache.
 // These options are not directly related to the cache behavior itself,
 // but provide additional functionality like logging, tracing, and identification.
@@ -145,6 +312,14 @@ type Options struct {
 	// This is useful for identifying the cache in logs and traces,
 	// especially when multiple caches are used in the same application.
 	Name string
+
+	// Meter is used to create metrics for cache operations.
+	// If nil, no metrics are recorded; Stats is always available.
+	Meter metric.Meter
+
+	// Clock is used to expire items and to schedule the cleanup of expired items.
+	// If nil, the system clock will be used.
+	Clock clock.Clock
 }
 
 // DefaultOptions returns default options for cache operations.
@@ -206,11 +381,40 @@ func (o Options) WithName(name string) Options {
 	return o
 }
 
-// Cache is a generic in-memory cache with expiration.
+// WithMeter sets the OpenTelemetry meter used to record cache metrics.
+// The cache records hits, misses, evictions by reason, its current size, and
+// loader latency, each with a cache.name attribute. Use telemetry.PrometheusMeter
+// to expose the metrics on the handler from telemetry.CreatePrometheusHandler.
+//
+// Parameters:
+//   - meter: An OpenTelemetry metric.Meter instance.
+//
+// Returns:
+//   - A new Options instance with the updated Meter value.
+func (o Options) WithMeter(meter metric.Meter) Options {
+	o.Meter = meter
+	return o
+}
+
+// WithClock sets the clock of the cache.
+// Tests use it to pass a clock.Fake, so that items expire without real time passing.
+// The default MemoryStore uses the same clock; a custom store keeps its own.
+//
+// Parameters:
+//   - c: The clock used to expire items and schedule cleanups.
+//
+// Returns:
+//   - A new Options instance with the updated Clock value.
+func (o Options) WithClock(c clock.Clock) Options {
+	o.Clock = c
+	return o
+}
+
+// Cache is a generic cache with expiration.
 // It provides thread-safe operations for storing and retrieving values of any type,
-// with automatic expiration and cleanup of expired items. The cache supports
-// configurable size limits with eviction strategies, and integrates with
-// OpenTelemetry for tracing and monitoring.
+// with automatic expiration and cleanup of expired items. Items are kept in a Store,
+// which defaults to an in-process MemoryStore with configurable size limits and
+// eviction strategies. The cache integrates with OpenTelemetry for tracing and monitoring.
 //
 // Cache is implemented as a generic type, allowing it to store values of any type
 // while maintaining type safety.
@@ -218,11 +422,8 @@ type Cache[T any] struct {
 	// name is the identifier for this cache instance
 	name string
 
-	// items is the map that stores the cached data
-	items map[string]Item[T]
-
-	// mu protects the items map for concurrent access
-	mu sync.RWMutex
+	// store holds the cached data
+	store Store[T]
 
 	// defaultTTL is the default time-to-live for cache items
 	defaultTTL time.Duration
@@ -239,11 +440,56 @@ type Cache[T any] struct {
 	// tracer is used for tracing cache operations
 	tracer telemetry.Tracer
 
+	// clock is used to expire items and schedule cleanups
+	clock clock.Clock
+
 	// stopCleanup is a channel used to signal the cleanup goroutine to stop
 	stopCleanup chan bool
 
 	// evictionStrategy determines how items are evicted when the cache is full
 	evictionStrategy EvictionStrategy
+
+	// loads coalesces concurrent WithCache loads of the same key
+	loads *flightGroup[T]
+
+	// negativeTTL is how long loader errors are cached
+	negativeTTL time.Duration
+
+	// negative holds cached loader errors by key
+	negative map[string]negativeEntry
+
+	// negativeMu protects the negative map for concurrent access
+	negativeMu sync.Mutex
+
+	// staleTTL is the grace period during which expired items may be served
+	staleTTL time.Duration
+
+	// refreshAhead is the fraction of an item's lifetime in which reads trigger a refresh
+	refreshAhead float64
+
+	// loader is the registered loader used by Get to refresh items
+	loader Loader[T]
+
+	// loaderMu protects the loader for concurrent access
+	loaderMu sync.RWMutex
+
+	// stats holds the counters reported by Stats
+	stats statsCounters
+
+	// metrics holds the OpenTelemetry instruments, or nil if metrics are disabled
+	metrics *cacheMetrics
+
+	// cost computes the cost of stored values, or nil if items have no cost
+	cost CostFunc[T]
+
+	// costMu protects the cost function for concurrent access
+	costMu sync.RWMutex
+}
+
+// negativeEntry is a cached loader error with its expiration time.
+type negativeEntry struct {
+	err        error
+	expiration int64
 }
 
 // EvictionStrategy defines the strategy for evicting items when the cache is full.
@@ -293,6 +539,29 @@ type Cache[T any] struct {
 //   - *Cache[T]: A new cache instance configured according to the provided parameters,
 //     or nil if the cache is disabled.
 func NewCache[T any](config Config, options Options) *Cache[T] {
+	return NewCacheWithStore[T](config, options, nil)
+}
+
+// NewCacheWithStore creates a new cache that keeps its items in the given store.
+// It behaves like NewCache, except that items are read from and written to store
+// instead of an in-process map. This allows a cache to be backed by a shared store,
+// such as a RedisStore, so that all replicas of a service see the same data.
+//
+// MaxSize, MaxBytes and EvictionStrategy in the configuration only apply to the
+// default in-process store; a custom store is responsible for its own capacity.
+//
+// Type Parameters:
+//   - T: The type of values to be stored in the cache.
+//
+// Parameters:
+//   - config: The configuration parameters for the cache.
+//   - options: Additional options for the cache, such as logging and tracing.
+//   - store: The store that holds the cached items. If nil, a MemoryStore is used.
+//
+// Returns:
+//   - *Cache[T]: A new cache instance configured according to the provided parameters,
+//     or nil if the cache is disabled.
+func NewCacheWithStore[T any](config Config, options Options, store Store[T]) *Cache[T] {
 	if !config.Enabled {
 		if options.Logger != nil {
 			options.Logger.Info(context.Background(), "Cache is disabled", zap.String("name", options.Name))
@@ -316,18 +585,57 @@ func NewCache[T any](config Config, options Options) *Cache[T] {
 		zap.String("name", options.Name),
 		zap.Duration("ttl", config.TTL),
 		zap.Int("max_size", config.MaxSize),
-		zap.Duration("purge_interval", config.PurgeInterval))
+		zap.Int64("max_bytes", config.MaxBytes),
+		zap.Duration("purge_interval", config.PurgeInterval),
+		zap.String("eviction_strategy", config.EvictionStrategy.String()),
+		zap.Duration("negative_ttl", config.NegativeTTL),
+		zap.Duration("stale_ttl", config.StaleTTL),
+		zap.Float64("refresh_ahead", config.RefreshAhead))
+
+	if store == nil {
+		memoryStore := NewMemoryStore[T](config.MaxSize, config.EvictionStrategy)
+		memoryStore.SetMaxBytes(config.MaxBytes)
+		memoryStore.SetClock(options.Clock)
+		store = memoryStore
+	}
 
 	cache := &Cache[T]{
 		name:             options.Name,
-		items:            make(map[string]Item[T]),
+		store:            store,
 		defaultTTL:       config.TTL,
 		maxSize:          config.MaxSize,
 		cleanupInterval:  config.PurgeInterval,
 		logger:           logger,
 		tracer:           tracer,
+		clock:            clock.OrReal(options.Clock),
 		stopCleanup:      make(chan bool),
-		evictionStrategy: LRU,
+		evictionStrategy: config.EvictionStrategy,
+		loads:            newFlightGroup[T](),
+		negativeTTL:      config.NegativeTTL,
+		negative:         make(map[string]negativeEntry),
+		staleTTL:         config.StaleTTL,
+		refreshAhead:     config.RefreshAhead,
+	}
+
+	if notifier, ok := store.(EvictionNotifier); ok {
+		notifier.OnEvict(func(key string, reason EvictionReason) {
+			cache.recordEvictions(reason, 1)
+		})
+	}
+
+	if options.Meter != nil {
+		var bytes func() int64
+		if _, ok := store.(*MemoryStore[T]); ok {
+			bytes = cache.bytes
+		}
+		metrics, err := newCacheMetrics(options.Meter, options.Name, cache.Size, bytes)
+		if err != nil {
+			logger.Warn(context.Background(), "Failed to create cache metrics, continuing without metrics",
+				zap.String("name", options.Name),
+				zap.Error(err))
+		} else {
+			cache.metrics = metrics
+		}
 	}
 
 	// Start the cleanup goroutine
@@ -365,20 +673,7 @@ func (c *Cache[T]) Set(ctx context.Context, key string, value T) {
 		attribute.String("cache.operation", "set"),
 	)
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Check if we need to evict an item
-	if len(c.items) >= c.maxSize && !c.itemExists(key) {
-		c.evictItem()
-	}
-
-	c.items[key] = Item[T]{
-		Value:      value,
-		Expiration: time.Now().Add(c.defaultTTL).UnixNano(),
-	}
-
-	span.SetAttributes(attribute.Int("cache.size", len(c.items)))
+	c.setItem(ctx, span, key, value, c.defaultTTL, nil)
 }
 
 // SetWithTTL adds an item to the cache with a custom expiration time.
@@ -414,20 +709,41 @@ func (c *Cache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time
 		attribute.Int64("cache.ttl_ms", ttl.Milliseconds()),
 	)
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.setItem(ctx, span, key, value, ttl, nil)
+}
 
-	// Check if we need to evict an item
-	if len(c.items) >= c.maxSize && !c.itemExists(key) {
-		c.evictItem()
+// SetWithTags adds an item to the cache with the default expiration time and
+// associates it with one or more tags. All items stored with a tag can later be
+// removed at once with InvalidateTag, for example every key derived from a tenant
+// or an entity. If an item with the same key already exists, it is replaced
+// together with its tags.
+//
+// This method is thread-safe and can be called concurrently from multiple goroutines.
+// If the cache is nil (which happens when the cache is disabled), this method is a no-op.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - key: The key under which to store the value.
+//   - value: The value to store in the cache.
+//   - tags: The tags to associate with the item.
+func (c *Cache[T]) SetWithTags(ctx context.Context, key string, value T, tags ...string) {
+	if c == nil {
+		return
 	}
 
-	c.items[key] = Item[T]{
-		Value:      value,
-		Expiration: time.Now().Add(ttl).UnixNano(),
-	}
+	// Create a span for the cache operation
+	var span telemetry.Span
+	ctx, span = c.tracer.Start(ctx, "cache.SetWithTags")
+	defer span.End()
 
-	span.SetAttributes(attribute.Int("cache.size", len(c.items)))
+	span.SetAttributes(
+		attribute.String("cache.name", c.name),
+		attribute.String("cache.key", key),
+		attribute.String("cache.operation", "set_with_tags"),
+		attribute.StringSlice("cache.tags", tags),
+	)
+
+	c.setItem(ctx, span, key, value, c.defaultTTL, append([]string(nil), tags...))
 }
 
 // Get retrieves an item from the cache.
@@ -435,6 +751,11 @@ func (c *Cache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time
 // If the key doesn't exist or the item has expired, the zero value of type T
 // and false are returned.
 //
+// If a loader has been registered with SetLoader, Get also keeps items fresh:
+// an expired item within the StaleTTL grace period is returned while it is reloaded
+// in the background, and an item read within the RefreshAhead window before its
+// expiration is reloaded in the background.
+//
 // This method is thread-safe and can be called concurrently from multiple goroutines.
 // If the cache is nil (which happens when the cache is disabled), this method returns
 // the zero value of type T and false.
@@ -452,6 +773,20 @@ func (c *Cache[T]) Get(ctx context.Context, key string) (T, bool) {
 		return zero, false
 	}
 
+	return c.get(ctx, key, c.loaderRefresh(key))
+}
+
+// get retrieves a value from the cache, using refresh to reload stale items and
+// items inside the refresh-ahead window. If refresh is nil, items are never served
+// stale or refreshed ahead.
+func (c *Cache[T]) get(ctx context.Context, key string, refresh *refreshFunc[T]) (T, bool) {
+	item, found := c.getItem(ctx, key, refresh)
+	return item.Value, found
+}
+
+// getItem retrieves an item from the cache as get does, together with its
+// expiration and tags.
+func (c *Cache[T]) getItem(ctx context.Context, key string, refresh *refreshFunc[T]) (Item[T], bool) {
 	// Create a span for the cache operation
 	var span telemetry.Span
 	ctx, span = c.tracer.Start(ctx, "cache.Get")
@@ -463,26 +798,49 @@ func (c *Cache[T]) Get(ctx context.Context, key string) (T, bool) {
 		attribute.String("cache.operation", "get"),
 	)
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	item, found, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.logger.Warn(ctx, "Failed to read from cache store, treating as a miss",
+			zap.String("name", c.name),
+			zap.String("key", key),
+			zap.Error(err))
+		span.RecordError(err)
+		found = false
+	}
 
-	item, found := c.items[key]
 	if !found {
 		span.SetAttributes(attribute.Bool("cache.hit", false))
-		var zero T
-		return zero, false
+		c.recordMiss(ctx)
+		return Item[T]{}, false
 	}
 
 	// Check if the item has expired
-	if time.Now().UnixNano() > item.Expiration {
+	now := c.clock.Now().UnixNano()
+	if now > item.Expiration {
+		if refresh != nil && now <= item.StaleUntil {
+			// Serve the stale value while it is refreshed in the background
+			span.SetAttributes(
+				attribute.Bool("cache.hit", true),
+				attribute.Bool("cache.stale", true),
+			)
+			c.triggerRefresh(ctx, span, key, item.Tags, refresh, "stale")
+			c.recordHit(ctx, true)
+			return item, true
+		}
+
 		span.SetAttributes(attribute.Bool("cache.hit", false))
 		span.SetAttributes(attribute.Bool("cache.expired", true))
-		var zero T
-		return zero, false
+		c.recordMiss(ctx)
+		return Item[T]{}, false
+	}
+
+	if refresh != nil && c.inRefreshAheadWindow(item, now) {
+		c.triggerRefresh(ctx, span, key, item.Tags, refresh, "refresh_ahead")
 	}
 
 	span.SetAttributes(attribute.Bool("cache.hit", true))
-	return item.Value, true
+	c.recordHit(ctx, false)
+	return item, true
 }
 
 // Delete removes an item from the cache.
@@ -511,16 +869,103 @@ func (c *Cache[T]) Delete(ctx context.Context, key string) {
 		attribute.String("cache.operation", "delete"),
 	)
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.forgetError(key)
+
+	found, err := c.store.Delete(ctx, key)
+	if err != nil {
+		c.logger.Warn(ctx, "Failed to delete from cache store",
+			zap.String("name", c.name),
+			zap.String("key", key),
+			zap.Error(err))
+		span.RecordError(err)
+	}
+
+	span.SetAttributes(attribute.Bool("cache.found", found))
+	c.recordSize(span)
+}
 
-	_, found := c.items[key]
-	delete(c.items, key)
+// InvalidateTag removes every item that was stored with the given tag through
+// SetWithTags. Items stored without the tag are left in place.
+//
+// This method is thread-safe and can be called concurrently from multiple goroutines.
+// If the cache is nil (which happens when the cache is disabled), this method is a no-op.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - tag: The tag whose items should be removed.
+func (c *Cache[T]) InvalidateTag(ctx context.Context, tag string) {
+	if c == nil {
+		return
+	}
+
+	// Create a span for the cache operation
+	var span telemetry.Span
+	ctx, span = c.tracer.Start(ctx, "cache.InvalidateTag")
+	defer span.End()
 
 	span.SetAttributes(
-		attribute.Bool("cache.found", found),
-		attribute.Int("cache.size", len(c.items)),
+		attribute.String("cache.name", c.name),
+		attribute.String("cache.tag", tag),
+		attribute.String("cache.operation", "invalidate_tag"),
 	)
+
+	removed, err := c.store.DeleteTag(ctx, tag)
+	if err != nil {
+		c.logger.Warn(ctx, "Failed to invalidate tag in cache store",
+			zap.String("name", c.name),
+			zap.String("tag", tag),
+			zap.Error(err))
+		span.RecordError(err)
+	}
+
+	span.SetAttributes(attribute.Int("cache.removed", removed))
+	c.recordSize(span)
+}
+
+// DeletePrefix removes every item whose key starts with the given prefix, along
+// with any cached loader errors for those keys. An empty prefix removes all items.
+//
+// This method is thread-safe and can be called concurrently from multiple goroutines.
+// If the cache is nil (which happens when the cache is disabled), this method is a no-op.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - prefix: The key prefix of the items to remove, such as "tenant:42:".
+func (c *Cache[T]) DeletePrefix(ctx context.Context, prefix string) {
+	if c == nil {
+		return
+	}
+
+	// Create a span for the cache operation
+	var span telemetry.Span
+	ctx, span = c.tracer.Start(ctx, "cache.DeletePrefix")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("cache.name", c.name),
+		attribute.String("cache.prefix", prefix),
+		attribute.String("cache.operation", "delete_prefix"),
+	)
+
+	c.negativeMu.Lock()
+	for k := range c.negative {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.negative, k)
+		}
+	}
+	c.negativeMu.Unlock()
+
+	removed, err := c.store.DeletePrefix(ctx, prefix)
+	if err != nil {
+		c.logger.Warn(ctx, "Failed to delete prefix from cache store",
+			zap.String("name", c.name),
+			zap.String("prefix", prefix),
+			zap.Error(err))
+		span.RecordError(err)
+	}
+
+	span.SetAttributes(attribute.Int("cache.removed", removed))
+	c.recordSize(span)
 }
 
 // Clear removes all items from the cache.
@@ -548,11 +993,18 @@ func (c *Cache[T]) Clear(ctx context.Context) {
 		attribute.String("cache.operation", "clear"),
 	)
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.negativeMu.Lock()
+	c.negative = make(map[string]negativeEntry)
+	c.negativeMu.Unlock()
 
-	oldSize := len(c.items)
-	c.items = make(map[string]Item[T])
+	oldSize, _ := c.store.Len(ctx)
+	if err := c.store.Clear(ctx); err != nil {
+		c.logger.Warn(ctx, "Failed to clear cache store",
+			zap.String("name", c.name),
+			zap.Error(err))
+		span.RecordError(err)
+		return
+	}
 
 	span.SetAttributes(
 		attribute.Int("cache.old_size", oldSize),
@@ -564,6 +1016,7 @@ func (c *Cache[T]) Clear(ctx context.Context) {
 // This method counts all items currently in the cache, including those that may have
 // expired but haven't been removed by the cleanup process yet. For an accurate count
 // of non-expired items, you would need to implement a custom counting method.
+// If the store cannot be read, the error is logged and 0 is returned.
 //
 // This method is thread-safe and can be called concurrently from multiple goroutines.
 // If the cache is nil (which happens when the cache is disabled), this method returns 0.
@@ -575,20 +1028,24 @@ func (c *Cache[T]) Size() int {
 		return 0
 	}
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	return len(c.items)
+	size, err := c.store.Len(context.Background())
+	if err != nil {
+		c.logger.Warn(context.Background(), "Failed to read cache store size",
+			zap.String("name", c.name),
+			zap.Error(err))
+		return 0
+	}
+	return size
 }
 
 // startCleanupTimer starts the cleanup timer
 func (c *Cache[T]) startCleanupTimer() {
-	ticker := time.NewTicker(c.cleanupInterval)
+	ticker := c.clock.NewTicker(c.cleanupInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			c.cleanup()
 		case <-c.stopCleanup:
 			return
@@ -598,15 +1055,28 @@ func (c *Cache[T]) startCleanupTimer() {
 
 // cleanup removes expired items from the cache
 func (c *Cache[T]) cleanup() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	ctx := context.Background()
+	now := c.clock.Now().UnixNano()
 
-	now := time.Now().UnixNano()
-	for k, v := range c.items {
-		if now > v.Expiration {
-			delete(c.items, k)
+	c.negativeMu.Lock()
+	for k, v := range c.negative {
+		if now > v.expiration {
+			delete(c.negative, k)
 		}
 	}
+	c.negativeMu.Unlock()
+
+	removed, err := c.store.DeleteExpired(ctx, now)
+	if err != nil {
+		c.logger.Warn(ctx, "Failed to purge expired items from cache store",
+			zap.String("name", c.name),
+			zap.Error(err))
+	}
+
+	// Stores that report their own evictions have already been counted
+	if _, ok := c.store.(EvictionNotifier); !ok {
+		c.recordEvictions(EvictionExpired, removed)
+	}
 }
 
 // Shutdown stops the cleanup timer and gracefully shuts down the cache.
@@ -626,23 +1096,124 @@ func (c *Cache[T]) Shutdown() {
 
 	c.logger.Info(context.Background(), "Shutting down cache", zap.String("name", c.name))
 	c.stopCleanup <- true
+	if c.metrics != nil {
+		if err := c.metrics.sizeRegistration.Unregister(); err != nil {
+			c.logger.Warn(context.Background(), "Failed to unregister cache metrics",
+				zap.String("name", c.name),
+				zap.Error(err))
+		}
+	}
 	c.logger.Info(context.Background(), "Cache shut down successfully", zap.String("name", c.name))
 }
 
-// itemExists checks if an item exists in the cache
-func (c *Cache[T]) itemExists(key string) bool {
-	_, found := c.items[key]
-	return found
+// setItem stores an item with the given time-to-live in the store.
+// Store errors are logged and recorded on the span; the cache is best-effort.
+func (c *Cache[T]) setItem(ctx context.Context, span telemetry.Span, key string, value T, ttl time.Duration, tags []string) {
+	now := c.clock.Now()
+	item := Item[T]{
+		Value:      value,
+		Expiration: now.Add(ttl).UnixNano(),
+		Created:    now.UnixNano(),
+		StaleUntil: now.Add(ttl + c.staleTTL).UnixNano(),
+		Tags:       tags,
+		Cost:       c.costOf(value),
+	}
+
+	// A fresh value supersedes any cached loader error
+	c.forgetError(key)
+
+	if err := c.store.Set(ctx, key, item); err != nil {
+		c.logger.Warn(ctx, "Failed to write to cache store",
+			zap.String("name", c.name),
+			zap.String("key", key),
+			zap.Error(err))
+		span.RecordError(err)
+		return
+	}
+
+	c.recordSize(span)
+}
+
+// cachedError returns the cached loader error for key, if one has not expired.
+func (c *Cache[T]) cachedError(key string) (error, bool) {
+	c.negativeMu.Lock()
+	defer c.negativeMu.Unlock()
+
+	entry, found := c.negative[key]
+	if !found || c.clock.Now().UnixNano() > entry.expiration {
+		return nil, false
+	}
+	return entry.err, true
 }
 
-// evictItem evicts an item from the cache based on the eviction strategy
-func (c *Cache[T]) evictItem() {
-	// For now, just evict a random item
-	// In a real implementation, this would use the eviction strategy
-	for k := range c.items {
-		delete(c.items, k)
+// cacheError caches a loader error for key if negative caching is enabled.
+func (c *Cache[T]) cacheError(key string, err error) {
+	if c.negativeTTL <= 0 {
 		return
 	}
+
+	c.negativeMu.Lock()
+	defer c.negativeMu.Unlock()
+
+	c.negative[key] = negativeEntry{
+		err:        err,
+		expiration: c.clock.Now().Add(c.negativeTTL).UnixNano(),
+	}
+}
+
+// forgetError removes any cached loader error for key.
+func (c *Cache[T]) forgetError(key string) {
+	c.negativeMu.Lock()
+	defer c.negativeMu.Unlock()
+
+	delete(c.negative, key)
+}
+
+// load calls fn to produce the value for a key that was not found in the cache.
+// Concurrent loads of the same key are coalesced so that fn runs once and every
+// caller receives its result; each caller stops waiting when its own context is done.
+// On success, set stores the value before any waiting caller is released. On failure,
+// the error is cached when negative caching is enabled.
+func (c *Cache[T]) load(ctx context.Context, span telemetry.Span, key string, fn func(ctx context.Context) (T, error), set func(ctx context.Context, value T)) (T, error) {
+	if err, found := c.cachedError(key); found {
+		span.SetAttributes(attribute.Bool("cache.negative_hit", true))
+		span.RecordError(err)
+		var zero T
+		return zero, err
+	}
+
+	value, err, shared := c.loads.do(ctx, key, func(loadCtx context.Context) (T, error) {
+		start := c.clock.Now()
+		value, err := fn(loadCtx)
+		c.recordLoad(loadCtx, c.clock.Since(start), err)
+		if err != nil {
+			// Do not cache errors caused by every waiting caller giving up
+			if loadCtx.Err() == nil {
+				c.cacheError(key, err)
+			}
+			return value, err
+		}
+
+		set(loadCtx, value)
+		return value, nil
+	})
+
+	span.SetAttributes(attribute.Bool("cache.shared_load", shared))
+	if err != nil {
+		span.RecordError(err)
+	}
+	return value, err
+}
+
+// recordSize adds the current cache size to a span when the store can report it cheaply.
+func (c *Cache[T]) recordSize(span telemetry.Span) {
+	if s, ok := c.store.(*MemoryStore[T]); ok {
+		size, _ := s.Len(context.Background())
+		span.SetAttributes(
+			attribute.Int("cache.size", size),
+			attribute.Int64("cache.bytes", s.Bytes()),
+		)
+	}
 }
 
 // WithCache executes a function with caching.
@@ -655,6 +1226,18 @@ func (c *Cache[T]) evictItem() {
 // parameters, such as database queries or API calls. The cached results use the default TTL
 // configured for the cache. For custom TTL, use WithCacheTTL instead.
 //
+// Concurrent calls that miss on the same key are coalesced: the function runs once and
+// every caller receives its result. The function runs with a context that carries the
+// values of the first caller's context but not its cancellation; each caller stops
+// waiting with a context error when its own context is done, and the function's context
+// is cancelled once no caller is waiting for it. Errors returned by the function are not
+// cached unless Config.NegativeTTL is set.
+//
+// If Config.StaleTTL is set, an expired value within the stale period is returned
+// immediately and the function is called in the background to refresh it. If
+// Config.RefreshAhead is set, a value read shortly before it expires is also refreshed
+// in the background.
+//
 // If the cache is nil (which happens when the cache is disabled), the function is executed
 // directly without any caching.
 //
@@ -686,24 +1269,18 @@ func WithCache[T any](ctx context.Context, cache *Cache[T], key string, fn func(
 		attribute.String("cache.operation", "with_cache"),
 	)
 
-	// Try to get from cache
-	if value, found := cache.Get(ctx, key); found {
+	// Try to get from cache, refreshing stale items with the function
+	if value, found := cache.get(ctx, key, &refreshFunc[T]{fn: fn, ttl: cache.defaultTTL}); found {
 		span.SetAttributes(attribute.Bool("cache.hit", true))
 		return value, nil
 	}
 
 	span.SetAttributes(attribute.Bool("cache.hit", false))
 
-	// If not found, call the function
-	value, err := fn(ctx)
-	if err != nil {
-		span.RecordError(err)
-		return value, err
-	}
-
-	// Store the result in cache
-	cache.Set(ctx, key, value)
-	return value, nil
+	// If not found, call the function and store the result in cache
+	return cache.load(ctx, span, key, fn, func(ctx context.Context, value T) {
+		cache.Set(ctx, key, value)
+	})
 }
 
 // WithCacheTTL executes a function with caching and a custom time-to-live.
@@ -718,6 +1295,9 @@ func WithCache[T any](ctx context.Context, cache *Cache[T], key string, fn func(
 // control over how long results should be cached, which can be useful for data with
 // different freshness requirements.
 //
+// Concurrent misses on the same key are coalesced, and errors and stale values are
+// handled in the same way as in WithCache.
+//
 // If the cache is nil (which happens when the cache is disabled), the function is executed
 // directly without any caching.
 //
@@ -751,22 +1331,16 @@ func WithCacheTTL[T any](ctx context.Context, cache *Cache[T], key string, ttl t
 		attribute.Int64("cache.ttl_ms", ttl.Milliseconds()),
 	)
 
-	// Try to get from cache
-	if value, found := cache.Get(ctx, key); found {
+	// Try to get from cache, refreshing stale items with the function
+	if value, found := cache.get(ctx, key, &refreshFunc[T]{fn: fn, ttl: ttl}); found {
 		span.SetAttributes(attribute.Bool("cache.hit", true))
 		return value, nil
 	}
 
 	span.SetAttributes(attribute.Bool("cache.hit", false))
 
-	// If not found, call the function
-	value, err := fn(ctx)
-	if err != nil {
-		span.RecordError(err)
-		return value, err
-	}
-
-	// Store the result in cache with custom TTL
-	cache.SetWithTTL(ctx, key, value, ttl)
-	return value, nil
+	// If not found, call the function and store the result in cache with custom TTL
+	return cache.load(ctx, span, key, fn, func(ctx context.Context, value T) {
+		cache.SetWithTTL(ctx, key, value, ttl)
+	})
 }
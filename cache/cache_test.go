@@ -8,6 +8,7 @@
 	"testing"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -84,10 +85,12 @@ func TestCache_SetWithTTL(t *testing.T) {
 		WithEnabled(true).
 		WithTTL(1 * time.Hour)
 
+	fake := clock.NewFake(time.Time{})
 	logger := logging.NewContextLogger(zap.NewNop())
 	options := DefaultOptions().
 		WithName("test").
-		WithLogger(logger)
+		WithLogger(logger).
+		WithClock(fake)
 
 	cache := NewCache[string](cfg, options)
 	assert.NotNil(t, cache)
@@ -102,8 +105,8 @@ func TestCache_SetWithTTL(t *testing.T) {
 	assert.True(t, found)
 	assert.Equal(t, "value1", value)
 
-	// Wait for the TTL to expire
-	time.Sleep(20 * time.Millisecond)
+	// Let the TTL expire
+	fake.Advance(20 * time.Millisecond)
 
 	// Get the value after expiration
 	value, found = cache.Get(ctx, "key1")
@@ -254,10 +257,12 @@ func TestCache_Cleanup(t *testing.T) {
 		WithTTL(10 * time.Millisecond).
 		WithPurgeInterval(20 * time.Millisecond)
 
+	fake := clock.NewFake(time.Time{})
 	logger := logging.NewContextLogger(zap.NewNop())
 	options := DefaultOptions().
 		WithName("test").
-		WithLogger(logger)
+		WithLogger(logger).
+		WithClock(fake)
 
 	cache := NewCache[string](cfg, options)
 	assert.NotNil(t, cache)
@@ -268,11 +273,12 @@ func TestCache_Cleanup(t *testing.T) {
 	cache.Set(ctx, "key1", "value1")
 	assert.Equal(t, 1, cache.Size())
 
-	// Wait for the cleanup to run
-	time.Sleep(50 * time.Millisecond)
+	// Wait for the cleanup ticker to start, then let the item expire and the cleanup run
+	fake.BlockUntil(1)
+	fake.Advance(20 * time.Millisecond)
 
 	// The item should be gone
-	assert.Equal(t, 0, cache.Size())
+	assert.Eventually(t, func() bool { return cache.Size() == 0 }, time.Second, time.Millisecond)
 }
 
 func TestCache_Shutdown(t *testing.T) {
@@ -365,10 +371,12 @@ func TestWithCacheTTL(t *testing.T) {
 	cfg := DefaultConfig().
 		WithEnabled(true)
 
+	fake := clock.NewFake(time.Time{})
 	logger := logging.NewContextLogger(zap.NewNop())
 	options := DefaultOptions().
 		WithName("test").
-		WithLogger(logger)
+		WithLogger(logger).
+		WithClock(fake)
 
 	cache := NewCache[string](cfg, options)
 	assert.NotNil(t, cache)
@@ -394,8 +402,8 @@ func TestWithCacheTTL(t *testing.T) {
 	assert.Equal(t, "computed value", result)
 	assert.Equal(t, 1, callCount) // Function should not be called again
 
-	// Wait for the TTL to expire
-	time.Sleep(20 * time.Millisecond)
+	// Let the TTL expire
+	fake.Advance(20 * time.Millisecond)
 
 	// Third call should compute the value again
 	result, err = WithCacheTTL(ctx, cache, "key1", 10*time.Millisecond, fn)
@@ -0,0 +1,36 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"encoding/json"
+)
+
+// Codec converts cached values to and from bytes.
+// It is used by stores that keep values outside the process, such as RedisStore.
+//
+// Type Parameters:
+//   - T: The type of values to be encoded.
+type Codec[T any] interface {
+	// Marshal encodes a value.
+	Marshal(value T) ([]byte, error)
+
+	// Unmarshal decodes a value previously encoded by Marshal.
+	Unmarshal(data []byte) (T, error)
+}
+
+// JSONCodec is a Codec that encodes values as JSON.
+// Only exported fields of struct values are preserved.
+type JSONCodec[T any] struct{}
+
+// Marshal encodes a value as JSON.
+func (JSONCodec[T]) Marshal(value T) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Unmarshal decodes a JSON-encoded value.
+func (JSONCodec[T]) Unmarshal(data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
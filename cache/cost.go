@@ -0,0 +1,42 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+// CostFunc returns the cost of a cached value, usually its approximate size in
+// bytes. Costs are compared against Config.MaxBytes to decide when to evict.
+// It must be cheap and safe for concurrent use.
+type CostFunc[T any] func(value T) int64
+
+// SetCost registers the function used to compute the cost of each value stored
+// in the cache. It should be called before any item is stored: items stored
+// earlier keep the cost they were stored with. Without a cost function, every
+// item costs zero and Config.MaxBytes has no effect.
+//
+// If the cache is nil (which happens when the cache is disabled), this method is a no-op.
+//
+// Parameters:
+//   - cost: The cost function, or nil to store new items without a cost.
+func (c *Cache[T]) SetCost(cost CostFunc[T]) {
+	if c == nil {
+		return
+	}
+
+	c.costMu.Lock()
+	defer c.costMu.Unlock()
+	c.cost = cost
+}
+
+// costOf returns the cost of value, clamping negative costs to zero.
+func (c *Cache[T]) costOf(value T) int64 {
+	c.costMu.RLock()
+	cost := c.cost
+	c.costMu.RUnlock()
+	if cost == nil {
+		return 0
+	}
+
+	if n := cost(value); n > 0 {
+		return n
+	}
+	return 0
+}
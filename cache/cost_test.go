@@ -0,0 +1,205 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abitofhelp/servicelib/valueobject/measurement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCostTestCache(t *testing.T, maxBytes int64, strategy EvictionStrategy) *Cache[string] {
+	t.Helper()
+
+	cfg := DefaultConfig().WithMaxBytes(maxBytes).WithEvictionStrategy(strategy)
+	cache := NewCache[string](cfg, DefaultOptions())
+	require.NotNil(t, cache)
+	t.Cleanup(cache.Shutdown)
+
+	cache.SetCost(func(value string) int64 {
+		return int64(len(value))
+	})
+	return cache
+}
+
+func TestCache_MaxBytesEvictsByCost(t *testing.T) {
+	cache := newCostTestCache(t, 10, LRU)
+	ctx := context.Background()
+
+	cache.Set(ctx, "a", "aaaa")
+	cache.Set(ctx, "b", "bbbb")
+	assert.Equal(t, int64(8), cache.Stats().Bytes)
+
+	// Reading a makes b the least recently used item
+	_, _ = cache.Get(ctx, "a")
+
+	// A large item evicts as many items as needed to fit
+	cache.Set(ctx, "c", "cccccc")
+	_, found := cache.Get(ctx, "b")
+	assert.False(t, found)
+	_, found = cache.Get(ctx, "a")
+	assert.True(t, found)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(10), stats.Bytes)
+	assert.Equal(t, uint64(1), stats.Evictions)
+
+	cache.Set(ctx, "d", "dddddddddd")
+	assert.Equal(t, 1, cache.Size())
+	assert.Equal(t, int64(10), cache.Stats().Bytes)
+}
+
+func TestCache_MaxBytesOverwriteDoesNotEvictItself(t *testing.T) {
+	for _, strategy := range []EvictionStrategy{LRU, LFU, FIFO, Random} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			cache := newCostTestCache(t, 10, strategy)
+			ctx := context.Background()
+
+			cache.Set(ctx, "a", "aaaa")
+			cache.Set(ctx, "b", "bbbb")
+
+			// Growing a evicts b instead of a
+			cache.Set(ctx, "a", "aaaaaaaa")
+			value, found := cache.Get(ctx, "a")
+			assert.True(t, found)
+			assert.Equal(t, "aaaaaaaa", value)
+			_, found = cache.Get(ctx, "b")
+			assert.False(t, found)
+			assert.Equal(t, int64(8), cache.Stats().Bytes)
+		})
+	}
+}
+
+func TestCache_MaxBytesOverwriteKeepsEvictionOrder(t *testing.T) {
+	t.Run("LFU", func(t *testing.T) {
+		cache := newCostTestCache(t, 3, LFU)
+		ctx := context.Background()
+
+		cache.Set(ctx, "a", "a")
+		for i := 0; i < 3; i++ {
+			_, _ = cache.Get(ctx, "a")
+		}
+		cache.Set(ctx, "b", "b")
+		for i := 0; i < 2; i++ {
+			_, _ = cache.Get(ctx, "b")
+		}
+
+		// Overwriting a keeps its access count, so b is less frequently used
+		cache.Set(ctx, "a", "A")
+		cache.Set(ctx, "c", "cc")
+		_, found := cache.Get(ctx, "a")
+		assert.True(t, found)
+		_, found = cache.Get(ctx, "b")
+		assert.False(t, found)
+	})
+
+	t.Run("FIFO", func(t *testing.T) {
+		cache := newCostTestCache(t, 2, FIFO)
+		ctx := context.Background()
+
+		cache.Set(ctx, "a", "a")
+		cache.Set(ctx, "b", "b")
+
+		// Overwriting a does not make it the newest item
+		cache.Set(ctx, "a", "A")
+		cache.Set(ctx, "c", "c")
+		_, found := cache.Get(ctx, "a")
+		assert.False(t, found)
+		_, found = cache.Get(ctx, "b")
+		assert.True(t, found)
+	})
+}
+
+func TestCache_ItemLargerThanMaxBytes(t *testing.T) {
+	cache := newCostTestCache(t, 10, LRU)
+	ctx := context.Background()
+
+	cache.Set(ctx, "small", "s")
+	cache.Set(ctx, "big", "x")
+	cache.Set(ctx, "big", "this value is too large")
+
+	// The oversized item is not stored and its previous value is dropped
+	_, found := cache.Get(ctx, "big")
+	assert.False(t, found)
+	_, found = cache.Get(ctx, "small")
+	assert.True(t, found)
+	assert.Equal(t, int64(1), cache.Stats().Bytes)
+}
+
+func TestCache_CostTrackedOnDeleteAndClear(t *testing.T) {
+	cache := newCostTestCache(t, 100, LRU)
+	ctx := context.Background()
+
+	cache.SetWithTags(ctx, "a", "aaa", "t")
+	cache.Set(ctx, "b", "bb")
+	cache.Set(ctx, "c", "c")
+
+	cache.Delete(ctx, "c")
+	assert.Equal(t, int64(5), cache.Stats().Bytes)
+
+	cache.InvalidateTag(ctx, "t")
+	assert.Equal(t, int64(2), cache.Stats().Bytes)
+
+	cache.Clear(ctx)
+	assert.Equal(t, int64(0), cache.Stats().Bytes)
+}
+
+func TestCache_WithoutCostFunc(t *testing.T) {
+	cache := NewCache[string](DefaultConfig().WithMaxBytes(1), DefaultOptions())
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.Set(ctx, "a", "aaaa")
+	cache.Set(ctx, "b", "bbbb")
+	assert.Equal(t, 2, cache.Size())
+
+	// Negative costs are treated as zero
+	cache.SetCost(func(value string) int64 { return -1 })
+	cache.Set(ctx, "c", "cccc")
+	assert.Equal(t, int64(0), cache.Stats().Bytes)
+}
+
+func TestMemoryStore_SetMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore[string](0, FIFO)
+
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, store.Set(ctx, key, Item[string]{Value: key, Expiration: 1 << 62, Cost: 4}))
+	}
+	assert.Equal(t, int64(12), store.Bytes())
+
+	// Lowering the limit evicts the oldest items
+	store.SetMaxBytes(5)
+	size, _ := store.Len(ctx)
+	assert.Equal(t, 1, size)
+	_, found, _ := store.Get(ctx, "c")
+	assert.True(t, found)
+
+	err := store.Set(ctx, "d", Item[string]{Value: "d", Expiration: 1 << 62, Cost: 6})
+	assert.Error(t, err)
+
+	store.SetMaxBytes(-1)
+	require.NoError(t, store.Set(ctx, "d", Item[string]{Value: "d", Expiration: 1 << 62, Cost: 6}))
+	assert.Equal(t, int64(10), store.Bytes())
+}
+
+func TestConfig_WithMaxBytes(t *testing.T) {
+	assert.Equal(t, int64(0), DefaultConfig().MaxBytes)
+	assert.Equal(t, int64(1024), DefaultConfig().WithMaxBytes(1024).MaxBytes)
+	assert.Equal(t, int64(0), DefaultConfig().WithMaxBytes(-1).MaxBytes)
+
+	size, err := measurement.ParseMemSize("256MB")
+	require.NoError(t, err)
+	assert.Equal(t, int64(256*1024*1024), DefaultConfig().WithMaxMemory(size).MaxBytes)
+}
+
+func TestCache_SetCostNilCache(t *testing.T) {
+	var cache *Cache[string]
+	assert.NotPanics(t, func() {
+		cache.SetCost(func(value string) int64 { return 1 })
+	})
+}
@@ -2,22 +2,32 @@
 
 // Package cache provides functionality for caching frequently accessed data.
 //
-// This package implements a generic in-memory cache with expiration, supporting
-// various eviction strategies and thread-safe operations. It is designed to be
+// This package implements a generic cache with expiration, supporting
+// various eviction strategies, pluggable storage backends, and thread-safe operations. It is designed to be
 // flexible and easy to use, with configurable time-to-live (TTL) for cache items,
 // maximum size limits, and automatic cleanup of expired items.
 //
 // Key features:
 //   - Generic implementation that works with any data type
 //   - Configurable item expiration (TTL)
-//   - Maximum size limits with various eviction strategies
+//   - Item count and byte cost limits with various eviction strategies
 //   - Thread-safe operations for concurrent access
 //   - Automatic cleanup of expired items
-//   - Integration with OpenTelemetry for tracing
+//   - Pluggable stores: in-process by default, or shared through a Redis-protocol server
+//   - Stale-while-revalidate and refresh-ahead background reloading
+//   - Tag-based and prefix-based invalidation
+//   - Two-tier caching with invalidations broadcast between replicas
+//   - Integration with OpenTelemetry for tracing and metrics
+//   - Hit, miss, eviction, and load statistics through Stats
 //   - Comprehensive logging of cache operations
+//   - A clock.Clock option, so that tests can advance time instead of waiting
 //
 // The package provides several main components:
-//   - Cache: A generic in-memory cache with expiration
+//   - Cache: A generic cache with expiration
+//   - Store: The storage backend, implemented by MemoryStore and RedisStore
+//   - Codec: Converts values to and from bytes for out-of-process stores
+//   - Tiered: An in-process cache in front of a shared cache
+//   - PubSub: Carries invalidations between Tiered caches, implemented in-process by MemoryPubSub
 //   - Config: Configuration for cache behavior
 //   - Options: Additional options for logging and tracing
 //   - EvictionStrategy: Different strategies for evicting items when the cache is full
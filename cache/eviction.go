@@ -0,0 +1,321 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"container/list"
+	"math/rand"
+)
+
+// String returns a string representation of the eviction strategy.
+// This method implements the fmt.Stringer interface, allowing EvictionStrategy values
+// to be easily formatted in log messages and trace attributes.
+//
+// Returns:
+//   - A human-readable string representing the strategy: "LRU", "LFU", "FIFO", "Random", or "Unknown".
+func (s EvictionStrategy) String() string {
+	switch s {
+	case LRU:
+		return "LRU"
+	case LFU:
+		return "LFU"
+	case FIFO:
+		return "FIFO"
+	case Random:
+		return "Random"
+	default:
+		return "Unknown"
+	}
+}
+
+// evictionPolicy tracks the bookkeeping required to select an eviction victim.
+// All operations run in O(1) time. Implementations are not thread-safe; the cache
+// calls them while holding its write lock.
+type evictionPolicy interface {
+	// add records a newly inserted key.
+	add(key string)
+
+	// access records a read or an overwrite of an existing key.
+	access(key string)
+
+	// remove forgets a key that has been deleted, expired, or evicted.
+	remove(key string)
+
+	// victim returns the key that should be evicted next, if any.
+	victim() (string, bool)
+
+	// victimExcept returns the key that should be evicted next other than keep,
+	// if any. It lets an item be overwritten without evicting the item itself
+	// or losing its place in the eviction order.
+	victimExcept(keep string) (string, bool)
+
+	// reset forgets all keys.
+	reset()
+}
+
+// newEvictionPolicy creates the eviction policy for the given strategy.
+// Unknown strategies fall back to LRU, which is the cache's default.
+func newEvictionPolicy(strategy EvictionStrategy) evictionPolicy {
+	switch strategy {
+	case LFU:
+		return newLFUPolicy()
+	case FIFO:
+		return newListPolicy(false)
+	case Random:
+		return newRandomPolicy()
+	default:
+		return newListPolicy(true)
+	}
+}
+
+// listPolicy implements LRU and FIFO eviction with a doubly linked list.
+// The front of the list holds the newest (or most recently used) key and the
+// back holds the next victim.
+type listPolicy struct {
+	order *list.List
+	items map[string]*list.Element
+
+	// promote determines whether accesses move a key to the front (LRU)
+	// or leave insertion order untouched (FIFO).
+	promote bool
+}
+
+// newListPolicy creates an LRU policy when promote is true and a FIFO policy otherwise.
+func newListPolicy(promote bool) *listPolicy {
+	return &listPolicy{
+		order:   list.New(),
+		items:   make(map[string]*list.Element),
+		promote: promote,
+	}
+}
+
+func (p *listPolicy) add(key string) {
+	if elem, found := p.items[key]; found {
+		p.order.MoveToFront(elem)
+		return
+	}
+	p.items[key] = p.order.PushFront(key)
+}
+
+func (p *listPolicy) access(key string) {
+	if !p.promote {
+		return
+	}
+	if elem, found := p.items[key]; found {
+		p.order.MoveToFront(elem)
+	}
+}
+
+func (p *listPolicy) remove(key string) {
+	if elem, found := p.items[key]; found {
+		p.order.Remove(elem)
+		delete(p.items, key)
+	}
+}
+
+func (p *listPolicy) victim() (string, bool) {
+	elem := p.order.Back()
+	if elem == nil {
+		return "", false
+	}
+	return elem.Value.(string), true
+}
+
+func (p *listPolicy) victimExcept(keep string) (string, bool) {
+	elem := p.order.Back()
+	if elem != nil && elem.Value.(string) == keep {
+		elem = elem.Prev()
+	}
+	if elem == nil {
+		return "", false
+	}
+	return elem.Value.(string), true
+}
+
+func (p *listPolicy) reset() {
+	p.order.Init()
+	p.items = make(map[string]*list.Element)
+}
+
+// lfuBucket groups all keys that have been accessed the same number of times.
+// Keys within a bucket are ordered from least to most recently used, so ties
+// between equally frequent keys are broken by recency.
+type lfuBucket struct {
+	count   int
+	entries *list.List
+}
+
+// lfuEntry is the value stored in a bucket's entry list.
+type lfuEntry struct {
+	key    string
+	bucket *list.Element
+}
+
+// lfuPolicy implements O(1) LFU eviction using a list of frequency buckets
+// ordered by ascending access count.
+type lfuPolicy struct {
+	buckets *list.List
+	items   map[string]*list.Element
+}
+
+// newLFUPolicy creates an empty LFU policy.
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{
+		buckets: list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+func (p *lfuPolicy) add(key string) {
+	if _, found := p.items[key]; found {
+		p.access(key)
+		return
+	}
+
+	front := p.buckets.Front()
+	if front == nil || front.Value.(*lfuBucket).count != 1 {
+		front = p.buckets.PushFront(&lfuBucket{count: 1, entries: list.New()})
+	}
+
+	entry := &lfuEntry{key: key, bucket: front}
+	p.items[key] = front.Value.(*lfuBucket).entries.PushBack(entry)
+}
+
+func (p *lfuPolicy) access(key string) {
+	elem, found := p.items[key]
+	if !found {
+		return
+	}
+
+	entry := elem.Value.(*lfuEntry)
+	current := entry.bucket.Value.(*lfuBucket)
+
+	next := entry.bucket.Next()
+	if next == nil || next.Value.(*lfuBucket).count != current.count+1 {
+		next = p.buckets.InsertAfter(&lfuBucket{count: current.count + 1, entries: list.New()}, entry.bucket)
+	}
+
+	current.entries.Remove(elem)
+	if current.entries.Len() == 0 {
+		p.buckets.Remove(entry.bucket)
+	}
+
+	entry.bucket = next
+	p.items[key] = next.Value.(*lfuBucket).entries.PushBack(entry)
+}
+
+func (p *lfuPolicy) remove(key string) {
+	elem, found := p.items[key]
+	if !found {
+		return
+	}
+
+	entry := elem.Value.(*lfuEntry)
+	bucket := entry.bucket.Value.(*lfuBucket)
+	bucket.entries.Remove(elem)
+	if bucket.entries.Len() == 0 {
+		p.buckets.Remove(entry.bucket)
+	}
+	delete(p.items, key)
+}
+
+func (p *lfuPolicy) victim() (string, bool) {
+	front := p.buckets.Front()
+	if front == nil {
+		return "", false
+	}
+	return front.Value.(*lfuBucket).entries.Front().Value.(*lfuEntry).key, true
+}
+
+func (p *lfuPolicy) victimExcept(keep string) (string, bool) {
+	front := p.buckets.Front()
+	if front == nil {
+		return "", false
+	}
+
+	elem := front.Value.(*lfuBucket).entries.Front()
+	if elem.Value.(*lfuEntry).key == keep {
+		// The next victim is the next key in the same bucket, or the first key
+		// of the next bucket
+		elem = elem.Next()
+		if elem == nil {
+			next := front.Next()
+			if next == nil {
+				return "", false
+			}
+			elem = next.Value.(*lfuBucket).entries.Front()
+		}
+	}
+	return elem.Value.(*lfuEntry).key, true
+}
+
+func (p *lfuPolicy) reset() {
+	p.buckets.Init()
+	p.items = make(map[string]*list.Element)
+}
+
+// randomPolicy implements O(1) random eviction by keeping keys in a dense slice
+// and removing them with a swap-with-last operation.
+type randomPolicy struct {
+	keys  []string
+	index map[string]int
+}
+
+// newRandomPolicy creates an empty random policy.
+func newRandomPolicy() *randomPolicy {
+	return &randomPolicy{
+		index: make(map[string]int),
+	}
+}
+
+func (p *randomPolicy) add(key string) {
+	if _, found := p.index[key]; found {
+		return
+	}
+	p.index[key] = len(p.keys)
+	p.keys = append(p.keys, key)
+}
+
+func (p *randomPolicy) access(key string) {}
+
+func (p *randomPolicy) remove(key string) {
+	i, found := p.index[key]
+	if !found {
+		return
+	}
+
+	last := len(p.keys) - 1
+	p.keys[i] = p.keys[last]
+	p.index[p.keys[i]] = i
+	p.keys = p.keys[:last]
+	delete(p.index, key)
+}
+
+func (p *randomPolicy) victim() (string, bool) {
+	if len(p.keys) == 0 {
+		return "", false
+	}
+	return p.keys[rand.Intn(len(p.keys))], true
+}
+
+func (p *randomPolicy) victimExcept(keep string) (string, bool) {
+	i, found := p.index[keep]
+	if !found {
+		return p.victim()
+	}
+	if len(p.keys) == 1 {
+		return "", false
+	}
+
+	// Pick uniformly among the other keys by skipping over keep's index
+	j := rand.Intn(len(p.keys) - 1)
+	if j >= i {
+		j++
+	}
+	return p.keys[j], true
+}
+
+func (p *randomPolicy) reset() {
+	p.keys = nil
+	p.index = make(map[string]int)
+}
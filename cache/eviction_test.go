@@ -0,0 +1,265 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newEvictionTestCache(t *testing.T, strategy EvictionStrategy, maxSize int) *Cache[string] {
+	t.Helper()
+
+	cfg := DefaultConfig().
+		WithEnabled(true).
+		WithTTL(1 * time.Hour).
+		WithMaxSize(maxSize).
+		WithEvictionStrategy(strategy)
+
+	cache := NewCache[string](cfg, DefaultOptions().WithName("eviction-test"))
+	assert.NotNil(t, cache)
+	t.Cleanup(cache.Shutdown)
+	return cache
+}
+
+func TestEvictionStrategy_String(t *testing.T) {
+	assert.Equal(t, "LRU", LRU.String())
+	assert.Equal(t, "LFU", LFU.String())
+	assert.Equal(t, "FIFO", FIFO.String())
+	assert.Equal(t, "Random", Random.String())
+	assert.Equal(t, "Unknown", EvictionStrategy(42).String())
+}
+
+func TestConfig_WithEvictionStrategy(t *testing.T) {
+	assert.Equal(t, LRU, DefaultConfig().EvictionStrategy)
+	assert.Equal(t, LFU, DefaultConfig().WithEvictionStrategy(LFU).EvictionStrategy)
+	assert.Equal(t, LRU, DefaultConfig().WithEvictionStrategy(EvictionStrategy(-1)).EvictionStrategy)
+	assert.Equal(t, LRU, DefaultConfig().WithEvictionStrategy(EvictionStrategy(42)).EvictionStrategy)
+}
+
+func TestCache_EvictionLRU(t *testing.T) {
+	cache := newEvictionTestCache(t, LRU, 3)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	cache.Set(ctx, "key3", "value3")
+
+	// Touch key1 so that key2 becomes the least recently used item
+	_, found := cache.Get(ctx, "key1")
+	assert.True(t, found)
+
+	cache.Set(ctx, "key4", "value4")
+
+	_, found = cache.Get(ctx, "key2")
+	assert.False(t, found)
+	for _, key := range []string{"key1", "key3", "key4"} {
+		_, found := cache.Get(ctx, key)
+		assert.True(t, found, key)
+	}
+}
+
+func TestCache_EvictionLRU_OverwriteCountsAsUse(t *testing.T) {
+	cache := newEvictionTestCache(t, LRU, 2)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	cache.Set(ctx, "key1", "value1-updated")
+	cache.Set(ctx, "key3", "value3")
+
+	_, found := cache.Get(ctx, "key2")
+	assert.False(t, found)
+
+	value, found := cache.Get(ctx, "key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1-updated", value)
+}
+
+func TestCache_EvictionLFU(t *testing.T) {
+	cache := newEvictionTestCache(t, LFU, 3)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	cache.Set(ctx, "key3", "value3")
+
+	// key1 is read twice, key3 once, key2 never
+	cache.Get(ctx, "key1")
+	cache.Get(ctx, "key1")
+	cache.Get(ctx, "key3")
+
+	cache.Set(ctx, "key4", "value4")
+	_, found := cache.Get(ctx, "key2")
+	assert.False(t, found)
+
+	// key4 is now the least frequently used item
+	cache.Set(ctx, "key5", "value5")
+	_, found = cache.Get(ctx, "key4")
+	assert.False(t, found)
+
+	for _, key := range []string{"key1", "key3", "key5"} {
+		_, found := cache.Get(ctx, key)
+		assert.True(t, found, key)
+	}
+}
+
+func TestCache_EvictionLFU_TiesBrokenByRecency(t *testing.T) {
+	cache := newEvictionTestCache(t, LFU, 2)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	cache.Get(ctx, "key2")
+	cache.Get(ctx, "key1")
+
+	// Both keys have the same frequency; key2 was used least recently
+	cache.Set(ctx, "key3", "value3")
+
+	_, found := cache.Get(ctx, "key2")
+	assert.False(t, found)
+	_, found = cache.Get(ctx, "key1")
+	assert.True(t, found)
+}
+
+func TestCache_EvictionFIFO(t *testing.T) {
+	cache := newEvictionTestCache(t, FIFO, 3)
+	ctx := context.Background()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	cache.Set(ctx, "key3", "value3")
+
+	// Reads and overwrites do not change insertion order
+	cache.Get(ctx, "key1")
+	cache.Set(ctx, "key1", "value1-updated")
+
+	cache.Set(ctx, "key4", "value4")
+
+	_, found := cache.Get(ctx, "key1")
+	assert.False(t, found)
+	for _, key := range []string{"key2", "key3", "key4"} {
+		_, found := cache.Get(ctx, key)
+		assert.True(t, found, key)
+	}
+}
+
+func TestCache_EvictionRandom(t *testing.T) {
+	cache := newEvictionTestCache(t, Random, 10)
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		cache.Set(ctx, fmt.Sprintf("key%d", i), "value")
+		assert.LessOrEqual(t, cache.Size(), 10)
+	}
+
+	// The most recently written key is always present
+	_, found := cache.Get(ctx, "key99")
+	assert.True(t, found)
+	assert.Equal(t, 10, cache.Size())
+}
+
+func TestCache_EvictionBookkeepingAfterDeleteAndClear(t *testing.T) {
+	for _, strategy := range []EvictionStrategy{LRU, LFU, FIFO, Random} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			cache := newEvictionTestCache(t, strategy, 2)
+			ctx := context.Background()
+
+			cache.Set(ctx, "key1", "value1")
+			cache.Set(ctx, "key2", "value2")
+			cache.Delete(ctx, "key1")
+
+			// There is room again, so nothing is evicted
+			cache.Set(ctx, "key3", "value3")
+			assert.Equal(t, 2, cache.Size())
+			_, found := cache.Get(ctx, "key2")
+			assert.True(t, found)
+
+			cache.Clear(ctx)
+			cache.Set(ctx, "key4", "value4")
+			cache.Set(ctx, "key5", "value5")
+			assert.Equal(t, 2, cache.Size())
+
+			cache.Set(ctx, "key6", "value6")
+			assert.Equal(t, 2, cache.Size())
+		})
+	}
+}
+
+func TestLFUPolicy_Buckets(t *testing.T) {
+	p := newLFUPolicy()
+
+	_, found := p.victim()
+	assert.False(t, found)
+
+	p.add("a")
+	p.add("b")
+	p.access("a")
+	p.access("a")
+	p.access("b")
+
+	// Buckets: b(2), a(3)
+	assert.Equal(t, 2, p.buckets.Len())
+	victim, found := p.victim()
+	assert.True(t, found)
+	assert.Equal(t, "b", victim)
+
+	p.remove("b")
+	assert.Equal(t, 1, p.buckets.Len())
+	victim, _ = p.victim()
+	assert.Equal(t, "a", victim)
+
+	p.reset()
+	_, found = p.victim()
+	assert.False(t, found)
+}
+
+func TestEvictionPolicy_VictimExcept(t *testing.T) {
+	for _, strategy := range []EvictionStrategy{LRU, LFU, FIFO, Random} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			p := newEvictionPolicy(strategy)
+			_, found := p.victimExcept("a")
+			assert.False(t, found)
+
+			p.add("a")
+			_, found = p.victimExcept("a")
+			assert.False(t, found)
+			victim, found := p.victimExcept("missing")
+			assert.True(t, found)
+			assert.Equal(t, "a", victim)
+
+			p.add("b")
+			for i := 0; i < 10; i++ {
+				victim, found = p.victimExcept("a")
+				assert.True(t, found)
+				assert.Equal(t, "b", victim)
+			}
+		})
+	}
+
+	// An LFU key alone in its bucket is skipped for the next bucket
+	p := newLFUPolicy()
+	p.add("a")
+	p.add("b")
+	p.access("b")
+	victim, _ := p.victimExcept("a")
+	assert.Equal(t, "b", victim)
+}
+
+func TestRandomPolicy_Remove(t *testing.T) {
+	p := newRandomPolicy()
+	p.add("a")
+	p.add("b")
+	p.add("c")
+	p.add("a")
+	assert.Len(t, p.keys, 3)
+
+	p.remove("a")
+	p.remove("missing")
+	assert.Len(t, p.keys, 2)
+	assert.Equal(t, map[string]int{"c": 0, "b": 1}, p.index)
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+// tagIndex maps each tag to the set of keys stored with it.
+// It is not safe for concurrent use; MemoryStore guards it with its lock.
+type tagIndex struct {
+	keys map[string]map[string]struct{}
+}
+
+// newTagIndex creates an empty tag index.
+func newTagIndex() *tagIndex {
+	return &tagIndex{keys: make(map[string]map[string]struct{})}
+}
+
+// add records that key is stored with each of tags.
+func (t *tagIndex) add(key string, tags []string) {
+	for _, tag := range tags {
+		keys, found := t.keys[tag]
+		if !found {
+			keys = make(map[string]struct{})
+			t.keys[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// remove forgets that key is stored with each of tags.
+func (t *tagIndex) remove(key string, tags []string) {
+	for _, tag := range tags {
+		keys, found := t.keys[tag]
+		if !found {
+			continue
+		}
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(t.keys, tag)
+		}
+	}
+}
+
+// lookup returns the keys stored with tag.
+func (t *tagIndex) lookup(tag string) []string {
+	keys := make([]string, 0, len(t.keys[tag]))
+	for key := range t.keys[tag] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// reset removes all entries from the index.
+func (t *tagIndex) reset() {
+	t.keys = make(map[string]map[string]struct{})
+}
+
+// prefixNode is a node of a prefixIndex trie.
+type prefixNode struct {
+	children map[byte]*prefixNode
+	terminal bool
+}
+
+// prefixIndex is a byte trie of keys that finds all keys with a given prefix
+// in time proportional to the prefix length and the number of matches.
+// It is not safe for concurrent use; MemoryStore guards it with its lock.
+type prefixIndex struct {
+	root *prefixNode
+}
+
+// newPrefixIndex creates an empty prefix index.
+func newPrefixIndex() *prefixIndex {
+	return &prefixIndex{root: &prefixNode{}}
+}
+
+// add inserts key into the index.
+func (p *prefixIndex) add(key string) {
+	node := p.root
+	for i := 0; i < len(key); i++ {
+		if node.children == nil {
+			node.children = make(map[byte]*prefixNode)
+		}
+		child, found := node.children[key[i]]
+		if !found {
+			child = &prefixNode{}
+			node.children[key[i]] = child
+		}
+		node = child
+	}
+	node.terminal = true
+}
+
+// remove deletes key from the index and prunes nodes that no longer lead to a key.
+func (p *prefixIndex) remove(key string) {
+	path := make([]*prefixNode, 0, len(key)+1)
+	node := p.root
+	path = append(path, node)
+	for i := 0; i < len(key); i++ {
+		child, found := node.children[key[i]]
+		if !found {
+			return
+		}
+		node = child
+		path = append(path, node)
+	}
+	if !node.terminal {
+		return
+	}
+	node.terminal = false
+
+	for i := len(key); i > 0; i-- {
+		n := path[i]
+		if n.terminal || len(n.children) > 0 {
+			return
+		}
+		delete(path[i-1].children, key[i-1])
+	}
+}
+
+// lookup returns all keys that start with prefix.
+func (p *prefixIndex) lookup(prefix string) []string {
+	node := p.root
+	for i := 0; i < len(prefix); i++ {
+		child, found := node.children[prefix[i]]
+		if !found {
+			return nil
+		}
+		node = child
+	}
+
+	var keys []string
+	buf := []byte(prefix)
+	var walk func(n *prefixNode)
+	walk = func(n *prefixNode) {
+		if n.terminal {
+			keys = append(keys, string(buf))
+		}
+		for b, child := range n.children {
+			buf = append(buf, b)
+			walk(child)
+			buf = buf[:len(buf)-1]
+		}
+	}
+	walk(node)
+	return keys
+}
+
+// reset removes all keys from the index.
+func (p *prefixIndex) reset() {
+	p.root = &prefixNode{}
+}
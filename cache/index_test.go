@@ -0,0 +1,57 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagIndex(t *testing.T) {
+	index := newTagIndex()
+	index.add("key1", []string{"tenant:1", "user"})
+	index.add("key2", []string{"tenant:1"})
+
+	assert.ElementsMatch(t, []string{"key1", "key2"}, index.lookup("tenant:1"))
+	assert.Equal(t, []string{"key1"}, index.lookup("user"))
+	assert.Empty(t, index.lookup("missing"))
+
+	index.remove("key1", []string{"tenant:1", "user"})
+	assert.Equal(t, []string{"key2"}, index.lookup("tenant:1"))
+	_, found := index.keys["user"]
+	assert.False(t, found, "empty tags should be removed")
+
+	index.reset()
+	assert.Empty(t, index.lookup("tenant:1"))
+}
+
+func TestPrefixIndex(t *testing.T) {
+	index := newPrefixIndex()
+	for _, key := range []string{"tenant:1:user:1", "tenant:1:user:2", "tenant:10:user:1", "tenant:2", ""} {
+		index.add(key)
+	}
+
+	assert.ElementsMatch(t, []string{"tenant:1:user:1", "tenant:1:user:2"}, index.lookup("tenant:1:"))
+	assert.ElementsMatch(t, []string{"tenant:1:user:1", "tenant:1:user:2", "tenant:10:user:1"}, index.lookup("tenant:1"))
+	assert.Len(t, index.lookup(""), 5)
+	assert.Empty(t, index.lookup("tenant:3"))
+
+	// Removing a key prunes the nodes that only led to it
+	index.remove("tenant:10:user:1")
+	assert.Empty(t, index.lookup("tenant:10"))
+	assert.NotContains(t, index.root.children['t'].children['e'].children['n'].children['a'].children['n'].children['t'].children[':'].children['1'].children, byte('0'))
+
+	// Removing a prefix of another key keeps the longer key
+	index.add("tenant")
+	index.remove("tenant")
+	assert.Len(t, index.lookup("tenant"), 3)
+
+	// Removing unknown keys is a no-op
+	index.remove("unknown")
+	index.remove("tenant:")
+	assert.Len(t, index.lookup(""), 4)
+
+	index.reset()
+	assert.Empty(t, index.lookup(""))
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/abitofhelp/servicelib/errors"
+)
+
+// PubSub carries messages between replicas of a service. Tiered uses it to
+// broadcast invalidations so that every replica drops stale items from its
+// in-process tier. Implementations must be safe for concurrent use by multiple
+// goroutines.
+type PubSub interface {
+	// Publish sends payload to every subscriber of channel, including
+	// subscribers in the publishing process.
+	Publish(ctx context.Context, channel string, payload []byte) error
+
+	// Subscribe calls handler with the payload of every message published to
+	// channel until the returned subscription is closed. Handlers may be called
+	// concurrently and should return quickly.
+	Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (Subscription, error)
+}
+
+// Subscription is an active PubSub subscription.
+type Subscription interface {
+	// Close stops delivering messages to the subscription's handler.
+	Close() error
+}
+
+// MemoryPubSub is an in-process PubSub. Messages are delivered synchronously
+// to every handler before Publish returns, which makes it suitable for tests
+// and for connecting several Tiered caches inside one process.
+type MemoryPubSub struct {
+	// mu protects the subscribers map for concurrent access
+	mu sync.RWMutex
+
+	// subscribers holds the handlers of each channel by subscription
+	subscribers map[string]map[*memorySubscription]func(payload []byte)
+
+	// closed is set once Close has been called
+	closed bool
+}
+
+// NewMemoryPubSub creates an in-process PubSub with no subscribers.
+//
+// Returns:
+//   - *MemoryPubSub: A new PubSub.
+func NewMemoryPubSub() *MemoryPubSub {
+	return &MemoryPubSub{
+		subscribers: make(map[string]map[*memorySubscription]func(payload []byte)),
+	}
+}
+
+// Publish delivers payload to every subscriber of channel.
+func (p *MemoryPubSub) Publish(ctx context.Context, channel string, payload []byte) error {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return errors.New(errors.InternalErrorCode, "pub/sub is closed")
+	}
+	handlers := make([]func(payload []byte), 0, len(p.subscribers[channel]))
+	for _, handler := range p.subscribers[channel] {
+		handlers = append(handlers, handler)
+	}
+	p.mu.RUnlock()
+
+	// Deliver outside the lock so that handlers may publish or subscribe
+	for _, handler := range handlers {
+		data := make([]byte, len(payload))
+		copy(data, payload)
+		handler(data)
+	}
+	return nil
+}
+
+// Subscribe registers handler for the messages published to channel.
+func (p *MemoryPubSub) Subscribe(ctx context.Context, channel string, handler func(payload []byte)) (Subscription, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, errors.New(errors.InternalErrorCode, "pub/sub is closed")
+	}
+
+	sub := &memorySubscription{pubsub: p, channel: channel}
+	if p.subscribers[channel] == nil {
+		p.subscribers[channel] = make(map[*memorySubscription]func(payload []byte))
+	}
+	p.subscribers[channel][sub] = handler
+	return sub, nil
+}
+
+// Close removes every subscription. Later calls to Publish and Subscribe fail.
+func (p *MemoryPubSub) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	p.subscribers = make(map[string]map[*memorySubscription]func(payload []byte))
+	return nil
+}
+
+// memorySubscription is a subscription to a MemoryPubSub channel.
+type memorySubscription struct {
+	pubsub  *MemoryPubSub
+	channel string
+}
+
+// Close removes the subscription from its channel.
+func (s *memorySubscription) Close() error {
+	s.pubsub.mu.Lock()
+	defer s.pubsub.mu.Unlock()
+
+	subscribers := s.pubsub.subscribers[s.channel]
+	delete(subscribers, s)
+	if len(subscribers) == 0 {
+		delete(s.pubsub.subscribers, s.channel)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryPubSub(t *testing.T) {
+	ctx := context.Background()
+	pubsub := NewMemoryPubSub()
+
+	var received1, received2 []string
+	sub1, err := pubsub.Subscribe(ctx, "events", func(payload []byte) {
+		received1 = append(received1, string(payload))
+	})
+	require.NoError(t, err)
+	_, err = pubsub.Subscribe(ctx, "events", func(payload []byte) {
+		received2 = append(received2, string(payload))
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, pubsub.Publish(ctx, "events", []byte("one")))
+	require.NoError(t, pubsub.Publish(ctx, "other", []byte("ignored")))
+
+	require.NoError(t, sub1.Close())
+	require.NoError(t, pubsub.Publish(ctx, "events", []byte("two")))
+
+	assert.Equal(t, []string{"one"}, received1)
+	assert.Equal(t, []string{"one", "two"}, received2)
+
+	require.NoError(t, pubsub.Close())
+	assert.Error(t, pubsub.Publish(ctx, "events", []byte("three")))
+	_, err = pubsub.Subscribe(ctx, "events", func(payload []byte) {})
+	assert.Error(t, err)
+}
+
+func TestMemoryPubSub_HandlerCanPublish(t *testing.T) {
+	ctx := context.Background()
+	pubsub := NewMemoryPubSub()
+
+	var echoed []string
+	_, err := pubsub.Subscribe(ctx, "ping", func(payload []byte) {
+		_ = pubsub.Publish(ctx, "pong", payload)
+	})
+	require.NoError(t, err)
+	_, err = pubsub.Subscribe(ctx, "pong", func(payload []byte) {
+		echoed = append(echoed, string(payload))
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, pubsub.Publish(ctx, "ping", []byte("hello")))
+	assert.Equal(t, []string{"hello"}, echoed)
+}
@@ -0,0 +1,379 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/resp"
+)
+
+// itemHeaderSize is the size of the fixed header stored before each encoded value.
+// It holds the item's expiration, creation and stale-until timestamps.
+const itemHeaderSize = 24
+
+// tagKeyMarker separates the store's prefix from a tag name in the keys of the
+// sets that index items by tag. The NUL byte keeps tag keys apart from item keys.
+const tagKeyMarker = "\x00tag:"
+
+// defaultRedisPrefix is the key prefix used by a RedisStore created with an empty prefix.
+// Clear and Len scan the keys with the store's prefix, so without one they would
+// operate on the whole database.
+const defaultRedisPrefix = "cache:"
+
+// setScriptSource stores an item and adds its key to the sets of its tags in one
+// atomic step, so that a tagged item is never visible without its tag entries.
+// Tag sets are extended to live at least as long as the item.
+//
+// KEYS[1] is the item key. ARGV[1] is the encoded item, ARGV[2] its time to live
+// in milliseconds, ARGV[3] the cache key added to the tag sets, and ARGV[4..]
+// the keys of the tag sets.
+const setScriptSource = `
+local ttl = tonumber(ARGV[2])
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ttl)
+for i = 4, #ARGV do
+  redis.call('SADD', ARGV[i], ARGV[3])
+  if redis.call('PTTL', ARGV[i]) < ttl then
+    redis.call('PEXPIRE', ARGV[i], ttl)
+  end
+end
+return 1
+`
+
+// setScript is the script that stores tagged items.
+var setScript = resp.NewScript(1, setScriptSource)
+
+// RedisStore is a Store backed by a server that speaks the Redis protocol.
+// It allows multiple replicas of a service to share cached data.
+//
+// Each item is stored as a single string key made of the store's prefix and the
+// cache key. The value is the item's expiration, creation and stale-until times
+// as big-endian Unix nanosecond timestamps, then the item's tags as a big-endian
+// uint16 count followed by length-prefixed strings, then the value encoded with
+// the store's codec. Keys are written with a PX expiry covering the stale period,
+// so the server removes expired items itself.
+//
+// Each tag also has a set under the store's prefix that holds the keys stored
+// with it. Tag sets are not trimmed when an item is overwritten without the tag,
+// so DeleteTag may remove such items as well; for a cache this only costs an
+// extra miss. A tagged item and its tag entries are written atomically by a
+// Lua script.
+type RedisStore[T any] struct {
+	client *resp.Client
+	codec  Codec[T]
+	prefix string
+	clock  clock.Clock
+}
+
+// NewRedisStore creates a new store backed by a RESP server.
+// The store does not own the client; the caller is responsible for closing it.
+//
+// Type Parameters:
+//   - T: The type of values to be stored.
+//
+// Parameters:
+//   - client: The client used to talk to the server.
+//   - codec: The codec used to encode values. If nil, JSONCodec is used.
+//   - prefix: A prefix added to every key, such as "cache:users:". Clear and Len
+//     only operate on keys with this prefix. If empty, "cache:" is used.
+//
+// Returns:
+//   - *RedisStore[T]: A new store.
+func NewRedisStore[T any](client *resp.Client, codec Codec[T], prefix string) *RedisStore[T] {
+	if codec == nil {
+		codec = JSONCodec[T]{}
+	}
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+	return &RedisStore[T]{
+		client: client,
+		codec:  codec,
+		prefix: prefix,
+		clock:  clock.New(),
+	}
+}
+
+// SetClock sets the clock used to compute the time to live of items stored
+// without a creation time. Items written by a Cache always have one, so their
+// time to live follows the cache's clock. Call it before the store is used.
+//
+// Parameters:
+//   - c: The clock. If nil, the system clock is used.
+func (s *RedisStore[T]) SetClock(c clock.Clock) {
+	s.clock = clock.OrReal(c)
+}
+
+// Get returns the item stored under key.
+func (s *RedisStore[T]) Get(ctx context.Context, key string) (Item[T], bool, error) {
+	data, err := resp.Bytes(s.client.Do(ctx, "GET", s.prefix+key))
+	if err != nil {
+		if errors.Is(err, resp.ErrNil) {
+			return Item[T]{}, false, nil
+		}
+		return Item[T]{}, false, err
+	}
+
+	if len(data) < itemHeaderSize {
+		return Item[T]{}, false, errors.New(errors.DataCorruptionCode, "cached item is too short to contain an item header")
+	}
+
+	tags, payload, err := decodeTags(data[itemHeaderSize:])
+	if err != nil {
+		return Item[T]{}, false, err
+	}
+
+	value, err := s.codec.Unmarshal(payload)
+	if err != nil {
+		return Item[T]{}, false, errors.Wrap(err, errors.DataCorruptionCode, "failed to decode cached value")
+	}
+
+	return Item[T]{
+		Value:      value,
+		Expiration: int64(binary.BigEndian.Uint64(data[0:8])),
+		Created:    int64(binary.BigEndian.Uint64(data[8:16])),
+		StaleUntil: int64(binary.BigEndian.Uint64(data[16:24])),
+		Tags:       tags,
+	}, true, nil
+}
+
+// Set stores an item. Items that are already expired are deleted instead.
+func (s *RedisStore[T]) Set(ctx context.Context, key string, item Item[T]) error {
+	ttl := s.ttl(item)
+	if ttl < time.Millisecond {
+		_, err := s.Delete(ctx, key)
+		return err
+	}
+
+	encoded, err := s.codec.Marshal(item.Value)
+	if err != nil {
+		return errors.Wrap(err, errors.InvalidInputCode, "failed to encode cached value")
+	}
+
+	data := make([]byte, itemHeaderSize, itemHeaderSize+len(encoded))
+	binary.BigEndian.PutUint64(data[0:8], uint64(item.Expiration))
+	binary.BigEndian.PutUint64(data[8:16], uint64(item.Created))
+	binary.BigEndian.PutUint64(data[16:24], uint64(item.StaleUntil))
+	data, err = encodeTags(data, item.Tags)
+	if err != nil {
+		return err
+	}
+	data = append(data, encoded...)
+
+	if len(item.Tags) == 0 {
+		_, err = s.client.Do(ctx, "SET", s.prefix+key, data, "PX", ttl.Milliseconds())
+		return err
+	}
+
+	args := make([]interface{}, 0, len(item.Tags)+4)
+	args = append(args, s.prefix+key, data, ttl.Milliseconds(), key)
+	for _, tag := range item.Tags {
+		args = append(args, s.prefix+tagKeyMarker+tag)
+	}
+	_, err = setScript.Do(ctx, s.client, args...)
+	return err
+}
+
+// ttl returns how long the server should keep item. It is measured from the
+// item's creation time rather than the wall clock, so that it is right for a
+// cache with an injected clock.
+func (s *RedisStore[T]) ttl(item Item[T]) time.Duration {
+	from := item.Created
+	if from == 0 {
+		from = s.clock.Now().UnixNano()
+	}
+	return time.Duration(item.retainUntil() - from)
+}
+
+// Delete removes the item stored under key.
+func (s *RedisStore[T]) Delete(ctx context.Context, key string) (bool, error) {
+	n, err := resp.Int64(s.client.Do(ctx, "DEL", s.prefix+key))
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Clear removes all items whose keys start with the store's prefix.
+func (s *RedisStore[T]) Clear(ctx context.Context) error {
+	return s.scan(ctx, func(keys []interface{}) error {
+		if len(keys) == 0 {
+			return nil
+		}
+		args := append([]interface{}{"DEL"}, keys...)
+		_, err := s.client.Do(ctx, args...)
+		return err
+	})
+}
+
+// Len returns the number of items whose keys start with the store's prefix.
+func (s *RedisStore[T]) Len(ctx context.Context) (int, error) {
+	count := 0
+	err := s.scan(ctx, func(keys []interface{}) error {
+		for _, key := range keys {
+			if !s.isTagKey(key) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// DeleteTag removes all items in the set of tag, and the set itself.
+func (s *RedisStore[T]) DeleteTag(ctx context.Context, tag string) (int, error) {
+	tagKey := s.prefix + tagKeyMarker + tag
+	members, err := resp.Strings(s.client.Do(ctx, "SMEMBERS", tagKey))
+	if err != nil {
+		return 0, err
+	}
+
+	args := make([]interface{}, 0, len(members)+2)
+	args = append(args, "DEL")
+	for _, member := range members {
+		args = append(args, s.prefix+member)
+	}
+
+	removed := int64(0)
+	if len(members) > 0 {
+		removed, err = resp.Int64(s.client.Do(ctx, args...))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := s.client.Do(ctx, "DEL", tagKey); err != nil {
+		return int(removed), err
+	}
+	return int(removed), nil
+}
+
+// DeletePrefix removes all items whose keys start with prefix.
+func (s *RedisStore[T]) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	removed := 0
+	err := s.scanMatch(ctx, s.prefix+prefix, func(keys []interface{}) error {
+		args := make([]interface{}, 0, len(keys)+1)
+		args = append(args, "DEL")
+		for _, key := range keys {
+			if !s.isTagKey(key) {
+				args = append(args, key)
+			}
+		}
+		if len(args) == 1 {
+			return nil
+		}
+
+		n, err := resp.Int64(s.client.Do(ctx, args...))
+		removed += int(n)
+		return err
+	})
+	return removed, err
+}
+
+// isTagKey reports whether a key returned by SCAN holds a tag set.
+func (s *RedisStore[T]) isTagKey(key interface{}) bool {
+	name, err := resp.String(key, nil)
+	return err == nil && strings.HasPrefix(name, s.prefix+tagKeyMarker)
+}
+
+// DeleteExpired is a no-op because the server expires keys itself.
+func (s *RedisStore[T]) DeleteExpired(ctx context.Context, now int64) (int, error) {
+	return 0, nil
+}
+
+// scan iterates over all keys with the store's prefix, one SCAN page at a time.
+func (s *RedisStore[T]) scan(ctx context.Context, fn func(keys []interface{}) error) error {
+	return s.scanMatch(ctx, s.prefix, fn)
+}
+
+// scanMatch iterates over all keys that start with prefix, one SCAN page at a time.
+func (s *RedisStore[T]) scanMatch(ctx context.Context, prefix string, fn func(keys []interface{}) error) error {
+	pattern := escapeGlob(prefix) + "*"
+	cursor := "0"
+	for {
+		page, err := resp.Values(s.client.Do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", 100))
+		if err != nil {
+			return err
+		}
+		if len(page) != 2 {
+			return errors.New(errors.ExternalServiceErrorCode, "unexpected SCAN reply")
+		}
+
+		cursor, err = resp.String(page[0], nil)
+		if err != nil {
+			return err
+		}
+		keys, err := resp.Values(page[1], nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(keys); err != nil {
+			return err
+		}
+
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// encodeTags appends tags to data as a big-endian uint16 count followed by
+// strings that are each prefixed with their big-endian uint16 length.
+func encodeTags(data []byte, tags []string) ([]byte, error) {
+	if len(tags) > math.MaxUint16 {
+		return nil, errors.New(errors.InvalidInputCode, "too many tags for a cached item")
+	}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(tags)))
+	for _, tag := range tags {
+		if len(tag) > math.MaxUint16 {
+			return nil, errors.New(errors.InvalidInputCode, "cache tag is too long")
+		}
+		data = binary.BigEndian.AppendUint16(data, uint16(len(tag)))
+		data = append(data, tag...)
+	}
+	return data, nil
+}
+
+// decodeTags reads the tags written by encodeTags and returns them together with
+// the rest of data.
+func decodeTags(data []byte) ([]string, []byte, error) {
+	corrupt := errors.New(errors.DataCorruptionCode, "cached item has a truncated tag list")
+	if len(data) < 2 {
+		return nil, nil, corrupt
+	}
+	count := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+
+	var tags []string
+	for i := 0; i < count; i++ {
+		if len(data) < 2 {
+			return nil, nil, corrupt
+		}
+		n := int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+n {
+			return nil, nil, corrupt
+		}
+		tags = append(tags, string(data[2:2+n]))
+		data = data[2+n:]
+	}
+	return tags, data, nil
+}
+
+// escapeGlob escapes the characters that have a special meaning in SCAN MATCH patterns.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
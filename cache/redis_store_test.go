@@ -0,0 +1,307 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/resp"
+	"github.com/abitofhelp/servicelib/resp/resptest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testUser struct {
+	ID   string
+	Name string
+}
+
+func newTestRedisStore[T any](t *testing.T, prefix string) (*RedisStore[T], *resptest.Server, *resp.Client) {
+	t.Helper()
+
+	server, err := resptest.NewServer()
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+	server.HandleScript(setScriptSource, emulateSetScript)
+
+	client := resp.NewClient(resp.DefaultConfig().WithAddress(server.Addr()))
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewRedisStore[T](client, nil, prefix), server, client
+}
+
+// emulateSetScript is a Go emulation of setScriptSource for the fake server.
+func emulateSetScript(db *resptest.DB, keys, args []string) interface{} {
+	ttl, _ := strconv.ParseInt(args[1], 10, 64)
+	db.Call("SET", keys[0], args[0], "PX", args[1])
+	for _, tagKey := range args[3:] {
+		db.Call("SADD", tagKey, args[2])
+		if remaining, _ := db.Call("PTTL", tagKey).(int64); remaining < ttl {
+			db.Call("PEXPIRE", tagKey, args[1])
+		}
+	}
+	return int64(1)
+}
+
+func TestRedisStore(t *testing.T) {
+	store, server, _ := newTestRedisStore[testUser](t, "cache:users:")
+	ctx := context.Background()
+	now := time.Now()
+	expiration := now.Add(time.Hour).UnixNano()
+	staleUntil := now.Add(2 * time.Hour).UnixNano()
+
+	err := store.Set(ctx, "1", Item[testUser]{Value: testUser{ID: "1", Name: "Alice"}, Expiration: expiration, Created: now.UnixNano(), StaleUntil: staleUntil})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache:users:1"}, server.Keys())
+
+	item, found, err := store.Get(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, testUser{ID: "1", Name: "Alice"}, item.Value)
+	assert.Equal(t, expiration, item.Expiration)
+	assert.Equal(t, now.UnixNano(), item.Created)
+	assert.Equal(t, staleUntil, item.StaleUntil)
+
+	_, found, err = store.Get(ctx, "2")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	size, err := store.Len(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, size)
+
+	deleted, err := store.Delete(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	removed, err := store.DeleteExpired(ctx, time.Now().UnixNano())
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+}
+
+func TestRedisStore_ExpiredItemsAreNotWritten(t *testing.T) {
+	store, server, _ := newTestRedisStore[string](t, "c:")
+	ctx := context.Background()
+
+	require.NoError(t, store.Set(ctx, "key1", Item[string]{Value: "v", Expiration: time.Now().Add(time.Hour).UnixNano()}))
+	require.NoError(t, store.Set(ctx, "key1", Item[string]{Value: "v", Expiration: time.Now().Add(-time.Second).UnixNano()}))
+
+	assert.Empty(t, server.Keys())
+}
+
+func TestRedisStore_ServerExpiresItems(t *testing.T) {
+	store, _, _ := newTestRedisStore[string](t, "c:")
+	ctx := context.Background()
+
+	require.NoError(t, store.Set(ctx, "key1", Item[string]{Value: "v", Expiration: time.Now().Add(20 * time.Millisecond).UnixNano()}))
+	time.Sleep(40 * time.Millisecond)
+
+	_, found, err := store.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestRedisStore_ClearOnlyRemovesPrefixedKeys(t *testing.T) {
+	store, server, client := newTestRedisStore[string](t, "cache:a*:")
+	ctx := context.Background()
+	expiration := time.Now().Add(time.Hour).UnixNano()
+
+	_, err := client.Do(ctx, "SET", "cache:ab:other", "x")
+	require.NoError(t, err)
+	require.NoError(t, store.Set(ctx, "key1", Item[string]{Value: "v1", Expiration: expiration}))
+	require.NoError(t, store.Set(ctx, "key2", Item[string]{Value: "v2", Expiration: expiration}))
+
+	size, err := store.Len(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, size)
+
+	require.NoError(t, store.Clear(ctx))
+	assert.Equal(t, []string{"cache:ab:other"}, server.Keys())
+}
+
+func TestRedisStore_CorruptValue(t *testing.T) {
+	store, _, client := newTestRedisStore[string](t, "c:")
+	ctx := context.Background()
+
+	_, err := client.Do(ctx, "SET", "c:short", "abc")
+	require.NoError(t, err)
+	_, found, err := store.Get(ctx, "short")
+	assert.Error(t, err)
+	assert.False(t, found)
+
+	_, err = client.Do(ctx, "SET", "c:badjson", "123456781234567812345678\x00\x00{")
+	require.NoError(t, err)
+	_, found, err = store.Get(ctx, "badjson")
+	assert.Error(t, err)
+	assert.False(t, found)
+}
+
+func TestCache_WithRedisStore(t *testing.T) {
+	store, _, _ := newTestRedisStore[testUser](t, "cache:users:")
+
+	// Two caches sharing one store behave like two replicas of a service
+	replica1 := NewCacheWithStore[testUser](DefaultConfig(), DefaultOptions().WithName("users"), store)
+	replica2 := NewCacheWithStore[testUser](DefaultConfig(), DefaultOptions().WithName("users"), store)
+	defer replica1.Shutdown()
+	defer replica2.Shutdown()
+
+	ctx := context.Background()
+	calls := 0
+	load := func(ctx context.Context) (testUser, error) {
+		calls++
+		return testUser{ID: "1", Name: "Alice"}, nil
+	}
+
+	user, err := WithCache(ctx, replica1, "1", load)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", user.Name)
+
+	user, err = WithCacheTTL(ctx, replica2, "1", time.Minute, load)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", user.Name)
+	assert.Equal(t, 1, calls)
+
+	assert.Equal(t, 1, replica2.Size())
+	replica2.Delete(ctx, "1")
+	_, found := replica1.Get(ctx, "1")
+	assert.False(t, found)
+
+	replica1.Set(ctx, "2", testUser{ID: "2"})
+	replica1.Clear(ctx)
+	assert.Equal(t, 0, replica2.Size())
+}
+
+func TestEscapeGlob(t *testing.T) {
+	assert.Equal(t, `cache:\*\?\[x\]\\:`, escapeGlob(`cache:*?[x]\:`))
+}
+
+func TestRedisStore_Tags(t *testing.T) {
+	store, server, _ := newTestRedisStore[string](t, "c:")
+	ctx := context.Background()
+	expiration := time.Now().Add(time.Hour).UnixNano()
+
+	require.NoError(t, store.Set(ctx, "user:1", Item[string]{Value: "a", Expiration: expiration, Tags: []string{"tenant:1", "users"}}))
+	require.NoError(t, store.Set(ctx, "user:2", Item[string]{Value: "b", Expiration: expiration, Tags: []string{"tenant:2", "users"}}))
+	require.NoError(t, store.Set(ctx, "config", Item[string]{Value: "c", Expiration: expiration}))
+
+	item, found, err := store.Get(ctx, "user:1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"tenant:1", "users"}, item.Tags)
+
+	// Tag sets are not counted as items
+	size, err := store.Len(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, size)
+
+	removed, err := store.DeleteTag(ctx, "tenant:1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.NotContains(t, server.Keys(), "c:\x00tag:tenant:1")
+
+	removed, err = store.DeletePrefix(ctx, "user:")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, found, _ = store.Get(ctx, "config")
+	assert.True(t, found)
+
+	// Deleting a tag whose items are already gone is a no-op
+	removed, err = store.DeleteTag(ctx, "users")
+	require.NoError(t, err)
+	assert.Equal(t, 0, removed)
+
+	require.NoError(t, store.Clear(ctx))
+	assert.Empty(t, server.Keys())
+}
+
+func TestRedisStore_TagSetOutlivesItems(t *testing.T) {
+	store, _, client := newTestRedisStore[string](t, "c:")
+	ctx := context.Background()
+
+	require.NoError(t, store.Set(ctx, "key1", Item[string]{Value: "a", Expiration: time.Now().Add(time.Hour).UnixNano(), Tags: []string{"t"}}))
+	require.NoError(t, store.Set(ctx, "key2", Item[string]{Value: "b", Expiration: time.Now().Add(time.Minute).UnixNano(), Tags: []string{"t"}}))
+
+	ttl, err := resp.Int64(client.Do(ctx, "PTTL", "c:\x00tag:t"))
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute.Milliseconds())
+}
+
+func TestRedisStore_CorruptTags(t *testing.T) {
+	store, _, client := newTestRedisStore[string](t, "c:")
+	ctx := context.Background()
+
+	_, err := client.Do(ctx, "SET", "c:badtags", "123456781234567812345678\x00\x02\x00\x05ab")
+	require.NoError(t, err)
+	_, found, err := store.Get(ctx, "badtags")
+	assert.Error(t, err)
+	assert.False(t, found)
+}
+
+func TestRedisStore_EmptyPrefix(t *testing.T) {
+	store, server, client := newTestRedisStore[string](t, "")
+	ctx := context.Background()
+
+	_, err := client.Do(ctx, "SET", "other", "x")
+	require.NoError(t, err)
+	require.NoError(t, store.Set(ctx, "key1", Item[string]{Value: "v", Expiration: time.Now().Add(time.Hour).UnixNano()}))
+	assert.Contains(t, server.Keys(), "cache:key1")
+
+	// Clear must not remove keys that do not belong to the store
+	require.NoError(t, store.Clear(ctx))
+	assert.Equal(t, []string{"other"}, server.Keys())
+}
+
+func TestRedisStore_SetScript(t *testing.T) {
+	// The fake server emulates the script, so run the Lua source on miniredis
+	server := miniredis.RunT(t)
+	client := resp.NewClient(resp.DefaultConfig().WithAddress(server.Addr()))
+	t.Cleanup(func() { _ = client.Close() })
+	store := NewRedisStore[string](client, nil, "c:")
+	ctx := context.Background()
+
+	require.NoError(t, store.Set(ctx, "key1", Item[string]{Value: "a", Expiration: time.Now().Add(time.Hour).UnixNano(), Tags: []string{"t", "u"}}))
+	require.NoError(t, store.Set(ctx, "key2", Item[string]{Value: "b", Expiration: time.Now().Add(time.Minute).UnixNano(), Tags: []string{"t"}}))
+
+	members, err := server.Members("c:\x00tag:t")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key1", "key2"}, members)
+	assert.Greater(t, server.TTL("c:\x00tag:t"), 59*time.Minute)
+	assert.Greater(t, server.TTL("c:\x00tag:u"), 59*time.Minute)
+	assert.Greater(t, server.TTL("c:key2"), 59*time.Second)
+
+	item, found, err := store.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"t", "u"}, item.Tags)
+
+	removed, err := store.DeleteTag(ctx, "t")
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+}
+
+func TestRedisStore_TTLFollowsCacheClock(t *testing.T) {
+	store, _, client := newTestRedisStore[string](t, "c:")
+	fake := clock.NewFake(time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := NewCacheWithStore[string](DefaultConfig().WithTTL(time.Hour), DefaultOptions().WithClock(fake), store)
+	defer cache.Shutdown()
+	ctx := context.Background()
+
+	// The item's timestamps are in the fake clock's past, but it lives for the TTL
+	cache.Set(ctx, "key1", "value1")
+	ttl, err := resp.Int64(client.Do(ctx, "PTTL", "c:key1"))
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Milliseconds(), ttl, float64(time.Second.Milliseconds()))
+
+	// Items without a creation time are measured from the store's clock
+	store.SetClock(fake)
+	require.NoError(t, store.Set(ctx, "key2", Item[string]{Value: "v", Expiration: fake.Now().Add(time.Minute).UnixNano()}))
+	ttl, err = resp.Int64(client.Do(ctx, "PTTL", "c:key2"))
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute.Milliseconds(), ttl, float64(time.Second.Milliseconds()))
+}
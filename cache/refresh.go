@@ -0,0 +1,140 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/abitofhelp/servicelib/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// Loader loads the value for a key from the underlying data source.
+// It is registered with SetLoader and used by Get to refresh stale items and
+// items inside the refresh-ahead window.
+type Loader[T any] func(ctx context.Context, key string) (T, error)
+
+// refreshFunc describes how to reload a key and how long to cache the result.
+type refreshFunc[T any] struct {
+	// fn loads the new value
+	fn func(ctx context.Context) (T, error)
+
+	// ttl is the time-to-live of the reloaded value
+	ttl time.Duration
+}
+
+// SetLoader registers the loader used by Get to refresh items in the background.
+// Without a loader, Get never serves stale items or refreshes items ahead of their
+// expiration; WithCache and WithCacheTTL always use their own function instead.
+// Refreshed items are stored with the cache's default TTL.
+//
+// If the cache is nil (which happens when the cache is disabled), this method is a no-op.
+//
+// Parameters:
+//   - loader: The loader to register, or nil to remove the current loader.
+func (c *Cache[T]) SetLoader(loader Loader[T]) {
+	if c == nil {
+		return
+	}
+
+	c.loaderMu.Lock()
+	defer c.loaderMu.Unlock()
+	c.loader = loader
+}
+
+// loaderRefresh returns a refresh function for key that uses the registered
+// loader, or nil if no loader is registered or refreshing is disabled.
+func (c *Cache[T]) loaderRefresh(key string) *refreshFunc[T] {
+	if c.staleTTL <= 0 && c.refreshAhead <= 0 {
+		return nil
+	}
+
+	c.loaderMu.RLock()
+	loader := c.loader
+	c.loaderMu.RUnlock()
+	if loader == nil {
+		return nil
+	}
+
+	return &refreshFunc[T]{
+		fn: func(ctx context.Context) (T, error) {
+			return loader(ctx, key)
+		},
+		ttl: c.defaultTTL,
+	}
+}
+
+// inRefreshAheadWindow reports whether a fresh item is close enough to its
+// expiration that it should be refreshed ahead of time.
+func (c *Cache[T]) inRefreshAheadWindow(item Item[T], now int64) bool {
+	if c.refreshAhead <= 0 || item.Created <= 0 {
+		return false
+	}
+
+	lifetime := item.Expiration - item.Created
+	if lifetime <= 0 {
+		return false
+	}
+	return float64(item.Expiration-now) <= c.refreshAhead*float64(lifetime)
+}
+
+// triggerRefresh starts a background reload of key unless a load for key is
+// already in flight. The refresh shares the in-flight load with any concurrent
+// WithCache misses on the same key.
+//
+// Parameters:
+//   - ctx: The context of the read that triggered the refresh. The refresh keeps its
+//     values, such as the active trace span, but not its cancellation.
+//   - span: The span of the read that triggered the refresh.
+//   - key: The key to refresh.
+//   - tags: The tags of the current item, kept on the refreshed item.
+//   - refresh: How to reload the key.
+//   - reason: Why the refresh was triggered, either "stale" or "refresh_ahead".
+func (c *Cache[T]) triggerRefresh(ctx context.Context, span telemetry.Span, key string, tags []string, refresh *refreshFunc[T], reason string) {
+	if c.loads.inFlight(key) {
+		span.SetAttributes(attribute.String("cache.refresh_triggered", "in_flight"))
+		return
+	}
+	span.SetAttributes(attribute.String("cache.refresh_triggered", reason))
+
+	go c.refresh(context.WithoutCancel(ctx), key, tags, refresh, reason)
+}
+
+// refresh reloads key and stores the result. Failures are logged and leave the
+// current item in place.
+func (c *Cache[T]) refresh(ctx context.Context, key string, tags []string, refresh *refreshFunc[T], reason string) {
+	var span telemetry.Span
+	ctx, span = c.tracer.Start(ctx, "cache.Refresh")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("cache.name", c.name),
+		attribute.String("cache.key", key),
+		attribute.String("cache.operation", "refresh"),
+		attribute.String("cache.refresh_reason", reason),
+	)
+
+	_, err, shared := c.loads.do(ctx, key, func(loadCtx context.Context) (T, error) {
+		start := c.clock.Now()
+		value, err := refresh.fn(loadCtx)
+		c.recordLoad(loadCtx, c.clock.Since(start), err)
+		if err != nil {
+			return value, err
+		}
+
+		c.setItem(loadCtx, span, key, value, refresh.ttl, tags)
+		return value, nil
+	})
+
+	span.SetAttributes(attribute.Bool("cache.shared_load", shared))
+	if err != nil {
+		c.logger.Warn(ctx, "Failed to refresh cache item, keeping the current value",
+			zap.String("name", c.name),
+			zap.String("key", key),
+			zap.String("reason", reason),
+			zap.Error(err))
+		span.RecordError(err)
+	}
+}
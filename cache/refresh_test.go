@@ -0,0 +1,249 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCache_StaleWhileRevalidate(t *testing.T) {
+	cfg := DefaultConfig().WithTTL(20 * time.Millisecond).WithStaleTTL(time.Minute)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[int](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	var calls int32
+	refreshed := make(chan struct{}, 1)
+	fn := func(ctx context.Context) (int, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n > 1 {
+			refreshed <- struct{}{}
+		}
+		return int(n), nil
+	}
+
+	value, err := WithCache(ctx, cache, "key1", fn)
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+
+	fake.Advance(30 * time.Millisecond)
+
+	// The expired value is served immediately and refreshed in the background
+	value, err = WithCache(ctx, cache, "key1", fn)
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale item was not refreshed")
+	}
+
+	assert.Eventually(t, func() bool {
+		value, found := cache.Get(ctx, "key1")
+		return found && value == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestCache_GetWithoutLoaderDoesNotServeStale(t *testing.T) {
+	cfg := DefaultConfig().WithTTL(10 * time.Millisecond).WithStaleTTL(time.Minute)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.Set(ctx, "key1", "value1")
+	fake.Advance(20 * time.Millisecond)
+
+	_, found := cache.Get(ctx, "key1")
+	assert.False(t, found)
+
+	// The stale item is kept by the store until the stale period ends
+	assert.Equal(t, 1, cache.Size())
+}
+
+func TestCache_GetWithLoaderServesStale(t *testing.T) {
+	cfg := DefaultConfig().WithTTL(10 * time.Millisecond).WithStaleTTL(time.Minute)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.SetLoader(func(ctx context.Context, key string) (string, error) {
+		return "reloaded " + key, nil
+	})
+
+	cache.Set(ctx, "key1", "value1")
+	fake.Advance(20 * time.Millisecond)
+
+	value, found := cache.Get(ctx, "key1")
+	assert.True(t, found)
+	assert.Equal(t, "value1", value)
+
+	assert.Eventually(t, func() bool {
+		value, found := cache.Get(ctx, "key1")
+		return found && value == "reloaded key1"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestCache_StaleItemsExpireAfterStalePeriod(t *testing.T) {
+	cfg := DefaultConfig().WithTTL(10 * time.Millisecond).WithStaleTTL(10 * time.Millisecond)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	calls := 0
+	fn := func(ctx context.Context) (string, error) {
+		calls++
+		return "value", nil
+	}
+
+	_, _ = WithCache(ctx, cache, "key1", fn)
+	fake.Advance(30 * time.Millisecond)
+
+	// Past the stale period the item is a plain miss and is loaded synchronously
+	_, _ = WithCache(ctx, cache, "key1", fn)
+	assert.Equal(t, 2, calls)
+
+	fake.Advance(30 * time.Millisecond)
+	cache.cleanup()
+	assert.Equal(t, 0, cache.Size())
+}
+
+func TestCache_RefreshAhead(t *testing.T) {
+	cfg := DefaultConfig().WithTTL(100 * time.Millisecond).WithRefreshAhead(0.5)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[int](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	var calls int32
+	cache.SetLoader(func(ctx context.Context, key string) (int, error) {
+		return int(atomic.AddInt32(&calls, 1)), nil
+	})
+	cache.Set(ctx, "key1", 0)
+
+	// Outside the refresh-ahead window reads do not trigger a refresh
+	value, found := cache.Get(ctx, "key1")
+	assert.True(t, found)
+	assert.Equal(t, 0, value)
+	fake.Advance(10 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+
+	// Inside the window the current value is returned and refreshed in the background
+	fake.Advance(50 * time.Millisecond)
+	value, found = cache.Get(ctx, "key1")
+	assert.True(t, found)
+	assert.Equal(t, 0, value)
+
+	assert.Eventually(t, func() bool {
+		value, found := cache.Get(ctx, "key1")
+		return found && value == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestCache_RefreshFailureKeepsStaleValue(t *testing.T) {
+	cfg := DefaultConfig().WithTTL(10 * time.Millisecond).WithStaleTTL(time.Minute).WithNegativeTTL(time.Minute)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	var calls int32
+	fn := func(ctx context.Context) (string, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return "value1", nil
+		}
+		return "", errors.New("backend unavailable")
+	}
+
+	_, _ = WithCache(ctx, cache, "key1", fn)
+	fake.Advance(20 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		value, err := WithCache(ctx, cache, "key1", fn)
+		require.NoError(t, err)
+		assert.Equal(t, "value1", value)
+
+		// Wait for the refresh triggered by the read to fail before reading again
+		want := int32(i + 2)
+		require.Eventually(t, func() bool { return atomic.LoadInt32(&calls) >= want }, time.Second, time.Millisecond)
+		fake.Advance(10 * time.Millisecond)
+	}
+
+	// Failed refreshes are retried on later reads and are not negatively cached
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&calls), int32(3))
+}
+
+func TestCache_RefreshCoalescesWithInFlightLoad(t *testing.T) {
+	cfg := DefaultConfig().WithTTL(10 * time.Millisecond).WithStaleTTL(time.Minute)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	var calls int32
+	release := make(chan struct{})
+	cache.SetLoader(func(ctx context.Context, key string) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "reloaded", nil
+	})
+
+	cache.Set(ctx, "key1", "value1")
+	fake.Advance(20 * time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		value, found := cache.Get(ctx, "key1")
+		assert.True(t, found)
+		assert.Equal(t, "value1", value)
+	}
+
+	// Give the background refreshes real time to join the load in flight
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	assert.Eventually(t, func() bool {
+		value, _ := cache.Get(ctx, "key1")
+		return value == "reloaded"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestConfig_WithStaleTTLAndRefreshAhead(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, time.Duration(0), cfg.StaleTTL)
+	assert.Equal(t, 0.0, cfg.RefreshAhead)
+
+	assert.Equal(t, time.Minute, cfg.WithStaleTTL(time.Minute).StaleTTL)
+	assert.Equal(t, time.Duration(0), cfg.WithStaleTTL(-time.Minute).StaleTTL)
+
+	assert.Equal(t, 0.25, cfg.WithRefreshAhead(0.25).RefreshAhead)
+	assert.Equal(t, 0.0, cfg.WithRefreshAhead(-1).RefreshAhead)
+	assert.Equal(t, 1.0, cfg.WithRefreshAhead(2).RefreshAhead)
+}
+
+func TestCache_SetLoaderNilCache(t *testing.T) {
+	var cache *Cache[string]
+	assert.NotPanics(t, func() {
+		cache.SetLoader(func(ctx context.Context, key string) (string, error) {
+			return "", nil
+		})
+	})
+}
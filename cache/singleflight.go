@@ -0,0 +1,135 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/abitofhelp/servicelib/errors"
+)
+
+// flightCall is an in-flight or completed load for a single key.
+type flightCall[T any] struct {
+	// done is closed when the load has completed
+	done chan struct{}
+
+	// value and err hold the result of the load once done is closed
+	value T
+	err   error
+
+	// waiters is the number of callers still waiting for the result
+	waiters int
+
+	// cancel cancels the loader's context
+	cancel context.CancelFunc
+}
+
+// flightGroup coalesces concurrent loads of the same key so that only one
+// loader runs at a time per key.
+//
+// Unlike a plain singleflight, the loader runs in its own goroutine with a
+// context that is detached from any single caller's cancellation. Each caller
+// waits for the result on its own context, and the loader's context is only
+// cancelled once every caller waiting for it has given up.
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+// newFlightGroup creates an empty flight group.
+func newFlightGroup[T any]() *flightGroup[T] {
+	return &flightGroup[T]{
+		calls: make(map[string]*flightCall[T]),
+	}
+}
+
+// do runs fn for key unless a load for key is already in flight, in which case
+// it waits for that load instead. The loader's context keeps the values of the
+// first caller's context, such as the active trace span.
+//
+// Returns the loaded value, the loader's error or a context error if ctx was
+// done before the load completed, and whether the result was shared with a
+// load started by another caller.
+func (g *flightGroup[T]) do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error, bool) {
+	g.mu.Lock()
+	c, shared := g.calls[key]
+	if shared {
+		c.waiters++
+	} else {
+		loadCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &flightCall[T]{
+			done:    make(chan struct{}),
+			waiters: 1,
+			cancel:  cancel,
+		}
+		g.calls[key] = c
+		go g.run(loadCtx, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, c.err, shared
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// Nobody wants the result any more. Forget the call now, so that a
+			// new caller starts a fresh load instead of joining a cancelled one.
+			c.cancel()
+			g.forget(key, c)
+		}
+		g.mu.Unlock()
+
+		var zero T
+		return zero, errors.NewContextError("context cancelled or timed out while waiting for cache loader", ctx.Err()), shared
+	}
+}
+
+// inFlight reports whether a load for key is currently running.
+func (g *flightGroup[T]) inFlight(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, found := g.calls[key]
+	return found
+}
+
+// waiting returns the number of callers waiting for the load of key.
+func (g *flightGroup[T]) waiting(key string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c, found := g.calls[key]; found {
+		return c.waiters
+	}
+	return 0
+}
+
+// forget removes c from the in-flight calls unless a newer call for key has
+// replaced it. The caller must hold g.mu.
+func (g *flightGroup[T]) forget(key string, c *flightCall[T]) {
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+// run executes a load and publishes its result to all waiters.
+func (g *flightGroup[T]) run(ctx context.Context, key string, c *flightCall[T], fn func(ctx context.Context) (T, error)) {
+	defer c.cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			c.err = errors.New(errors.InternalErrorCode, fmt.Sprintf("panic in cache loader: %v", r))
+		}
+
+		g.mu.Lock()
+		g.forget(key, c)
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	c.value, c.err = fn(ctx)
+}
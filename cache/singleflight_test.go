@@ -0,0 +1,319 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	serviceerrors "github.com/abitofhelp/servicelib/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForWaiters waits until n callers are waiting for the load of key.
+func waitForWaiters[T any](t *testing.T, g *flightGroup[T], key string, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return g.waiting(key) == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestWithCache_CoalescesConcurrentMisses(t *testing.T) {
+	cache := NewCache[string](DefaultConfig(), DefaultOptions().WithName("coalesce"))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "computed value", nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = WithCache(context.Background(), cache, "key1", fn)
+		}(i)
+	}
+
+	// Every caller joins the in-flight load before it completes
+	waitForWaiters(t, cache.loads, "key1", callers)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for i := 0; i < callers; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, "computed value", results[i])
+	}
+
+	value, found := cache.Get(context.Background(), "key1")
+	assert.True(t, found)
+	assert.Equal(t, "computed value", value)
+}
+
+func TestWithCacheTTL_CoalescesConcurrentMisses(t *testing.T) {
+	cache := NewCache[int](DefaultConfig(), DefaultOptions())
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := WithCacheTTL(context.Background(), cache, "answer", time.Minute, fn)
+			assert.NoError(t, err)
+			assert.Equal(t, 42, value)
+		}()
+	}
+
+	waitForWaiters(t, cache.loads, "answer", 5)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestWithCache_CallerCancellation(t *testing.T) {
+	cache := NewCache[string](DefaultConfig(), DefaultOptions())
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	release := make(chan struct{})
+	loaderDone := make(chan error, 1)
+	fn := func(ctx context.Context) (string, error) {
+		select {
+		case <-release:
+			loaderDone <- nil
+			return "computed value", nil
+		case <-ctx.Done():
+			loaderDone <- ctx.Err()
+			return "", ctx.Err()
+		}
+	}
+
+	// The first caller gives up, but a second caller is still waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := WithCache(ctx, cache, "key1", fn)
+		firstErr <- err
+	}()
+	waitForWaiters(t, cache.loads, "key1", 1)
+
+	secondResult := make(chan string, 1)
+	go func() {
+		value, err := WithCache(context.Background(), cache, "key1", fn)
+		assert.NoError(t, err)
+		secondResult <- value
+	}()
+	waitForWaiters(t, cache.loads, "key1", 2)
+
+	cancel()
+	err := <-firstErr
+	require.Error(t, err)
+	assert.True(t, serviceerrors.IsContextError(err))
+
+	// The loader keeps running for the remaining caller
+	close(release)
+	assert.NoError(t, <-loaderDone)
+	assert.Equal(t, "computed value", <-secondResult)
+}
+
+func TestWithCache_LoaderCancelledWhenAllCallersGiveUp(t *testing.T) {
+	cache := NewCache[string](DefaultConfig().WithNegativeTTL(time.Minute), DefaultOptions())
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	loaderErr := make(chan error, 1)
+	fn := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		loaderErr <- ctx.Err()
+		return "", ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := WithCache(ctx, cache, "key1", fn)
+	assert.True(t, serviceerrors.IsContextError(err))
+
+	select {
+	case err := <-loaderErr:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("loader context was not cancelled")
+	}
+
+	// The cancellation is not cached as a loader error
+	value, err := WithCache(context.Background(), cache, "key1", func(ctx context.Context) (string, error) {
+		return "computed value", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "computed value", value)
+}
+
+func TestFlightGroup_NewCallerAfterAllWaitersGaveUp(t *testing.T) {
+	g := newFlightGroup[string]()
+
+	// The abandoned loader ignores its cancellation and keeps running
+	releaseAbandoned := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err, _ := g.do(ctx, "key1", func(ctx context.Context) (string, error) {
+			<-releaseAbandoned
+			return "abandoned", nil
+		})
+		firstErr <- err
+	}()
+	waitForWaiters(t, g, "key1", 1)
+	g.mu.Lock()
+	abandoned := g.calls["key1"]
+	g.mu.Unlock()
+	cancel()
+	require.Error(t, <-firstErr)
+
+	// A new caller starts a fresh load instead of joining the cancelled one
+	releaseFresh := make(chan struct{})
+	result := make(chan string, 1)
+	go func() {
+		value, err, shared := g.do(context.Background(), "key1", func(ctx context.Context) (string, error) {
+			<-releaseFresh
+			return "fresh", ctx.Err()
+		})
+		assert.NoError(t, err)
+		assert.False(t, shared)
+		result <- value
+	}()
+	waitForWaiters(t, g, "key1", 1)
+
+	// The abandoned load finishing does not remove the fresh one
+	close(releaseAbandoned)
+	<-abandoned.done
+	assert.Equal(t, 1, g.waiting("key1"))
+
+	close(releaseFresh)
+	assert.Equal(t, "fresh", <-result)
+	assert.False(t, g.inFlight("key1"))
+}
+
+func TestWithCache_LoaderPanic(t *testing.T) {
+	cache := NewCache[string](DefaultConfig(), DefaultOptions())
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	_, err := WithCache(context.Background(), cache, "key1", func(ctx context.Context) (string, error) {
+		panic("boom")
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "panic in cache loader: boom")
+}
+
+func TestWithCache_NegativeCaching(t *testing.T) {
+	cfg := DefaultConfig().WithNegativeTTL(30 * time.Millisecond)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	loadErr := errors.New("backend unavailable")
+	calls := 0
+	failing := func(ctx context.Context) (string, error) {
+		calls++
+		return "", loadErr
+	}
+
+	_, err := WithCache(ctx, cache, "key1", failing)
+	assert.Equal(t, loadErr, err)
+
+	// The cached error is returned without calling the loader
+	_, err = WithCacheTTL(ctx, cache, "key1", time.Minute, failing)
+	assert.Equal(t, loadErr, err)
+	assert.Equal(t, 1, calls)
+
+	// After the negative TTL the loader is called again
+	fake.Advance(40 * time.Millisecond)
+	_, err = WithCache(ctx, cache, "key1", failing)
+	assert.Equal(t, loadErr, err)
+	assert.Equal(t, 2, calls)
+
+	// Writing a value or deleting the key clears the cached error
+	cache.Set(ctx, "key1", "value1")
+	value, err := WithCache(ctx, cache, "key1", failing)
+	assert.NoError(t, err)
+	assert.Equal(t, "value1", value)
+
+	cache.Delete(ctx, "key1")
+	_, _ = WithCache(ctx, cache, "key1", failing)
+	cache.Delete(ctx, "key1")
+	value, err = WithCache(ctx, cache, "key1", func(ctx context.Context) (string, error) {
+		return "recovered", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "recovered", value)
+}
+
+func TestWithCache_NegativeCachingDisabledByDefault(t *testing.T) {
+	cache := NewCache[string](DefaultConfig(), DefaultOptions())
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	calls := 0
+	failing := func(ctx context.Context) (string, error) {
+		calls++
+		return "", errors.New("backend unavailable")
+	}
+
+	_, _ = WithCache(context.Background(), cache, "key1", failing)
+	_, _ = WithCache(context.Background(), cache, "key1", failing)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCache_NegativeEntriesPurged(t *testing.T) {
+	cfg := DefaultConfig().WithNegativeTTL(time.Millisecond)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	_, _ = WithCache(context.Background(), cache, "key1", func(ctx context.Context) (string, error) {
+		return "", errors.New("backend unavailable")
+	})
+
+	fake.Advance(5 * time.Millisecond)
+	cache.cleanup()
+
+	cache.negativeMu.Lock()
+	defer cache.negativeMu.Unlock()
+	assert.Empty(t, cache.negative)
+}
+
+func TestConfig_WithNegativeTTL(t *testing.T) {
+	assert.Equal(t, time.Duration(0), DefaultConfig().NegativeTTL)
+	assert.Equal(t, time.Second, DefaultConfig().WithNegativeTTL(time.Second).NegativeTTL)
+	assert.Equal(t, time.Duration(0), DefaultConfig().WithNegativeTTL(-time.Second).NegativeTTL)
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Stats is a point-in-time snapshot of a cache's counters.
+// Counters start at zero when the cache is created and are not reset by Clear.
+type Stats struct {
+	// Hits is the number of lookups that returned a value, including stale values
+	Hits uint64
+
+	// Misses is the number of lookups that did not return a value
+	Misses uint64
+
+	// StaleHits is the number of hits that returned an expired value within the stale period
+	StaleHits uint64
+
+	// Evictions is the number of items evicted to make room for other items
+	Evictions uint64
+
+	// Expirations is the number of expired items purged from the store
+	Expirations uint64
+
+	// Loads is the number of times a loader ran, for WithCache misses and background refreshes
+	Loads uint64
+
+	// LoadErrors is the number of loads that returned an error
+	LoadErrors uint64
+
+	// TotalLoadTime is the total time spent in loaders
+	TotalLoadTime time.Duration
+
+	// Size is the number of items in the store when the snapshot was taken
+	Size int
+
+	// Bytes is the total cost of the items in a MemoryStore when the snapshot was
+	// taken. It is zero for other stores.
+	Bytes int64
+}
+
+// HitRatio returns the fraction of lookups that were hits.
+//
+// Returns:
+//   - float64: The hit ratio between 0 and 1, or 0 if there were no lookups.
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// AverageLoadTime returns the mean time spent in a loader.
+//
+// Returns:
+//   - time.Duration: The average load time, or 0 if there were no loads.
+func (s Stats) AverageLoadTime() time.Duration {
+	if s.Loads == 0 {
+		return 0
+	}
+	return s.TotalLoadTime / time.Duration(s.Loads)
+}
+
+// statsCounters holds the counters behind Stats.
+type statsCounters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	staleHits   atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+	loads       atomic.Uint64
+	loadErrors  atomic.Uint64
+	loadTime    atomic.Int64
+}
+
+// cacheMetrics holds the OpenTelemetry instruments of a cache.
+type cacheMetrics struct {
+	// hits counts lookups that returned a value
+	hits metric.Int64Counter
+
+	// misses counts lookups that did not return a value
+	misses metric.Int64Counter
+
+	// evictions counts items removed by the store, by reason
+	evictions metric.Int64Counter
+
+	// loadDuration records the time spent in loaders
+	loadDuration metric.Float64Histogram
+
+	// sizeRegistration is the callback registration of the size and bytes gauges
+	sizeRegistration metric.Registration
+
+	// name identifies the cache in every measurement
+	name attribute.KeyValue
+}
+
+// newCacheMetrics creates the instruments of a cache.
+//
+// Parameters:
+//   - meter: The meter used to create the instruments.
+//   - name: The name of the cache, recorded as the cache.name attribute.
+//   - size: A function returning the current number of items, observed by the size gauge.
+//   - bytes: A function returning the total cost of the items, observed by the bytes gauge,
+//     or nil if the store does not track costs.
+//
+// Returns:
+//   - *cacheMetrics: The instruments.
+//   - error: An error if an instrument could not be created.
+func newCacheMetrics(meter metric.Meter, name string, size func() int, bytes func() int64) (*cacheMetrics, error) {
+	m := &cacheMetrics{name: attribute.String("cache.name", name)}
+
+	var err error
+	m.hits, err = meter.Int64Counter(
+		"cache.hits",
+		metric.WithDescription("Number of cache lookups that returned a value"),
+		metric.WithUnit("{hit}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache.hits counter: %w", err)
+	}
+
+	m.misses, err = meter.Int64Counter(
+		"cache.misses",
+		metric.WithDescription("Number of cache lookups that did not return a value"),
+		metric.WithUnit("{miss}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache.misses counter: %w", err)
+	}
+
+	m.evictions, err = meter.Int64Counter(
+		"cache.evictions",
+		metric.WithDescription("Number of items removed from the cache by eviction or expiration"),
+		metric.WithUnit("{item}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache.evictions counter: %w", err)
+	}
+
+	m.loadDuration, err = meter.Float64Histogram(
+		"cache.load.duration",
+		metric.WithDescription("Time spent loading values on cache misses and refreshes"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache.load.duration histogram: %w", err)
+	}
+
+	sizeGauge, err := meter.Int64ObservableGauge(
+		"cache.size",
+		metric.WithDescription("Number of items in the cache"),
+		metric.WithUnit("{item}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache.size gauge: %w", err)
+	}
+
+	bytesGauge, err := meter.Int64ObservableGauge(
+		"cache.bytes",
+		metric.WithDescription("Total cost of the items in the cache"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache.bytes gauge: %w", err)
+	}
+
+	m.sizeRegistration, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		o.ObserveInt64(sizeGauge, int64(size()), metric.WithAttributes(m.name))
+		if bytes != nil {
+			o.ObserveInt64(bytesGauge, bytes(), metric.WithAttributes(m.name))
+		}
+		return nil
+	}, sizeGauge, bytesGauge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register cache.size callback: %w", err)
+	}
+
+	return m, nil
+}
+
+// Stats returns a snapshot of the cache's counters.
+// If the cache is nil (which happens when the cache is disabled), the zero Stats is returned.
+//
+// Returns:
+//   - Stats: The current counters and size of the cache.
+func (c *Cache[T]) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+
+	return Stats{
+		Hits:          c.stats.hits.Load(),
+		Misses:        c.stats.misses.Load(),
+		StaleHits:     c.stats.staleHits.Load(),
+		Evictions:     c.stats.evictions.Load(),
+		Expirations:   c.stats.expirations.Load(),
+		Loads:         c.stats.loads.Load(),
+		LoadErrors:    c.stats.loadErrors.Load(),
+		TotalLoadTime: time.Duration(c.stats.loadTime.Load()),
+		Size:          c.Size(),
+		Bytes:         c.bytes(),
+	}
+}
+
+// bytes returns the total cost of the items if the store tracks costs.
+func (c *Cache[T]) bytes() int64 {
+	if s, ok := c.store.(*MemoryStore[T]); ok {
+		return s.Bytes()
+	}
+	return 0
+}
+
+// recordHit counts a lookup that returned a value.
+func (c *Cache[T]) recordHit(ctx context.Context, stale bool) {
+	c.stats.hits.Add(1)
+	if stale {
+		c.stats.staleHits.Add(1)
+	}
+	if c.metrics != nil {
+		c.metrics.hits.Add(ctx, 1, metric.WithAttributes(c.metrics.name, attribute.Bool("stale", stale)))
+	}
+}
+
+// recordMiss counts a lookup that did not return a value.
+func (c *Cache[T]) recordMiss(ctx context.Context) {
+	c.stats.misses.Add(1)
+	if c.metrics != nil {
+		c.metrics.misses.Add(ctx, 1, metric.WithAttributes(c.metrics.name))
+	}
+}
+
+// recordEvictions counts items removed by the store.
+func (c *Cache[T]) recordEvictions(reason EvictionReason, n int) {
+	if n <= 0 {
+		return
+	}
+
+	switch reason {
+	case EvictionCapacity:
+		c.stats.evictions.Add(uint64(n))
+	case EvictionExpired:
+		c.stats.expirations.Add(uint64(n))
+	}
+	if c.metrics != nil {
+		c.metrics.evictions.Add(context.Background(), int64(n),
+			metric.WithAttributes(c.metrics.name, attribute.String("reason", reason.String())))
+	}
+}
+
+// recordLoad records a completed loader call.
+func (c *Cache[T]) recordLoad(ctx context.Context, duration time.Duration, err error) {
+	c.stats.loads.Add(1)
+	c.stats.loadTime.Add(int64(duration))
+	if err != nil {
+		c.stats.loadErrors.Add(1)
+	}
+	if c.metrics != nil {
+		c.metrics.loadDuration.Record(ctx, duration.Seconds(),
+			metric.WithAttributes(c.metrics.name, attribute.Bool("success", err == nil)))
+	}
+}
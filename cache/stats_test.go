@@ -0,0 +1,187 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestCache_Stats(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](DefaultConfig().WithMaxSize(2), DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	cache.Set(ctx, "key3", "value3") // evicts key1
+
+	_, _ = cache.Get(ctx, "key1")
+	_, _ = cache.Get(ctx, "key2")
+	_, _ = cache.Get(ctx, "key3")
+
+	_, _ = WithCache(ctx, cache, "key4", func(ctx context.Context) (string, error) {
+		fake.Advance(5 * time.Millisecond)
+		return "value4", nil
+	})
+	_, _ = WithCache(ctx, cache, "key5", func(ctx context.Context) (string, error) {
+		return "", errors.New("backend unavailable")
+	})
+
+	cache.SetWithTTL(ctx, "short", "value", time.Millisecond)
+	fake.Advance(5 * time.Millisecond)
+	cache.cleanup()
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, uint64(3), stats.Evictions)
+	assert.Equal(t, uint64(1), stats.Expirations)
+	assert.Equal(t, uint64(2), stats.Loads)
+	assert.Equal(t, uint64(1), stats.LoadErrors)
+	assert.Equal(t, 5*time.Millisecond, stats.TotalLoadTime)
+	assert.Equal(t, 1, stats.Size)
+	assert.InDelta(t, 0.4, stats.HitRatio(), 0.001)
+	assert.Equal(t, stats.TotalLoadTime/2, stats.AverageLoadTime())
+}
+
+func TestCache_StatsStaleHits(t *testing.T) {
+	cfg := DefaultConfig().WithTTL(time.Millisecond).WithStaleTTL(time.Minute)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.SetLoader(func(ctx context.Context, key string) (string, error) {
+		return "reloaded", nil
+	})
+	cache.Set(ctx, "key1", "value1")
+	fake.Advance(5 * time.Millisecond)
+
+	_, found := cache.Get(ctx, "key1")
+	assert.True(t, found)
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.StaleHits)
+}
+
+func TestCache_StatsExpirationsWithoutNotifier(t *testing.T) {
+	store, _, _ := newTestRedisStore[string](t, "c:")
+	cache := NewCacheWithStore[string](DefaultConfig(), DefaultOptions(), expiringStore[string]{Store: store})
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	cache.cleanup()
+	assert.Equal(t, uint64(2), cache.Stats().Expirations)
+}
+
+// expiringStore wraps a Store and reports that it purged two items on every cleanup.
+type expiringStore[T any] struct {
+	Store[T]
+}
+
+func (expiringStore[T]) DeleteExpired(ctx context.Context, now int64) (int, error) {
+	return 2, nil
+}
+
+func TestCache_StatsNilCache(t *testing.T) {
+	var cache *Cache[string]
+	assert.Equal(t, Stats{}, cache.Stats())
+	assert.Equal(t, 0.0, Stats{}.HitRatio())
+	assert.Equal(t, time.Duration(0), Stats{}.AverageLoadTime())
+}
+
+func TestCache_Metrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	meter := provider.Meter("cache-test")
+
+	cache := NewCache[string](DefaultConfig().WithMaxSize(1), DefaultOptions().WithName("users").WithMeter(meter))
+	require.NotNil(t, cache)
+
+	ctx := context.Background()
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	_, _ = cache.Get(ctx, "key2")
+	_, _ = cache.Get(ctx, "missing")
+	_, _ = WithCache(ctx, cache, "key3", func(ctx context.Context) (string, error) {
+		return "value3", nil
+	})
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	metrics := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	hits := metrics["cache.hits"].(metricdata.Sum[int64])
+	require.Len(t, hits.DataPoints, 1)
+	assert.Equal(t, int64(1), hits.DataPoints[0].Value)
+	name, _ := hits.DataPoints[0].Attributes.Value(attribute.Key("cache.name"))
+	assert.Equal(t, "users", name.AsString())
+
+	misses := metrics["cache.misses"].(metricdata.Sum[int64])
+	assert.Equal(t, int64(2), misses.DataPoints[0].Value)
+
+	evictions := metrics["cache.evictions"].(metricdata.Sum[int64])
+	require.Len(t, evictions.DataPoints, 1)
+	assert.Equal(t, int64(2), evictions.DataPoints[0].Value)
+	reason, _ := evictions.DataPoints[0].Attributes.Value(attribute.Key("reason"))
+	assert.Equal(t, "capacity", reason.AsString())
+
+	size := metrics["cache.size"].(metricdata.Gauge[int64])
+	assert.Equal(t, int64(1), size.DataPoints[0].Value)
+
+	loads := metrics["cache.load.duration"].(metricdata.Histogram[float64])
+	assert.Equal(t, uint64(1), loads.DataPoints[0].Count)
+
+	// The size gauge is no longer observed after shutdown
+	cache.Shutdown()
+	rm = metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(ctx, &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "cache.size" {
+				assert.Empty(t, m.Data.(metricdata.Gauge[int64]).DataPoints)
+			}
+		}
+	}
+}
+
+func TestCache_MetricsOnPrometheusHandler(t *testing.T) {
+	meter, err := telemetry.PrometheusMeter("github.com/abitofhelp/servicelib/cache")
+	require.NoError(t, err)
+
+	cache := NewCache[string](DefaultConfig(), DefaultOptions().WithName("prometheus_test").WithMeter(meter))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.Set(ctx, "key1", "value1")
+	_, _ = cache.Get(ctx, "key1")
+
+	rr := httptest.NewRecorder()
+	telemetry.CreatePrometheusHandler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rr.Body.String()
+	assert.Contains(t, body, `cache_hits_total{cache_name="prometheus_test"`)
+	assert.Contains(t, body, `cache_size{cache_name="prometheus_test"`)
+}
@@ -0,0 +1,375 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/errors"
+)
+
+// Store is the storage backend behind a Cache.
+// It stores items together with their expiration time; deciding whether an
+// item has expired is left to the Cache. Implementations must be safe for
+// concurrent use by multiple goroutines.
+//
+// The default store is an in-process MemoryStore. A RedisStore can be used
+// instead to share cached data between replicas of a service.
+type Store[T any] interface {
+	// Get returns the item stored under key, whether it was found, and any
+	// error that prevented the lookup.
+	Get(ctx context.Context, key string) (Item[T], bool, error)
+
+	// Set stores an item under key, replacing any existing item.
+	Set(ctx context.Context, key string, item Item[T]) error
+
+	// Delete removes the item stored under key and reports whether it existed.
+	Delete(ctx context.Context, key string) (bool, error)
+
+	// Clear removes all items from the store.
+	Clear(ctx context.Context) error
+
+	// Len returns the number of items in the store, including expired items
+	// that have not been purged yet.
+	Len(ctx context.Context) (int, error)
+
+	// DeleteExpired removes items whose expiration and stale period are before
+	// now (in Unix nanoseconds) and returns the number of items removed. Stores that expire
+	// items natively may return 0.
+	DeleteExpired(ctx context.Context, now int64) (int, error)
+
+	// DeleteTag removes all items stored with tag in their Tags and returns the
+	// number of items removed. Stores may also remove items that were stored with
+	// the tag and later overwritten without it.
+	DeleteTag(ctx context.Context, tag string) (int, error)
+
+	// DeletePrefix removes all items whose keys start with prefix and returns the
+	// number of items removed.
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+}
+
+// EvictionReason describes why a store removed an item on its own.
+type EvictionReason int
+
+const (
+	// EvictionCapacity means the item was evicted to make room for another item.
+	EvictionCapacity EvictionReason = iota
+
+	// EvictionExpired means the item was purged after it expired.
+	EvictionExpired
+)
+
+// String returns the name of the eviction reason as used in metric attributes.
+//
+// Returns:
+//   - string: "capacity", "expired", or "unknown".
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionCapacity:
+		return "capacity"
+	case EvictionExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// EvictionNotifier is implemented by stores that can report the items they
+// remove on their own, through capacity eviction or expiration. Cache uses it
+// to count evictions. Items removed by Delete and Clear are not reported.
+type EvictionNotifier interface {
+	// OnEvict registers a function that is called with the key of each item the
+	// store evicts and the reason it was evicted. The function is called while the
+	// store is locked and must not call back into the store.
+	OnEvict(fn func(key string, reason EvictionReason))
+}
+
+// MemoryStore is an in-process Store backed by a map.
+// When the store is full, either by item count or by the total Cost of its items,
+// items are evicted according to its eviction strategy.
+// Tags and key prefixes are kept in secondary indexes that are updated whenever an
+// item is stored, deleted, evicted or purged, so DeleteTag and DeletePrefix only
+// visit the matching items.
+type MemoryStore[T any] struct {
+	// items is the map that stores the cached data
+	items map[string]Item[T]
+
+	// mu protects the items map and eviction policy for concurrent access
+	mu sync.Mutex
+
+	// maxSize is the maximum number of items allowed in the store
+	maxSize int
+
+	// maxBytes is the maximum total cost of the items in the store
+	maxBytes int64
+
+	// bytes is the total cost of the items in the store
+	bytes int64
+
+	// policy tracks access order or frequency for the eviction strategy
+	policy evictionPolicy
+
+	// onEvict is called for each item the store evicts, if set
+	onEvict func(key string, reason EvictionReason)
+
+	// tags indexes keys by the tags they were stored with
+	tags *tagIndex
+
+	// prefixes indexes keys for prefix lookups
+	prefixes *prefixIndex
+
+	// clock is used to decide whether an item is still retained when it is read
+	clock clock.Clock
+}
+
+// NewMemoryStore creates a new in-process store.
+//
+// Type Parameters:
+//   - T: The type of values to be stored.
+//
+// Parameters:
+//   - maxSize: The maximum number of items. If non-positive, the store is unbounded.
+//   - strategy: The strategy used to evict items when the store is full.
+//
+// Returns:
+//   - *MemoryStore[T]: A new, empty store.
+func NewMemoryStore[T any](maxSize int, strategy EvictionStrategy) *MemoryStore[T] {
+	return &MemoryStore[T]{
+		items:    make(map[string]Item[T]),
+		maxSize:  maxSize,
+		policy:   newEvictionPolicy(strategy),
+		tags:     newTagIndex(),
+		prefixes: newPrefixIndex(),
+		clock:    clock.New(),
+	}
+}
+
+// Get returns the item stored under key.
+// A lookup of an unexpired or stale item counts as a use for LRU and LFU eviction.
+func (s *MemoryStore[T]) Get(ctx context.Context, key string) (Item[T], bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, found := s.items[key]
+	if found && s.clock.Now().UnixNano() <= item.retainUntil() {
+		s.policy.access(key)
+	}
+	return item, found, nil
+}
+
+// SetClock sets the clock the store uses to decide whether an item it reads has
+// expired. NewCache sets it to the clock of the cache's Options.
+//
+// Parameters:
+//   - c: The clock. If nil, the system clock is used.
+func (s *MemoryStore[T]) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clock = clock.OrReal(c)
+}
+
+// SetMaxBytes sets the maximum total Cost of the items in the store, evicting
+// items if the store is already over the new limit.
+//
+// Parameters:
+//   - maxBytes: The maximum total cost. If non-positive, the total cost is not limited.
+func (s *MemoryStore[T]) SetMaxBytes(maxBytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+	s.maxBytes = maxBytes
+	s.evictWhile(func() bool {
+		return s.maxBytes > 0 && s.bytes > s.maxBytes
+	})
+}
+
+// Bytes returns the total Cost of the items in the store.
+func (s *MemoryStore[T]) Bytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.bytes
+}
+
+// Set stores an item, evicting other items first if the store is full.
+// Overwriting an existing item counts as a use for LRU and LFU eviction.
+// An item whose Cost alone exceeds the store's byte limit is not stored; any
+// existing item under the same key is removed and an error is returned.
+func (s *MemoryStore[T]) Set(ctx context.Context, key string, item Item[T]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxBytes > 0 && item.Cost > s.maxBytes {
+		s.removeItem(key)
+		return errors.New(errors.ResourceExhaustedCode,
+			fmt.Sprintf("cache item cost %d exceeds the store limit of %d bytes", item.Cost, s.maxBytes))
+	}
+
+	old, exists := s.items[key]
+
+	// Check if we need to evict an item
+	if s.maxSize > 0 && len(s.items) >= s.maxSize && !exists {
+		s.evictItem()
+	}
+
+	// Evict items until the new item fits, never evicting the item it replaces.
+	// The replaced item keeps its place in the eviction order.
+	if s.maxBytes > 0 {
+		for s.bytes-old.Cost+item.Cost > s.maxBytes {
+			victim, found := s.policy.victimExcept(key)
+			if !found {
+				break
+			}
+			s.removeItem(victim)
+			s.notifyEvict(victim, EvictionCapacity)
+		}
+	}
+
+	s.items[key] = item
+	s.bytes += item.Cost - old.Cost
+
+	if exists {
+		s.policy.access(key)
+		s.tags.remove(key, old.Tags)
+	} else {
+		s.policy.add(key)
+		s.prefixes.add(key)
+	}
+	s.tags.add(key, item.Tags)
+	return nil
+}
+
+// Delete removes the item stored under key.
+func (s *MemoryStore[T]) Delete(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, found := s.items[key]
+	s.removeItem(key)
+	return found, nil
+}
+
+// Clear removes all items. It replaces the items map so that the garbage
+// collector can reclaim the memory used by the old items.
+func (s *MemoryStore[T]) Clear(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items = make(map[string]Item[T])
+	s.bytes = 0
+	s.policy.reset()
+	s.tags.reset()
+	s.prefixes.reset()
+	return nil
+}
+
+// Len returns the number of items in the store.
+func (s *MemoryStore[T]) Len(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.items), nil
+}
+
+// DeleteExpired removes all items whose expiration and stale period are before now.
+func (s *MemoryStore[T]) DeleteExpired(ctx context.Context, now int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for k, v := range s.items {
+		if now > v.retainUntil() {
+			s.removeItem(k)
+			s.notifyEvict(k, EvictionExpired)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// DeleteTag removes all items stored with tag.
+func (s *MemoryStore[T]) DeleteTag(ctx context.Context, tag string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := s.tags.lookup(tag)
+	for _, key := range keys {
+		s.removeItem(key)
+	}
+	return len(keys), nil
+}
+
+// DeletePrefix removes all items whose keys start with prefix.
+func (s *MemoryStore[T]) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := s.prefixes.lookup(prefix)
+	for _, key := range keys {
+		s.removeItem(key)
+	}
+	return len(keys), nil
+}
+
+// removeItem removes an item, its eviction bookkeeping and its index entries.
+// The caller must hold the lock.
+func (s *MemoryStore[T]) removeItem(key string) {
+	item, found := s.items[key]
+	if !found {
+		return
+	}
+
+	delete(s.items, key)
+	s.bytes -= item.Cost
+	s.policy.remove(key)
+	s.tags.remove(key, item.Tags)
+	s.prefixes.remove(key)
+}
+
+// evictItem evicts an item based on the eviction strategy.
+// The caller must hold the lock.
+func (s *MemoryStore[T]) evictItem() {
+	key, found := s.policy.victim()
+	if !found {
+		return
+	}
+	s.removeItem(key)
+	s.notifyEvict(key, EvictionCapacity)
+}
+
+// evictWhile evicts items based on the eviction strategy for as long as over
+// reports that the store is over its limit. The caller must hold the lock.
+func (s *MemoryStore[T]) evictWhile(over func() bool) {
+	for over() {
+		key, found := s.policy.victim()
+		if !found {
+			return
+		}
+		s.removeItem(key)
+		s.notifyEvict(key, EvictionCapacity)
+	}
+}
+
+// OnEvict registers a function that is called for each item the store evicts.
+// It replaces any previously registered function.
+func (s *MemoryStore[T]) OnEvict(fn func(key string, reason EvictionReason)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onEvict = fn
+}
+
+// notifyEvict reports an evicted item to the registered function.
+// The caller must hold the lock.
+func (s *MemoryStore[T]) notifyEvict(key string, reason EvictionReason) {
+	if s.onEvict != nil {
+		s.onEvict(key, reason)
+	}
+}
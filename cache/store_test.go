@@ -0,0 +1,167 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingStore is a Store whose operations always fail.
+type failingStore[T any] struct{}
+
+var errStoreUnavailable = errors.New("store unavailable")
+
+func (failingStore[T]) Get(ctx context.Context, key string) (Item[T], bool, error) {
+	return Item[T]{}, false, errStoreUnavailable
+}
+
+func (failingStore[T]) Set(ctx context.Context, key string, item Item[T]) error {
+	return errStoreUnavailable
+}
+
+func (failingStore[T]) Delete(ctx context.Context, key string) (bool, error) {
+	return false, errStoreUnavailable
+}
+
+func (failingStore[T]) Clear(ctx context.Context) error {
+	return errStoreUnavailable
+}
+
+func (failingStore[T]) Len(ctx context.Context) (int, error) {
+	return 0, errStoreUnavailable
+}
+
+func (failingStore[T]) DeleteExpired(ctx context.Context, now int64) (int, error) {
+	return 0, errStoreUnavailable
+}
+
+func (failingStore[T]) DeleteTag(ctx context.Context, tag string) (int, error) {
+	return 0, errStoreUnavailable
+}
+
+func (failingStore[T]) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	return 0, errStoreUnavailable
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore[string](2, LRU)
+	expiration := time.Now().Add(time.Hour).UnixNano()
+
+	require.NoError(t, store.Set(ctx, "key1", Item[string]{Value: "value1", Expiration: expiration}))
+	require.NoError(t, store.Set(ctx, "key2", Item[string]{Value: "value2", Expiration: expiration}))
+
+	item, found, err := store.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value1", item.Value)
+	assert.Equal(t, expiration, item.Expiration)
+
+	// key2 is the least recently used item and is evicted
+	require.NoError(t, store.Set(ctx, "key3", Item[string]{Value: "value3", Expiration: expiration}))
+	_, found, _ = store.Get(ctx, "key2")
+	assert.False(t, found)
+
+	deleted, err := store.Delete(ctx, "key1")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, _ = store.Delete(ctx, "key1")
+	assert.False(t, deleted)
+
+	size, _ := store.Len(ctx)
+	assert.Equal(t, 1, size)
+
+	require.NoError(t, store.Clear(ctx))
+	size, _ = store.Len(ctx)
+	assert.Equal(t, 0, size)
+}
+
+func TestMemoryStore_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore[string](0, LRU)
+	now := time.Now()
+
+	require.NoError(t, store.Set(ctx, "expired", Item[string]{Value: "a", Expiration: now.Add(-time.Second).UnixNano()}))
+	require.NoError(t, store.Set(ctx, "fresh", Item[string]{Value: "b", Expiration: now.Add(time.Hour).UnixNano()}))
+
+	removed, err := store.DeleteExpired(ctx, now.UnixNano())
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, found, _ := store.Get(ctx, "fresh")
+	assert.True(t, found)
+	_, found, _ = store.Get(ctx, "expired")
+	assert.False(t, found)
+}
+
+func TestNewCacheWithStore(t *testing.T) {
+	store := NewMemoryStore[string](10, FIFO)
+	cache := NewCacheWithStore[string](DefaultConfig(), DefaultOptions(), store)
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.Set(ctx, "key1", "value1")
+
+	item, found, err := store.Get(ctx, "key1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "value1", item.Value)
+
+	assert.Nil(t, NewCacheWithStore[string](DefaultConfig().WithEnabled(false), DefaultOptions(), store))
+}
+
+func TestCache_StoreErrors(t *testing.T) {
+	cache := NewCacheWithStore[string](DefaultConfig(), DefaultOptions(), failingStore[string]{})
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+
+	assert.NotPanics(t, func() {
+		cache.Set(ctx, "key1", "value1")
+		cache.SetWithTTL(ctx, "key1", "value1", time.Minute)
+		cache.Delete(ctx, "key1")
+		cache.Clear(ctx)
+		cache.SetWithTags(ctx, "key1", "value1", "tag1")
+		cache.InvalidateTag(ctx, "tag1")
+		cache.DeletePrefix(ctx, "key")
+		cache.cleanup()
+	})
+
+	// Read errors are treated as misses
+	_, found := cache.Get(ctx, "key1")
+	assert.False(t, found)
+	assert.Equal(t, 0, cache.Size())
+
+	// WithCache still calls the function
+	result, err := WithCache(ctx, cache, "key1", func(ctx context.Context) (string, error) {
+		return "computed value", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "computed value", result)
+}
+
+func TestJSONCodec(t *testing.T) {
+	type product struct {
+		ID    string
+		Price float64
+	}
+
+	codec := JSONCodec[product]{}
+	data, err := codec.Marshal(product{ID: "123", Price: 9.99})
+	require.NoError(t, err)
+
+	decoded, err := codec.Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, product{ID: "123", Price: 9.99}, decoded)
+
+	_, err = codec.Unmarshal([]byte("not json"))
+	assert.Error(t, err)
+}
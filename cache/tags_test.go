@@ -0,0 +1,176 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_InvalidateTag(t *testing.T) {
+	cache := NewCache[string](DefaultConfig(), DefaultOptions())
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.SetWithTags(ctx, "user:1", "alice", "tenant:1", "users")
+	cache.SetWithTags(ctx, "order:7", "order", "tenant:1")
+	cache.SetWithTags(ctx, "user:2", "bob", "tenant:2", "users")
+	cache.Set(ctx, "config", "value")
+
+	cache.InvalidateTag(ctx, "tenant:1")
+
+	_, found := cache.Get(ctx, "user:1")
+	assert.False(t, found)
+	_, found = cache.Get(ctx, "order:7")
+	assert.False(t, found)
+	_, found = cache.Get(ctx, "user:2")
+	assert.True(t, found)
+	_, found = cache.Get(ctx, "config")
+	assert.True(t, found)
+
+	cache.InvalidateTag(ctx, "users")
+	assert.Equal(t, 1, cache.Size())
+}
+
+func TestCache_SetWithTagsReplacesTags(t *testing.T) {
+	cache := NewCache[string](DefaultConfig(), DefaultOptions())
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.SetWithTags(ctx, "key1", "v1", "old")
+	cache.SetWithTags(ctx, "key1", "v2", "new")
+
+	cache.InvalidateTag(ctx, "old")
+	value, found := cache.Get(ctx, "key1")
+	assert.True(t, found)
+	assert.Equal(t, "v2", value)
+
+	// Overwriting without tags removes the item from its tags
+	cache.Set(ctx, "key1", "v3")
+	cache.InvalidateTag(ctx, "new")
+	_, found = cache.Get(ctx, "key1")
+	assert.True(t, found)
+}
+
+func TestCache_DeletePrefix(t *testing.T) {
+	cfg := DefaultConfig().WithNegativeTTL(time.Minute)
+	cache := NewCache[string](cfg, DefaultOptions())
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.Set(ctx, "tenant:1:user:1", "a")
+	cache.Set(ctx, "tenant:1:user:2", "b")
+	cache.Set(ctx, "tenant:10:user:1", "c")
+	_, _ = WithCache(ctx, cache, "tenant:1:user:3", func(ctx context.Context) (string, error) {
+		return "", errors.New("not found")
+	})
+
+	cache.DeletePrefix(ctx, "tenant:1:")
+
+	_, found := cache.Get(ctx, "tenant:1:user:1")
+	assert.False(t, found)
+	_, found = cache.Get(ctx, "tenant:10:user:1")
+	assert.True(t, found)
+	assert.Equal(t, 1, cache.Size())
+
+	// Cached loader errors under the prefix are removed too
+	value, err := WithCache(ctx, cache, "tenant:1:user:3", func(ctx context.Context) (string, error) {
+		return "d", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "d", value)
+}
+
+func TestCache_IndexesFollowEvictionAndExpiry(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	store := NewMemoryStore[string](2, FIFO)
+	store.SetClock(fake)
+	cache := NewCacheWithStore[string](DefaultConfig(), DefaultOptions().WithClock(fake), store)
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.SetWithTags(ctx, "a:1", "v", "t")
+	cache.SetWithTags(ctx, "a:2", "v", "t")
+	cache.SetWithTags(ctx, "b:1", "v", "t") // evicts a:1
+
+	store.mu.Lock()
+	assert.ElementsMatch(t, []string{"a:2", "b:1"}, store.tags.lookup("t"))
+	assert.Equal(t, []string{"a:2"}, store.prefixes.lookup("a:"))
+	store.mu.Unlock()
+
+	cache.SetWithTTL(ctx, "a:2", "v", time.Millisecond)
+	fake.Advance(5 * time.Millisecond)
+	cache.cleanup()
+
+	store.mu.Lock()
+	assert.Equal(t, []string{"b:1"}, store.tags.lookup("t"))
+	assert.Empty(t, store.prefixes.lookup("a:"))
+	store.mu.Unlock()
+}
+
+func TestCache_RefreshKeepsTags(t *testing.T) {
+	cfg := DefaultConfig().WithTTL(5 * time.Millisecond).WithStaleTTL(time.Minute)
+	fake := clock.NewFake(time.Time{})
+	cache := NewCache[string](cfg, DefaultOptions().WithClock(fake))
+	require.NotNil(t, cache)
+	defer cache.Shutdown()
+
+	ctx := context.Background()
+	cache.SetLoader(func(ctx context.Context, key string) (string, error) {
+		return "reloaded", nil
+	})
+	cache.SetWithTags(ctx, "key1", "value1", "tag1")
+	fake.Advance(10 * time.Millisecond)
+
+	_, _ = cache.Get(ctx, "key1")
+	assert.Eventually(t, func() bool {
+		value, _ := cache.Get(ctx, "key1")
+		return value == "reloaded"
+	}, time.Second, 5*time.Millisecond)
+
+	cache.InvalidateTag(ctx, "tag1")
+	assert.Equal(t, 0, cache.Size())
+}
+
+func TestMemoryStore_DeleteTagAndPrefix(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore[string](0, LRU)
+	expiration := time.Now().Add(time.Hour).UnixNano()
+
+	require.NoError(t, store.Set(ctx, "x:1", Item[string]{Value: "a", Expiration: expiration, Tags: []string{"t1"}}))
+	require.NoError(t, store.Set(ctx, "x:2", Item[string]{Value: "b", Expiration: expiration, Tags: []string{"t1", "t2"}}))
+	require.NoError(t, store.Set(ctx, "y:1", Item[string]{Value: "c", Expiration: expiration}))
+
+	removed, err := store.DeleteTag(ctx, "t2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	removed, err = store.DeletePrefix(ctx, "x:")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	size, _ := store.Len(ctx)
+	assert.Equal(t, 1, size)
+
+	removed, _ = store.DeleteTag(ctx, "t1")
+	assert.Equal(t, 0, removed)
+}
+
+func TestCache_TagsNilCache(t *testing.T) {
+	var cache *Cache[string]
+	assert.NotPanics(t, func() {
+		cache.SetWithTags(context.Background(), "key1", "value1", "tag1")
+		cache.InvalidateTag(context.Background(), "tag1")
+		cache.DeletePrefix(context.Background(), "key")
+	})
+}
@@ -0,0 +1,472 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/logging"
+	"github.com/abitofhelp/servicelib/telemetry"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// invalidationOp identifies the kind of invalidation broadcast by a Tiered cache.
+type invalidationOp string
+
+const (
+	// invalidateKey drops a single key
+	invalidateKey invalidationOp = "key"
+
+	// invalidateTag drops every item stored with a tag
+	invalidateTag invalidationOp = "tag"
+
+	// invalidatePrefix drops every key with a prefix
+	invalidatePrefix invalidationOp = "prefix"
+
+	// invalidateAll drops every item
+	invalidateAll invalidationOp = "all"
+)
+
+// invalidation is the message a Tiered cache broadcasts after a write.
+type invalidation struct {
+	// Origin is the ID of the Tiered cache that sent the message
+	Origin string `json:"origin"`
+
+	// Op is the kind of invalidation
+	Op invalidationOp `json:"op"`
+
+	// Value is the key, tag or prefix to invalidate, depending on Op
+	Value string `json:"value,omitempty"`
+}
+
+// Tiered is a two-tier cache that puts a small in-process cache (L1) in front of
+// a slower cache shared between replicas (L2), such as a Cache backed by a
+// RedisStore.
+//
+// Reads go through L1, then L2, and copy L2 hits into L1 together with their
+// tags, for the rest of their lifetime in L2. Writes go through to
+// L2 and then L1. After every write or invalidation, Tiered broadcasts a message
+// over a PubSub so that the other replicas drop the affected items from their L1.
+// L1 entries live for at most L1's default TTL, which bounds how long a replica
+// can serve a stale value if a broadcast is lost.
+//
+// Tiered does not own its tiers: shut down L1 and L2 separately after calling Close.
+type Tiered[T any] struct {
+	// name is the identifier for this cache, also used to build the channel name
+	name string
+
+	// l1 is the in-process tier
+	l1 *Cache[T]
+
+	// l2 is the shared tier
+	l2 *Cache[T]
+
+	// pubsub carries invalidations between replicas, or nil to disable broadcasting
+	pubsub PubSub
+
+	// channel is the PubSub channel used for invalidations
+	channel string
+
+	// id identifies this replica so that it can ignore its own broadcasts
+	id string
+
+	// subscription is the active invalidation subscription
+	subscription Subscription
+
+	// logger is used for logging cache operations
+	logger *logging.ContextLogger
+
+	// tracer is used for tracing cache operations
+	tracer telemetry.Tracer
+}
+
+// NewTiered creates a two-tier cache and subscribes to invalidations from other replicas.
+// If either tier is nil (which happens when it is disabled), the other tier is used on its own.
+//
+// Type Parameters:
+//   - T: The type of values to be stored in the cache.
+//
+// Parameters:
+//   - l1: The in-process tier, typically a small Cache with a short TTL.
+//   - l2: The shared tier, typically created with NewCacheWithStore and a RedisStore.
+//   - pubsub: The PubSub used to broadcast invalidations, or nil to disable broadcasting.
+//   - options: Logging, tracing and the cache name. Replicas that share an L2 must use
+//     the same name, because it selects the invalidation channel.
+//
+// Returns:
+//   - *Tiered[T]: A new two-tier cache.
+//   - error: An error if subscribing to invalidations fails.
+func NewTiered[T any](l1, l2 *Cache[T], pubsub PubSub, options Options) (*Tiered[T], error) {
+	// Use the provided logger or create a no-op logger
+	logger := options.Logger
+	if logger == nil {
+		logger = logging.NewContextLogger(zap.NewNop())
+	}
+
+	// Use the provided tracer or create a no-op tracer
+	tracer := options.Tracer
+	if tracer == nil {
+		tracer = telemetry.NewNoopTracer()
+	}
+
+	t := &Tiered[T]{
+		name:    options.Name,
+		l1:      l1,
+		l2:      l2,
+		pubsub:  pubsub,
+		channel: "cache.invalidate." + options.Name,
+		id:      uuid.New().String(),
+		logger:  logger,
+		tracer:  tracer,
+	}
+
+	if pubsub != nil {
+		subscription, err := pubsub.Subscribe(context.Background(), t.channel, t.handleInvalidation)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ExternalServiceErrorCode, "failed to subscribe to cache invalidations")
+		}
+		t.subscription = subscription
+	}
+
+	logger.Info(context.Background(), "Tiered cache initialized",
+		zap.String("name", options.Name),
+		zap.String("channel", t.channel),
+		zap.Bool("broadcast", pubsub != nil))
+	return t, nil
+}
+
+// Get retrieves an item from L1, or from L2 if L1 misses. L2 hits are copied into L1.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - key: The key to look up in the cache.
+//
+// Returns:
+//   - T: The value associated with the key, or the zero value of type T if not found.
+//   - bool: true if the key was found in either tier, false otherwise.
+func (t *Tiered[T]) Get(ctx context.Context, key string) (T, bool) {
+	if t == nil {
+		var zero T
+		return zero, false
+	}
+
+	// Create a span for the cache operation
+	var span telemetry.Span
+	ctx, span = t.tracer.Start(ctx, "cache.Tiered.Get")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("cache.name", t.name),
+		attribute.String("cache.key", key),
+		attribute.String("cache.operation", "tiered_get"),
+	)
+
+	value, tier, found := t.get(ctx, span, key)
+	span.SetAttributes(
+		attribute.Bool("cache.hit", found),
+		attribute.String("cache.tier", tier),
+	)
+	return value, found
+}
+
+// GetOrLoad retrieves an item from L1 or L2, or calls fn and stores its result in
+// both tiers. Concurrent misses in this replica are coalesced by L2 as in WithCache,
+// and loader errors are cached when L2 has a NegativeTTL.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - key: The key under which to store and retrieve the value.
+//   - fn: The function to execute if the value is not found in either tier.
+//
+// Returns:
+//   - T: The value retrieved from the cache or returned by the function.
+//   - error: An error if the function execution fails, or nil if successful.
+func (t *Tiered[T]) GetOrLoad(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	if t == nil {
+		return fn(ctx)
+	}
+
+	// Create a span for the cache operation
+	var span telemetry.Span
+	ctx, span = t.tracer.Start(ctx, "cache.Tiered.GetOrLoad")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("cache.name", t.name),
+		attribute.String("cache.key", key),
+		attribute.String("cache.operation", "tiered_get_or_load"),
+	)
+
+	if value, tier, found := t.get(ctx, span, key); found {
+		span.SetAttributes(
+			attribute.Bool("cache.hit", true),
+			attribute.String("cache.tier", tier),
+		)
+		return value, nil
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", false))
+
+	var value T
+	var err error
+	if t.l2 == nil {
+		value, err = fn(ctx)
+	} else {
+		value, err = t.l2.load(ctx, span, key, fn, func(ctx context.Context, value T) {
+			t.l2.Set(ctx, key, value)
+		})
+	}
+	if err != nil {
+		span.RecordError(err)
+		return value, err
+	}
+
+	// The loaded item has no tags and lives for L2's default TTL
+	t.l1.SetWithTTL(ctx, key, value, t.l1TTL(t.l2TTL()))
+	return value, nil
+}
+
+// Set adds an item to both tiers with L2's default TTL and tells other replicas
+// to drop the key from their L1.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - key: The key under which to store the value.
+//   - value: The value to store in the cache.
+func (t *Tiered[T]) Set(ctx context.Context, key string, value T) {
+	if t == nil {
+		return
+	}
+
+	t.l2.Set(ctx, key, value)
+	t.l1.SetWithTTL(ctx, key, value, t.l1TTL(t.l2TTL()))
+	t.broadcast(ctx, invalidateKey, key)
+}
+
+// SetWithTTL adds an item to both tiers and tells other replicas to drop the key
+// from their L1. The item is kept in L1 for at most L1's default TTL.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - key: The key under which to store the value.
+//   - value: The value to store in the cache.
+//   - ttl: The time-to-live for the item in L2.
+func (t *Tiered[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.l2.SetWithTTL(ctx, key, value, ttl)
+	t.l1.SetWithTTL(ctx, key, value, t.l1TTL(ttl))
+	t.broadcast(ctx, invalidateKey, key)
+}
+
+// SetWithTags adds an item with tags to both tiers and tells other replicas to
+// drop the key from their L1.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - key: The key under which to store the value.
+//   - value: The value to store in the cache.
+//   - tags: The tags to associate with the item.
+func (t *Tiered[T]) SetWithTags(ctx context.Context, key string, value T, tags ...string) {
+	if t == nil {
+		return
+	}
+
+	t.l2.SetWithTags(ctx, key, value, tags...)
+	t.l1.SetWithTags(ctx, key, value, tags...)
+	t.broadcast(ctx, invalidateKey, key)
+}
+
+// Delete removes an item from both tiers on this replica and from L1 on the others.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - key: The key of the item to remove.
+func (t *Tiered[T]) Delete(ctx context.Context, key string) {
+	if t == nil {
+		return
+	}
+
+	t.l2.Delete(ctx, key)
+	t.l1.Delete(ctx, key)
+	t.broadcast(ctx, invalidateKey, key)
+}
+
+// InvalidateTag removes every item stored with tag from both tiers on this
+// replica and from L1 on the others.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - tag: The tag whose items should be removed.
+func (t *Tiered[T]) InvalidateTag(ctx context.Context, tag string) {
+	if t == nil {
+		return
+	}
+
+	t.l2.InvalidateTag(ctx, tag)
+	t.l1.InvalidateTag(ctx, tag)
+	t.broadcast(ctx, invalidateTag, tag)
+}
+
+// DeletePrefix removes every item whose key starts with prefix from both tiers on
+// this replica and from L1 on the others.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - prefix: The key prefix of the items to remove.
+func (t *Tiered[T]) DeletePrefix(ctx context.Context, prefix string) {
+	if t == nil {
+		return
+	}
+
+	t.l2.DeletePrefix(ctx, prefix)
+	t.l1.DeletePrefix(ctx, prefix)
+	t.broadcast(ctx, invalidatePrefix, prefix)
+}
+
+// Clear removes all items from both tiers on this replica and from L1 on the others.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+func (t *Tiered[T]) Clear(ctx context.Context) {
+	if t == nil {
+		return
+	}
+
+	t.l2.Clear(ctx)
+	t.l1.Clear(ctx)
+	t.broadcast(ctx, invalidateAll, "")
+}
+
+// Close stops receiving invalidations from other replicas.
+// It does not shut down the tiers.
+//
+// Returns:
+//   - error: An error if closing the subscription fails.
+func (t *Tiered[T]) Close() error {
+	if t == nil || t.subscription == nil {
+		return nil
+	}
+
+	t.logger.Info(context.Background(), "Closing tiered cache", zap.String("name", t.name))
+	return t.subscription.Close()
+}
+
+// get looks key up in L1 and then L2, copying L2 hits into L1.
+// It returns the value, the tier that served it ("l1", "l2" or "miss"), and whether it was found.
+func (t *Tiered[T]) get(ctx context.Context, span telemetry.Span, key string) (T, string, bool) {
+	if value, found := t.l1.Get(ctx, key); found {
+		return value, "l1", true
+	}
+
+	if t.l2 == nil {
+		var zero T
+		return zero, "miss", false
+	}
+	item, found := t.l2.getItem(ctx, key, t.l2.loaderRefresh(key))
+	if !found {
+		return item.Value, "miss", false
+	}
+
+	t.fill(ctx, span, key, item)
+	return item.Value, "l2", true
+}
+
+// fill copies an item read from L2 into L1 with its tags, so that tag
+// invalidations reach the copy. The copy lives for the rest of the item's
+// lifetime in L2, capped at L1's default TTL. Stale items served by L2 while
+// they are refreshed are not copied.
+func (t *Tiered[T]) fill(ctx context.Context, span telemetry.Span, key string, item Item[T]) {
+	if t.l1 == nil {
+		return
+	}
+
+	remaining := time.Duration(item.Expiration - t.l2.clock.Now().UnixNano())
+	if remaining <= 0 {
+		return
+	}
+	t.l1.setItem(ctx, span, key, item.Value, t.l1TTL(remaining), append([]string(nil), item.Tags...))
+}
+
+// l2TTL returns L2's default TTL, or zero if L2 is disabled.
+func (t *Tiered[T]) l2TTL() time.Duration {
+	if t.l2 == nil {
+		return 0
+	}
+	return t.l2.defaultTTL
+}
+
+// l1TTL returns the TTL for an L1 entry of an item that lives for ttl in L2,
+// capped at L1's default TTL.
+func (t *Tiered[T]) l1TTL(ttl time.Duration) time.Duration {
+	if t.l1 == nil {
+		return ttl
+	}
+	if ttl <= 0 || ttl > t.l1.defaultTTL {
+		return t.l1.defaultTTL
+	}
+	return ttl
+}
+
+// broadcast tells other replicas to apply an invalidation to their L1.
+// Failures are logged; L1's TTL bounds how long other replicas may serve stale data.
+func (t *Tiered[T]) broadcast(ctx context.Context, op invalidationOp, value string) {
+	if t.pubsub == nil {
+		return
+	}
+
+	payload, err := json.Marshal(invalidation{Origin: t.id, Op: op, Value: value})
+	if err == nil {
+		err = t.pubsub.Publish(ctx, t.channel, payload)
+	}
+	if err != nil {
+		t.logger.Warn(ctx, "Failed to broadcast cache invalidation",
+			zap.String("name", t.name),
+			zap.String("op", string(op)),
+			zap.String("value", value),
+			zap.Error(err))
+	}
+}
+
+// handleInvalidation applies an invalidation broadcast by another replica to L1.
+// L2 is shared, so the sender has already updated it.
+func (t *Tiered[T]) handleInvalidation(payload []byte) {
+	ctx := context.Background()
+
+	var msg invalidation
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.logger.Warn(ctx, "Ignoring malformed cache invalidation",
+			zap.String("name", t.name),
+			zap.Error(err))
+		return
+	}
+	if msg.Origin == t.id {
+		return
+	}
+
+	t.logger.Debug(ctx, "Applying cache invalidation from another replica",
+		zap.String("name", t.name),
+		zap.String("op", string(msg.Op)),
+		zap.String("value", msg.Value))
+
+	switch msg.Op {
+	case invalidateKey:
+		t.l1.Delete(ctx, msg.Value)
+	case invalidateTag:
+		t.l1.InvalidateTag(ctx, msg.Value)
+	case invalidatePrefix:
+		t.l1.DeletePrefix(ctx, msg.Value)
+	case invalidateAll:
+		t.l1.Clear(ctx)
+	default:
+		t.logger.Warn(ctx, "Ignoring unknown cache invalidation",
+			zap.String("name", t.name),
+			zap.String("op", string(msg.Op)))
+	}
+}
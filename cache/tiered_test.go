@@ -0,0 +1,286 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tieredReplica is one replica of a service with its own L1 and a shared L2.
+type tieredReplica struct {
+	l1     *Cache[string]
+	l2     *Cache[string]
+	tiered *Tiered[string]
+}
+
+// newTieredReplicas creates replicas that share one L2 store and one PubSub.
+func newTieredReplicas(t *testing.T, n int) []tieredReplica {
+	t.Helper()
+
+	store, _, _ := newTestRedisStore[string](t, "cache:tiered:")
+	pubsub := NewMemoryPubSub()
+
+	replicas := make([]tieredReplica, n)
+	for i := range replicas {
+		l1 := NewCache[string](DefaultConfig().WithTTL(time.Minute).WithMaxSize(100), DefaultOptions().WithName("l1"))
+		l2 := NewCacheWithStore[string](DefaultConfig().WithTTL(time.Hour), DefaultOptions().WithName("l2"), store)
+		tiered, err := NewTiered[string](l1, l2, pubsub, DefaultOptions().WithName("users"))
+		require.NoError(t, err)
+
+		t.Cleanup(func() {
+			_ = tiered.Close()
+			l1.Shutdown()
+			l2.Shutdown()
+		})
+		replicas[i] = tieredReplica{l1: l1, l2: l2, tiered: tiered}
+	}
+	return replicas
+}
+
+func TestTiered_ReadThrough(t *testing.T) {
+	replicas := newTieredReplicas(t, 2)
+	ctx := context.Background()
+	a, b := replicas[0], replicas[1]
+
+	a.tiered.Set(ctx, "user:1", "alice")
+
+	// Written through to both tiers
+	_, found := a.l1.Get(ctx, "user:1")
+	assert.True(t, found)
+	_, found = a.l2.Get(ctx, "user:1")
+	assert.True(t, found)
+
+	// Another replica reads from L2 and populates its L1
+	_, found = b.l1.Get(ctx, "user:1")
+	assert.False(t, found)
+	value, found := b.tiered.Get(ctx, "user:1")
+	assert.True(t, found)
+	assert.Equal(t, "alice", value)
+	value, found = b.l1.Get(ctx, "user:1")
+	assert.True(t, found)
+	assert.Equal(t, "alice", value)
+
+	_, found = b.tiered.Get(ctx, "missing")
+	assert.False(t, found)
+}
+
+func TestTiered_BroadcastsInvalidations(t *testing.T) {
+	replicas := newTieredReplicas(t, 2)
+	ctx := context.Background()
+	a, b := replicas[0], replicas[1]
+
+	a.tiered.Set(ctx, "user:1", "alice")
+	_, _ = b.tiered.Get(ctx, "user:1")
+
+	// An update on one replica drops the key from the other replica's L1
+	a.tiered.Set(ctx, "user:1", "alice v2")
+	_, found := b.l1.Get(ctx, "user:1")
+	assert.False(t, found)
+	value, _ := b.tiered.Get(ctx, "user:1")
+	assert.Equal(t, "alice v2", value)
+
+	// The writer keeps its own L1 entry
+	value, found = a.l1.Get(ctx, "user:1")
+	assert.True(t, found)
+	assert.Equal(t, "alice v2", value)
+
+	a.tiered.Delete(ctx, "user:1")
+	_, found = b.tiered.Get(ctx, "user:1")
+	assert.False(t, found)
+}
+
+func TestTiered_BroadcastsTagPrefixAndClear(t *testing.T) {
+	replicas := newTieredReplicas(t, 2)
+	ctx := context.Background()
+	a, b := replicas[0], replicas[1]
+
+	a.tiered.SetWithTags(ctx, "tenant:1:user:1", "alice", "tenant:1")
+	a.tiered.SetWithTags(ctx, "tenant:2:user:1", "bob", "tenant:2")
+	a.tiered.SetWithTTL(ctx, "tenant:3:user:1", "carol", time.Minute)
+
+	// Populate b's L1
+	for _, key := range []string{"tenant:1:user:1", "tenant:2:user:1", "tenant:3:user:1"} {
+		_, found := b.tiered.Get(ctx, key)
+		require.True(t, found)
+	}
+
+	a.tiered.InvalidateTag(ctx, "tenant:1")
+	_, found := b.tiered.Get(ctx, "tenant:1:user:1")
+	assert.False(t, found)
+
+	a.tiered.DeletePrefix(ctx, "tenant:2:")
+	_, found = b.tiered.Get(ctx, "tenant:2:user:1")
+	assert.False(t, found)
+
+	a.tiered.Clear(ctx)
+	_, found = b.tiered.Get(ctx, "tenant:3:user:1")
+	assert.False(t, found)
+	assert.Equal(t, 0, b.l1.Size())
+}
+
+func TestTiered_L1CopyKeepsTags(t *testing.T) {
+	replicas := newTieredReplicas(t, 2)
+	ctx := context.Background()
+	a, b := replicas[0], replicas[1]
+
+	a.tiered.SetWithTags(ctx, "user:1", "alice", "tenant:1")
+	_, found := b.tiered.Get(ctx, "user:1")
+	require.True(t, found)
+	_, found = b.l1.Get(ctx, "user:1")
+	require.True(t, found)
+
+	// The tag invalidation broadcast by a reaches the copy in b's L1
+	a.tiered.InvalidateTag(ctx, "tenant:1")
+	_, found = b.l1.Get(ctx, "user:1")
+	assert.False(t, found)
+	_, found = b.tiered.Get(ctx, "user:1")
+	assert.False(t, found)
+
+	// So does a tag invalidation made directly on b
+	a.tiered.SetWithTags(ctx, "user:2", "bob", "tenant:2")
+	_, found = b.tiered.Get(ctx, "user:2")
+	require.True(t, found)
+	b.l1.InvalidateTag(ctx, "tenant:2")
+	_, found = b.l1.Get(ctx, "user:2")
+	assert.False(t, found)
+}
+
+func TestTiered_L1CopyKeepsRemainingTTL(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	l1 := NewCache[string](DefaultConfig().WithTTL(time.Minute), DefaultOptions().WithClock(fake))
+	l2 := NewCache[string](DefaultConfig().WithTTL(time.Hour), DefaultOptions().WithClock(fake))
+	defer l1.Shutdown()
+	defer l2.Shutdown()
+
+	tiered, err := NewTiered[string](l1, l2, nil, DefaultOptions())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	l2.SetWithTTL(ctx, "key1", "value1", 10*time.Second)
+	fake.Advance(5 * time.Second)
+
+	// The copy expires with the item in L2, not after L2's or L1's default TTL
+	_, found := tiered.Get(ctx, "key1")
+	require.True(t, found)
+	fake.Advance(6 * time.Second)
+	_, found = l1.Get(ctx, "key1")
+	assert.False(t, found)
+	_, found = tiered.Get(ctx, "key1")
+	assert.False(t, found)
+}
+
+func TestTiered_GetOrLoad(t *testing.T) {
+	replicas := newTieredReplicas(t, 2)
+	ctx := context.Background()
+	a, b := replicas[0], replicas[1]
+
+	calls := 0
+	load := func(ctx context.Context) (string, error) {
+		calls++
+		return "alice", nil
+	}
+
+	value, err := a.tiered.GetOrLoad(ctx, "user:1", load)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", value)
+
+	// The other replica finds the value in L2
+	value, err = b.tiered.GetOrLoad(ctx, "user:1", load)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", value)
+	assert.Equal(t, 1, calls)
+
+	_, err = a.tiered.GetOrLoad(ctx, "user:2", func(ctx context.Context) (string, error) {
+		return "", errors.New("not found")
+	})
+	assert.Error(t, err)
+	_, found := a.l1.Get(ctx, "user:2")
+	assert.False(t, found)
+}
+
+func TestTiered_L1TTLIsCapped(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	l1 := NewCache[string](DefaultConfig().WithTTL(20*time.Millisecond), DefaultOptions().WithClock(fake))
+	l2 := NewCache[string](DefaultConfig().WithTTL(time.Hour), DefaultOptions().WithClock(fake))
+	defer l1.Shutdown()
+	defer l2.Shutdown()
+
+	tiered, err := NewTiered[string](l1, l2, nil, DefaultOptions())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	tiered.Set(ctx, "key1", "value1")
+	tiered.SetWithTTL(ctx, "key2", "value2", 5*time.Millisecond)
+	fake.Advance(30 * time.Millisecond)
+
+	_, found := l1.Get(ctx, "key1")
+	assert.False(t, found)
+	_, found = l2.Get(ctx, "key1")
+	assert.True(t, found)
+	_, found = l2.Get(ctx, "key2")
+	assert.False(t, found)
+}
+
+func TestTiered_IgnoresMalformedInvalidations(t *testing.T) {
+	pubsub := NewMemoryPubSub()
+	l1 := NewCache[string](DefaultConfig(), DefaultOptions())
+	defer l1.Shutdown()
+
+	tiered, err := NewTiered[string](l1, nil, pubsub, DefaultOptions().WithName("users"))
+	require.NoError(t, err)
+	defer tiered.Close()
+
+	ctx := context.Background()
+	l1.Set(ctx, "key1", "value1")
+	require.NoError(t, pubsub.Publish(ctx, "cache.invalidate.users", []byte("not json")))
+	require.NoError(t, pubsub.Publish(ctx, "cache.invalidate.users", []byte(`{"origin":"other","op":"bogus"}`)))
+	assert.Equal(t, 1, l1.Size())
+
+	require.NoError(t, pubsub.Publish(ctx, "cache.invalidate.users", []byte(`{"origin":"other","op":"key","value":"key1"}`)))
+	assert.Equal(t, 0, l1.Size())
+
+	// After Close, invalidations are no longer applied
+	require.NoError(t, tiered.Close())
+	l1.Set(ctx, "key1", "value1")
+	require.NoError(t, pubsub.Publish(ctx, "cache.invalidate.users", []byte(`{"origin":"other","op":"all"}`)))
+	assert.Equal(t, 1, l1.Size())
+}
+
+func TestTiered_SubscribeError(t *testing.T) {
+	pubsub := NewMemoryPubSub()
+	require.NoError(t, pubsub.Close())
+
+	_, err := NewTiered[string](nil, nil, pubsub, DefaultOptions())
+	assert.Error(t, err)
+}
+
+func TestTiered_NilTiered(t *testing.T) {
+	var tiered *Tiered[string]
+	ctx := context.Background()
+
+	assert.NotPanics(t, func() {
+		tiered.Set(ctx, "key1", "value1")
+		tiered.SetWithTTL(ctx, "key1", "value1", time.Minute)
+		tiered.SetWithTags(ctx, "key1", "value1", "tag")
+		tiered.Delete(ctx, "key1")
+		tiered.InvalidateTag(ctx, "tag")
+		tiered.DeletePrefix(ctx, "key")
+		tiered.Clear(ctx)
+		assert.NoError(t, tiered.Close())
+	})
+
+	_, found := tiered.Get(ctx, "key1")
+	assert.False(t, found)
+	value, err := tiered.GetOrLoad(ctx, "key1", func(ctx context.Context) (string, error) {
+		return "loaded", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "loaded", value)
+}
@@ -8,13 +8,16 @@
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
-	"github.com/abitofhelp/servicelib/errors/recovery"
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/errors"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/abitofhelp/servicelib/telemetry"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
@@ -59,6 +62,16 @@ func (s State) String() string {
 	}
 }
 
+// MarshalText encodes the state as its string representation, so that states
+// appear as "Closed", "Open" or "HalfOpen" in JSON.
+//
+// Returns:
+//   - []byte: The string representation of the state.
+//   - error: Always nil.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // Config contains circuit breaker configuration parameters.
 // It defines the behavior of the circuit breaker, including thresholds for
 // tripping the circuit, timeouts, and recovery behavior.
@@ -68,26 +81,46 @@ type Config struct {
 	Enabled bool
 
 	// Timeout is the maximum time allowed for a request.
-	// Requests that exceed this timeout are considered failures.
+	// The request's context is canceled after this time, and requests that
+	// exceed it are counted as timeouts, which count as failures.
 	Timeout time.Duration
 
 	// MaxConcurrent is the maximum number of concurrent requests allowed.
-	// This helps prevent resource exhaustion during high load.
+	// Requests beyond it are rejected without being executed, so that a slow
+	// dependency cannot tie up every goroutine of the caller (a bulkhead).
 	MaxConcurrent int
 
 	// ErrorThreshold is the percentage of errors that will trip the circuit (0.0-1.0).
-	// When the error rate exceeds this threshold, the circuit will open.
+	// When the error rate in the rolling window reaches this threshold, the circuit will open.
 	// For example, 0.5 means the circuit will open when 50% or more of requests fail.
 	ErrorThreshold float64
 
-	// VolumeThreshold is the minimum number of requests before the error threshold is checked.
+	// VolumeThreshold is the minimum number of requests in the rolling window
+	// before the error threshold is checked.
 	// This prevents the circuit from opening due to a small number of failures.
 	VolumeThreshold int
 
-	// SleepWindow is the time to wait before allowing a single request through in half-open state.
-	// After this duration has elapsed since the circuit opened, a test request will be allowed
-	// to determine if the dependency has recovered.
+	// SleepWindow is the time to wait before allowing trial requests through in half-open state.
+	// After this duration has elapsed since the circuit opened, HalfOpenRequests trial
+	// requests will be allowed to determine if the dependency has recovered.
 	SleepWindow time.Duration
+
+	// Window is the length of the rolling window in which successes, failures,
+	// timeouts and rejections are counted. Outcomes older than the window no
+	// longer count against the error threshold.
+	Window time.Duration
+
+	// Buckets is the number of buckets the rolling window is split into.
+	// Outcomes expire one bucket, or Window / Buckets, at a time.
+	Buckets int
+
+	// HalfOpenRequests is the number of trial requests allowed in half-open state.
+	// The circuit closes once all of them succeed, and opens again as soon as one fails.
+	HalfOpenRequests int
+
+	// ConsecutiveFailures is the number of failures or timeouts in a row that trip
+	// the circuit, whatever the error rate. Zero disables this check.
+	ConsecutiveFailures int
 }
 
 // DefaultConfig returns a default circuit breaker configuration with reasonable values.
@@ -98,17 +131,24 @@ type Config struct {
 //   - ErrorThreshold: 0.5 (circuit opens when 50% or more of requests fail)
 //   - VolumeThreshold: 10 (minimum of 10 requests before checking error threshold)
 //   - SleepWindow: 1 second (wait 1 second before testing if dependency has recovered)
+//   - Window: 10 seconds (outcomes count against the error threshold for 10 seconds)
+//   - Buckets: 10 (outcomes expire one second at a time)
+//   - HalfOpenRequests: 1 (a single successful trial request closes the circuit)
+//   - ConsecutiveFailures: 0 (only the error rate trips the circuit)
 //
 // Returns:
 //   - A Config instance with default values.
 func DefaultConfig() Config {
 	return Config{
-		Enabled:         true,
-		Timeout:         5 * time.Second,
-		MaxConcurrent:   100,
-		ErrorThreshold:  0.5,
-		VolumeThreshold: 10,
-		SleepWindow:     1 * time.Second,
+		Enabled:          true,
+		Timeout:          5 * time.Second,
+		MaxConcurrent:    100,
+		ErrorThreshold:   0.5,
+		VolumeThreshold:  10,
+		SleepWindow:      1 * time.Second,
+		Window:           10 * time.Second,
+		Buckets:          10,
+		HalfOpenRequests: 1,
 	}
 }
 
@@ -197,10 +237,10 @@ func (c Config) WithVolumeThreshold(volumeThreshold int) Config {
 	return c
 }
 
-// WithSleepWindow sets the time to wait before allowing a single request through in half-open state.
-// After this duration has elapsed since the circuit opened, a test request will be allowed
-// to determine if the dependency has recovered. If the test request succeeds, the circuit
-// will close; if it fails, the circuit will remain open for another sleep window.
+// WithSleepWindow sets the time to wait before allowing trial requests through in half-open state.
+// After this duration has elapsed since the circuit opened, trial requests will be allowed
+// to determine if the dependency has recovered. If the trial requests succeed, the circuit
+// will close; if one fails, the circuit will remain open for another sleep window.
 // If a non-positive value is provided, it will be set to 1 millisecond.
 //
 // Parameters:
@@ -216,6 +256,74 @@ func (c Config) WithSleepWindow(sleepWindow time.Duration) Config {
 	return c
 }
 
+// WithWindow sets the length of the rolling window in which outcomes are counted.
+// If a non-positive value is provided, it will be set to 1 millisecond.
+//
+// Parameters:
+//   - window: The length of the rolling window.
+//
+// Returns:
+//   - A new Config instance with the updated Window value.
+func (c Config) WithWindow(window time.Duration) Config {
+	if window <= 0 {
+		window = 1 * time.Millisecond
+	}
+	c.Window = window
+	return c
+}
+
+// WithBuckets sets the number of buckets the rolling window is split into.
+// More buckets make outcomes expire more smoothly at the cost of memory.
+// If a non-positive value is provided, it will be set to 1.
+//
+// Parameters:
+//   - buckets: The number of buckets in the rolling window.
+//
+// Returns:
+//   - A new Config instance with the updated Buckets value.
+func (c Config) WithBuckets(buckets int) Config {
+	if buckets <= 0 {
+		buckets = 1
+	}
+	c.Buckets = buckets
+	return c
+}
+
+// WithHalfOpenRequests sets the number of trial requests allowed in half-open state.
+// The circuit closes once all of them succeed.
+// If a non-positive value is provided, it will be set to 1.
+//
+// Parameters:
+//   - halfOpenRequests: The number of trial requests.
+//
+// Returns:
+//   - A new Config instance with the updated HalfOpenRequests value.
+func (c Config) WithHalfOpenRequests(halfOpenRequests int) Config {
+	if halfOpenRequests <= 0 {
+		halfOpenRequests = 1
+	}
+	c.HalfOpenRequests = halfOpenRequests
+	return c
+}
+
+// WithConsecutiveFailures sets the number of failures in a row that trip the circuit,
+// whatever the error rate in the rolling window. This suits dependencies that fail
+// outright rather than intermittently.
+// If a negative value is provided, it will be set to 0, which disables the check.
+//
+// Parameters:
+//   - consecutiveFailures: The number of consecutive failures that trip the circuit.
+//
+// Returns:
+//   - A new Config instance with the updated ConsecutiveFailures value.
+func (c Config) WithConsecutiveFailures(consecutiveFailures int) Config {
+	if consecutiveFailures < 0 {
+		consecutiveFailures = 0
+	}
+	c.ConsecutiveFailures = consecutiveFailures
+	return c
+}
+
 // Options contains additional options for the circuit breaker.
 // These options are not directly related to the circuit breaker behavior itself,
 // but provide additional functionality like logging, tracing, and identification.
@@ -232,6 +340,21 @@ type Options struct {
 	// This is useful for identifying the circuit breaker in logs and traces,
 	// especially when multiple circuit breakers are used in the same application.
 	Name string
+
+	// Meter is used to create metrics for circuit breaker operations.
+	// If nil, no metrics are recorded.
+	Meter metric.Meter
+
+	// OnStateChange is called whenever the circuit moves from one state to another,
+	// with the name of the circuit breaker. It is called after the circuit
+	// breaker's lock is released, so it may call methods of the circuit breaker,
+	// but it runs on the goroutine of the request that caused the change and
+	// should return quickly. If nil, state changes are only logged.
+	OnStateChange func(name string, from, to State)
+
+	// Clock is used to measure requests, time out calls and expire the sleep
+	// window and the rolling window. If nil, the system clock will be used.
+	Clock clock.Clock
 }
 
 // DefaultOptions returns default options for circuit breaker operations.
@@ -295,19 +418,124 @@ func (o Options) WithName(name string) Options {
 	return o
 }
 
-// CircuitBreaker implements the circuit breaker pattern
+// WithMeter sets the OpenTelemetry meter used to record circuit breaker metrics.
+// The circuit breaker records its state as a gauge (0 for Closed, 1 for Open and
+// 2 for HalfOpen), and counts executed requests by outcome, rejected requests by
+// reason, and state transitions, each with a circuit.name attribute.
+//
+// Parameters:
+//   - meter: An OpenTelemetry metric.Meter instance.
+//
+// Returns:
+//   - A new Options instance with the updated Meter value.
+func (o Options) WithMeter(meter metric.Meter) Options {
+	o.Meter = meter
+	return o
+}
+
+// WithOnStateChange sets the function called whenever the circuit changes state.
+// This can be used to alert on circuits that open, or to record the transitions
+// in an application's own metrics.
+//
+// Parameters:
+//   - onStateChange: The function to call with the name of the circuit breaker
+//     and the states it moved from and to.
+//
+// Returns:
+//   - A new Options instance with the updated OnStateChange value.
+func (o Options) WithOnStateChange(onStateChange func(name string, from, to State)) Options {
+	o.OnStateChange = onStateChange
+	return o
+}
+
+// WithClock sets the clock of the circuit breaker.
+// Tests use it to pass a clock.Fake, so that the sleep window elapses without
+// real time passing.
+//
+// Parameters:
+//   - c: The clock used to measure requests and expire windows.
+//
+// Returns:
+//   - A new Options instance with the updated Clock value.
+func (o Options) WithClock(c clock.Clock) Options {
+	o.Clock = c
+	return o
+}
+
+// CircuitBreaker implements the circuit breaker pattern.
+//
+// The outcome of every request is counted in a rolling window split into
+// buckets. When at least VolumeThreshold requests in the window have been
+// executed and the fraction that failed or timed out reaches ErrorThreshold, or
+// ConsecutiveFailures requests in a row fail, the circuit opens and rejects requests for SleepWindow. It then lets
+// HalfOpenRequests trial requests through, and closes once they all succeed.
+// At most MaxConcurrent requests run at once in any state.
+//
+// Operators can force the circuit open or closed with ForceOpen and ForceClosed,
+// which hold it in that state until ClearForce or Reset is called.
+//
+// This implementation is thread-safe and can be used concurrently from multiple
+// goroutines.
 type CircuitBreaker struct {
-	name            string
-	config          Config
-	logger          *logging.ContextLogger
-	tracer          telemetry.Tracer
-	state           State
-	failureCount    int
-	lastFailureTime time.Time
-	mutex           sync.RWMutex
+	name   string
+	config Config
+	logger *logging.ContextLogger
+	tracer telemetry.Tracer
+	clock  clock.Clock
+	mutex  sync.RWMutex
+
+	state    State
+	openedAt time.Time
+	window   *rollingWindow
+
+	// generation changes with every state transition, so that the outcome of a
+	// request admitted in an earlier state is not applied to the current one
+	generation uint64
+
+	// inFlight is the number of requests being executed
+	inFlight int
+
+	// trialsInFlight and trialSuccesses count the trial requests of the current half-open state
+	trialsInFlight int
+	trialSuccesses int
+
+	// failureStreak is the number of failures in a row while closed
+	failureStreak int
+
+	// forced is true while the state was set by ForceOpen or ForceClosed
+	forced bool
+
+	// transitions holds the state changes made while the lock is held, which
+	// are reported once it is released
+	transitions   []transition
+	onStateChange func(name string, from, to State)
+
+	// metrics holds the OpenTelemetry instruments, or nil if metrics are disabled
+	metrics *circuitMetrics
 }
 
-// NewCircuitBreaker creates a new circuit breaker
+// transition is a change from one state to another.
+type transition struct {
+	from State
+	to   State
+}
+
+// admission records the state in which a request was admitted.
+type admission struct {
+	state      State
+	generation uint64
+}
+
+// NewCircuitBreaker creates a new circuit breaker with the specified configuration and options.
+// If the circuit breaker is disabled (config.Enabled is false), nil is returned,
+// which allows all requests through.
+//
+// Parameters:
+//   - config: The configuration parameters for the circuit breaker.
+//   - options: Additional options for the circuit breaker, such as logging and tracing.
+//
+// Returns:
+//   - A new CircuitBreaker instance configured according to the provided parameters.
 func NewCircuitBreaker(config Config, options Options) *CircuitBreaker {
 	if !config.Enabled {
 		if options.Logger != nil {
@@ -328,30 +556,74 @@ func NewCircuitBreaker(config Config, options Options) *CircuitBreaker {
 		tracer = telemetry.NewNoopTracer()
 	}
 
+	// Fill in settings that a hand-built Config may leave unset
+	defaults := DefaultConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.Buckets <= 0 {
+		config.Buckets = defaults.Buckets
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = defaults.HalfOpenRequests
+	}
+
 	logger.Info(context.Background(), "Initializing circuit breaker",
 		zap.String("name", options.Name),
 		zap.Duration("timeout", config.Timeout),
 		zap.Int("max_concurrent", config.MaxConcurrent),
 		zap.Float64("error_threshold", config.ErrorThreshold),
 		zap.Int("volume_threshold", config.VolumeThreshold),
-		zap.Duration("sleep_window", config.SleepWindow))
+		zap.Duration("sleep_window", config.SleepWindow),
+		zap.Duration("window", config.Window),
+		zap.Int("buckets", config.Buckets),
+		zap.Int("half_open_requests", config.HalfOpenRequests),
+		zap.Int("consecutive_failures", config.ConsecutiveFailures))
+
+	cb := &CircuitBreaker{
+		name:          options.Name,
+		config:        config,
+		logger:        logger,
+		tracer:        tracer,
+		clock:         clock.OrReal(options.Clock),
+		state:         Closed,
+		window:        newRollingWindow(config.Window, config.Buckets),
+		onStateChange: options.OnStateChange,
+	}
 
-	return &CircuitBreaker{
-		name:            options.Name,
-		config:          config,
-		logger:          logger,
-		tracer:          tracer,
-		state:           Closed,
-		failureCount:    0,
-		lastFailureTime: time.Time{},
-		mutex:           sync.RWMutex{},
+	if options.Meter != nil {
+		metrics, err := newCircuitMetrics(options.Meter, options.Name, cb.GetState)
+		if err != nil {
+			logger.Warn(context.Background(), "Failed to create circuit breaker metrics, continuing without metrics",
+				zap.String("name", options.Name),
+				zap.Error(err))
+		} else {
+			cb.metrics = metrics
+		}
 	}
+
+	return cb
 }
 
-// Execute executes the given function with circuit breaking
-// If the circuit is open, it will return an error immediately
-// If the circuit is closed or half-open, it will execute the function
-// and update the circuit state based on the result
+// Execute executes the given function with circuit breaking.
+// If the circuit is open, it returns an errors.CircuitBreakerOpenError immediately
+// without executing the function, which matches errors.ErrCircuitBreakerOpen with
+// errors.Is. If MaxConcurrent requests are already in flight, it returns an error
+// with the ResourceExhaustedCode. Otherwise it executes the function with the
+// configured Timeout and updates the circuit state based on the result.
+//
+// Type Parameters:
+//   - T: The return type of the function to execute.
+//
+// Parameters:
+//   - ctx: The context for the operation.
+//   - cb: The circuit breaker to use. If nil, the function is executed without circuit breaking.
+//   - operation: A name for the operation being performed, used in logs and traces.
+//   - fn: The function to execute.
+//
+// Returns:
+//   - The result of the function execution, or the zero value of T if the request was rejected.
+//   - An error if the request was rejected or if the function returns an error.
 func Execute[T any](ctx context.Context, cb *CircuitBreaker, operation string, fn func(ctx context.Context) (T, error)) (T, error) {
 	var zero T
 
@@ -371,12 +643,13 @@ func Execute[T any](ctx context.Context, cb *CircuitBreaker, operation string, f
 		attribute.String("circuit.state", cb.GetState().String()),
 	)
 
-	// Check if the circuit is open
-	if !cb.allowRequest() {
-		err := recovery.ErrCircuitBreakerOpen
-		cb.logger.Warn(ctx, "Circuit is open, rejecting request",
+	// Check if the request may run
+	admitted, err := cb.admit()
+	if err != nil {
+		cb.logger.Warn(ctx, "Circuit breaker rejected request",
 			zap.String("circuit", cb.name),
-			zap.String("operation", operation))
+			zap.String("operation", operation),
+			zap.Error(err))
 
 		span.SetAttributes(attribute.String("circuit.result", "rejected"))
 		span.RecordError(err)
@@ -385,18 +658,15 @@ func Execute[T any](ctx context.Context, cb *CircuitBreaker, operation string, f
 	}
 
 	// Execute the function
-	startTime := time.Now()
-	result, err := fn(ctx)
-	duration := time.Since(startTime)
+	startTime := cb.clock.Now()
+	result, err := call(ctx, cb, admitted, fn)
+	duration := cb.clock.Since(startTime)
 
 	span.SetAttributes(
 		attribute.Bool("circuit.success", err == nil),
 		attribute.Int64("circuit.duration_ms", duration.Milliseconds()),
 	)
 
-	// Update the circuit state based on the result
-	cb.updateState(err)
-
 	if err != nil {
 		span.RecordError(err)
 	}
@@ -404,8 +674,23 @@ func Execute[T any](ctx context.Context, cb *CircuitBreaker, operation string, f
 	return result, err
 }
 
-// ExecuteWithFallback executes the given function with circuit breaking
-// If the circuit is open or the function fails, it will execute the fallback function
+// ExecuteWithFallback executes the given function with circuit breaking.
+// If the request is rejected or the function fails, it executes the fallback
+// function with the error instead.
+//
+// Type Parameters:
+//   - T: The return type of the function to execute.
+//
+// Parameters:
+//   - ctx: The context for the operation.
+//   - cb: The circuit breaker to use. If nil, the function is executed without circuit breaking.
+//   - operation: A name for the operation being performed, used in logs and traces.
+//   - fn: The function to execute.
+//   - fallback: The function to execute if the request is rejected or fn fails.
+//
+// Returns:
+//   - The result of the function, or of the fallback if it was used.
+//   - An error if the fallback returns an error.
 func ExecuteWithFallback[T any](ctx context.Context, cb *CircuitBreaker, operation string, fn func(ctx context.Context) (T, error), fallback func(ctx context.Context, err error) (T, error)) (T, error) {
 	if cb == nil {
 		// If circuit breaker is disabled, just execute the function
@@ -427,12 +712,13 @@ func ExecuteWithFallback[T any](ctx context.Context, cb *CircuitBreaker, operati
 		attribute.String("circuit.state", cb.GetState().String()),
 	)
 
-	// Check if the circuit is open
-	if !cb.allowRequest() {
-		err := recovery.ErrCircuitBreakerOpen
-		cb.logger.Warn(ctx, "Circuit is open, using fallback",
+	// Check if the request may run
+	admitted, err := cb.admit()
+	if err != nil {
+		cb.logger.Warn(ctx, "Circuit breaker rejected request, using fallback",
 			zap.String("circuit", cb.name),
-			zap.String("operation", operation))
+			zap.String("operation", operation),
+			zap.Error(err))
 
 		span.SetAttributes(attribute.String("circuit.result", "fallback_used"))
 		span.RecordError(err)
@@ -441,18 +727,15 @@ func ExecuteWithFallback[T any](ctx context.Context, cb *CircuitBreaker, operati
 	}
 
 	// Execute the function
-	startTime := time.Now()
-	result, err := fn(ctx)
-	duration := time.Since(startTime)
+	startTime := cb.clock.Now()
+	result, err := call(ctx, cb, admitted, fn)
+	duration := cb.clock.Since(startTime)
 
 	span.SetAttributes(
 		attribute.Bool("circuit.success", err == nil),
 		attribute.Int64("circuit.duration_ms", duration.Milliseconds()),
 	)
 
-	// Update the circuit state based on the result
-	cb.updateState(err)
-
 	// If the function failed, execute the fallback
 	if err != nil {
 		span.RecordError(err)
@@ -464,74 +747,200 @@ func ExecuteWithFallback[T any](ctx context.Context, cb *CircuitBreaker, operati
 	return result, nil
 }
 
-// allowRequest checks if a request should be allowed through the circuit
-func (cb *CircuitBreaker) allowRequest() bool {
-	cb.mutex.RLock()
-	defer cb.mutex.RUnlock()
+// call executes fn with the breaker's timeout and records its outcome, even if fn panics.
+func call[T any](ctx context.Context, cb *CircuitBreaker, admitted admission, fn func(ctx context.Context) (T, error)) (result T, err error) {
+	callCtx := ctx
+	if cb.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = clock.WithTimeout(ctx, cb.clock, cb.config.Timeout)
+		defer cancel()
+	}
+
+	startTime := cb.clock.Now()
+	completed := false
+	defer func() {
+		if !completed {
+			// fn panicked
+			cb.done(admitted, failed)
+		}
+	}()
+
+	result, err = fn(callCtx)
+	completed = true
+
+	switch {
+	case ctx.Err() != nil:
+		cb.done(admitted, abandoned)
+	case cb.config.Timeout > 0 && (cb.clock.Since(startTime) > cb.config.Timeout || callCtx.Err() != nil):
+		cb.done(admitted, timedOut)
+	case err != nil:
+		cb.done(admitted, failed)
+	default:
+		cb.done(admitted, succeeded)
+	}
+	return result, err
+}
+
+// admit decides whether a request may run, and returns the error to reject it with if not.
+func (cb *CircuitBreaker) admit() (admission, error) {
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	now := cb.clock.Now()
+	if cb.state == Open && !cb.forced && now.Sub(cb.openedAt) >= cb.config.SleepWindow {
+		cb.logger.Info(context.Background(), "Circuit breaker sleep window elapsed, allowing trial requests",
+			zap.String("circuit", cb.name),
+			zap.Int("half_open_requests", cb.config.HalfOpenRequests))
+		cb.setState(HalfOpen, now)
+	}
 
 	switch cb.state {
-	case Closed:
-		return true
 	case Open:
-		// Check if the sleep window has elapsed
-		if time.Since(cb.lastFailureTime) > cb.config.SleepWindow {
-			// Transition to half-open state
-			cb.mutex.RUnlock()
-			cb.mutex.Lock()
-			cb.state = HalfOpen
-			cb.mutex.Unlock()
-			cb.mutex.RLock()
-			return true
-		}
-		return false
+		cb.reject(now, "open")
+		return admission{}, errors.NewCircuitBreakerOpenError(cb.name)
 	case HalfOpen:
-		// In half-open state, allow a limited number of requests through
-		return true
-	default:
-		return true
+		if cb.trialsInFlight+cb.trialSuccesses >= cb.config.HalfOpenRequests {
+			cb.reject(now, "half_open")
+			return admission{}, errors.NewCircuitBreakerOpenError(cb.name)
+		}
 	}
+
+	if cb.config.MaxConcurrent > 0 && cb.inFlight >= cb.config.MaxConcurrent {
+		cb.reject(now, "max_concurrent")
+		return admission{}, errors.New(errors.ResourceExhaustedCode,
+			fmt.Sprintf("circuit breaker %s has reached its limit of %d concurrent requests", cb.name, cb.config.MaxConcurrent))
+	}
+
+	if cb.state == HalfOpen {
+		cb.trialsInFlight++
+	}
+	cb.inFlight++
+	return admission{state: cb.state, generation: cb.generation}, nil
 }
 
-// updateState updates the state of the circuit based on the result of a request
-func (cb *CircuitBreaker) updateState(err error) {
+// reject records a rejected request. The caller must hold the write lock.
+func (cb *CircuitBreaker) reject(now time.Time, reason string) {
+	cb.window.record(now, rejected)
+	cb.metrics.recordRejection(reason)
+}
+
+// done records the outcome of an admitted request and updates the circuit state.
+func (cb *CircuitBreaker) done(admitted admission, o outcome) {
+	cb.metrics.recordCall(o)
+
 	cb.mutex.Lock()
-	defer cb.mutex.Unlock()
+	defer cb.unlock()
 
-	if err != nil {
-		// Request failed
-		cb.failureCount++
-		cb.lastFailureTime = time.Now()
+	cb.inFlight--
+
+	// The state has changed since the request was admitted, for example by a
+	// Reset, so its outcome says nothing about the current state
+	if admitted.generation != cb.generation {
+		return
+	}
+
+	now := cb.clock.Now()
+	switch cb.state {
+	case Closed:
+		if o == abandoned {
+			return
+		}
+		cb.window.record(now, o)
+		if !o.isError() {
+			cb.failureStreak = 0
+			return
+		}
+		cb.failureStreak++
+		if cb.forced {
+			return
+		}
+
+		if cb.config.ConsecutiveFailures > 0 && cb.failureStreak >= cb.config.ConsecutiveFailures {
+			cb.logger.Warn(context.Background(), "Opening circuit breaker due to consecutive failures",
+				zap.String("circuit", cb.name),
+				zap.Int("consecutive_failures", cb.failureStreak))
+			cb.setState(Open, now)
+			return
+		}
 
-		// Check if the circuit should be opened
-		if cb.state == Closed && cb.failureCount >= cb.config.VolumeThreshold && float64(cb.failureCount)/float64(cb.config.VolumeThreshold) >= cb.config.ErrorThreshold {
+		counts := cb.window.counts(now)
+		if counts.Requests() >= cb.config.VolumeThreshold && counts.ErrorRate() >= cb.config.ErrorThreshold {
 			cb.logger.Warn(context.Background(), "Opening circuit breaker due to error threshold exceeded",
 				zap.String("circuit", cb.name),
-				zap.Int("failure_count", cb.failureCount),
+				zap.Int("requests", counts.Requests()),
+				zap.Int("failures", counts.Failures),
+				zap.Int("timeouts", counts.Timeouts),
 				zap.Int("volume_threshold", cb.config.VolumeThreshold),
 				zap.Float64("error_threshold", cb.config.ErrorThreshold))
-			cb.state = Open
-		} else if cb.state == HalfOpen {
-			// If a request fails in half-open state, go back to open
+			cb.setState(Open, now)
+		}
+	case HalfOpen:
+		cb.trialsInFlight--
+		switch {
+		case o == abandoned:
+			// Let another trial request take its place
+		case o.isError():
 			cb.logger.Warn(context.Background(), "Reopening circuit breaker due to failure in half-open state",
 				zap.String("circuit", cb.name))
-			cb.state = Open
+			cb.setState(Open, now)
+		default:
+			cb.trialSuccesses++
+			if cb.trialSuccesses >= cb.config.HalfOpenRequests {
+				cb.logger.Info(context.Background(), "Closing circuit breaker after successful requests in half-open state",
+					zap.String("circuit", cb.name),
+					zap.Int("half_open_requests", cb.config.HalfOpenRequests))
+				cb.setState(Closed, now)
+			}
 		}
-	} else {
-		// Request succeeded
-		if cb.state == HalfOpen {
-			// If a request succeeds in half-open state, close the circuit
-			cb.logger.Info(context.Background(), "Closing circuit breaker after successful request in half-open state",
-				zap.String("circuit", cb.name))
-			cb.state = Closed
-			cb.failureCount = 0
-		} else if cb.state == Closed {
-			// Reset failure count on successful request
-			cb.failureCount = 0
+	}
+}
+
+// setState moves the circuit to a new state. The caller must hold the write lock,
+// and release it with unlock so that the change is reported.
+func (cb *CircuitBreaker) setState(state State, now time.Time) {
+	if state != cb.state {
+		cb.transitions = append(cb.transitions, transition{from: cb.state, to: state})
+	}
+	cb.state = state
+	cb.generation++
+	cb.trialsInFlight = 0
+	cb.trialSuccesses = 0
+	cb.failureStreak = 0
+
+	switch state {
+	case Open:
+		cb.openedAt = now
+	case Closed:
+		// Start counting afresh, so that failures from before the circuit
+		// opened do not trip it again
+		cb.window.reset()
+		cb.openedAt = time.Time{}
+	}
+}
+
+// unlock releases the write lock, then reports the state changes made while it was held.
+func (cb *CircuitBreaker) unlock() {
+	transitions := cb.transitions
+	cb.transitions = nil
+	cb.mutex.Unlock()
+
+	for _, t := range transitions {
+		cb.logger.Info(context.Background(), "Circuit breaker state changed",
+			zap.String("circuit", cb.name),
+			zap.String("from", t.from.String()),
+			zap.String("to", t.to.String()))
+		cb.metrics.recordTransition(t)
+		if cb.onStateChange != nil {
+			cb.onStateChange(cb.name, t.from, t.to)
 		}
 	}
 }
 
-// GetState returns the current state of the circuit breaker
+// GetState returns the current state of the circuit breaker.
+// If the circuit breaker is nil, Closed is returned.
+//
+// Returns:
+//   - State: The current state.
 func (cb *CircuitBreaker) GetState() State {
 	if cb == nil {
 		return Closed
@@ -542,15 +951,117 @@ func (cb *CircuitBreaker) GetState() State {
 	return cb.state
 }
 
-// Reset resets the circuit breaker to its initial state
+// Counts returns the number of successes, failures, timeouts and rejections
+// in the circuit breaker's rolling window.
+// If the circuit breaker is nil, the zero Counts is returned.
+//
+// Returns:
+//   - Counts: The outcomes of the requests in the rolling window.
+func (cb *CircuitBreaker) Counts() Counts {
+	if cb == nil {
+		return Counts{}
+	}
+
+	cb.mutex.RLock()
+	defer cb.mutex.RUnlock()
+	return cb.window.counts(cb.clock.Now())
+}
+
+// Reset resets the circuit breaker to its initial state: closed, with an empty
+// rolling window, and no longer forced. Requests in flight are still counted
+// against MaxConcurrent, but their outcomes are ignored.
+// If the circuit breaker is nil, this method does nothing.
 func (cb *CircuitBreaker) Reset() {
 	if cb == nil {
 		return
 	}
 
 	cb.mutex.Lock()
-	defer cb.mutex.Unlock()
-	cb.state = Closed
-	cb.failureCount = 0
-	cb.lastFailureTime = time.Time{}
+	defer cb.unlock()
+	cb.forced = false
+	cb.setState(Closed, cb.clock.Now())
+}
+
+// ForceOpen opens the circuit and holds it open, rejecting every request, until
+// ClearForce or Reset is called. This lets operators cut off a dependency that
+// is known to be failing, or is being taken down for maintenance.
+// If the circuit breaker is nil, this method does nothing.
+func (cb *CircuitBreaker) ForceOpen() {
+	cb.force(Open)
+}
+
+// ForceClosed closes the circuit and holds it closed, executing every request
+// however many fail, until ClearForce or Reset is called. Requests beyond
+// MaxConcurrent are still rejected.
+// If the circuit breaker is nil, this method does nothing.
+func (cb *CircuitBreaker) ForceClosed() {
+	cb.force(Closed)
+}
+
+// force moves the circuit to state and holds it there.
+func (cb *CircuitBreaker) force(state State) {
+	if cb == nil {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	cb.logger.Warn(context.Background(), "Circuit breaker forced",
+		zap.String("circuit", cb.name),
+		zap.String("state", state.String()))
+
+	cb.forced = true
+	if cb.state != state {
+		cb.setState(state, cb.clock.Now())
+	}
+}
+
+// ClearForce ends a ForceOpen or ForceClosed, and the circuit resumes normal
+// operation from its current state: a closed circuit opens once the error
+// threshold is reached, and an open circuit lets trial requests through once
+// SleepWindow has passed since it opened.
+// If the circuit breaker is nil, this method does nothing.
+func (cb *CircuitBreaker) ClearForce() {
+	if cb == nil {
+		return
+	}
+
+	cb.mutex.Lock()
+	defer cb.unlock()
+
+	if cb.forced {
+		cb.logger.Info(context.Background(), "Circuit breaker no longer forced",
+			zap.String("circuit", cb.name),
+			zap.String("state", cb.state.String()))
+	}
+	cb.forced = false
+}
+
+// IsForced reports whether the state of the circuit breaker was set by
+// ForceOpen or ForceClosed.
+// If the circuit breaker is nil, false is returned.
+//
+// Returns:
+//   - bool: Whether the circuit is held in its current state.
+func (cb *CircuitBreaker) IsForced() bool {
+	if cb == nil {
+		return false
+	}
+
+	cb.mutex.RLock()
+	defer cb.mutex.RUnlock()
+	return cb.forced
+}
+
+// Name returns the name of the circuit breaker.
+// If the circuit breaker is nil, an empty string is returned.
+//
+// Returns:
+//   - string: The name from the Options the circuit breaker was created with.
+func (cb *CircuitBreaker) Name() string {
+	if cb == nil {
+		return ""
+	}
+	return cb.name
 }
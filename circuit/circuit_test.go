@@ -5,11 +5,15 @@
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
+	svcerrors "github.com/abitofhelp/servicelib/errors"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -174,10 +178,12 @@ func TestCircuitBreaker_HalfOpen(t *testing.T) {
 		WithVolumeThreshold(2).
 		WithSleepWindow(10 * time.Millisecond)
 
+	fake := clock.NewFake(time.Time{})
 	logger := logging.NewContextLogger(zap.NewNop())
 	options := DefaultOptions().
 		WithName("test").
-		WithLogger(logger)
+		WithLogger(logger).
+		WithClock(fake)
 
 	cb := NewCircuitBreaker(cfg, options)
 	assert.NotNil(t, cb)
@@ -193,8 +199,8 @@ func TestCircuitBreaker_HalfOpen(t *testing.T) {
 	Execute(context.Background(), cb, "TestHalfOpen", failFunc)
 	assert.Equal(t, Open, cb.GetState())
 
-	// Wait for the sleep window to elapse
-	time.Sleep(20 * time.Millisecond)
+	// Let the sleep window elapse
+	fake.Advance(20 * time.Millisecond)
 
 	// Next execution should put the circuit in half-open state
 	successFunc := func(ctx context.Context) (bool, error) {
@@ -220,10 +226,12 @@ func TestCircuitBreaker_Reset(t *testing.T) {
 		WithErrorThreshold(0.5).
 		WithVolumeThreshold(2)
 
+	fake := clock.NewFake(time.Time{})
 	logger := logging.NewContextLogger(zap.NewNop())
 	options := DefaultOptions().
 		WithName("test").
-		WithLogger(logger)
+		WithLogger(logger).
+		WithClock(fake)
 
 	cb := NewCircuitBreaker(cfg, options)
 	assert.NotNil(t, cb)
@@ -287,3 +295,387 @@ func TestCircuitBreaker_NilSafety(t *testing.T) {
 		cb.Reset()
 	})
 }
+
+func TestCircuitBreaker_FailuresExpire(t *testing.T) {
+	cfg := DefaultConfig().
+		WithErrorThreshold(0.5).
+		WithVolumeThreshold(2).
+		WithWindow(50 * time.Millisecond).
+		WithBuckets(5)
+	fake := clock.NewFake(time.Time{})
+	cb := NewCircuitBreaker(cfg, DefaultOptions().WithClock(fake))
+
+	failFunc := func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	}
+
+	// A failure that has left the window does not count towards the threshold
+	_, _ = Execute(context.Background(), cb, "TestExpire", failFunc)
+	fake.Advance(70 * time.Millisecond)
+	_, _ = Execute(context.Background(), cb, "TestExpire", failFunc)
+	assert.Equal(t, Closed, cb.GetState())
+	assert.Equal(t, Counts{Failures: 1}, cb.Counts())
+
+	_, _ = Execute(context.Background(), cb, "TestExpire", failFunc)
+	assert.Equal(t, Open, cb.GetState())
+}
+
+func TestCircuitBreaker_ErrorRate(t *testing.T) {
+	cfg := DefaultConfig().
+		WithErrorThreshold(0.5).
+		WithVolumeThreshold(4)
+	cb := NewCircuitBreaker(cfg, DefaultOptions())
+
+	successFunc := func(ctx context.Context) (bool, error) {
+		return true, nil
+	}
+	failFunc := func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	}
+
+	// Successes no longer wipe out earlier failures; the rate over the window decides
+	_, _ = Execute(context.Background(), cb, "TestRate", failFunc)
+	_, _ = Execute(context.Background(), cb, "TestRate", successFunc)
+	_, _ = Execute(context.Background(), cb, "TestRate", successFunc)
+	_, _ = Execute(context.Background(), cb, "TestRate", successFunc)
+	_, _ = Execute(context.Background(), cb, "TestRate", failFunc)
+	assert.Equal(t, Closed, cb.GetState()) // 2 of 5
+
+	_, _ = Execute(context.Background(), cb, "TestRate", failFunc)
+	assert.Equal(t, Open, cb.GetState()) // 3 of 6
+
+	// Rejections are counted but do not change the rate
+	_, err := Execute(context.Background(), cb, "TestRate", successFunc)
+	assert.Error(t, err)
+	assert.Equal(t, Counts{Successes: 3, Failures: 3, Rejections: 1}, cb.Counts())
+}
+
+func TestCircuitBreaker_Timeout(t *testing.T) {
+	cfg := DefaultConfig().
+		WithTimeout(10 * time.Millisecond).
+		WithErrorThreshold(0.5).
+		WithVolumeThreshold(1)
+	fake := clock.NewFake(time.Time{})
+	cb := NewCircuitBreaker(cfg, DefaultOptions().WithClock(fake))
+
+	// The function's context is canceled after the timeout
+	_, err := Execute(context.Background(), cb, "TestTimeout", func(ctx context.Context) (bool, error) {
+		fake.Advance(10 * time.Millisecond)
+		<-ctx.Done()
+		return false, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, Counts{Timeouts: 1}, cb.Counts())
+	assert.Equal(t, Open, cb.GetState())
+}
+
+func TestCircuitBreaker_CallerCancellationNotCounted(t *testing.T) {
+	cb := NewCircuitBreaker(DefaultConfig().WithVolumeThreshold(1), DefaultOptions())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := Execute(ctx, cb, "TestCancel", func(ctx context.Context) (bool, error) {
+		cancel()
+		return false, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, Counts{}, cb.Counts())
+	assert.Equal(t, Closed, cb.GetState())
+}
+
+func TestCircuitBreaker_HalfOpenRequests(t *testing.T) {
+	cfg := DefaultConfig().
+		WithErrorThreshold(0.5).
+		WithVolumeThreshold(1).
+		WithSleepWindow(10 * time.Millisecond).
+		WithHalfOpenRequests(2)
+	fake := clock.NewFake(time.Time{})
+	cb := NewCircuitBreaker(cfg, DefaultOptions().WithClock(fake))
+
+	failFunc := func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	}
+	_, _ = Execute(context.Background(), cb, "TestTrials", failFunc)
+	require.Equal(t, Open, cb.GetState())
+	fake.Advance(20 * time.Millisecond)
+
+	// Two trial requests run at once; a third is rejected while they are in flight
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Execute(context.Background(), cb, "TestTrials", func(ctx context.Context) (bool, error) {
+				started <- struct{}{}
+				<-release
+				return true, nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	<-started
+	<-started
+	assert.Equal(t, HalfOpen, cb.GetState())
+
+	_, err := Execute(context.Background(), cb, "TestTrials", func(ctx context.Context) (bool, error) {
+		t.Fatal("This function should not be called while the trial requests are in flight")
+		return true, nil
+	})
+	assert.ErrorIs(t, err, svcerrors.ErrCircuitBreakerOpen)
+
+	// The circuit closes once both trial requests succeed
+	close(release)
+	wg.Wait()
+	assert.Equal(t, Closed, cb.GetState())
+	assert.Equal(t, Counts{}, cb.Counts())
+}
+
+func TestCircuitBreaker_HalfOpenFailureReopens(t *testing.T) {
+	cfg := DefaultConfig().
+		WithVolumeThreshold(1).
+		WithSleepWindow(10 * time.Millisecond).
+		WithHalfOpenRequests(3)
+	fake := clock.NewFake(time.Time{})
+	cb := NewCircuitBreaker(cfg, DefaultOptions().WithClock(fake))
+
+	successFunc := func(ctx context.Context) (bool, error) {
+		return true, nil
+	}
+	failFunc := func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	}
+
+	_, _ = Execute(context.Background(), cb, "TestReopen", failFunc)
+	fake.Advance(20 * time.Millisecond)
+
+	_, err := Execute(context.Background(), cb, "TestReopen", successFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, HalfOpen, cb.GetState())
+
+	_, _ = Execute(context.Background(), cb, "TestReopen", failFunc)
+	assert.Equal(t, Open, cb.GetState())
+}
+
+func TestCircuitBreaker_MaxConcurrent(t *testing.T) {
+	cb := NewCircuitBreaker(DefaultConfig().WithMaxConcurrent(1), DefaultOptions())
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = Execute(context.Background(), cb, "TestBulkhead", func(ctx context.Context) (bool, error) {
+			close(started)
+			<-release
+			return true, nil
+		})
+	}()
+	<-started
+
+	_, err := Execute(context.Background(), cb, "TestBulkhead", func(ctx context.Context) (bool, error) {
+		t.Fatal("This function should not be called when the bulkhead is full")
+		return true, nil
+	})
+	require.Error(t, err)
+	assert.True(t, svcerrors.Is(err, svcerrors.New(svcerrors.ResourceExhaustedCode, "")))
+
+	// A full bulkhead does not open the circuit
+	close(release)
+	<-done
+	assert.Equal(t, Closed, cb.GetState())
+	assert.Equal(t, Counts{Successes: 1, Rejections: 1}, cb.Counts())
+
+	result, err := Execute(context.Background(), cb, "TestBulkhead", func(ctx context.Context) (bool, error) {
+		return true, nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, result)
+}
+
+func TestCircuitBreaker_PanicCountsAsFailure(t *testing.T) {
+	cb := NewCircuitBreaker(DefaultConfig().WithVolumeThreshold(1).WithMaxConcurrent(1), DefaultOptions())
+
+	assert.Panics(t, func() {
+		_, _ = Execute(context.Background(), cb, "TestPanic", func(ctx context.Context) (bool, error) {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, Open, cb.GetState())
+
+	// The panicking request released its slot
+	cb.Reset()
+	_, err := Execute(context.Background(), cb, "TestPanic", func(ctx context.Context) (bool, error) {
+		return true, nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestConfig_WindowMethods(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, 10*time.Second, cfg.Window)
+	assert.Equal(t, 10, cfg.Buckets)
+	assert.Equal(t, 1, cfg.HalfOpenRequests)
+
+	assert.Equal(t, time.Minute, cfg.WithWindow(time.Minute).Window)
+	assert.Equal(t, time.Millisecond, cfg.WithWindow(0).Window)
+	assert.Equal(t, 20, cfg.WithBuckets(20).Buckets)
+	assert.Equal(t, 1, cfg.WithBuckets(-1).Buckets)
+	assert.Equal(t, 5, cfg.WithHalfOpenRequests(5).HalfOpenRequests)
+	assert.Equal(t, 1, cfg.WithHalfOpenRequests(0).HalfOpenRequests)
+	assert.Equal(t, 0, cfg.ConsecutiveFailures)
+	assert.Equal(t, 3, cfg.WithConsecutiveFailures(3).ConsecutiveFailures)
+	assert.Equal(t, 0, cfg.WithConsecutiveFailures(-1).ConsecutiveFailures)
+
+	// A hand-built Config gets a usable window
+	cb := NewCircuitBreaker(Config{Enabled: true, ErrorThreshold: 0.5, VolumeThreshold: 1}, DefaultOptions())
+	_, _ = Execute(context.Background(), cb, "TestZeroConfig", func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	})
+	assert.Equal(t, Open, cb.GetState())
+}
+
+func TestCircuitBreaker_OnStateChange(t *testing.T) {
+	type change struct {
+		name     string
+		from, to State
+	}
+	var mu sync.Mutex
+	var changes []change
+
+	var cb *CircuitBreaker
+	fake := clock.NewFake(time.Time{})
+	cfg := DefaultConfig().WithVolumeThreshold(1).WithSleepWindow(10 * time.Millisecond)
+	cb = NewCircuitBreaker(cfg, DefaultOptions().WithName("hooked").WithClock(fake).WithOnStateChange(func(name string, from, to State) {
+		// The hook may call the circuit breaker without deadlocking
+		assert.Equal(t, to, cb.GetState())
+
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change{name, from, to})
+	}))
+
+	_, err := Execute(context.Background(), cb, "fail", func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	})
+	require.Error(t, err)
+
+	fake.Advance(20 * time.Millisecond)
+	_, err = Execute(context.Background(), cb, "succeed", func(ctx context.Context) (bool, error) {
+		return true, nil
+	})
+	require.NoError(t, err)
+
+	// Resetting a closed circuit is not a change
+	cb.Reset()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []change{
+		{"hooked", Closed, Open},
+		{"hooked", Open, HalfOpen},
+		{"hooked", HalfOpen, Closed},
+	}, changes)
+}
+
+func TestCircuitBreaker_Force(t *testing.T) {
+	cfg := DefaultConfig().WithVolumeThreshold(1).WithSleepWindow(time.Millisecond)
+	fake := clock.NewFake(time.Time{})
+	cb := NewCircuitBreaker(cfg, DefaultOptions().WithName("forced").WithClock(fake))
+	require.NotNil(t, cb)
+
+	fail := func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	}
+	succeed := func(ctx context.Context) (bool, error) {
+		return true, nil
+	}
+
+	// A forced open circuit rejects requests however long it has been open
+	cb.ForceOpen()
+	assert.True(t, cb.IsForced())
+	assert.Equal(t, Open, cb.GetState())
+	fake.Advance(5 * time.Millisecond)
+	_, err := Execute(context.Background(), cb, "forced_open", succeed)
+	assert.ErrorIs(t, err, svcerrors.ErrCircuitBreakerOpen)
+	assert.Equal(t, Open, cb.GetState())
+
+	// A forced closed circuit does not trip
+	cb.ForceClosed()
+	assert.Equal(t, Closed, cb.GetState())
+	for i := 0; i < 5; i++ {
+		_, err := Execute(context.Background(), cb, "forced_closed", fail)
+		require.Error(t, err)
+		require.False(t, svcerrors.Is(err, svcerrors.ErrCircuitBreakerOpen))
+	}
+	assert.Equal(t, Closed, cb.GetState())
+	assert.Equal(t, 5, cb.Counts().Failures)
+
+	// Once cleared, the circuit trips again
+	cb.ClearForce()
+	assert.False(t, cb.IsForced())
+	_, err = Execute(context.Background(), cb, "cleared", fail)
+	require.Error(t, err)
+	assert.Equal(t, Open, cb.GetState())
+
+	// Reset clears the force as well
+	cb.ForceOpen()
+	cb.Reset()
+	assert.False(t, cb.IsForced())
+	assert.Equal(t, Closed, cb.GetState())
+
+	// Nil circuit breakers ignore forcing
+	var nilCB *CircuitBreaker
+	nilCB.ForceOpen()
+	nilCB.ForceClosed()
+	nilCB.ClearForce()
+	assert.False(t, nilCB.IsForced())
+	assert.Equal(t, "", nilCB.Name())
+	assert.Equal(t, "forced", cb.Name())
+}
+
+func TestCircuitBreaker_OpenError(t *testing.T) {
+	cb := NewCircuitBreaker(DefaultConfig(), DefaultOptions().WithName("payments"))
+	cb.ForceOpen()
+
+	_, err := Execute(context.Background(), cb, "TestOpenError", func(ctx context.Context) (bool, error) {
+		return true, nil
+	})
+	require.Error(t, err)
+
+	// Rejections are typed errors that name the circuit breaker
+	assert.ErrorIs(t, err, svcerrors.ErrCircuitBreakerOpen)
+	assert.True(t, svcerrors.IsCircuitBreakerOpenError(err))
+	var openErr *svcerrors.CircuitBreakerOpenError
+	require.ErrorAs(t, err, &openErr)
+	assert.Equal(t, "payments", openErr.Name)
+	assert.Equal(t, 503, svcerrors.GetHTTPStatus(err))
+	assert.Contains(t, err.Error(), "circuit breaker payments is open")
+}
+
+func TestCircuitBreaker_ConsecutiveFailures(t *testing.T) {
+	cfg := DefaultConfig().
+		WithErrorThreshold(1).
+		WithVolumeThreshold(100).
+		WithConsecutiveFailures(3)
+	cb := NewCircuitBreaker(cfg, DefaultOptions())
+
+	fail := func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	}
+	succeed := func(ctx context.Context) (bool, error) {
+		return true, nil
+	}
+
+	// A success ends the streak
+	_, _ = Execute(context.Background(), cb, "fail", fail)
+	_, _ = Execute(context.Background(), cb, "fail", fail)
+	_, _ = Execute(context.Background(), cb, "succeed", succeed)
+	_, _ = Execute(context.Background(), cb, "fail", fail)
+	_, _ = Execute(context.Background(), cb, "fail", fail)
+	assert.Equal(t, Closed, cb.GetState())
+
+	// Three failures in a row trip the circuit, well below the volume threshold
+	_, _ = Execute(context.Background(), cb, "fail", fail)
+	assert.Equal(t, Open, cb.GetState())
+}
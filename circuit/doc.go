@@ -11,14 +11,19 @@
 //   - Closed: The circuit is closed and requests are allowed through normally.
 //   - Open: The circuit is open and requests are immediately rejected without being attempted.
 //   - HalfOpen: After a configurable sleep window, the circuit transitions to half-open state,
-//     allowing a limited number of requests through to test if the dependency is healthy.
+//     allowing a configurable number of trial requests through to test if the dependency is healthy.
 //
 // Key features:
-//   - Configurable error threshold and volume threshold for tripping the circuit
-//   - Automatic recovery with configurable sleep window
+//   - Configurable error threshold and volume threshold for tripping the circuit, applied to
+//     the successes, failures and timeouts counted in a rolling bucketed window
+//   - Automatic recovery with configurable sleep window and number of trial requests
+//   - Request timeouts, and a bulkhead that limits the number of concurrent requests
 //   - Support for fallback functions when the circuit is open
-//   - Integration with OpenTelemetry for tracing
-//   - Comprehensive logging of circuit state changes
+//   - Integration with OpenTelemetry for tracing and metrics
+//   - Comprehensive logging of circuit state changes, and an OnStateChange callback
+//   - A Registry of circuit breakers by name, with HTTP handlers that report their states
+//     and let operators force them open or closed
+//   - A clock.Clock option, so that tests can advance time instead of waiting
 //
 // Example usage:
 //
@@ -43,6 +48,12 @@
 //	    },
 //	)
 //
+// Example of a registry of circuit breakers:
+//
+//	breakers := circuit.NewRegistry(circuit.DefaultOptions().WithMeter(meter))
+//	db := breakers.GetOrCreate("database", circuit.DefaultConfig())
+//	mux.Handle("/admin/circuits", breakers.AdminHandler())
+//
 // The circuit package is designed to be used as a dependency by other packages in the application,
 // providing a consistent circuit breaking interface throughout the codebase.
 package circuit
@@ -0,0 +1,117 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package circuit
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// circuitMetrics holds the OpenTelemetry instruments of a circuit breaker.
+// Its methods do nothing on a nil receiver, so that they can be called
+// whether or not metrics are enabled.
+type circuitMetrics struct {
+	// calls counts executed requests, by outcome
+	calls metric.Int64Counter
+
+	// rejections counts requests rejected without being executed, by reason
+	rejections metric.Int64Counter
+
+	// transitions counts state changes, by the states moved from and to
+	transitions metric.Int64Counter
+
+	// name identifies the circuit breaker in every measurement
+	name attribute.KeyValue
+}
+
+// newCircuitMetrics creates the instruments of a circuit breaker.
+//
+// Parameters:
+//   - meter: The meter used to create the instruments.
+//   - name: The name of the circuit breaker, recorded as the circuit.name attribute.
+//   - state: A function returning the current state, observed by the state gauge.
+//
+// Returns:
+//   - *circuitMetrics: The instruments.
+//   - error: An error if an instrument could not be created.
+func newCircuitMetrics(meter metric.Meter, name string, state func() State) (*circuitMetrics, error) {
+	m := &circuitMetrics{name: attribute.String("circuit.name", name)}
+
+	var err error
+	m.calls, err = meter.Int64Counter(
+		"circuit_breaker.calls",
+		metric.WithDescription("Number of requests executed through the circuit breaker, by outcome"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit_breaker.calls counter: %w", err)
+	}
+
+	m.rejections, err = meter.Int64Counter(
+		"circuit_breaker.rejections",
+		metric.WithDescription("Number of requests rejected by the circuit breaker without being executed, by reason"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit_breaker.rejections counter: %w", err)
+	}
+
+	m.transitions, err = meter.Int64Counter(
+		"circuit_breaker.transitions",
+		metric.WithDescription("Number of times the circuit breaker changed state"),
+		metric.WithUnit("{transition}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit_breaker.transitions counter: %w", err)
+	}
+
+	stateGauge, err := meter.Int64ObservableGauge(
+		"circuit_breaker.state",
+		metric.WithDescription("State of the circuit breaker: 0 for closed, 1 for open and 2 for half-open"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit_breaker.state gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		o.ObserveInt64(stateGauge, int64(state()), metric.WithAttributes(m.name))
+		return nil
+	}, stateGauge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register circuit_breaker.state callback: %w", err)
+	}
+
+	return m, nil
+}
+
+// recordCall counts an executed request.
+func (m *circuitMetrics) recordCall(o outcome) {
+	if m == nil {
+		return
+	}
+	m.calls.Add(context.Background(), 1,
+		metric.WithAttributes(m.name, attribute.String("circuit.outcome", o.String())))
+}
+
+// recordRejection counts a rejected request.
+func (m *circuitMetrics) recordRejection(reason string) {
+	if m == nil {
+		return
+	}
+	m.rejections.Add(context.Background(), 1,
+		metric.WithAttributes(m.name, attribute.String("circuit.reason", reason)))
+}
+
+// recordTransition counts a state change.
+func (m *circuitMetrics) recordTransition(t transition) {
+	if m == nil {
+		return
+	}
+	m.transitions.Add(context.Background(), 1,
+		metric.WithAttributes(m.name,
+			attribute.String("circuit.from", t.from.String()),
+			attribute.String("circuit.to", t.to.String())))
+}
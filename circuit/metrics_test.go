@@ -0,0 +1,83 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package circuit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestCircuitBreaker_Metrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	cfg := DefaultConfig().WithVolumeThreshold(2)
+	cb := NewCircuitBreaker(cfg, DefaultOptions().WithName("metered").WithMeter(provider.Meter("test")))
+	require.NotNil(t, cb)
+
+	_, err := Execute(context.Background(), cb, "succeed", func(ctx context.Context) (bool, error) {
+		return true, nil
+	})
+	require.NoError(t, err)
+	_, err = Execute(context.Background(), cb, "fail", func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	})
+	require.Error(t, err)
+	require.Equal(t, Open, cb.GetState())
+	_, err = Execute(context.Background(), cb, "rejected", func(ctx context.Context) (bool, error) {
+		return true, nil
+	})
+	require.Error(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	sums := make(map[string]int64)
+	var state int64 = -1
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					name, _ := dp.Attributes.Value("circuit.name")
+					assert.Equal(t, "metered", name.AsString())
+					sums[m.Name+" "+attributeString(dp.Attributes)] = dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				require.Len(t, data.DataPoints, 1)
+				state = data.DataPoints[0].Value
+			}
+		}
+	}
+
+	assert.Equal(t, int64(Open), state)
+	assert.Equal(t, map[string]int64{
+		"circuit_breaker.calls circuit.outcome=success":                   1,
+		"circuit_breaker.calls circuit.outcome=failure":                   1,
+		"circuit_breaker.rejections circuit.reason=open":                  1,
+		"circuit_breaker.transitions circuit.from=Closed,circuit.to=Open": 1,
+	}, sums)
+}
+
+// attributeString formats the attributes of a data point other than the circuit name.
+func attributeString(set attribute.Set) string {
+	var s string
+	for _, kv := range set.ToSlice() {
+		if kv.Key == "circuit.name" {
+			continue
+		}
+		if s != "" {
+			s += ","
+		}
+		s += string(kv.Key) + "=" + kv.Value.AsString()
+	}
+	return s
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package circuit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/abitofhelp/servicelib/errors"
+	errhttp "github.com/abitofhelp/servicelib/errors/http"
+)
+
+// Status is a point-in-time snapshot of a circuit breaker, as reported by a Registry.
+type Status struct {
+	// Name is the name of the circuit breaker
+	Name string `json:"name"`
+
+	// State is the current state of the circuit
+	State State `json:"state"`
+
+	// Forced is true if the state was set by ForceOpen or ForceClosed
+	Forced bool `json:"forced"`
+
+	// Counts holds the outcomes of the requests in the rolling window
+	Counts Counts `json:"counts"`
+}
+
+// Registry creates circuit breakers by name and keeps track of them, so that an
+// application can look them up wherever a dependency is called, report their
+// states, and let operators force them open or closed at runtime.
+//
+// This implementation is thread-safe and can be used concurrently from multiple
+// goroutines.
+type Registry struct {
+	options  Options
+	mutex    sync.RWMutex
+	breakers map[string]*CircuitBreaker
+}
+
+// NewRegistry creates a new, empty registry of circuit breakers.
+//
+// Parameters:
+//   - options: The options for the circuit breakers created by the registry, such as
+//     the logger, tracer, meter and OnStateChange function. The name is replaced by
+//     the name each circuit breaker is created with.
+//
+// Returns:
+//   - *Registry: A new Registry instance.
+func NewRegistry(options Options) *Registry {
+	return &Registry{
+		options:  options,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+// GetOrCreate returns the circuit breaker with the given name, creating it with
+// config if there is none. The config is ignored if the circuit breaker exists.
+// If the config is disabled, nil is returned, which allows all requests through,
+// and nothing is registered.
+// If the registry is nil, nil is returned.
+//
+// Parameters:
+//   - name: The name of the circuit breaker.
+//   - config: The configuration of the circuit breaker, if it is created.
+//
+// Returns:
+//   - *CircuitBreaker: The circuit breaker registered under name.
+func (r *Registry) GetOrCreate(name string, config Config) *CircuitBreaker {
+	if r == nil {
+		return nil
+	}
+
+	if cb, ok := r.Get(name); ok {
+		return cb
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Another goroutine may have created it in the meantime
+	if cb, ok := r.breakers[name]; ok {
+		return cb
+	}
+
+	cb := NewCircuitBreaker(config, r.options.WithName(name))
+	if cb != nil {
+		r.breakers[name] = cb
+	}
+	return cb
+}
+
+// Get returns the circuit breaker with the given name.
+// If the registry is nil, false is returned.
+//
+// Parameters:
+//   - name: The name of the circuit breaker.
+//
+// Returns:
+//   - *CircuitBreaker: The circuit breaker, or nil if there is none.
+//   - bool: Whether a circuit breaker is registered under name.
+func (r *Registry) Get(name string) (*CircuitBreaker, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	cb, ok := r.breakers[name]
+	return cb, ok
+}
+
+// Names returns the names of the registered circuit breakers, in sorted order.
+// If the registry is nil, nil is returned.
+//
+// Returns:
+//   - []string: The names of the circuit breakers.
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.RLock()
+	names := make([]string, 0, len(r.breakers))
+	for name := range r.breakers {
+		names = append(names, name)
+	}
+	r.mutex.RUnlock()
+
+	slices.Sort(names)
+	return names
+}
+
+// Statuses returns a snapshot of every registered circuit breaker, sorted by name.
+// If the registry is nil, nil is returned.
+//
+// Returns:
+//   - []Status: The state and counts of each circuit breaker.
+func (r *Registry) Statuses() []Status {
+	if r == nil {
+		return nil
+	}
+
+	names := r.Names()
+	statuses := make([]Status, 0, len(names))
+	for _, name := range names {
+		if cb, ok := r.Get(name); ok {
+			statuses = append(statuses, cb.status())
+		}
+	}
+	return statuses
+}
+
+// status returns a snapshot of the circuit breaker.
+func (cb *CircuitBreaker) status() Status {
+	return Status{
+		Name:   cb.name,
+		State:  cb.GetState(),
+		Forced: cb.IsForced(),
+		Counts: cb.Counts(),
+	}
+}
+
+// Handler returns an HTTP handler that reports the status of the registered
+// circuit breakers as JSON. A GET request returns a list of every circuit
+// breaker; with a name query parameter, it returns only that circuit breaker.
+//
+// Returns:
+//   - http.Handler: A read-only handler, suitable for a diagnostics endpoint.
+func (r *Registry) Handler() http.Handler {
+	return r.handler(false)
+}
+
+// AdminHandler returns an HTTP handler that reports the status of the registered
+// circuit breakers like Handler, and also lets operators force a circuit breaker
+// open or closed. A POST request with the name and force query parameters, where
+// force is "open", "closed" or "none", calls ForceOpen, ForceClosed or ClearForce
+// and returns the new status of the circuit breaker.
+//
+// The handler does no authorization of its own, so it must only be served
+// behind authentication, such as the auth middleware, or on an internal port.
+//
+// Returns:
+//   - http.Handler: A handler that reports and changes the circuit breakers.
+func (r *Registry) AdminHandler() http.Handler {
+	return r.handler(true)
+}
+
+// handler serves the status of the circuit breakers, and forces them if admin is true.
+func (r *Registry) handler(admin bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		name := req.URL.Query().Get("name")
+
+		switch {
+		case req.Method == http.MethodGet && name == "":
+			writeJSON(w, r.Statuses())
+		case req.Method == http.MethodGet:
+			cb, ok := r.Get(name)
+			if !ok {
+				errhttp.WriteError(w, errors.New(errors.NotFoundCode, fmt.Sprintf("circuit breaker %s not found", name)))
+				return
+			}
+			writeJSON(w, cb.status())
+		case req.Method == http.MethodPost && admin:
+			cb, ok := r.Get(name)
+			if !ok {
+				errhttp.WriteError(w, errors.New(errors.NotFoundCode, fmt.Sprintf("circuit breaker %s not found", name)))
+				return
+			}
+			switch force := strings.ToLower(req.URL.Query().Get("force")); force {
+			case "open":
+				cb.ForceOpen()
+			case "closed":
+				cb.ForceClosed()
+			case "none":
+				cb.ClearForce()
+			default:
+				errhttp.WriteError(w, errors.New(errors.InvalidInputCode,
+					fmt.Sprintf("invalid force %q: must be open, closed or none", force)))
+				return
+			}
+			writeJSON(w, cb.status())
+		default:
+			if admin {
+				w.Header().Set("Allow", "GET, POST")
+			} else {
+				w.Header().Set("Allow", "GET")
+			}
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// writeJSON writes v as a JSON response with status 200.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(v)
+}
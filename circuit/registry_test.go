@@ -0,0 +1,163 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package circuit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_GetOrCreate(t *testing.T) {
+	var mu sync.Mutex
+	var changed []string
+	registry := NewRegistry(DefaultOptions().WithOnStateChange(func(name string, from, to State) {
+		mu.Lock()
+		defer mu.Unlock()
+		changed = append(changed, name)
+	}))
+
+	db := registry.GetOrCreate("db", DefaultConfig())
+	require.NotNil(t, db)
+	assert.Equal(t, "db", db.Name())
+
+	// The same name returns the same circuit breaker, whatever the config
+	assert.Same(t, db, registry.GetOrCreate("db", DefaultConfig().WithVolumeThreshold(1)))
+
+	got, ok := registry.Get("db")
+	assert.True(t, ok)
+	assert.Same(t, db, got)
+
+	_, ok = registry.Get("missing")
+	assert.False(t, ok)
+
+	// Disabled circuit breakers are not registered
+	assert.Nil(t, registry.GetOrCreate("disabled", DefaultConfig().WithEnabled(false)))
+	_, ok = registry.Get("disabled")
+	assert.False(t, ok)
+
+	registry.GetOrCreate("api", DefaultConfig())
+	assert.Equal(t, []string{"api", "db"}, registry.Names())
+
+	// The registry's options apply to every circuit breaker
+	db.ForceOpen()
+	mu.Lock()
+	assert.Equal(t, []string{"db"}, changed)
+	mu.Unlock()
+
+	statuses := registry.Statuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, Status{Name: "api", State: Closed}, statuses[0])
+	assert.Equal(t, Status{Name: "db", State: Open, Forced: true}, statuses[1])
+}
+
+func TestRegistry_GetOrCreateConcurrently(t *testing.T) {
+	registry := NewRegistry(DefaultOptions())
+
+	breakers := make([]*CircuitBreaker, 10)
+	var wg sync.WaitGroup
+	for i := range breakers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			breakers[i] = registry.GetOrCreate("shared", DefaultConfig())
+		}()
+	}
+	wg.Wait()
+
+	for _, cb := range breakers {
+		assert.Same(t, breakers[0], cb)
+	}
+}
+
+func TestRegistry_NilSafety(t *testing.T) {
+	var registry *Registry
+	assert.Nil(t, registry.GetOrCreate("db", DefaultConfig()))
+	_, ok := registry.Get("db")
+	assert.False(t, ok)
+	assert.Nil(t, registry.Names())
+	assert.Nil(t, registry.Statuses())
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := NewRegistry(DefaultOptions())
+	db := registry.GetOrCreate("db", DefaultConfig())
+	_, err := Execute(context.Background(), db, "fail", func(ctx context.Context) (bool, error) {
+		return false, errors.New("test error")
+	})
+	require.Error(t, err)
+
+	handler := registry.Handler()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/circuits", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `[{"name":"db","state":"Closed","forced":false,
+		"counts":{"successes":0,"failures":1,"timeouts":0,"rejections":0}}]`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/circuits?name=db", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var status map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &status))
+	assert.Equal(t, "db", status["name"])
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/circuits?name=missing", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// The read-only handler cannot force circuit breakers
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/circuits?name=db&force=open", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, "GET", rr.Header().Get("Allow"))
+	assert.Equal(t, Closed, db.GetState())
+}
+
+func TestRegistry_AdminHandler(t *testing.T) {
+	registry := NewRegistry(DefaultOptions())
+	db := registry.GetOrCreate("db", DefaultConfig())
+	handler := registry.AdminHandler()
+
+	post := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/circuits?"+query, nil))
+		return rr
+	}
+
+	rr := post("name=db&force=open")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, Open, db.GetState())
+	assert.True(t, db.IsForced())
+	var status map[string]any
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &status))
+	assert.Equal(t, "Open", status["state"])
+	assert.Equal(t, true, status["forced"])
+
+	rr = post("name=db&force=CLOSED")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, Closed, db.GetState())
+
+	rr = post("name=db&force=none")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.False(t, db.IsForced())
+
+	rr = post("name=db&force=sideways")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = post("name=missing&force=open")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/circuits?name=db", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, "GET, POST", rr.Header().Get("Allow"))
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package circuit
+
+import (
+	"time"
+)
+
+// Counts holds the number of requests in a circuit breaker's rolling window, by outcome.
+type Counts struct {
+	// Successes is the number of requests that succeeded
+	Successes int `json:"successes"`
+
+	// Failures is the number of requests that returned an error
+	Failures int `json:"failures"`
+
+	// Timeouts is the number of requests that took longer than the Timeout
+	Timeouts int `json:"timeouts"`
+
+	// Rejections is the number of requests rejected without being executed,
+	// because the circuit was open or MaxConcurrent requests were in flight
+	Rejections int `json:"rejections"`
+}
+
+// Requests returns the number of requests that were executed.
+// Rejected requests are not included.
+//
+// Returns:
+//   - int: The number of successes, failures and timeouts.
+func (c Counts) Requests() int {
+	return c.Successes + c.Failures + c.Timeouts
+}
+
+// ErrorRate returns the fraction of executed requests that failed or timed out.
+//
+// Returns:
+//   - float64: The error rate between 0 and 1, or 0 if no requests were executed.
+func (c Counts) ErrorRate() float64 {
+	requests := c.Requests()
+	if requests == 0 {
+		return 0
+	}
+	return float64(c.Failures+c.Timeouts) / float64(requests)
+}
+
+// outcome is the result of a single request.
+type outcome int
+
+const (
+	// succeeded means the request returned no error
+	succeeded outcome = iota
+
+	// failed means the request returned an error
+	failed
+
+	// timedOut means the request took longer than the Timeout
+	timedOut
+
+	// rejected means the request was not executed
+	rejected
+
+	// abandoned means the caller's context ended, which says nothing about the
+	// dependency, so the request is not counted
+	abandoned
+)
+
+// String returns the name of the outcome, as recorded in metrics.
+func (o outcome) String() string {
+	switch o {
+	case succeeded:
+		return "success"
+	case failed:
+		return "failure"
+	case timedOut:
+		return "timeout"
+	case rejected:
+		return "rejected"
+	case abandoned:
+		return "abandoned"
+	default:
+		return "unknown"
+	}
+}
+
+// isError reports whether the outcome counts against the error threshold.
+func (o outcome) isError() bool {
+	return o == failed || o == timedOut
+}
+
+// bucket holds the counts of one slice of the rolling window.
+type bucket struct {
+	start  time.Time
+	counts Counts
+}
+
+// rollingWindow counts the outcomes of requests over a sliding period of time,
+// split into buckets so that old outcomes expire one bucket at a time.
+type rollingWindow struct {
+	buckets []bucket
+	width   time.Duration
+}
+
+// newRollingWindow creates a window of the given length split into n buckets.
+func newRollingWindow(length time.Duration, n int) *rollingWindow {
+	n = max(n, 1)
+	return &rollingWindow{
+		buckets: make([]bucket, n),
+		width:   max(length/time.Duration(n), time.Nanosecond),
+	}
+}
+
+// record counts an outcome in the bucket that contains now.
+func (w *rollingWindow) record(now time.Time, o outcome) {
+	start := now.Truncate(w.width)
+	b := &w.buckets[int(start.UnixNano()/int64(w.width))%len(w.buckets)]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+
+	switch o {
+	case succeeded:
+		b.counts.Successes++
+	case failed:
+		b.counts.Failures++
+	case timedOut:
+		b.counts.Timeouts++
+	case rejected:
+		b.counts.Rejections++
+	}
+}
+
+// counts returns the sum of the buckets that are still inside the window at now.
+func (w *rollingWindow) counts(now time.Time) Counts {
+	oldest := now.Truncate(w.width).Add(-w.width * time.Duration(len(w.buckets)-1))
+
+	var total Counts
+	for _, b := range w.buckets {
+		if b.start.Before(oldest) {
+			continue
+		}
+		total.Successes += b.counts.Successes
+		total.Failures += b.counts.Failures
+		total.Timeouts += b.counts.Timeouts
+		total.Rejections += b.counts.Rejections
+	}
+	return total
+}
+
+// reset discards all outcomes.
+func (w *rollingWindow) reset() {
+	clear(w.buckets)
+}
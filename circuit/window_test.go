@@ -0,0 +1,42 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollingWindow(t *testing.T) {
+	w := newRollingWindow(time.Second, 4)
+	start := time.Unix(1000, 0)
+
+	w.record(start, succeeded)
+	w.record(start.Add(100*time.Millisecond), failed)
+	w.record(start.Add(300*time.Millisecond), timedOut)
+	w.record(start.Add(600*time.Millisecond), rejected)
+	w.record(start.Add(600*time.Millisecond), abandoned)
+
+	counts := w.counts(start.Add(900 * time.Millisecond))
+	assert.Equal(t, Counts{Successes: 1, Failures: 1, Timeouts: 1, Rejections: 1}, counts)
+	assert.Equal(t, 3, counts.Requests())
+	assert.InDelta(t, 2.0/3.0, counts.ErrorRate(), 0.001)
+
+	// The first bucket expires once the window has moved past it
+	counts = w.counts(start.Add(1100 * time.Millisecond))
+	assert.Equal(t, Counts{Timeouts: 1, Rejections: 1}, counts)
+
+	// A bucket is reused for a later slice of time
+	w.record(start.Add(1200*time.Millisecond), succeeded)
+	assert.Equal(t, Counts{Successes: 1, Timeouts: 1, Rejections: 1}, w.counts(start.Add(1200*time.Millisecond)))
+
+	// Everything expires after a full window without requests
+	assert.Equal(t, Counts{}, w.counts(start.Add(5*time.Second)))
+
+	w.record(start, failed)
+	w.reset()
+	assert.Equal(t, Counts{}, w.counts(start))
+	assert.Equal(t, 0.0, Counts{}.ErrorRate())
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for it to pass. The library's packages call it
+// instead of the time package, so that tests can replace it with a Fake.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Since returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+
+	// Sleep pauses the current goroutine for at least the duration d.
+	Sleep(d time.Duration)
+
+	// After waits for the duration to elapse and then sends the current time on
+	// the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// AfterFunc waits for the duration to elapse and then calls f.
+	AfterFunc(d time.Duration, f func()) Timer
+
+	// NewTimer creates a Timer that sends the current time on its channel after
+	// at least the duration d.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker creates a Ticker that sends the current time on its channel
+	// every period d. It panics if d is not positive.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a single event, like time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered. It is nil for a
+	// timer created by AfterFunc.
+	C() <-chan time.Time
+
+	// Stop prevents the Timer from firing. It returns false if the timer has
+	// already fired or been stopped.
+	Stop() bool
+
+	// Reset changes the timer to fire after the duration d. It returns true if
+	// the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker. No more ticks will be sent.
+	Stop()
+
+	// Reset stops the ticker and resets its period to the duration d.
+	Reset(d time.Duration)
+}
+
+// New returns a Clock that uses the time package.
+//
+// Returns:
+//   - Clock: The system clock.
+func New() Clock {
+	return realClock{}
+}
+
+// OrReal returns c, or the system clock if c is nil. Packages use it to fall
+// back to the real time when no clock was configured in their options.
+//
+// Parameters:
+//   - c: The configured clock, which may be nil.
+//
+// Returns:
+//   - Clock: c if it is not nil, otherwise the system clock.
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return New()
+	}
+	return c
+}
+
+// realClock implements Clock with the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// realTimer adapts a time.Timer to the Timer interface.
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// realTicker adapts a time.Ticker to the Ticker interface.
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// WithTimeout returns a copy of the parent context that is cancelled when the
+// duration d has elapsed on the clock c, like context.WithTimeout. With the
+// system clock it is context.WithTimeout; with a Fake, the deadline passes when
+// the fake is advanced beyond it, and Err then returns context.DeadlineExceeded.
+//
+// Parameters:
+//   - ctx: The parent context.
+//   - c: The clock that measures the timeout. If nil, the system clock is used.
+//   - d: The timeout.
+//
+// Returns:
+//   - context.Context: The derived context.
+//   - context.CancelFunc: A function that releases the context's resources. It
+//     must be called when the operation is done.
+func WithTimeout(ctx context.Context, c Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := OrReal(c).(realClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+
+	inner, cancel := context.WithCancel(ctx)
+	dc := &deadlineContext{Context: inner, deadline: c.Now().Add(d)}
+	if d <= 0 {
+		dc.expire(cancel)
+		return dc, cancel
+	}
+
+	timer := c.AfterFunc(d, func() { dc.expire(cancel) })
+	return dc, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// deadlineContext is a context whose deadline is measured by a Clock other
+// than the system clock.
+type deadlineContext struct {
+	context.Context
+	deadline time.Time
+
+	mu  sync.Mutex
+	err error
+}
+
+// Deadline returns the time at which the context expires.
+func (c *deadlineContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+// Err returns context.DeadlineExceeded once the deadline has passed, or the
+// error of the parent if it was done first.
+func (c *deadlineContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return c.Context.Err()
+}
+
+// expire marks the deadline as exceeded, unless the context is already done,
+// and cancels the context.
+func (c *deadlineContext) expire(cancel context.CancelFunc) {
+	c.mu.Lock()
+	if c.Context.Err() == nil {
+		c.err = context.DeadlineExceeded
+	}
+	c.mu.Unlock()
+	cancel()
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrReal(t *testing.T) {
+	assert.Equal(t, New(), OrReal(nil))
+
+	fake := NewFake(time.Time{})
+	assert.Same(t, fake, OrReal(fake))
+}
+
+func TestRealClock(t *testing.T) {
+	c := New()
+
+	start := c.Now()
+	c.Sleep(time.Millisecond)
+	assert.GreaterOrEqual(t, c.Since(start), time.Millisecond)
+
+	select {
+	case <-c.After(time.Millisecond):
+	case <-time.After(time.Second):
+		t.Fatal("After did not fire")
+	}
+
+	timer := c.NewTimer(time.Hour)
+	assert.True(t, timer.Stop())
+	assert.False(t, timer.Stop())
+
+	ticker := c.NewTicker(time.Millisecond)
+	<-ticker.C()
+	ticker.Stop()
+
+	fired := make(chan struct{})
+	c.AfterFunc(time.Millisecond, func() { close(fired) })
+	<-fired
+}
+
+func TestWithTimeout_RealClock(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), nil, time.Millisecond)
+	defer cancel()
+
+	<-ctx.Done()
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
+
+func TestWithTimeout_FakeClock(t *testing.T) {
+	fake := NewFake(time.Time{})
+
+	ctx, cancel := WithTimeout(context.Background(), fake, time.Second)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.Equal(t, fake.Now().Add(time.Second), deadline)
+
+	fake.Advance(999 * time.Millisecond)
+	assert.NoError(t, ctx.Err())
+
+	fake.Advance(time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("context is not done")
+	}
+}
+
+func TestWithTimeout_FakeClockCancel(t *testing.T) {
+	fake := NewFake(time.Time{})
+
+	ctx, cancel := WithTimeout(context.Background(), fake, time.Second)
+	assert.Equal(t, 1, fake.Waiters())
+
+	cancel()
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Equal(t, 0, fake.Waiters())
+
+	// The deadline passing after cancellation does not change the error
+	fake.Advance(time.Second)
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestWithTimeout_FakeClockParentDone(t *testing.T) {
+	fake := NewFake(time.Time{})
+	parent, cancelParent := context.WithCancel(context.Background())
+
+	ctx, cancel := WithTimeout(parent, fake, time.Second)
+	defer cancel()
+
+	cancelParent()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestWithTimeout_FakeClockExpired(t *testing.T) {
+	fake := NewFake(time.Time{})
+
+	ctx, cancel := WithTimeout(context.Background(), fake, 0)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
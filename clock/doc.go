@@ -0,0 +1,30 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package clock provides an abstraction of time, so that code which reads the
+// time or waits for it to pass can be tested without delay.
+//
+// Packages of the library that depend on time accept a Clock through their
+// Options and fall back to the system clock when none is given. Tests pass a
+// Fake instead, whose time only moves when the test advances it.
+//
+// Key components:
+//   - Clock: The interface for reading the time, sleeping and creating timers
+//   - New: The system clock, backed by the time package
+//   - Fake: A clock that is advanced by the test, with Advance, Set and BlockUntil
+//   - WithTimeout: A context.WithTimeout whose deadline is measured by a Clock
+//
+// Example usage:
+//
+//	fake := clock.NewFake(time.Time{})
+//
+//	done := make(chan struct{})
+//	go func() {
+//	    fake.Sleep(time.Minute)
+//	    close(done)
+//	}()
+//
+//	// Wait for the goroutine to start sleeping, then let a minute pass at once
+//	fake.BlockUntil(1)
+//	fake.Advance(time.Minute)
+//	<-done
+package clock
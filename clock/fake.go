@@ -0,0 +1,319 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when a test advances it. Timers, tickers
+// and sleeps fire when the time passes their deadline, in order of deadline, so
+// that code which waits on the clock runs in a test without delay and with
+// predictable timing.
+//
+// Functions passed to AfterFunc run synchronously in the goroutine that
+// advances the clock, after the clock's lock is released.
+//
+// This implementation is thread-safe and can be used concurrently from multiple
+// goroutines.
+type Fake struct {
+	mu          sync.Mutex
+	now         time.Time
+	timers      []*fakeTimer
+	autoAdvance bool
+
+	// changed is closed and replaced whenever a timer is added or removed
+	changed chan struct{}
+}
+
+// NewFake creates a fake clock set to the given time. If now is the zero time,
+// the clock starts at 2025-01-01 00:00:00 UTC, so that times are easy to read in
+// test failures.
+//
+// Parameters:
+//   - now: The initial time of the clock.
+//
+// Returns:
+//   - *Fake: A new Fake clock.
+func NewFake(now time.Time) *Fake {
+	if now.IsZero() {
+		now = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &Fake{now: now, changed: make(chan struct{})}
+}
+
+// SetAutoAdvance turns on or off automatic advancing. When it is on, Sleep and
+// After advance the clock by the duration of the wait at once, firing the timers
+// that fall due on the way, so that code under test that waits on the clock runs
+// to completion in a single goroutine. Timers and tickers never advance the clock.
+//
+// Parameters:
+//   - on: Whether waits advance the clock.
+func (f *Fake) SetAutoAdvance(on bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.autoAdvance = on
+}
+
+// Now returns the current time of the fake clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the time elapsed on the fake clock since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Sleep blocks until the fake clock has been advanced by d.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// After returns a channel that receives the time once the fake clock has been
+// advanced by d.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	t := f.NewTimer(d)
+
+	f.mu.Lock()
+	auto := f.autoAdvance
+	f.mu.Unlock()
+	if auto && d > 0 {
+		f.Advance(d)
+	}
+	return t.C()
+}
+
+// AfterFunc calls fn in the goroutine that advances the fake clock by d.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{fake: f, fn: fn}
+	f.schedule(t, d)
+	return t
+}
+
+// NewTimer creates a Timer that fires once the fake clock has been advanced by d.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{fake: f, ch: make(chan time.Time, 1)}
+	f.schedule(t, d)
+	return t
+}
+
+// NewTicker creates a Ticker that ticks every time the fake clock has been
+// advanced by d. It panics if d is not positive.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	t := &fakeTimer{fake: f, ch: make(chan time.Time, 1), period: d}
+	f.schedule(t, d)
+	return fakeTicker{t}
+}
+
+// Advance moves the fake clock forward by d and fires the timers whose
+// deadlines have passed.
+//
+// Parameters:
+//   - d: The duration to move the clock forward. Negative durations are ignored.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	target := f.now.Add(max(d, 0))
+	f.mu.Unlock()
+	f.Set(target)
+}
+
+// Set moves the fake clock to t and fires the timers whose deadlines have
+// passed. The clock never moves backwards; an earlier t is ignored.
+//
+// Parameters:
+//   - t: The new time of the clock.
+func (f *Fake) Set(t time.Time) {
+	for {
+		f.mu.Lock()
+		next := f.due(t)
+		if next == nil {
+			if t.After(f.now) {
+				f.now = t
+			}
+			f.mu.Unlock()
+			return
+		}
+
+		// Move to each deadline in turn, so that the timers see the time they fire at
+		if next.at.After(f.now) {
+			f.now = next.at
+		}
+		now := f.now
+		f.remove(next)
+		if next.period > 0 {
+			f.add(next, nextTick(next.at, next.period, now))
+		}
+		f.mu.Unlock()
+
+		next.fire(now)
+	}
+}
+
+// Waiters returns the number of timers, tickers and sleeps that are waiting for
+// the fake clock to advance.
+//
+// Returns:
+//   - int: The number of pending timers.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil blocks until at least n timers, tickers or sleeps are waiting for
+// the fake clock to advance. Tests use it to wait for the code under test to
+// reach a wait before they advance the clock.
+//
+// Parameters:
+//   - n: The number of waiters to wait for.
+func (f *Fake) BlockUntil(n int) {
+	for {
+		f.mu.Lock()
+		if len(f.timers) >= n {
+			f.mu.Unlock()
+			return
+		}
+		changed := f.changed
+		f.mu.Unlock()
+		<-changed
+	}
+}
+
+// schedule adds t to fire after d, or fires it at once if d is not positive.
+func (f *Fake) schedule(t *fakeTimer, d time.Duration) {
+	f.mu.Lock()
+	if d <= 0 && t.period == 0 {
+		now := f.now
+		f.mu.Unlock()
+		t.fire(now)
+		return
+	}
+	f.add(t, f.now.Add(d))
+	f.mu.Unlock()
+}
+
+// due returns the pending timer with the earliest deadline at or before t.
+// The caller must hold the lock.
+func (f *Fake) due(t time.Time) *fakeTimer {
+	var next *fakeTimer
+	for _, timer := range f.timers {
+		if timer.at.After(t) {
+			continue
+		}
+		if next == nil || timer.at.Before(next.at) {
+			next = timer
+		}
+	}
+	return next
+}
+
+// add makes t pending with the given deadline. The caller must hold the lock.
+func (f *Fake) add(t *fakeTimer, at time.Time) {
+	t.at = at
+	t.active = true
+	f.timers = append(f.timers, t)
+	f.notify()
+}
+
+// remove makes t no longer pending and reports whether it was. The caller must
+// hold the lock.
+func (f *Fake) remove(t *fakeTimer) bool {
+	if !t.active {
+		return false
+	}
+	t.active = false
+	for i, timer := range f.timers {
+		if timer == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			break
+		}
+	}
+	f.notify()
+	return true
+}
+
+// notify wakes the goroutines in BlockUntil. The caller must hold the lock.
+func (f *Fake) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// nextTick returns the first tick of a ticker after now, skipping the ticks
+// that were missed, as time.Ticker drops ticks for slow receivers.
+func nextTick(last time.Time, period time.Duration, now time.Time) time.Time {
+	next := last.Add(period)
+	if !next.After(now) {
+		next = last.Add(period * (now.Sub(last)/period + 1))
+	}
+	return next
+}
+
+// fakeTimer is a timer, ticker or AfterFunc of a Fake clock.
+type fakeTimer struct {
+	fake   *Fake
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+	fn     func()
+	active bool
+}
+
+// fire delivers the time on the channel, dropping it if the previous one has
+// not been received, or calls the function.
+func (t *fakeTimer) fire(now time.Time) {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+	select {
+	case t.ch <- now:
+	default:
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.fake.mu.Lock()
+	defer t.fake.mu.Unlock()
+	return t.fake.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.fake.mu.Lock()
+	active := t.fake.remove(t)
+	t.fake.mu.Unlock()
+
+	t.fake.schedule(t, d)
+	return active
+}
+
+// fakeTicker adapts a periodic fakeTimer to the Ticker interface.
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
+
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.fake.mu.Lock()
+	t.fake.remove(t.fakeTimer)
+	t.period = d
+	t.fake.mu.Unlock()
+
+	t.fake.schedule(t.fakeTimer, d)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package clock
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFake(t *testing.T) {
+	fake := NewFake(time.Time{})
+	assert.Equal(t, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), fake.Now())
+
+	start := time.Date(2030, time.June, 1, 12, 0, 0, 0, time.UTC)
+	fake = NewFake(start)
+	assert.Equal(t, start, fake.Now())
+
+	fake.Advance(time.Minute)
+	assert.Equal(t, time.Minute, fake.Since(start))
+
+	// The clock never moves backwards
+	fake.Set(start)
+	assert.Equal(t, start.Add(time.Minute), fake.Now())
+	fake.Advance(-time.Hour)
+	assert.Equal(t, start.Add(time.Minute), fake.Now())
+}
+
+func TestFake_Timer(t *testing.T) {
+	fake := NewFake(time.Time{})
+	timer := fake.NewTimer(time.Second)
+
+	fake.Advance(500 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	fake.Advance(500 * time.Millisecond)
+	select {
+	case now := <-timer.C():
+		assert.Equal(t, fake.Now(), now)
+	default:
+		t.Fatal("timer did not fire")
+	}
+
+	assert.False(t, timer.Stop())
+	assert.False(t, timer.Reset(time.Second))
+	assert.True(t, timer.Stop())
+	assert.Equal(t, 0, fake.Waiters())
+}
+
+func TestFake_TimerFiresAtDeadline(t *testing.T) {
+	fake := NewFake(time.Time{})
+	start := fake.Now()
+
+	var fired []time.Duration
+	fake.AfterFunc(2*time.Second, func() { fired = append(fired, fake.Since(start)) })
+	fake.AfterFunc(time.Second, func() { fired = append(fired, fake.Since(start)) })
+
+	fake.Advance(time.Minute)
+
+	// Each function runs in deadline order and sees the time of its deadline
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, fired)
+	assert.Equal(t, time.Minute, fake.Since(start))
+}
+
+func TestFake_AfterFuncScheduledByAfterFunc(t *testing.T) {
+	fake := NewFake(time.Time{})
+
+	var fired int
+	fake.AfterFunc(time.Second, func() {
+		fired++
+		fake.AfterFunc(time.Second, func() { fired++ })
+	})
+
+	fake.Advance(2 * time.Second)
+	assert.Equal(t, 2, fired)
+}
+
+func TestFake_Ticker(t *testing.T) {
+	fake := NewFake(time.Time{})
+	ticker := fake.NewTicker(time.Second)
+
+	fake.Advance(time.Second)
+	<-ticker.C()
+
+	// Missed ticks are dropped, like time.Ticker
+	fake.Advance(5 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Fatal("missed ticks were delivered")
+	default:
+	}
+
+	ticker.Reset(time.Minute)
+	fake.Advance(time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired before its new period")
+	default:
+	}
+	fake.Advance(time.Minute)
+	<-ticker.C()
+
+	ticker.Stop()
+	assert.Equal(t, 0, fake.Waiters())
+
+	assert.Panics(t, func() { fake.NewTicker(0) })
+	assert.Panics(t, func() { ticker.Reset(0) })
+}
+
+func TestFake_SleepAndBlockUntil(t *testing.T) {
+	fake := NewFake(time.Time{})
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fake.Sleep(time.Second)
+		}()
+	}
+
+	fake.BlockUntil(3)
+	assert.Equal(t, 3, fake.Waiters())
+
+	fake.Advance(time.Second)
+	wg.Wait()
+	assert.Equal(t, 0, fake.Waiters())
+}
+
+func TestFake_ZeroDurationFiresAtOnce(t *testing.T) {
+	fake := NewFake(time.Time{})
+
+	<-fake.After(0)
+	fake.Sleep(-time.Second)
+	assert.Equal(t, 0, fake.Waiters())
+}
+
+func TestFake_AutoAdvance(t *testing.T) {
+	fake := NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+	start := fake.Now()
+
+	var fired bool
+	fake.AfterFunc(time.Second, func() { fired = true })
+
+	fake.Sleep(3 * time.Second)
+	<-fake.After(2 * time.Second)
+
+	assert.True(t, fired)
+	assert.Equal(t, 5*time.Second, fake.Since(start))
+
+	// Timers do not advance the clock
+	timer := fake.NewTimer(time.Second)
+	assert.Equal(t, 5*time.Second, fake.Since(start))
+	timer.Stop()
+}
@@ -27,18 +27,32 @@
 const DefaultTimeout = 30 * time.Second
 
 // InitMongoClient initializes a MongoDB client.
+// If options are passed, the client is instrumented: every command produces a
+// span and is recorded with telemetry.RecordDBOperation, and the pool monitor
+// keeps the statistics of the connection pool, which are recorded as metrics
+// once the client is connected if the options have a Meter, until
+// UnregisterPoolMetrics is called.
 // Parameters:
 //   - ctx: The context for the operation
 //   - uri: The MongoDB connection URI
 //   - timeout: The timeout for the connection operation
+//   - opts: Optional instrumentation options
 //
 // Returns:
 //   - *mongo.Client: The initialized MongoDB client
 //   - error: An error if initialization fails
-func InitMongoClient(ctx context.Context, uri string, timeout time.Duration) (*mongo.Client, error) {
+func InitMongoClient(ctx context.Context, uri string, timeout time.Duration, opts ...Options) (*mongo.Client, error) {
 	// Set up MongoDB client options
 	clientOptions := options.Client().ApplyURI(uri)
 
+	// Instrument the client if requested
+	instrumentOpts, instrumented := resolveOptions(opts, "MongoDB")
+	stats := &mongoPoolStats{name: instrumentOpts.Name}
+	if instrumented {
+		clientOptions.SetMonitor(newMongoCommandMonitor(newInstrumentation(instrumentOpts, "mongodb")).commandMonitor())
+		clientOptions.SetPoolMonitor(stats.poolMonitor())
+	}
+
 	// Create a context with timeout for the connection
 	connectCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -51,9 +65,14 @@ func InitMongoClient(ctx context.Context, uri string, timeout time.Duration) (*m
 
 	// Ping the database to verify connection
 	if err := client.Ping(connectCtx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
 		return nil, dberrors.NewDatabaseError("failed to ping MongoDB", "ping", "MongoDB", err)
 	}
 
+	if instrumented {
+		registerPoolMetricsOrWarn(instrumentOpts, client, stats.stats)
+	}
+
 	return client, nil
 }
 
@@ -82,14 +101,19 @@ type PostgresConfig struct {
 }
 
 // InitPostgresPool initializes a PostgreSQL connection pool.
+// If options are passed, the pool is instrumented: a query tracer makes every
+// query produce a span and be recorded with telemetry.RecordDBOperation, and
+// the statistics of the pool are recorded as metrics if the options have a Meter,
+// until UnregisterPoolMetrics is called.
 // Parameters:
 //   - ctx: The context for the operation
 //   - config: The PostgreSQL connection configuration
+//   - opts: Optional instrumentation options
 //
 // Returns:
 //   - *pgxpool.Pool: The initialized PostgreSQL connection pool
 //   - error: An error if initialization fails
-func InitPostgresPool(ctx context.Context, config PostgresConfig) (*pgxpool.Pool, error) {
+func InitPostgresPool(ctx context.Context, config PostgresConfig, opts ...Options) (*pgxpool.Pool, error) {
 	// Create a context with timeout for the connection
 	connectCtx, cancel := context.WithTimeout(ctx, config.Timeout)
 	defer cancel()
@@ -122,6 +146,19 @@ func InitPostgresPool(ctx context.Context, config PostgresConfig) (*pgxpool.Pool
 		poolConfig.HealthCheckPeriod = config.HealthCheckPeriod
 	}
 
+	// Instrument the pool if requested
+	instrumentOpts, instrumented := resolveOptions(opts, "PostgreSQL")
+	if instrumented {
+		poolConfig.ConnConfig.Tracer = &pgxQueryTracer{instrumentation: newInstrumentation(instrumentOpts, "postgresql")}
+		poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			telemetry.UpdateDBConnections(ctx, instrumentOpts.Name, 1)
+			return nil
+		}
+		poolConfig.BeforeClose = func(conn *pgx.Conn) {
+			telemetry.UpdateDBConnections(context.Background(), instrumentOpts.Name, -1)
+		}
+	}
+
 	// Connect to PostgreSQL
 	pool, err := pgxpool.NewWithConfig(connectCtx, poolConfig)
 	if err != nil {
@@ -134,10 +171,18 @@ func InitPostgresPool(ctx context.Context, config PostgresConfig) (*pgxpool.Pool
 		return nil, dberrors.NewDatabaseError("failed to ping PostgreSQL", "ping", "PostgreSQL", err)
 	}
 
+	if instrumented {
+		registerPoolMetricsOrWarn(instrumentOpts, pool, postgresPoolStats(pool))
+	}
+
 	return pool, nil
 }
 
 // InitSQLiteDB initializes a SQLite database connection.
+// If options are passed, the connections are instrumented: every query produces
+// a span and is recorded with telemetry.RecordDBOperation, and the statistics
+// of the pool are recorded as metrics if the options have a Meter, until
+// UnregisterPoolMetrics is called.
 // Parameters:
 //   - ctx: The context for the operation
 //   - uri: The SQLite connection URI
@@ -145,17 +190,25 @@ func InitPostgresPool(ctx context.Context, config PostgresConfig) (*pgxpool.Pool
 //   - maxOpenConns: The maximum number of open connections
 //   - maxIdleConns: The maximum number of idle connections
 //   - connMaxLifetime: The maximum lifetime of a connection
+//   - opts: Optional instrumentation options
 //
 // Returns:
 //   - *sql.DB: The initialized SQLite database connection
 //   - error: An error if initialization fails
-func InitSQLiteDB(ctx context.Context, uri string, timeout, connMaxLifetime time.Duration, maxOpenConns, maxIdleConns int) (*sql.DB, error) {
+func InitSQLiteDB(ctx context.Context, uri string, timeout, connMaxLifetime time.Duration, maxOpenConns, maxIdleConns int, opts ...Options) (*sql.DB, error) {
 	// Create a context with timeout for the connection
 	connectCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Connect to SQLite
-	db, err := sql.Open("sqlite3", uri)
+	// Connect to SQLite, instrumenting the connections if requested
+	instrumentOpts, instrumented := resolveOptions(opts, "SQLite")
+	var db *sql.DB
+	var err error
+	if instrumented {
+		db, err = openInstrumentedDB("sqlite3", uri, newInstrumentation(instrumentOpts, "sqlite"))
+	} else {
+		db, err = sql.Open("sqlite3", uri)
+	}
 	if err != nil {
 		return nil, dberrors.NewDatabaseError("failed to open SQLite database", "open", "SQLite", err)
 	}
@@ -167,9 +220,14 @@ func InitSQLiteDB(ctx context.Context, uri string, timeout, connMaxLifetime time
 
 	// Ping the database to verify connection
 	if err := db.PingContext(connectCtx); err != nil {
+		_ = db.Close()
 		return nil, dberrors.NewDatabaseError("failed to ping SQLite database", "ping", "SQLite", err)
 	}
 
+	if instrumented {
+		registerPoolMetricsOrWarn(instrumentOpts, db, sqlPoolStats(db))
+	}
+
 	return db, nil
 }
 
@@ -17,6 +17,7 @@
 //   - Health check functions for monitoring database connectivity
 //   - Error handling with classification of transient vs. permanent errors
 //   - Telemetry integration for tracing database operations
+//   - Optional query tracing and connection pool metrics for initialized pools
 //   - Logging of database operations and errors
 //
 // Example usage for PostgreSQL:
@@ -74,6 +75,17 @@
 //	    return err
 //	})
 //
+// Pools are instrumented by passing Options to the init functions. Every query
+// or command then produces a span and is recorded with telemetry.RecordDBOperation,
+// connections are counted with telemetry.UpdateDBConnections, and with a Meter,
+// the acquired and idle connections and the waits for a connection are recorded:
+//
+//	options := db.DefaultOptions().WithOtelTracer(tracer).WithMeter(meter).WithName("orders")
+//	pool, err := db.InitPostgresPool(ctx, config, options)
+//
+// The metrics are recorded until UnregisterPoolMetrics is called with the pool,
+// which should be done when it is closed.
+//
 // The package is designed to be used throughout the application to provide
 // consistent database access patterns and error handling.
 package db
@@ -0,0 +1,237 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/abitofhelp/servicelib/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// openInstrumentedDB opens a database/sql database whose connections are instrumented.
+// The driver is looked up by name, as by sql.Open.
+//
+// Parameters:
+//   - driverName: The name of the registered driver.
+//   - dsn: The data source name passed to the driver.
+//   - i: The instrumentation of the queries.
+//
+// Returns:
+//   - *sql.DB: The database.
+//   - error: An error if the driver is not registered.
+func openInstrumentedDB(driverName, dsn string, i *instrumentation) (*sql.DB, error) {
+	// sql.Open does not connect, so it is only used to look up the driver
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	_ = db.Close()
+
+	return sql.OpenDB(&instrumentedConnector{driver: d, dsn: dsn, instrumentation: i}), nil
+}
+
+// instrumentedConnector is a driver.Connector that opens instrumented connections.
+type instrumentedConnector struct {
+	driver          driver.Driver
+	dsn             string
+	instrumentation *instrumentation
+}
+
+// Connect opens a connection and counts it with telemetry.UpdateDBConnections.
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	telemetry.UpdateDBConnections(ctx, c.instrumentation.name, 1)
+	return &instrumentedConn{Conn: conn, instrumentation: c.instrumentation}, nil
+}
+
+// Driver returns the underlying driver.
+func (c *instrumentedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// instrumentedConn is a connection whose queries and statements are instrumented.
+// The optional interfaces of the underlying connection are forwarded to it.
+type instrumentedConn struct {
+	driver.Conn
+	instrumentation *instrumentation
+}
+
+// Close closes the connection and counts it with telemetry.UpdateDBConnections.
+func (c *instrumentedConn) Close() error {
+	telemetry.UpdateDBConnections(context.Background(), c.instrumentation.name, -1)
+	return c.Conn.Close()
+}
+
+// Prepare prepares an instrumented statement.
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext prepares an instrumented statement.
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, query: query, instrumentation: c.instrumentation}, nil
+}
+
+// BeginTx starts a transaction.
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+// ExecContext executes a query without preparing it, if the underlying connection supports it.
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	var result driver.Result
+	err := c.instrumentation.trace(ctx, query, func(ctx context.Context) error {
+		var err error
+		result, err = execer.ExecContext(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+// QueryContext runs a query without preparing it, if the underlying connection supports it.
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	var rows driver.Rows
+	err := c.instrumentation.trace(ctx, query, func(ctx context.Context) error {
+		var err error
+		rows, err = queryer.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+// Ping verifies the connection, if the underlying connection supports it.
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession resets the connection before it is reused, if the underlying connection supports it.
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid reports whether the connection can be reused, if the underlying connection supports it.
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue converts an argument, if the underlying connection supports it.
+func (c *instrumentedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// instrumentedStmt is a prepared statement whose executions are instrumented.
+type instrumentedStmt struct {
+	driver.Stmt
+	query           string
+	instrumentation *instrumentation
+}
+
+// ExecContext executes the statement.
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var result driver.Result
+	err := s.instrumentation.trace(ctx, s.query, func(ctx context.Context) error {
+		var err error
+		if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+			result, err = execer.ExecContext(ctx, args)
+		} else {
+			result, err = s.Stmt.Exec(namedValuesToValues(args))
+		}
+		return err
+	})
+	return result, err
+}
+
+// QueryContext runs the statement.
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	err := s.instrumentation.trace(ctx, s.query, func(ctx context.Context) error {
+		var err error
+		if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			rows, err = queryer.QueryContext(ctx, args)
+		} else {
+			rows, err = s.Stmt.Query(namedValuesToValues(args))
+		}
+		return err
+	})
+	return rows, err
+}
+
+// CheckNamedValue converts an argument, if the underlying statement supports it.
+func (s *instrumentedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// trace runs a SQL query in a span and records it. A query that the driver
+// skips, so that database/sql prepares it instead, is not recorded, because
+// the prepared statement is.
+func (i *instrumentation) trace(ctx context.Context, query string, fn func(ctx context.Context) error) error {
+	operation := sqlOperation(query)
+	ctx, span := i.start(ctx, "db.Query", operation, attribute.String("db.statement", query))
+
+	start := time.Now()
+	err := fn(ctx)
+	if errors.Is(err, driver.ErrSkip) {
+		span.End()
+		return err
+	}
+
+	i.end(ctx, span, operation, "", time.Since(start), err)
+	return err
+}
+
+// namedValuesToValues converts the arguments of a query for drivers that do not support named values.
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for n, arg := range args {
+		values[n] = arg.Value
+	}
+	return values
+}
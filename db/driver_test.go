@@ -0,0 +1,108 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInitSQLiteDB_Instrumented(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer func() { _ = tracerProvider.Shutdown(context.Background()) }()
+
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = meterProvider.Shutdown(context.Background()) }()
+
+	options := DefaultOptions().
+		WithOtelTracer(tracerProvider.Tracer("test")).
+		WithMeter(meterProvider.Meter("test")).
+		WithName("users")
+
+	// A single connection, so that every query sees the same in-memory database
+	ctx := context.Background()
+	sqliteDB, err := InitSQLiteDB(ctx, ":memory:", time.Second, time.Hour, 1, 1, options)
+	if err != nil {
+		t.Skip("Skipping test as SQLite is not available")
+	}
+	defer sqliteDB.Close()
+
+	_, err = sqliteDB.ExecContext(ctx, "CREATE TABLE users (name TEXT)")
+	require.NoError(t, err)
+
+	stmt, err := sqliteDB.PrepareContext(ctx, "INSERT INTO users (name) VALUES (?)")
+	require.NoError(t, err)
+	_, err = stmt.ExecContext(ctx, "John")
+	require.NoError(t, err)
+	require.NoError(t, stmt.Close())
+
+	err = ExecuteSQLTransaction(ctx, sqliteDB, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE users SET name = ?", "Jane")
+		return err
+	})
+	require.NoError(t, err)
+
+	var name string
+	require.NoError(t, sqliteDB.QueryRowContext(ctx, "SELECT name FROM users").Scan(&name))
+	assert.Equal(t, "Jane", name)
+
+	_, err = sqliteDB.ExecContext(ctx, "DELETE FROM missing")
+	assert.Error(t, err)
+
+	operations := make([]string, 0)
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, "db.Query", span.Name())
+		assert.Contains(t, span.Attributes(), attribute.String("db.system", "sqlite"))
+		for _, attr := range span.Attributes() {
+			if attr.Key == "db.operation" {
+				operations = append(operations, attr.Value.AsString())
+			}
+		}
+	}
+	assert.Equal(t, []string{"create", "insert", "update", "select", "delete"}, operations)
+
+	failed := recorder.Ended()[len(recorder.Ended())-1]
+	assert.Contains(t, failed.Attributes(), attribute.String("db.statement", "DELETE FROM missing"))
+	require.Len(t, failed.Events(), 1)
+	assert.Equal(t, "exception", failed.Events()[0].Name)
+
+	gauges := make(map[string]int64)
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if data, ok := m.Data.(metricdata.Gauge[int64]); ok {
+				for _, dp := range data.DataPoints {
+					database, _ := dp.Attributes.Value("database")
+					assert.Equal(t, "users", database.AsString())
+					gauges[m.Name] = dp.Value
+				}
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{
+		"db.pool.connections.acquired": 0,
+		"db.pool.connections.idle":     1,
+	}, gauges)
+
+	require.NoError(t, UnregisterPoolMetrics(sqliteDB))
+	assert.Empty(t, collectPoolMetrics(t, reader))
+}
+
+func TestOpenInstrumentedDB_UnknownDriver(t *testing.T) {
+	db, err := openInstrumentedDB("unknown", "", newInstrumentation(DefaultOptions(), "unknown"))
+	assert.Error(t, err)
+	assert.Nil(t, db)
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abitofhelp/servicelib/telemetry"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// poolStats are the statistics of a connection pool that are recorded as metrics.
+type poolStats struct {
+	// acquired is the number of connections in use
+	acquired int64
+
+	// idle is the number of connections waiting to be used
+	idle int64
+
+	// waitCount is the total number of times a connection was waited for
+	waitCount int64
+
+	// waitDuration is the total time spent waiting for connections
+	waitDuration time.Duration
+}
+
+// poolRegistrations holds the callback registrations of the pool metrics, keyed
+// by the pool, client or database returned by the init function.
+var poolRegistrations sync.Map
+
+// registerPoolMetrics registers the instruments that observe the statistics of a connection pool.
+//
+// Parameters:
+//   - meter: The meter used to create the instruments.
+//   - name: The name of the database, recorded as the database attribute.
+//   - stats: A function returning the current statistics of the pool.
+//
+// Returns:
+//   - metric.Registration: The registration of the callback that observes the statistics.
+//   - error: An error if an instrument could not be created.
+func registerPoolMetrics(meter metric.Meter, name string, stats func() poolStats) (metric.Registration, error) {
+	acquired, err := meter.Int64ObservableGauge(
+		"db.pool.connections.acquired",
+		metric.WithDescription("Number of connections in use"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create db.pool.connections.acquired gauge: %w", err)
+	}
+
+	idle, err := meter.Int64ObservableGauge(
+		"db.pool.connections.idle",
+		metric.WithDescription("Number of idle connections"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create db.pool.connections.idle gauge: %w", err)
+	}
+
+	waitCount, err := meter.Int64ObservableCounter(
+		"db.pool.wait.count",
+		metric.WithDescription("Total number of times a connection was waited for"),
+		metric.WithUnit("{wait}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create db.pool.wait.count counter: %w", err)
+	}
+
+	waitDuration, err := meter.Float64ObservableCounter(
+		"db.pool.wait.duration",
+		metric.WithDescription("Total time spent waiting for connections"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create db.pool.wait.duration counter: %w", err)
+	}
+
+	attrs := metric.WithAttributes(attribute.String("database", name))
+	registration, err := meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		s := stats()
+		o.ObserveInt64(acquired, s.acquired, attrs)
+		o.ObserveInt64(idle, s.idle, attrs)
+		o.ObserveInt64(waitCount, s.waitCount, attrs)
+		o.ObserveFloat64(waitDuration, s.waitDuration.Seconds(), attrs)
+		return nil
+	}, acquired, idle, waitCount, waitDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register connection pool callback: %w", err)
+	}
+
+	return registration, nil
+}
+
+// registerPoolMetricsOrWarn registers the pool metrics if the options have a meter,
+// so that UnregisterPoolMetrics can unregister them, and logs a warning if they
+// cannot be registered.
+//
+// Parameters:
+//   - options: The instrumentation options.
+//   - pool: The pool, client or database returned by the init function.
+//   - stats: A function returning the current statistics of the pool.
+func registerPoolMetricsOrWarn(options Options, pool any, stats func() poolStats) {
+	if options.Meter == nil {
+		return
+	}
+	registration, err := registerPoolMetrics(options.Meter, options.Name, stats)
+	if err != nil {
+		options.Logger.Warn(context.Background(), "Failed to create connection pool metrics, continuing without metrics",
+			zap.String("database", options.Name),
+			zap.Error(err))
+		return
+	}
+	poolRegistrations.Store(pool, registration)
+}
+
+// UnregisterPoolMetrics stops recording the statistics of a connection pool
+// returned by InitPostgresPool, InitMongoClient or InitSQLiteDB. It should be
+// called when the pool is closed: until then, the metrics keep observing the
+// pool, which cannot be garbage collected. It does nothing if the pool has no
+// metrics.
+//
+// Parameters:
+//   - pool: The *pgxpool.Pool, *mongo.Client or *sql.DB returned by an init function.
+//
+// Returns:
+//   - error: An error if the metrics could not be unregistered.
+func UnregisterPoolMetrics(pool any) error {
+	registration, ok := poolRegistrations.LoadAndDelete(pool)
+	if !ok {
+		return nil
+	}
+	if err := registration.(metric.Registration).Unregister(); err != nil {
+		return fmt.Errorf("failed to unregister connection pool callback: %w", err)
+	}
+	return nil
+}
+
+// postgresPoolStats returns the statistics of a PostgreSQL connection pool.
+// Acquisitions that found no idle connection are counted as waits.
+func postgresPoolStats(pool *pgxpool.Pool) func() poolStats {
+	return func() poolStats {
+		s := pool.Stat()
+		return poolStats{
+			acquired:     int64(s.AcquiredConns()),
+			idle:         int64(s.IdleConns()),
+			waitCount:    s.EmptyAcquireCount(),
+			waitDuration: s.EmptyAcquireWaitTime(),
+		}
+	}
+}
+
+// sqlPoolStats returns the statistics of a database/sql connection pool.
+func sqlPoolStats(db *sql.DB) func() poolStats {
+	return func() poolStats {
+		s := db.Stats()
+		return poolStats{
+			acquired:     int64(s.InUse),
+			idle:         int64(s.Idle),
+			waitCount:    s.WaitCount,
+			waitDuration: s.WaitDuration,
+		}
+	}
+}
+
+// mongoPoolStats tracks the statistics of the connection pools of a MongoDB client
+// from the events of its pool monitor. A check out that starts when the pool of
+// its server has no idle connection is counted as a wait, with the time it took.
+// The events do not tell which check out ended, so when check outs that waited
+// and ones that did not overlap, the first to end are counted.
+type mongoPoolStats struct {
+	// name is the name of the database, passed to telemetry.UpdateDBConnections
+	name string
+
+	mutex        sync.Mutex
+	pools        map[string]*mongoPool
+	waitCount    int64
+	waitDuration time.Duration
+}
+
+// mongoPool is the state of the connection pool of one server.
+type mongoPool struct {
+	// open is the number of open connections
+	open int64
+
+	// acquired is the number of connections checked out
+	acquired int64
+
+	// checkingOut is the number of check outs in progress
+	checkingOut int64
+
+	// waiting is the number of check outs in progress that found no idle connection
+	waiting int64
+}
+
+// poolMonitor returns the pool monitor that updates the statistics.
+func (s *mongoPoolStats) poolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: s.handle}
+}
+
+// handle updates the statistics with a pool event.
+func (s *mongoPoolStats) handle(evt *event.PoolEvent) {
+	switch evt.Type {
+	case event.ConnectionCreated:
+		telemetry.UpdateDBConnections(context.Background(), s.name, 1)
+	case event.ConnectionClosed:
+		telemetry.UpdateDBConnections(context.Background(), s.name, -1)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.pools == nil {
+		s.pools = make(map[string]*mongoPool)
+	}
+	pool, ok := s.pools[evt.Address]
+	if !ok {
+		pool = &mongoPool{}
+		s.pools[evt.Address] = pool
+	}
+
+	switch evt.Type {
+	case event.ConnectionCreated:
+		pool.open++
+	case event.ConnectionClosed:
+		pool.open--
+	case event.GetStarted:
+		// Idle connections are taken by the check outs in progress first
+		if pool.open-pool.acquired-pool.checkingOut <= 0 {
+			pool.waiting++
+		}
+		pool.checkingOut++
+	case event.GetSucceeded, event.GetFailed:
+		if evt.Type == event.GetSucceeded {
+			pool.acquired++
+		}
+		pool.checkingOut = max(pool.checkingOut-1, 0)
+		if pool.waiting > 0 {
+			pool.waiting--
+			s.waitCount++
+			s.waitDuration += evt.Duration
+		}
+	case event.ConnectionReturned:
+		pool.acquired--
+	}
+}
+
+// stats returns the current statistics.
+func (s *mongoPoolStats) stats() poolStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := poolStats{waitCount: s.waitCount, waitDuration: s.waitDuration}
+	for _, pool := range s.pools {
+		stats.acquired += pool.acquired
+		stats.idle += max(pool.open-pool.acquired, 0)
+	}
+	return stats
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/event"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRegisterPoolMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	stats := poolStats{acquired: 3, idle: 2, waitCount: 5, waitDuration: 1500 * time.Millisecond}
+	registration, err := registerPoolMetrics(provider.Meter("test"), "orders", func() poolStats { return stats })
+	require.NoError(t, err)
+	defer func() { _ = registration.Unregister() }()
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	values := make(map[string]float64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					database, _ := dp.Attributes.Value("database")
+					assert.Equal(t, "orders", database.AsString())
+					values[m.Name] = float64(dp.Value)
+				}
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					values[m.Name] = float64(dp.Value)
+				}
+			case metricdata.Sum[float64]:
+				for _, dp := range data.DataPoints {
+					values[m.Name] = dp.Value
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		"db.pool.connections.acquired": 3,
+		"db.pool.connections.idle":     2,
+		"db.pool.wait.count":           5,
+		"db.pool.wait.duration":        1.5,
+	}, values)
+}
+
+func TestMongoPoolStats(t *testing.T) {
+	stats := &mongoPoolStats{name: "MongoDB"}
+	monitor := stats.poolMonitor()
+
+	for _, evt := range []*event.PoolEvent{
+		// The pool is empty, so the first check out waits for a new connection
+		{Type: event.GetStarted, Address: "a"},
+		{Type: event.ConnectionCreated, Address: "a"},
+		{Type: event.GetSucceeded, Address: "a", Duration: 10 * time.Millisecond},
+		{Type: event.ConnectionReturned, Address: "a"},
+		// An idle connection is checked out without waiting
+		{Type: event.GetStarted, Address: "a"},
+		{Type: event.GetSucceeded, Address: "a", Duration: time.Millisecond},
+		// Two check outs start with one idle connection, so one of them waits
+		{Type: event.ConnectionCreated, Address: "a"},
+		{Type: event.ConnectionCreated, Address: "a"},
+		{Type: event.ConnectionClosed, Address: "a"},
+		{Type: event.GetStarted, Address: "a"},
+		{Type: event.GetStarted, Address: "a"},
+		{Type: event.GetSucceeded, Address: "a", Duration: 2 * time.Millisecond},
+		{Type: event.GetFailed, Address: "a", Duration: 30 * time.Millisecond},
+		// The idle connection of another server is not available
+		{Type: event.GetStarted, Address: "b"},
+		{Type: event.GetFailed, Address: "b", Duration: 20 * time.Millisecond},
+		{Type: event.ConnectionCreated, Address: "b"},
+	} {
+		monitor.Event(evt)
+	}
+
+	assert.Equal(t, poolStats{
+		acquired:     2,
+		idle:         1,
+		waitCount:    3,
+		waitDuration: 32 * time.Millisecond,
+	}, stats.stats())
+}
+
+func TestUnregisterPoolMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	pool := &mongoPoolStats{}
+	options, _ := resolveOptions([]Options{DefaultOptions().WithMeter(provider.Meter("test"))}, "MongoDB")
+	registerPoolMetricsOrWarn(options, pool, pool.stats)
+	assert.NotEmpty(t, collectPoolMetrics(t, reader))
+
+	require.NoError(t, UnregisterPoolMetrics(pool))
+	assert.Empty(t, collectPoolMetrics(t, reader))
+
+	// A pool without metrics is ignored
+	require.NoError(t, UnregisterPoolMetrics(pool))
+}
+
+func TestInitMongoClient_FailureLeavesNoPoolMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	options := DefaultOptions().WithMeter(provider.Meter("test"))
+	client, err := InitMongoClient(context.Background(), "mongodb://127.0.0.1:1/?connectTimeoutMS=50", 200*time.Millisecond, options)
+	require.Error(t, err)
+	assert.Nil(t, client)
+	assert.Empty(t, collectPoolMetrics(t, reader))
+}
+
+// collectPoolMetrics returns the names of the metrics with data points collected by a reader.
+func collectPoolMetrics(t *testing.T, reader sdkmetric.Reader) []string {
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	var names []string
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package db
+
+import (
+	"github.com/abitofhelp/servicelib/logging"
+	"github.com/abitofhelp/servicelib/telemetry"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Options contains the observability options of InitPostgresPool, InitMongoClient
+// and InitSQLiteDB. When options are passed to an init function, every query
+// or command run through the returned pool starts a span and is recorded with
+// telemetry.RecordDBOperation, and opened and closed connections are counted
+// with telemetry.UpdateDBConnections. If a Meter is set, the statistics of the
+// connection pool are recorded as well.
+type Options struct {
+	// Logger is used to log problems setting up the instrumentation.
+	// If nil, a no-op logger will be used.
+	Logger *logging.ContextLogger
+
+	// Tracer is used to create a span for every query or command.
+	// If nil, a no-op tracer will be used.
+	Tracer telemetry.Tracer
+
+	// Meter is used to record the statistics of the connection pool.
+	// If nil, no pool metrics are recorded.
+	Meter metric.Meter
+
+	// Name identifies the database in metrics, as the database attribute.
+	// If empty, the type of the database is used: "PostgreSQL", "MongoDB" or "SQLite".
+	Name string
+}
+
+// DefaultOptions returns default options for database instrumentation.
+// The default options include:
+//   - No logger (a no-op logger will be used)
+//   - A no-op tracer (no OpenTelemetry integration)
+//   - No meter (no pool metrics)
+//
+// Returns:
+//   - An Options instance with default values.
+func DefaultOptions() Options {
+	return Options{
+		Tracer: telemetry.NewNoopTracer(),
+	}
+}
+
+// WithLogger sets the logger used to log problems setting up the instrumentation.
+//
+// Parameters:
+//   - logger: A ContextLogger instance.
+//
+// Returns:
+//   - A new Options instance with the updated Logger value.
+func (o Options) WithLogger(logger *logging.ContextLogger) Options {
+	o.Logger = logger
+	return o
+}
+
+// WithOtelTracer returns Options with an OpenTelemetry tracer.
+// Every query or command then produces a span with the db.system, db.name and
+// db.operation attributes, and db.statement for SQL databases.
+//
+// Parameters:
+//   - tracer: An OpenTelemetry trace.Tracer instance.
+//
+// Returns:
+//   - A new Options instance with the provided OpenTelemetry tracer.
+func (o Options) WithOtelTracer(tracer trace.Tracer) Options {
+	o.Tracer = telemetry.NewOtelTracer(tracer)
+	return o
+}
+
+// WithMeter sets the OpenTelemetry meter used to record the statistics of the
+// connection pool: the acquired and idle connections, and the number of times
+// and total time spent waiting for a connection.
+//
+// Parameters:
+//   - meter: An OpenTelemetry metric.Meter instance.
+//
+// Returns:
+//   - A new Options instance with the updated Meter value.
+func (o Options) WithMeter(meter metric.Meter) Options {
+	o.Meter = meter
+	return o
+}
+
+// WithName sets the name that identifies the database in metrics.
+// This is useful when an application connects to several databases of the same type.
+//
+// Parameters:
+//   - name: The name of the database.
+//
+// Returns:
+//   - A new Options instance with the updated Name value.
+func (o Options) WithName(name string) Options {
+	o.Name = name
+	return o
+}
+
+// resolveOptions returns the first of the options passed to an init function,
+// with the defaults filled in, and whether any were passed.
+func resolveOptions(options []Options, dbType string) (Options, bool) {
+	if len(options) == 0 {
+		return Options{}, false
+	}
+
+	o := options[0]
+	if o.Logger == nil {
+		o.Logger = logging.NewContextLogger(zap.NewNop())
+	}
+	if o.Tracer == nil {
+		o.Tracer = telemetry.NewNoopTracer()
+	}
+	if o.Name == "" {
+		o.Name = dbType
+	}
+	return o, true
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package db
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	dberrors "github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/telemetry"
+	"github.com/jackc/pgx/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// instrumentation creates the spans and records the metrics of the queries run against a database.
+type instrumentation struct {
+	// tracer creates a span for each query
+	tracer telemetry.Tracer
+
+	// name is the name of the database, passed to telemetry.RecordDBOperation
+	name string
+
+	// system is the type of the database, recorded as the db.system attribute
+	system string
+}
+
+// newInstrumentation creates the instrumentation of a database from the options of an init function.
+func newInstrumentation(options Options, system string) *instrumentation {
+	return &instrumentation{
+		tracer: options.Tracer,
+		name:   options.Name,
+		system: system,
+	}
+}
+
+// start starts the span of a query.
+//
+// Parameters:
+//   - ctx: The context of the query.
+//   - spanName: The name of the span.
+//   - operation: The operation, such as "select" or "find".
+//   - attrs: Additional attributes of the span.
+//
+// Returns:
+//   - context.Context: The context containing the span.
+//   - telemetry.Span: The span, which is passed to end.
+func (i *instrumentation) start(ctx context.Context, spanName, operation string, attrs ...attribute.KeyValue) (context.Context, telemetry.Span) {
+	ctx, span := i.tracer.Start(ctx, spanName)
+	span.SetAttributes(
+		attribute.String("db.system", i.system),
+		attribute.String("db.operation", operation))
+	span.SetAttributes(attrs...)
+	return ctx, span
+}
+
+// end ends the span of a query and records it with telemetry.RecordDBOperation.
+//
+// Parameters:
+//   - ctx: The context of the query.
+//   - span: The span returned by start.
+//   - operation: The operation, as passed to start.
+//   - collection: The collection or table queried, if known.
+//   - duration: How long the query took.
+//   - err: The error of the query, if it failed.
+func (i *instrumentation) end(ctx context.Context, span telemetry.Span, operation, collection string, duration time.Duration, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+
+	telemetry.RecordDBOperation(ctx, operation, i.name, collection, duration, err)
+}
+
+// sqlOperation returns the operation of a SQL statement, which is its first keyword in lower case.
+func sqlOperation(statement string) string {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToLower(fields[0])
+}
+
+// pgxQueryTracer is a pgx.QueryTracer that instruments the queries run on a PostgreSQL connection.
+type pgxQueryTracer struct {
+	*instrumentation
+}
+
+// pgxQueryKey is the context key of the query being traced by a pgxQueryTracer.
+type pgxQueryKey struct{}
+
+// pgxQuery is a query being traced by a pgxQueryTracer.
+type pgxQuery struct {
+	span      telemetry.Span
+	operation string
+	start     time.Time
+}
+
+// TraceQueryStart starts the span of a query.
+func (t *pgxQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := sqlOperation(data.SQL)
+	ctx, span := t.start(ctx, "db.Query", operation, attribute.String("db.statement", data.SQL))
+	return context.WithValue(ctx, pgxQueryKey{}, &pgxQuery{span: span, operation: operation, start: time.Now()})
+}
+
+// TraceQueryEnd ends the span of a query and records it.
+func (t *pgxQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	query, ok := ctx.Value(pgxQueryKey{}).(*pgxQuery)
+	if !ok {
+		return
+	}
+	t.end(ctx, query.span, query.operation, "", time.Since(query.start), data.Err)
+}
+
+// mongoCommandMonitor instruments the commands run by a MongoDB client.
+// The span of a command is kept until the command succeeds or fails,
+// because the monitor cannot return a context.
+type mongoCommandMonitor struct {
+	*instrumentation
+
+	mutex    sync.Mutex
+	commands map[int64]*mongoCommand
+}
+
+// mongoCommand is a command being traced by a mongoCommandMonitor.
+type mongoCommand struct {
+	ctx        context.Context
+	span       telemetry.Span
+	collection string
+}
+
+// newMongoCommandMonitor creates a monitor of the commands run by a MongoDB client.
+func newMongoCommandMonitor(i *instrumentation) *mongoCommandMonitor {
+	return &mongoCommandMonitor{
+		instrumentation: i,
+		commands:        make(map[int64]*mongoCommand),
+	}
+}
+
+// commandMonitor returns the command monitor to set on the options of the client.
+func (m *mongoCommandMonitor) commandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: m.started,
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			m.finished(evt.CommandFinishedEvent, nil)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			m.finished(evt.CommandFinishedEvent,
+				dberrors.NewDatabaseError(evt.Failure, evt.CommandName, "", nil))
+		},
+	}
+}
+
+// started starts the span of a command.
+func (m *mongoCommandMonitor) started(ctx context.Context, evt *event.CommandStartedEvent) {
+	collection := mongoCollection(evt.Command)
+	ctx, span := m.start(ctx, "db.Command", evt.CommandName,
+		attribute.String("db.name", evt.DatabaseName),
+		attribute.String("db.mongodb.collection", collection))
+
+	m.mutex.Lock()
+	m.commands[evt.RequestID] = &mongoCommand{ctx: ctx, span: span, collection: collection}
+	m.mutex.Unlock()
+}
+
+// finished ends the span of a command and records it.
+func (m *mongoCommandMonitor) finished(evt event.CommandFinishedEvent, err error) {
+	m.mutex.Lock()
+	command, ok := m.commands[evt.RequestID]
+	delete(m.commands, evt.RequestID)
+	m.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+	m.end(command.ctx, command.span, evt.CommandName, command.collection, evt.Duration, err)
+}
+
+// mongoCollection returns the collection of a command. Most commands name it
+// as the value of their first element, as in {"find": "users"}, and getMore
+// names it in its collection element.
+func mongoCollection(command bson.Raw) string {
+	if value, err := command.LookupErr("collection"); err == nil {
+		if collection, ok := value.StringValueOK(); ok {
+			return collection
+		}
+	}
+
+	element, err := command.IndexErr(0)
+	if err != nil {
+		return ""
+	}
+	collection, _ := element.Value().StringValueOK()
+	return collection
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestInstrumentation(t *testing.T, system string) (*instrumentation, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	options, _ := resolveOptions([]Options{DefaultOptions().WithOtelTracer(provider.Tracer("test"))}, "Test")
+	return newInstrumentation(options, system), recorder
+}
+
+func TestSQLOperation(t *testing.T) {
+	assert.Equal(t, "select", sqlOperation("SELECT * FROM users"))
+	assert.Equal(t, "insert", sqlOperation("\n\tinsert into users values ($1)"))
+	assert.Equal(t, "query", sqlOperation("  "))
+}
+
+func TestResolveOptions(t *testing.T) {
+	_, instrumented := resolveOptions(nil, "PostgreSQL")
+	assert.False(t, instrumented)
+
+	options, instrumented := resolveOptions([]Options{{}}, "PostgreSQL")
+	assert.True(t, instrumented)
+	assert.Equal(t, "PostgreSQL", options.Name)
+	assert.NotNil(t, options.Logger)
+	assert.NotNil(t, options.Tracer)
+
+	options, _ = resolveOptions([]Options{DefaultOptions().WithName("orders")}, "PostgreSQL")
+	assert.Equal(t, "orders", options.Name)
+}
+
+func TestPgxQueryTracer(t *testing.T) {
+	i, recorder := newTestInstrumentation(t, "postgresql")
+	tracer := &pgxQueryTracer{instrumentation: i}
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "DELETE FROM users"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("permission denied")})
+
+	// A query that was not started is ignored
+	tracer.TraceQueryEnd(context.Background(), nil, pgx.TraceQueryEndData{})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "db.Query", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.system", "postgresql"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.operation", "select"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.statement", "SELECT 1"))
+	assert.Empty(t, spans[0].Events())
+
+	assert.Contains(t, spans[1].Attributes(), attribute.String("db.operation", "delete"))
+	require.Len(t, spans[1].Events(), 1)
+	assert.Equal(t, "exception", spans[1].Events()[0].Name)
+}
+
+func TestMongoCommandMonitor(t *testing.T) {
+	i, recorder := newTestInstrumentation(t, "mongodb")
+	monitor := newMongoCommandMonitor(i).commandMonitor()
+
+	find, err := bson.Marshal(bson.D{{Key: "find", Value: "users"}, {Key: "filter", Value: bson.D{}}})
+	require.NoError(t, err)
+	monitor.Started(context.Background(), &event.CommandStartedEvent{
+		Command: find, DatabaseName: "app", CommandName: "find", RequestID: 1,
+	})
+
+	getMore, err := bson.Marshal(bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "orders"}})
+	require.NoError(t, err)
+	monitor.Started(context.Background(), &event.CommandStartedEvent{
+		Command: getMore, DatabaseName: "app", CommandName: "getMore", RequestID: 2,
+	})
+
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "getMore", RequestID: 2, Duration: time.Millisecond},
+		Failure:              "cursor not found",
+	})
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, Duration: time.Millisecond},
+	})
+
+	// A command that was not started is ignored
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 3},
+	})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "db.Command", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.operation", "getMore"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("db.mongodb.collection", "orders"))
+	require.Len(t, spans[0].Events(), 1)
+
+	assert.Contains(t, spans[1].Attributes(), attribute.String("db.system", "mongodb"))
+	assert.Contains(t, spans[1].Attributes(), attribute.String("db.name", "app"))
+	assert.Contains(t, spans[1].Attributes(), attribute.String("db.operation", "find"))
+	assert.Contains(t, spans[1].Attributes(), attribute.String("db.mongodb.collection", "users"))
+	assert.Empty(t, spans[1].Events())
+}
+
+func TestMongoCollection(t *testing.T) {
+	hello, err := bson.Marshal(bson.D{{Key: "hello", Value: 1}})
+	require.NoError(t, err)
+	assert.Empty(t, mongoCollection(hello))
+
+	assert.Empty(t, mongoCollection(nil))
+}
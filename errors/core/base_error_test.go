@@ -175,6 +175,7 @@ func TestGetHTTPStatus(t *testing.T) {
 		{ResourceExhaustedCode, 429},
 		{DataCorruptionCode, 500},
 		{ConcurrencyErrorCode, 409},
+		{ServiceUnavailableCode, 503},
 	}
 
 	for _, tc := range testCases {
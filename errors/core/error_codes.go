@@ -82,6 +82,11 @@
 	// ConcurrencyErrorCode is used for concurrency-related errors.
 	// Maps to HTTP 409 Conflict.
 	ConcurrencyErrorCode ErrorCode = "CONCURRENCY_ERROR"
+
+	// ServiceUnavailableCode is used when a dependency is temporarily unavailable,
+	// for example because a circuit breaker is open.
+	// Maps to HTTP 503 Service Unavailable.
+	ServiceUnavailableCode ErrorCode = "SERVICE_UNAVAILABLE"
 )
 
 // Standard errors that can be used throughout the application
@@ -133,4 +138,5 @@
 	ResourceExhaustedCode:     http.StatusTooManyRequests,
 	DataCorruptionCode:        http.StatusInternalServerError,
 	ConcurrencyErrorCode:      http.StatusConflict,
+	ServiceUnavailableCode:    http.StatusServiceUnavailable,
 }
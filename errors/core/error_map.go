@@ -23,6 +23,7 @@
 	ResourceExhaustedCode:     http.StatusTooManyRequests,
 	DataCorruptionCode:        http.StatusInternalServerError,
 	ConcurrencyErrorCode:      http.StatusConflict,
+	ServiceUnavailableCode:    http.StatusServiceUnavailable,
 }
 
 // GetHTTPStatus returns the HTTP status code for an error code
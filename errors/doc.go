@@ -33,4 +33,9 @@
 //
 //	// Get HTTP status code for error
 //	statusCode := errors.GetHTTPStatus(dbErr)
+//
+//	// Detect requests rejected by an open circuit breaker
+//	if errors.Is(err, errors.ErrCircuitBreakerOpen) {
+//	    // Use a fallback
+//	}
 package errors
@@ -71,6 +71,9 @@
 
 	// ConcurrencyErrorCode indicates a concurrency-related error.
 	ConcurrencyErrorCode = core.ConcurrencyErrorCode
+
+	// ServiceUnavailableCode indicates that a dependency is temporarily unavailable.
+	ServiceUnavailableCode = core.ServiceUnavailableCode
 )
 
 // Standard errors provides commonly used error instances that can be used directly or wrapped.
@@ -98,6 +101,10 @@
 
 	// ErrAlreadyExists represents a generic "resource already exists" error.
 	ErrAlreadyExists = core.NewBaseError(AlreadyExistsCode, "resource already exists", nil)
+
+	// ErrCircuitBreakerOpen represents a request rejected by an open circuit breaker.
+	// Every CircuitBreakerOpenError matches it with Is, whatever its circuit breaker's name.
+	ErrCircuitBreakerOpen = infra.NewCircuitBreakerOpenError("")
 )
 
 // Error type aliases provide convenient access to error types from the core, domain, infra, and app packages.
@@ -146,6 +153,14 @@
 	// It includes the number of attempts made and the maximum allowed attempts.
 	RetryError = infra.RetryError
 
+	// RetryAttempt records one attempt of a retried operation, as kept in the
+	// History of a RetryError.
+	RetryAttempt = infra.RetryAttempt
+
+	// CircuitBreakerOpenError represents a request rejected by an open circuit breaker.
+	// It includes the name of the circuit breaker, and maps to HTTP 503 Service Unavailable.
+	CircuitBreakerOpenError = infra.CircuitBreakerOpenError
+
 	// ContextError represents an error that occurs due to context cancellation or timeout.
 	// Use this when an operation fails because its context was canceled or timed out.
 	ContextError = infra.ContextError
@@ -381,6 +396,18 @@ func NewRetryError(message string, cause error, attempts int, maxAttempts int) *
 	return infra.NewRetryError(message, cause, attempts, maxAttempts)
 }
 
+// NewCircuitBreakerOpenError creates a new CircuitBreakerOpenError for a circuit breaker.
+// Use this when a request is rejected because a circuit breaker is open.
+//
+// Parameters:
+//   - name: The name of the circuit breaker, or an empty string if it is unnamed.
+//
+// Returns:
+//   - A new CircuitBreakerOpenError instance with the ServiceUnavailableCode.
+func NewCircuitBreakerOpenError(name string) *CircuitBreakerOpenError {
+	return infra.NewCircuitBreakerOpenError(name)
+}
+
 // NewContextError creates a new ContextError when an operation fails due to context cancellation or timeout.
 // Use this when an operation fails because its context was canceled or timed out.
 //
@@ -645,6 +672,12 @@ func IsInfrastructureError(err error) bool {
 		return true
 	}
 
+	// Check if the error is a CircuitBreakerOpenError
+	var circuitErr *CircuitBreakerOpenError
+	if As(err, &circuitErr) {
+		return true
+	}
+
 	return false
 }
 
@@ -742,6 +775,20 @@ func IsRetryError(err error) bool {
 	return As(err, &e)
 }
 
+// IsCircuitBreakerOpenError checks if an error is a CircuitBreakerOpenError.
+// Use this to determine if a request was rejected by an open circuit breaker
+// without the dependency being called.
+//
+// Parameters:
+//   - err: The error to check.
+//
+// Returns:
+//   - true if the error is a CircuitBreakerOpenError, false otherwise.
+func IsCircuitBreakerOpenError(err error) bool {
+	var e *CircuitBreakerOpenError
+	return As(err, &e)
+}
+
 // IsContextError checks if an error is a ContextError.
 // Use this to determine if an error is related to context cancellation or timeout.
 //
@@ -278,3 +278,23 @@ func TestErrorInheritance(t *testing.T) {
 	assert.True(t, IsApplicationError(authErr))
 	assert.True(t, IsApplicationError(authzErr))
 }
+
+// TestCircuitBreakerOpenError tests the CircuitBreakerOpenError type
+func TestCircuitBreakerOpenError(t *testing.T) {
+	err := NewCircuitBreakerOpenError("payments")
+
+	// Check the type checking functions
+	assert.True(t, IsCircuitBreakerOpenError(err))
+	assert.True(t, IsInfrastructureError(err))
+	assert.False(t, IsCircuitBreakerOpenError(ErrInternal))
+
+	// Check that it matches the sentinel and its code, even when wrapped
+	wrapped := fmt.Errorf("calling payments: %w", err)
+	assert.True(t, Is(wrapped, ErrCircuitBreakerOpen))
+	assert.True(t, Is(wrapped, New(ServiceUnavailableCode, "")))
+	assert.False(t, Is(ErrInternal, ErrCircuitBreakerOpen))
+
+	// Check the HTTP status
+	assert.Equal(t, 503, GetHTTPStatus(err))
+	assert.Equal(t, ServiceUnavailableCode, ErrCircuitBreakerOpen.GetCode())
+}
@@ -5,6 +5,9 @@
 
 import (
 	"context"
+	"fmt"
+	"time"
+
 	"github.com/abitofhelp/servicelib/errors/core"
 )
 
@@ -35,6 +38,29 @@ type RetryError struct {
 	*InfrastructureError
 	Attempts    int `json:"attempts,omitempty"`
 	MaxAttempts int `json:"max_attempts,omitempty"`
+
+	// History records each attempt that was made, in order, if the retry
+	// package provided it.
+	History []RetryAttempt `json:"history,omitempty"`
+}
+
+// RetryAttempt records one attempt of a retried operation.
+type RetryAttempt struct {
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt int `json:"attempt"`
+
+	// Err is the error the attempt failed with.
+	Err error `json:"-"`
+
+	// Class is the class of the error, as classified by the retry package.
+	Class string `json:"class,omitempty"`
+
+	// Duration is how long the attempt took.
+	Duration time.Duration `json:"duration"`
+
+	// Backoff is how long the retry package waited after the attempt, or zero
+	// if it made no further attempt.
+	Backoff time.Duration `json:"backoff,omitempty"`
 }
 
 // NewRetryError creates a new RetryError.
@@ -51,6 +77,23 @@ func (e *RetryError) IsRetryError() bool {
 	return true
 }
 
+// WithHistory sets the attempts that were made and returns the error.
+func (e *RetryError) WithHistory(history []RetryAttempt) *RetryError {
+	e.History = history
+	return e
+}
+
+// Errors returns the error of each attempt in the history, in order.
+func (e *RetryError) Errors() []error {
+	errs := make([]error, 0, len(e.History))
+	for _, attempt := range e.History {
+		if attempt.Err != nil {
+			errs = append(errs, attempt.Err)
+		}
+	}
+	return errs
+}
+
 // ContextError represents an error that occurred due to a context cancellation or timeout during a retry operation.
 // It extends InfrastructureError with context-specific information.
 type ContextError struct {
@@ -143,3 +186,38 @@ func NewExternalServiceError(message string, serviceName string, endpoint string
 func (e *ExternalServiceError) IsExternalServiceError() bool {
 	return true
 }
+
+// CircuitBreakerOpenError represents a request rejected by an open circuit breaker,
+// without the dependency being called.
+// It extends InfrastructureError with the name of the circuit breaker.
+type CircuitBreakerOpenError struct {
+	*InfrastructureError
+	Name string `json:"name,omitempty"`
+}
+
+// NewCircuitBreakerOpenError creates a new CircuitBreakerOpenError.
+// The name may be empty if the circuit breaker is unnamed.
+func NewCircuitBreakerOpenError(name string) *CircuitBreakerOpenError {
+	message := "circuit breaker is open"
+	if name != "" {
+		message = fmt.Sprintf("circuit breaker %s is open", name)
+	}
+	return &CircuitBreakerOpenError{
+		InfrastructureError: NewInfrastructureError(core.ServiceUnavailableCode, message, nil),
+		Name:                name,
+	}
+}
+
+// IsCircuitBreakerOpenError identifies this as a circuit breaker open error.
+func (e *CircuitBreakerOpenError) IsCircuitBreakerOpenError() bool {
+	return true
+}
+
+// Is reports whether target is a CircuitBreakerOpenError, whatever the name of its
+// circuit breaker, or a BaseError with the same code.
+func (e *CircuitBreakerOpenError) Is(target error) bool {
+	if _, ok := target.(*CircuitBreakerOpenError); ok {
+		return true
+	}
+	return e.BaseError.Is(target)
+}
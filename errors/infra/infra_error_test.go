@@ -4,7 +4,9 @@
 
 import (
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/abitofhelp/servicelib/errors/core"
 	"github.com/stretchr/testify/assert"
@@ -147,3 +149,42 @@ func TestInfraErrorWithCause(t *testing.T) {
 	// Check that the error message includes the cause
 	assert.Contains(t, err.BaseError.Error(), "original error")
 }
+
+func TestNewCircuitBreakerOpenError(t *testing.T) {
+	// Test creating a new CircuitBreakerOpenError
+	err := NewCircuitBreakerOpenError("payments")
+
+	// Check that the error code, message and name are set correctly
+	assert.Equal(t, core.ServiceUnavailableCode, err.BaseError.GetCode())
+	assert.Equal(t, "circuit breaker payments is open", err.BaseError.GetMessage())
+	assert.Equal(t, "payments", err.Name)
+	assert.Equal(t, 503, err.GetHTTPStatus())
+	assert.Equal(t, "circuit breaker is open", NewCircuitBreakerOpenError("").BaseError.GetMessage())
+
+	// Check that IsCircuitBreakerOpenError returns true
+	assert.True(t, err.IsCircuitBreakerOpenError())
+	assert.True(t, err.IsInfrastructureError())
+
+	// Check that errors.Is matches any circuit breaker open error, and the error code
+	assert.True(t, errors.Is(err, NewCircuitBreakerOpenError("")))
+	assert.True(t, errors.Is(fmt.Errorf("wrapped: %w", err), NewCircuitBreakerOpenError("other")))
+	assert.True(t, errors.Is(err, core.NewBaseError(core.ServiceUnavailableCode, "", nil)))
+	assert.False(t, errors.Is(err, core.NewBaseError(core.NetworkErrorCode, "", nil)))
+	assert.False(t, errors.Is(NewNetworkError("down", "host", "80", nil), NewCircuitBreakerOpenError("")))
+}
+
+func TestRetryErrorHistory(t *testing.T) {
+	first := errors.New("connection refused")
+	second := errors.New("connection reset")
+
+	err := NewRetryError("maximum retry attempts reached", second, 2, 1).WithHistory([]RetryAttempt{
+		{Attempt: 1, Err: first, Class: "network", Duration: time.Millisecond, Backoff: 100 * time.Millisecond},
+		{Attempt: 2, Err: second, Class: "network", Duration: 2 * time.Millisecond},
+	})
+
+	assert.Len(t, err.History, 2)
+	assert.Equal(t, []error{first, second}, err.Errors())
+	assert.True(t, errors.Is(err, second))
+
+	assert.Empty(t, NewRetryError("no history", first, 1, 1).Errors())
+}
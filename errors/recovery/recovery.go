@@ -5,17 +5,20 @@
 import (
 	"context"
 	"fmt"
-	"sync"
 	"time"
 
+	"github.com/abitofhelp/servicelib/circuit"
 	"github.com/abitofhelp/servicelib/errors"
 	"github.com/abitofhelp/servicelib/logging"
 	"go.uber.org/zap"
 )
 
 var (
-	// ErrCircuitBreakerOpen is returned when the circuit breaker is in the open state
-	ErrCircuitBreakerOpen = errors.New(errors.InternalErrorCode, "circuit breaker is open")
+	// ErrCircuitBreakerOpen is returned when the circuit breaker is in the open state.
+	//
+	// Deprecated: Use errors.ErrCircuitBreakerOpen, which this is, so that code that
+	// only depends on the errors package can detect open circuits.
+	ErrCircuitBreakerOpen = errors.ErrCircuitBreakerOpen
 	// ErrServiceUnavailable is returned when a service is not available
 	ErrServiceUnavailable = errors.New(errors.NetworkErrorCode, "service unavailable")
 	// ErrContextCanceled is returned when the context is canceled
@@ -68,29 +71,50 @@ func (h *RecoveryHandler) WithRecovery(ctx context.Context, operation string, fn
 	return err
 }
 
-// CircuitBreaker implements the circuit breaker pattern
+// CircuitBreaker protects an operation that does not take a context with a circuit
+// breaker. It is a thin wrapper around circuit.CircuitBreaker, configured to open
+// after a number of consecutive failures and to let a trial request through once
+// the cooldown period has passed; the circuit closes again when the trial succeeds.
 type CircuitBreaker struct {
-	failureThreshold int
-	cooldownPeriod   time.Duration
-	failures         int
-	openUntil        time.Time
-	mu               sync.RWMutex
+	breaker *circuit.CircuitBreaker
 }
 
-// NewCircuitBreaker creates a new circuit breaker
+// NewCircuitBreaker creates a new circuit breaker that opens after failureThreshold
+// consecutive failures, and rejects requests for cooldownPeriod before trying again.
 func NewCircuitBreaker(failureThreshold int, cooldownPeriod time.Duration) *CircuitBreaker {
+	failureThreshold = max(failureThreshold, 1)
+
+	config := circuit.DefaultConfig().
+		WithConsecutiveFailures(failureThreshold).
+		WithVolumeThreshold(failureThreshold).
+		WithErrorThreshold(1).
+		WithSleepWindow(cooldownPeriod)
+
+	// Operations without a context cannot be canceled, so they are not timed out,
+	// and they are not limited in number
+	config.Timeout = 0
+	config.MaxConcurrent = 0
+
 	return &CircuitBreaker{
-		failureThreshold: failureThreshold,
-		cooldownPeriod:   cooldownPeriod,
+		breaker: circuit.NewCircuitBreaker(config, circuit.DefaultOptions().WithName("circuit-breaker")),
 	}
 }
 
+// Breaker returns the circuit.CircuitBreaker that the circuit breaker delegates to,
+// for example to inspect its state or force it open.
+func (cb *CircuitBreaker) Breaker() *circuit.CircuitBreaker {
+	return cb.breaker
+}
+
 // Execute executes an operation with circuit breaker protection
 func (cb *CircuitBreaker) Execute(ctx context.Context, operation func() error) (err error) {
 	return cb.ExecuteWithName(ctx, "circuit-breaker", operation)
 }
 
-// ExecuteWithName executes an operation with circuit breaker protection and a specific operation name
+// ExecuteWithName executes an operation with circuit breaker protection and a specific operation name.
+// If the circuit is open, an errors.CircuitBreakerOpenError is returned, which matches
+// ErrCircuitBreakerOpen with errors.Is. A panic in the operation is returned as an error
+// and counts as a failure.
 func (cb *CircuitBreaker) ExecuteWithName(ctx context.Context, operationName string, operation func() error) (err error) {
 	// Check if context is canceled or deadline exceeded
 	select {
@@ -107,70 +131,13 @@ func (cb *CircuitBreaker) ExecuteWithName(ctx context.Context, operationName str
 		// Continue with execution
 	}
 
-	if cb.isOpen() {
-		return ErrCircuitBreakerOpen
-	}
-
-	defer func() {
-		if r := recover(); r != nil {
-			cb.recordFailure()
-			err = fmt.Errorf("panic in %s: %v", operationName, r)
-		}
-	}()
-
-	err = operation()
-	if err != nil {
-		cb.recordFailure()
-		return err
-	}
-
-	cb.recordSuccess()
-	return nil
-}
-
-// isOpen checks if the circuit breaker is in the open state
-func (cb *CircuitBreaker) isOpen() bool {
-	cb.mu.RLock()
-
-	// Check if the circuit breaker is not open
-	if cb.openUntil.IsZero() {
-		cb.mu.RUnlock()
-		return false
-	}
-
-	// Check if the circuit breaker is still open
-	if time.Now().Before(cb.openUntil) {
-		cb.mu.RUnlock()
-		return true
-	}
-
-	// Cooldown period has passed, reset the circuit breaker
-	cb.mu.RUnlock()
-
-	// Acquire a write lock to reset the circuit breaker
-	cb.mu.Lock()
-	cb.openUntil = time.Time{}
-	cb.failures = 0
-	cb.mu.Unlock()
-
-	return false
-}
-
-// recordFailure records a failure and potentially opens the circuit
-func (cb *CircuitBreaker) recordFailure() {
-	cb.mu.Lock()
-	defer cb.mu.Unlock()
-
-	cb.failures++
-	if cb.failures >= cb.failureThreshold {
-		cb.openUntil = time.Now().Add(cb.cooldownPeriod)
-	}
-}
-
-// recordSuccess resets the failure counter
-func (cb *CircuitBreaker) recordSuccess() {
-	cb.mu.Lock()
-	defer cb.mu.Unlock()
-	cb.failures = 0
-	cb.openUntil = time.Time{}
+	_, err = circuit.Execute(ctx, cb.breaker, operationName, func(ctx context.Context) (_ struct{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic in %s: %v", operationName, r)
+			}
+		}()
+		return struct{}{}, operation()
+	})
+	return err
 }
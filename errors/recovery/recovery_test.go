@@ -5,11 +5,15 @@
 import (
 	"context"
 	"errors"
+	"net/http"
 	"testing"
 	"time"
 
+	"github.com/abitofhelp/servicelib/circuit"
+	svcerrors "github.com/abitofhelp/servicelib/errors"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -90,10 +94,9 @@ func TestNewCircuitBreaker(t *testing.T) {
 
 	// Assert
 	assert.NotNil(t, cb)
-	assert.Equal(t, failureThreshold, cb.failureThreshold)
-	assert.Equal(t, cooldownPeriod, cb.cooldownPeriod)
-	assert.Equal(t, 0, cb.failures)
-	assert.True(t, cb.openUntil.IsZero())
+	require.NotNil(t, cb.Breaker())
+	assert.Equal(t, circuit.Closed, cb.Breaker().GetState())
+	assert.Equal(t, circuit.Counts{}, cb.Breaker().Counts())
 }
 
 func TestCircuitBreaker_Execute(t *testing.T) {
@@ -144,7 +147,7 @@ func TestCircuitBreaker_ExecuteWithName_Error(t *testing.T) {
 	// Assert
 	assert.Error(t, err)
 	assert.Equal(t, expectedErr, err)
-	assert.Equal(t, 1, cb.failures)
+	assert.Equal(t, 1, cb.Breaker().Counts().Failures)
 }
 
 func TestCircuitBreaker_ExecuteWithName_Panic(t *testing.T) {
@@ -162,7 +165,7 @@ func TestCircuitBreaker_ExecuteWithName_Panic(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), panicMsg)
 	assert.Contains(t, err.Error(), "test-operation")
-	assert.Equal(t, 1, cb.failures)
+	assert.Equal(t, 1, cb.Breaker().Counts().Failures)
 }
 
 func TestCircuitBreaker_ExecuteWithName_ContextCanceled(t *testing.T) {
@@ -219,7 +222,9 @@ func TestCircuitBreaker_ExecuteWithName_CircuitOpen(t *testing.T) {
 
 	// Assert
 	assert.Error(t, err)
-	assert.Equal(t, ErrCircuitBreakerOpen, err)
+	assert.ErrorIs(t, err, ErrCircuitBreakerOpen)
+	assert.ErrorIs(t, err, svcerrors.ErrCircuitBreakerOpen)
+	assert.Equal(t, http.StatusServiceUnavailable, svcerrors.GetHTTPStatus(err))
 
 	// Wait for cooldown period to pass
 	time.Sleep(cooldownPeriod + 10*time.Millisecond)
@@ -233,63 +238,46 @@ func TestCircuitBreaker_ExecuteWithName_CircuitOpen(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestCircuitBreaker_recordSuccess(t *testing.T) {
+func TestCircuitBreaker_SuccessResetsFailures(t *testing.T) {
 	// Arrange
 	cb := NewCircuitBreaker(3, 5*time.Second)
-	cb.failures = 2
-	cb.openUntil = time.Now().Add(5 * time.Second)
-
-	// Act
-	cb.recordSuccess()
-
-	// Assert
-	assert.Equal(t, 0, cb.failures)
-	assert.True(t, cb.openUntil.IsZero())
-}
-
-func TestCircuitBreaker_recordFailure(t *testing.T) {
-	// Arrange
-	failureThreshold := 3
-	cooldownPeriod := 5 * time.Second
-	cb := NewCircuitBreaker(failureThreshold, cooldownPeriod)
+	ctx := context.Background()
+	fail := func() error { return errors.New("operation failed") }
 
-	// Act - Record failures below threshold
-	for i := 0; i < failureThreshold-1; i++ {
-		cb.recordFailure()
-	}
+	// Act - Two failures, a success, then two more failures
+	_ = cb.Execute(ctx, fail)
+	_ = cb.Execute(ctx, fail)
+	_ = cb.Execute(ctx, func() error { return nil })
+	_ = cb.Execute(ctx, fail)
+	_ = cb.Execute(ctx, fail)
 
-	// Assert - Circuit should still be closed
-	assert.Equal(t, failureThreshold-1, cb.failures)
-	assert.True(t, cb.openUntil.IsZero())
+	// Assert - The failures were not consecutive, so the circuit is still closed
+	assert.Equal(t, circuit.Closed, cb.Breaker().GetState())
 
-	// Act - Record one more failure to reach threshold
-	cb.recordFailure()
+	// Act - One more failure makes three in a row
+	_ = cb.Execute(ctx, fail)
 
 	// Assert - Circuit should be open
-	assert.Equal(t, failureThreshold, cb.failures)
-	assert.False(t, cb.openUntil.IsZero())
-	assert.True(t, cb.openUntil.After(time.Now()))
+	assert.Equal(t, circuit.Open, cb.Breaker().GetState())
 }
 
-func TestCircuitBreaker_isOpen(t *testing.T) {
+func TestCircuitBreaker_Cooldown(t *testing.T) {
 	// Arrange
-	cb := NewCircuitBreaker(3, 100*time.Millisecond)
-
-	// Test when circuit is closed
-	assert.False(t, cb.isOpen())
+	cb := NewCircuitBreaker(1, 100*time.Millisecond)
+	ctx := context.Background()
 
 	// Open the circuit
-	cb.failures = 3
-	cb.openUntil = time.Now().Add(100 * time.Millisecond)
-
-	// Test when circuit is open
-	assert.True(t, cb.isOpen())
+	_ = cb.Execute(ctx, func() error { return errors.New("operation failed") })
+	assert.Equal(t, circuit.Open, cb.Breaker().GetState())
 
-	// Wait for cooldown period to pass
+	// A failed trial after the cooldown opens the circuit again
 	time.Sleep(150 * time.Millisecond)
+	err := cb.Execute(ctx, func() error { return errors.New("still failing") })
+	assert.EqualError(t, err, "still failing")
+	assert.Equal(t, circuit.Open, cb.Breaker().GetState())
 
-	// Test after cooldown period
-	assert.False(t, cb.isOpen())
-	assert.Equal(t, 0, cb.failures)
-	assert.True(t, cb.openUntil.IsZero())
+	// A successful trial after the cooldown closes it
+	time.Sleep(150 * time.Millisecond)
+	assert.NoError(t, cb.Execute(ctx, func() error { return nil }))
+	assert.Equal(t, circuit.Closed, cb.Breaker().GetState())
 }
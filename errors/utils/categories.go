@@ -48,7 +48,8 @@ type ErrorCodeCategorizer struct {
 			core.ConcurrencyErrorCode:
 			return SystemError
 		case core.ExternalServiceErrorCode,
-			core.NetworkErrorCode:
+			core.NetworkErrorCode,
+			core.ServiceUnavailableCode:
 			return ExternalError
 		case core.UnauthorizedCode,
 			core.ForbiddenCode:
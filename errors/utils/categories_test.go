@@ -32,6 +32,7 @@ func TestErrorCodeCategorizer(t *testing.T) {
 		{"Concurrency", core.ConcurrencyErrorCode, SystemError},
 		{"ExternalService", core.ExternalServiceErrorCode, ExternalError},
 		{"Network", core.NetworkErrorCode, ExternalError},
+		{"ServiceUnavailable", core.ServiceUnavailableCode, ExternalError},
 		{"Unauthorized", core.UnauthorizedCode, SecurityError},
 		{"Forbidden", core.ForbiddenCode, SecurityError},
 	}
@@ -50,7 +50,8 @@ type ErrorCodeGrouper struct {
 			core.ForbiddenCode:
 			return SecurityGroup
 		case core.ExternalServiceErrorCode,
-			core.NetworkErrorCode:
+			core.NetworkErrorCode,
+			core.ServiceUnavailableCode:
 			return ExternalGroup
 		case core.ResourceExhaustedCode,
 			core.DataCorruptionCode,
@@ -22,6 +22,7 @@ func TestErrorCodeGrouper(t *testing.T) {
 		{"System", core.TimeoutCode, SystemGroup},
 		{"Security", core.UnauthorizedCode, SecurityGroup},
 		{"External", core.ExternalServiceErrorCode, ExternalGroup},
+		{"ServiceUnavailable", core.ServiceUnavailableCode, ExternalGroup},
 		{"Business", core.ResourceExhaustedCode, BusinessGroup},
 	}
 
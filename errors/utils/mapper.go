@@ -37,7 +37,8 @@
 			core.ConcurrencyErrorCode:
 			return "System"
 		case core.ExternalServiceErrorCode,
-			core.NetworkErrorCode:
+			core.NetworkErrorCode,
+			core.ServiceUnavailableCode:
 			return "External"
 		case core.UnauthorizedCode,
 			core.ForbiddenCode:
@@ -82,6 +83,8 @@
 			return "Data corruption detected"
 		case core.ConcurrencyErrorCode:
 			return "Concurrency violation"
+		case core.ServiceUnavailableCode:
+			return "The service is temporarily unavailable"
 		default:
 			return "An unknown error occurred"
 		}
@@ -53,7 +53,8 @@ func IsValid(code core.ErrorCode) bool {
 		core.ConfigurationErrorCode,
 		core.ResourceExhaustedCode,
 		core.DataCorruptionCode,
-		core.ConcurrencyErrorCode:
+		core.ConcurrencyErrorCode,
+		core.ServiceUnavailableCode:
 		return true
 	default:
 		return false
@@ -97,6 +98,8 @@ func getHumanReadableName(code core.ErrorCode) string {
 		return "DataCorruption"
 	case core.ConcurrencyErrorCode:
 		return "Concurrency"
+	case core.ServiceUnavailableCode:
+		return "ServiceUnavailable"
 	default:
 		return "Unknown"
 	}
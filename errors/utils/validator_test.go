@@ -62,6 +62,9 @@ func TestErrorCodeValidator(t *testing.T) {
 	if got := v.GetCodeName(core.NetworkErrorCode); got != "Network" {
 		t.Errorf("GetCodeName(NetworkErrorCode) = %v, want Network", got)
 	}
+	if got := v.GetCodeName(core.ServiceUnavailableCode); got != "ServiceUnavailable" {
+		t.Errorf("GetCodeName(ServiceUnavailableCode) = %v, want ServiceUnavailable", got)
+	}
 	if got := v.GetCodeName(core.ErrorCode("UNKNOWN")); got != "Unknown" {
 		t.Errorf("GetCodeName(UNKNOWN) = %v, want Unknown", got)
 	}
@@ -0,0 +1,323 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package health provides functionality for health checking applications.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Checker checks the health of a dependency, such as a database or a remote service.
+type Checker interface {
+	// Check returns nil if the dependency is healthy, or an error describing why it is not.
+	// It should return promptly when ctx is done.
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts an ordinary function to the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+//
+// Parameters:
+//   - ctx: The context for the check.
+//
+// Returns:
+//   - error: The error returned by f.
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Component types of the application/health+json format, which describe the kind
+// of dependency that a check checks.
+const (
+	// ComponentTypeDatastore is a database, cache or other store of data
+	ComponentTypeDatastore = "datastore"
+
+	// ComponentTypeComponent is a service or other component that the application depends on
+	ComponentTypeComponent = "component"
+
+	// ComponentTypeSystem is a resource of the system that the application runs on
+	ComponentTypeSystem = "system"
+)
+
+// ComponentTyper is implemented by Checkers that know the component type of their
+// dependency. The built-in checkers implement it, with ComponentTypeDatastore for
+// databases and cache stores, and ComponentTypeComponent for circuit breakers and
+// HTTP dependencies.
+type ComponentTyper interface {
+	// ComponentType returns the component type, such as ComponentTypeDatastore.
+	ComponentType() string
+}
+
+// typedChecker is a Checker with a component type.
+type typedChecker struct {
+	CheckerFunc
+	componentType string
+}
+
+// ComponentType returns the component type of the checker.
+//
+// Returns:
+//   - string: The component type.
+func (c typedChecker) ComponentType() string {
+	return c.componentType
+}
+
+// Probe is a kind of probe that runs a check. Probes can be combined with |.
+type Probe int
+
+const (
+	// Liveness probes report whether the process is running and should be
+	// restarted if not. Only checks of the process itself should run on them.
+	Liveness Probe = 1 << iota
+
+	// Readiness probes report whether the application can serve requests,
+	// including whether its dependencies are reachable.
+	Readiness
+
+	// Startup probes report whether the application has finished starting.
+	Startup
+)
+
+// String returns the name of the probe's endpoint, such as "readyz".
+//
+// Returns:
+//   - string: "livez", "readyz", "startupz", or "unknown" for combined probes.
+func (p Probe) String() string {
+	switch p {
+	case Liveness:
+		return "livez"
+	case Readiness:
+		return "readyz"
+	case Startup:
+		return "startupz"
+	default:
+		return "unknown"
+	}
+}
+
+// Check is a named Checker registered with a Registry.
+type Check struct {
+	// Name identifies the check in health check responses
+	Name string
+
+	// Checker performs the check
+	Checker Checker
+
+	// Timeout is how long the check may run before it is reported as down.
+	// If zero, the Timeout of the registry's Config is used.
+	Timeout time.Duration
+
+	// Critical is true if a failure of the check makes the application unhealthy.
+	// A failed non-critical check is reported, but does not fail the probe.
+	Critical bool
+
+	// Probes are the probes that run the check. If zero, the check runs on Readiness probes.
+	Probes Probe
+
+	// Interval is how often the check runs in the background, so that probes
+	// report its last result. If zero, the Interval of the registry's Config is used.
+	Interval time.Duration
+
+	// ComponentType is the component type reported in application/health+json
+	// responses, such as ComponentTypeDatastore. If empty, the component type of
+	// a Checker that implements ComponentTyper is used.
+	ComponentType string
+}
+
+// NewCheck creates a new critical Check that runs on Readiness probes and uses the registry's timeout.
+//
+// Parameters:
+//   - name: The name of the check.
+//   - checker: The Checker that performs the check.
+//
+// Returns:
+//   - Check: The new check.
+func NewCheck(name string, checker Checker) Check {
+	return Check{
+		Name:     name,
+		Checker:  checker,
+		Critical: true,
+		Probes:   Readiness,
+	}
+}
+
+// WithTimeout sets how long the check may run before it is reported as down.
+// If timeout is less than or equal to zero, the registry's timeout is used.
+//
+// Parameters:
+//   - timeout: The timeout of the check.
+//
+// Returns:
+//   - Check: A new Check with the updated Timeout value.
+func (c Check) WithTimeout(timeout time.Duration) Check {
+	if timeout < 0 {
+		timeout = 0
+	}
+	c.Timeout = timeout
+	return c
+}
+
+// WithCritical sets whether a failure of the check makes the application unhealthy.
+//
+// Parameters:
+//   - critical: False to report failures of the check without failing the probe.
+//
+// Returns:
+//   - Check: A new Check with the updated Critical value.
+func (c Check) WithCritical(critical bool) Check {
+	c.Critical = critical
+	return c
+}
+
+// WithProbes sets the probes that run the check, such as Liveness | Readiness.
+// If probes is zero, the check runs on Readiness probes.
+//
+// Parameters:
+//   - probes: The probes that run the check.
+//
+// Returns:
+//   - Check: A new Check with the updated Probes value.
+func (c Check) WithProbes(probes Probe) Check {
+	if probes == 0 {
+		probes = Readiness
+	}
+	c.Probes = probes
+	return c
+}
+
+// WithInterval sets how often the check runs in the background.
+// If interval is less than or equal to zero, the registry's interval is used.
+//
+// Parameters:
+//   - interval: The interval between background runs of the check.
+//
+// Returns:
+//   - Check: A new Check with the updated Interval value.
+func (c Check) WithInterval(interval time.Duration) Check {
+	if interval < 0 {
+		interval = 0
+	}
+	c.Interval = interval
+	return c
+}
+
+// WithComponentType sets the component type reported in application/health+json responses.
+//
+// Parameters:
+//   - componentType: The component type, such as ComponentTypeDatastore.
+//
+// Returns:
+//   - Check: A new Check with the updated ComponentType value.
+func (c Check) WithComponentType(componentType string) Check {
+	c.ComponentType = componentType
+	return c
+}
+
+// componentType returns the component type of the check, or of its checker.
+func (c Check) componentType() string {
+	if c.ComponentType != "" {
+		return c.ComponentType
+	}
+	if typer, ok := c.Checker.(ComponentTyper); ok {
+		return typer.ComponentType()
+	}
+	return ""
+}
+
+// CheckResult is the outcome of running a Check.
+type CheckResult struct {
+	// Status is ServiceUp if the check passed, or ServiceDown if it failed
+	Status string
+
+	// Latency is how long the check took
+	Latency time.Duration
+
+	// Error is the error message of a failed check, or empty if it passed
+	Error string
+
+	// Critical is true if the check is critical
+	Critical bool
+
+	// Time is when the check finished
+	Time time.Time
+
+	// ComponentType is the component type of the check. It is reported in
+	// application/health+json responses only.
+	ComponentType string
+}
+
+// checkResultJSON is the JSON representation of a CheckResult, with the latency
+// as a duration string such as "1.5ms".
+type checkResultJSON struct {
+	Status   string `json:"status"`
+	Latency  string `json:"latency"`
+	Error    string `json:"error,omitempty"`
+	Critical bool   `json:"critical"`
+	Time     string `json:"time,omitempty"`
+}
+
+// MarshalJSON encodes the result with its latency as a duration string.
+//
+// Returns:
+//   - []byte: The JSON encoding of the result.
+//   - error: An error if the result could not be encoded.
+func (r CheckResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(checkResultJSON{
+		Status:   r.Status,
+		Latency:  r.Latency.String(),
+		Error:    r.Error,
+		Critical: r.Critical,
+		Time:     formatTime(r.Time),
+	})
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON.
+//
+// Parameters:
+//   - data: The JSON encoding of the result.
+//
+// Returns:
+//   - error: An error if the data is not a valid result.
+func (r *CheckResult) UnmarshalJSON(data []byte) error {
+	var decoded checkResultJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	var latency time.Duration
+	if decoded.Latency != "" {
+		var err error
+		if latency, err = time.ParseDuration(decoded.Latency); err != nil {
+			return err
+		}
+	}
+
+	var t time.Time
+	if decoded.Time != "" {
+		var err error
+		if t, err = time.Parse(time.RFC3339Nano, decoded.Time); err != nil {
+			return err
+		}
+	}
+
+	*r = CheckResult{
+		Status:   decoded.Status,
+		Latency:  latency,
+		Error:    decoded.Error,
+		Critical: decoded.Critical,
+		Time:     t,
+	}
+	return nil
+}
+
+// formatTime formats a time in RFC 3339 format in UTC, or returns an empty string for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
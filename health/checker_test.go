@@ -0,0 +1,65 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckerFunc(t *testing.T) {
+	expected := errors.New("down")
+	checker := CheckerFunc(func(ctx context.Context) error {
+		return expected
+	})
+
+	assert.Equal(t, expected, checker.Check(context.Background()))
+}
+
+func TestNewCheck(t *testing.T) {
+	checker := CheckerFunc(func(ctx context.Context) error { return nil })
+
+	check := NewCheck("db", checker)
+	assert.Equal(t, "db", check.Name)
+	assert.NotNil(t, check.Checker)
+	assert.Zero(t, check.Timeout)
+	assert.True(t, check.Critical)
+	assert.Equal(t, Readiness, check.Probes)
+
+	assert.Equal(t, time.Second, check.WithTimeout(time.Second).Timeout)
+	assert.Zero(t, check.WithTimeout(-time.Second).Timeout)
+	assert.False(t, check.WithCritical(false).Critical)
+	assert.Equal(t, Liveness|Readiness, check.WithProbes(Liveness|Readiness).Probes)
+	assert.Equal(t, Readiness, check.WithProbes(0).Probes)
+}
+
+func TestProbe_String(t *testing.T) {
+	assert.Equal(t, "livez", Liveness.String())
+	assert.Equal(t, "readyz", Readiness.String())
+	assert.Equal(t, "startupz", Startup.String())
+	assert.Equal(t, "unknown", (Liveness | Readiness).String())
+}
+
+func TestCheckResult_JSON(t *testing.T) {
+	result := CheckResult{Status: ServiceDown, Latency: 1500 * time.Microsecond, Error: "connection refused", Critical: true}
+
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"Down","latency":"1.5ms","error":"connection refused","critical":true}`, string(data))
+
+	var decoded CheckResult
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, result, decoded)
+
+	data, err = json.Marshal(CheckResult{Status: ServiceUp})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"status":"Up","latency":"0s","critical":false}`, string(data))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"status":"Up","latency":"soon"}`), &decoded))
+}
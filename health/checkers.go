@@ -0,0 +1,87 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package health provides functionality for health checking applications.
+package health
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/abitofhelp/servicelib/cache"
+	"github.com/abitofhelp/servicelib/circuit"
+	"github.com/abitofhelp/servicelib/errors"
+)
+
+// cacheProbeKey is the key that CacheStoreChecker looks up.
+const cacheProbeKey = "health:probe"
+
+// CacheStoreChecker returns a Checker that looks up a probe key in a cache store.
+// It fails if the store cannot be reached, as when the server of a RedisStore is down;
+// whether the key is found does not matter.
+//
+// Parameters:
+//   - store: The cache store.
+//
+// Returns:
+//   - Checker: A checker of the store.
+func CacheStoreChecker[T any](store cache.Store[T]) Checker {
+	return typedChecker{componentType: ComponentTypeDatastore, CheckerFunc: func(ctx context.Context) error {
+		_, _, err := store.Get(ctx, cacheProbeKey)
+		return err
+	}}
+}
+
+// CircuitBreakerChecker returns a Checker that fails while a circuit breaker is
+// open or half-open, because the breaker is rejecting requests to its dependency.
+// A nil circuit breaker, which is disabled, always passes.
+//
+// Parameters:
+//   - cb: The circuit breaker.
+//
+// Returns:
+//   - Checker: A checker of the circuit breaker.
+func CircuitBreakerChecker(cb *circuit.CircuitBreaker) Checker {
+	return typedChecker{componentType: ComponentTypeComponent, CheckerFunc: func(ctx context.Context) error {
+		if cb == nil || cb.GetState() == circuit.Closed {
+			return nil
+		}
+		return errors.NewCircuitBreakerOpenError(cb.Name())
+	}}
+}
+
+// HTTPChecker returns a Checker that sends a GET request to the URL of a
+// dependency and fails if the request fails or the response status is 400 or above.
+//
+// Parameters:
+//   - url: The URL to request, typically the health endpoint of the dependency.
+//   - client: The HTTP client to send the request with. If nil, http.DefaultClient is used.
+//
+// Returns:
+//   - Checker: A checker of the URL.
+func HTTPChecker(url string, client *http.Client) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return typedChecker{componentType: ComponentTypeComponent, CheckerFunc: func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return errors.NewExternalServiceError("invalid health check request", "", url, err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return errors.NewExternalServiceError("health check request failed", req.URL.Host, url, err)
+		}
+		defer resp.Body.Close()
+
+		// Drain the body so that the connection can be reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			return errors.NewExternalServiceError("health check returned "+resp.Status, req.URL.Host, url, nil)
+		}
+		return nil
+	}}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abitofhelp/servicelib/cache"
+	"github.com/abitofhelp/servicelib/circuit"
+	serviceErrors "github.com/abitofhelp/servicelib/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStore is a cache store that cannot be reached
+type failingStore struct {
+	cache.Store[string]
+}
+
+func (failingStore) Get(ctx context.Context, key string) (cache.Item[string], bool, error) {
+	return cache.Item[string]{}, false, errors.New("connection refused")
+}
+
+func TestCacheStoreChecker(t *testing.T) {
+	store := cache.NewMemoryStore[string](10, cache.LRU)
+	assert.NoError(t, CacheStoreChecker[string](store).Check(context.Background()))
+
+	assert.EqualError(t, CacheStoreChecker[string](failingStore{}).Check(context.Background()), "connection refused")
+}
+
+func TestCircuitBreakerChecker(t *testing.T) {
+	assert.NoError(t, CircuitBreakerChecker(nil).Check(context.Background()))
+
+	cb := circuit.NewCircuitBreaker(circuit.DefaultConfig(), circuit.DefaultOptions().WithName("payments"))
+	checker := CircuitBreakerChecker(cb)
+	assert.NoError(t, checker.Check(context.Background()))
+
+	cb.ForceOpen()
+	err := checker.Check(context.Background())
+	assert.Error(t, err)
+	assert.True(t, serviceErrors.IsCircuitBreakerOpenError(err))
+}
+
+func TestHTTPChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	assert.NoError(t, HTTPChecker(server.URL+"/up", nil).Check(context.Background()))
+
+	err := HTTPChecker(server.URL+"/down", server.Client()).Check(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "503 Service Unavailable")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, HTTPChecker(server.URL+"/up", nil).Check(ctx))
+
+	assert.Error(t, HTTPChecker("://invalid", nil).Check(context.Background()))
+}
+
+func TestCheckers_ComponentType(t *testing.T) {
+	componentType := func(checker Checker) string {
+		return NewCheck("check", checker).componentType()
+	}
+
+	assert.Equal(t, ComponentTypeDatastore, componentType(CacheStoreChecker[string](failingStore{})))
+	assert.Equal(t, ComponentTypeComponent, componentType(CircuitBreakerChecker(nil)))
+	assert.Equal(t, ComponentTypeComponent, componentType(HTTPChecker("http://localhost", nil)))
+	assert.Empty(t, componentType(CheckerFunc(func(ctx context.Context) error { return nil })))
+
+	// The component type of a check overrides that of its checker
+	assert.Equal(t, ComponentTypeSystem, NewCheck("check", HTTPChecker("http://localhost", nil)).WithComponentType(ComponentTypeSystem).componentType())
+}
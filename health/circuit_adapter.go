@@ -0,0 +1,35 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package health provides functionality for health checking applications.
+package health
+
+import (
+	"github.com/abitofhelp/servicelib/circuit"
+)
+
+// CircuitBreakerAdapter adapts a circuit.Registry to the ServiceStatusProvider interface
+type CircuitBreakerAdapter struct {
+	registry *circuit.Registry
+}
+
+// NewCircuitBreakerAdapter creates a new CircuitBreakerAdapter
+func NewCircuitBreakerAdapter(registry *circuit.Registry) *CircuitBreakerAdapter {
+	return &CircuitBreakerAdapter{
+		registry: registry,
+	}
+}
+
+// GetServiceStatuses returns the status of each registered circuit breaker, keyed by
+// "circuit:" and its name. A closed circuit is up; an open or half-open circuit is down,
+// because it is rejecting requests to its dependency.
+func (a *CircuitBreakerAdapter) GetServiceStatuses() map[string]string {
+	statuses := make(map[string]string)
+	for _, status := range a.registry.Statuses() {
+		if status.State == circuit.Closed {
+			statuses["circuit:"+status.Name] = ServiceUp
+		} else {
+			statuses["circuit:"+status.Name] = ServiceDown
+		}
+	}
+	return statuses
+}
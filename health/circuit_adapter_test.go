@@ -0,0 +1,66 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abitofhelp/servicelib/circuit"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestCircuitBreakerAdapter tests the CircuitBreakerAdapter
+func TestCircuitBreakerAdapter(t *testing.T) {
+	// Create a registry with a closed and an open circuit breaker
+	registry := circuit.NewRegistry(circuit.DefaultOptions())
+	registry.GetOrCreate("api", circuit.DefaultConfig())
+	registry.GetOrCreate("db", circuit.DefaultConfig()).ForceOpen()
+
+	// Create a circuit breaker adapter
+	adapter := NewCircuitBreakerAdapter(registry)
+	assert.NotNil(t, adapter)
+
+	// Verify the statuses
+	assert.Equal(t, map[string]string{
+		"circuit:api": ServiceUp,
+		"circuit:db":  ServiceDown,
+	}, adapter.GetServiceStatuses())
+
+	// Verify that a nil registry reports no statuses
+	assert.Empty(t, NewCircuitBreakerAdapter(nil).GetServiceStatuses())
+}
+
+// TestNewGenericHandler_CircuitBreakers tests that the generic handler reports circuit breakers
+func TestNewGenericHandler_CircuitBreakers(t *testing.T) {
+	// Create a logger
+	logger := zap.NewNop()
+
+	// Create mock providers
+	mockProvider := new(MockHealthCheckProvider)
+	mockProvider.On("GetRepositoryFactory").Return("mock-repo")
+	mockVersionProvider := new(MockVersionProvider)
+	mockVersionProvider.On("GetVersion").Return("1.0.0")
+
+	// Create a registry with an open circuit breaker
+	registry := circuit.NewRegistry(circuit.DefaultOptions())
+	registry.GetOrCreate("db", circuit.DefaultConfig()).ForceOpen()
+
+	// Create a handler
+	handler := NewGenericHandler(mockProvider, mockVersionProvider, logger, 5, NewCircuitBreakerAdapter(registry))
+
+	// Call the handler
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	// An open circuit degrades the service
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var response GenericHealthStatus
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, StatusDegraded, response.Status)
+	assert.Equal(t, ServiceUnknown, response.Services["database"])
+	assert.Equal(t, ServiceDown, response.Services["circuit:db"])
+}
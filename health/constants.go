@@ -19,4 +19,7 @@
 
 	// ServiceDown represents a service that is down or unavailable
 	ServiceDown = "Down"
+
+	// ServiceUnknown represents a service whose status was not checked
+	ServiceUnknown = "Unknown"
 )
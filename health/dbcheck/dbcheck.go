@@ -0,0 +1,69 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package dbcheck provides health checkers of the databases of the db package.
+// They are kept out of the health package, so that it does not depend on the
+// database drivers.
+package dbcheck
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/abitofhelp/servicelib/db"
+	"github.com/abitofhelp/servicelib/health"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// datastoreChecker is a health.Checker of a datastore.
+type datastoreChecker struct {
+	health.CheckerFunc
+}
+
+// ComponentType returns health.ComponentTypeDatastore.
+//
+// Returns:
+//   - string: The component type.
+func (c datastoreChecker) ComponentType() string {
+	return health.ComponentTypeDatastore
+}
+
+// PostgresChecker returns a Checker that pings a PostgreSQL connection pool
+// with db.CheckPostgresHealth.
+//
+// Parameters:
+//   - pool: The PostgreSQL connection pool.
+//
+// Returns:
+//   - health.Checker: A checker of the pool.
+func PostgresChecker(pool *pgxpool.Pool) health.Checker {
+	return datastoreChecker{CheckerFunc: func(ctx context.Context) error {
+		return db.CheckPostgresHealth(ctx, pool)
+	}}
+}
+
+// MongoChecker returns a Checker that pings a MongoDB client with db.CheckMongoHealth.
+//
+// Parameters:
+//   - client: The MongoDB client.
+//
+// Returns:
+//   - health.Checker: A checker of the client.
+func MongoChecker(client *mongo.Client) health.Checker {
+	return datastoreChecker{CheckerFunc: func(ctx context.Context) error {
+		return db.CheckMongoHealth(ctx, client)
+	}}
+}
+
+// SQLiteChecker returns a Checker that pings a SQLite database with db.CheckSQLiteHealth.
+//
+// Parameters:
+//   - database: The SQLite database connection.
+//
+// Returns:
+//   - health.Checker: A checker of the database.
+func SQLiteChecker(database *sql.DB) health.Checker {
+	return datastoreChecker{CheckerFunc: func(ctx context.Context) error {
+		return db.CheckSQLiteHealth(ctx, database)
+	}}
+}
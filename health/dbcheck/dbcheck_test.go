@@ -0,0 +1,35 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package dbcheck
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/abitofhelp/servicelib/health"
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckers_ComponentType(t *testing.T) {
+	for _, checker := range []health.Checker{PostgresChecker(nil), MongoChecker(nil), SQLiteChecker(nil)} {
+		typer, ok := checker.(health.ComponentTyper)
+		require.True(t, ok)
+		assert.Equal(t, health.ComponentTypeDatastore, typer.ComponentType())
+	}
+}
+
+func TestSQLiteChecker(t *testing.T) {
+	database, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+
+	checker := SQLiteChecker(database)
+	if err := checker.Check(context.Background()); err != nil {
+		t.Skip("Skipping test as SQLite is not available")
+	}
+
+	require.NoError(t, database.Close())
+	assert.Error(t, checker.Check(context.Background()))
+}
@@ -18,6 +18,12 @@
 //   - Configurable health check timeouts
 //   - Version information inclusion in health responses
 //   - Support for different health status levels (healthy, degraded)
+//   - A Registry of named checks, run concurrently with per-check timeouts
+//   - Built-in checkers for databases, cache stores, circuit breakers and HTTP dependencies
+//   - Liveness, readiness and startup probe handlers, with critical and non-critical checks
+//   - Background checks whose last result is served to probes, with a staleness threshold
+//   - Status transitions logged and recorded as OpenTelemetry metrics
+//   - The application/health+json response format, selected by the Accept header
 //
 // The package defines several interfaces that applications can implement to
 // integrate with the health checking system:
@@ -25,6 +31,9 @@
 //   - RepositoryFactoryProvider: For checking database connectivity
 //   - VersionProvider: For including version information in health responses
 //   - HealthConfig: For configuring health check behavior
+//   - ServiceStatusProvider: For reporting the status of additional services
+//   - Checker: For checking the health of a single dependency
+//   - Drainer: For failing readiness probes while the application shuts down
 //
 // Example usage:
 //
@@ -38,6 +47,36 @@
 //	// Register the health check endpoint
 //	http.HandleFunc("/health", healthHandler)
 //
+// Dependencies are checked by registering a Checker for each in a Registry,
+// which is passed to NewGenericHandler as a service provider:
+//
+//	checks := health.NewRegistry(health.DefaultConfig(), health.DefaultOptions())
+//	checks.Register(health.NewCheck("postgres", dbcheck.PostgresChecker(pool)).WithTimeout(time.Second))
+//	checks.Register(health.NewCheck("payments", health.HTTPChecker("http://payments/livez", nil)))
+//
+//	http.HandleFunc("/health", health.NewGenericHandler(nil, versionProvider, logger, 5, checks))
+//
+// Checks are tagged with the probes that run them (Liveness, Readiness or Startup)
+// and as critical or non-critical. NewLivezHandler, NewReadyzHandler and
+// NewStartupzHandler serve the probes in the plain text format of the Kubernetes
+// API server, with ?verbose and ?exclude= query parameters. A readiness probe
+// given a signal.GracefulShutdown fails once the shutdown begins:
+//
+//	checks.Register(health.NewCheck("search", health.HTTPChecker(searchURL, nil)).WithCritical(false))
+//	checks.Register(health.NewCheck("migrations", migrationsDone).WithProbes(health.Startup))
+//
+//	ctx, gs := signal.SetupSignalHandler(30*time.Second, logger)
+//	health.RegisterProbeHandlers(mux, checks, gs)
+//
+// Checks that are expensive to run can run in the background instead, so that
+// probes report their last result rather than loading a dependency on every hit:
+//
+//	checks := health.NewRegistry(
+//	    health.DefaultConfig().WithInterval(15*time.Second),
+//	    health.DefaultOptions().WithLogger(logger).WithMeter(meter),
+//	)
+//	defer checks.Shutdown()
+//
 // The health check endpoint returns a JSON response with the following structure:
 //
 //	{
@@ -48,17 +87,38 @@
 //	        "database": "Up",
 //	        "cache": "Up",
 //	        "external_api": "Down"
+//	    },
+//	    "checks": {
+//	        "external_api": {"status": "Down", "latency": "1.2s", "error": "connection refused", "critical": true}
 //	    }
 //	}
 //
 // The status field can be "Healthy" or "Degraded", depending on the health of
 // the application and its dependencies. The services field contains the status
-// of each dependency, which can be "Up" or "Down".
+// of each dependency, which can be "Up", "Down", or "Unknown" if it was not
+// checked. The checks field is present
+// when a Registry is used, with the latency and error of each check.
+//
+// A client that prefers application/health+json in its Accept header receives a
+// HealthJSONStatus instead, in the format of the "Health Check Response Format for
+// HTTP APIs" draft, with a status of "pass", "warn" or "fail":
+//
+//	{
+//	    "status": "fail",
+//	    "version": "1.0.0",
+//	    "checks": {
+//	        "external_api:responseTime": [{"componentId": "external_api", "componentType": "component",
+//	            "observedValue": 1200, "observedUnit": "ms", "status": "fail", "time": "2023-06-25T12:34:56Z",
+//	            "output": "connection refused"}]
+//	    },
+//	    "output": "failed: external_api"
+//	}
 //
 // The package also provides adapters for common dependencies, making it easy
 // to integrate with existing code:
 //   - ConfigAdapter: Adapts a configuration object to the HealthConfig interface
 //   - RepositoryAdapter: Adapts a repository factory to the RepositoryFactoryProvider interface
+//   - CircuitBreakerAdapter: Reports the circuit breakers of a circuit.Registry as services
 //
 // Health checks are designed to be lightweight and fast, with configurable
 // timeouts to prevent them from impacting application performance.
@@ -11,11 +11,13 @@
 	"go.uber.org/zap"
 )
 
-// NewHealthHandler creates a new health check handler using the generic interfaces
+// NewHealthHandler creates a new health check handler using the generic interfaces.
+// The statuses reported by any serviceProviders are added to the services of the response.
 func NewHealthHandler(
 	repoFactory repository.RepositoryFactory,
 	cfg config.Config,
 	logger *zap.Logger,
+	serviceProviders ...ServiceStatusProvider,
 ) http.HandlerFunc {
 	// Create adapters
 	repoAdapter := NewRepositoryAdapter(repoFactory)
@@ -28,5 +30,5 @@ func NewHealthHandler(
 	cfg.GetApp().GetEnvironment()
 
 	// Create handler
-	return NewGenericHandler(repoAdapter, configAdapter, logger, configAdapter.GetTimeout())
+	return NewGenericHandler(repoAdapter, configAdapter, logger, configAdapter.GetTimeout(), serviceProviders...)
 }
@@ -14,14 +14,27 @@
 
 // GenericHealthStatus represents the health status response
 type GenericHealthStatus struct {
-	Status    string            `json:"status"`
-	Timestamp string            `json:"timestamp"`
-	Version   string            `json:"version"`
-	Services  map[string]string `json:"services,omitempty"`
+	Status    string                 `json:"status"`
+	Timestamp string                 `json:"timestamp"`
+	Version   string                 `json:"version"`
+	Services  map[string]string      `json:"services,omitempty"`
+	Checks    map[string]CheckResult `json:"checks,omitempty"`
 }
 
-// NewGenericHandler creates a new health check HTTP handler that uses the generic interfaces
-func NewGenericHandler(provider HealthCheckProvider, versionProvider VersionProvider, logger *zap.Logger, timeout int) http.HandlerFunc {
+// NewGenericHandler creates a new health check HTTP handler that uses the generic interfaces.
+// The statuses reported by any serviceProviders, such as a CircuitBreakerAdapter, are added
+// to the services of the response. A Registry passed as a service provider runs its checks
+// with the context of the request, and their latencies and errors are added to the checks
+// of the response. Failed non-critical checks are reported as down, but do not degrade
+// the overall status.
+//
+// The response is a GenericHealthStatus, or a HealthJSONStatus in the
+// application/health+json format if the Accept header of the request prefers it.
+//
+// The database is checked if the repository factory of the provider implements Checker.
+// Otherwise it is reported as unknown, which does not degrade the overall status, and
+// should be checked with a Registry instead. A nil provider reports no database.
+func NewGenericHandler(provider HealthCheckProvider, versionProvider VersionProvider, logger *zap.Logger, timeout int, serviceProviders ...ServiceStatusProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Create a context with timeout for the health check
 		ctx, cancel := context.WithTimeout(r.Context(), time.Duration(timeout)*time.Second)
@@ -33,33 +46,61 @@ func NewGenericHandler(provider HealthCheckProvider, versionProvider VersionProv
 		// Check the health of dependencies
 		services := make(map[string]string)
 
+		var checks map[string]CheckResult
+
 		// Check database connectivity through the repository factory
-		repoFactory := provider.GetRepositoryFactory()
-		if repoFactory != nil {
-			services["database"] = ServiceUp
-		} else {
-			services["database"] = ServiceDown
+		if provider != nil {
+			services["database"] = checkRepositoryFactory(ctx, provider.GetRepositoryFactory(), logger)
+		}
+
+		// Add the statuses of any other services
+		for _, serviceProvider := range serviceProviders {
+			if registry, ok := serviceProvider.(*Registry); ok {
+				if checks == nil {
+					checks = make(map[string]CheckResult)
+				}
+				for name, result := range registry.Run(ctx).Checks {
+					services[name] = result.Status
+					checks[name] = result
+				}
+				continue
+			}
+
+			for name, s := range serviceProvider.GetServiceStatuses() {
+				services[name] = s
+			}
 		}
 
-		// Overall status is healthy if all dependencies are healthy
+		// Overall status is healthy if all critical dependencies are healthy
 		status := StatusHealthy
-		for _, s := range services {
-			if s != ServiceUp {
+		for name, s := range services {
+			if result, ok := checks[name]; ok && !result.Critical {
+				continue
+			}
+			if s != ServiceUp && s != ServiceUnknown {
 				status = StatusDegraded
 				break
 			}
 		}
 
-		// Create the health response
-		healthResponse := GenericHealthStatus{
-			Status:    status,
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
-			Version:   versionProvider.GetVersion(),
-			Services:  services,
+		// Create the health response in the format the client prefers
+		contentType := negotiateContentType(r.Header.Get("Accept"))
+		var healthResponse any
+		if contentType == ContentTypeHealthJSON {
+			healthResponse = newHealthJSONStatus(versionProvider.GetVersion(), services, checks)
+		} else {
+			healthResponse = GenericHealthStatus{
+				Status:    status,
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+				Version:   versionProvider.GetVersion(),
+				Services:  services,
+				Checks:    checks,
+			}
 		}
 
 		// Set content type
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Add("Vary", "Accept")
 
 		// Set appropriate status code based on health
 		if status == StatusHealthy {
@@ -82,3 +123,22 @@ func NewGenericHandler(provider HealthCheckProvider, versionProvider VersionProv
 		)
 	}
 }
+
+// checkRepositoryFactory returns the status of the database behind a repository factory.
+// A nil factory is down, and a factory that implements Checker is up only if its check
+// passes. Any other factory cannot be checked, so its status is unknown.
+func checkRepositoryFactory(ctx context.Context, repoFactory any, logger *zap.Logger) string {
+	if repoFactory == nil {
+		return ServiceDown
+	}
+
+	checker, ok := repoFactory.(Checker)
+	if !ok {
+		return ServiceUnknown
+	}
+	if err := checker.Check(ctx); err != nil {
+		logger.Warn("Database health check failed", zap.Error(err))
+		return ServiceDown
+	}
+	return ServiceUp
+}
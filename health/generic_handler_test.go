@@ -3,7 +3,9 @@
 package health
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -73,9 +75,10 @@ func TestNewGenericHandler_HealthyService(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Check the response
+	// A repository factory that cannot be checked is unknown, which does not degrade the service
 	assert.Equal(t, StatusHealthy, response.Status)
 	assert.Equal(t, mockVersion, response.Version)
-	assert.Equal(t, ServiceUp, response.Services["database"])
+	assert.Equal(t, ServiceUnknown, response.Services["database"])
 
 	// Verify that the timestamp is in the correct format
 	_, err = time.Parse(time.RFC3339, response.Timestamp)
@@ -134,3 +137,91 @@ func TestNewGenericHandler_DegradedService(t *testing.T) {
 	mockProvider.AssertExpectations(t)
 	mockVersionProvider.AssertExpectations(t)
 }
+
+// checkedFactory is a repository factory that implements Checker
+type checkedFactory struct {
+	err error
+}
+
+func (f checkedFactory) Check(ctx context.Context) error {
+	return f.err
+}
+
+// TestNewGenericHandler_CheckedRepositoryFactory tests that a repository factory that implements Checker is checked
+func TestNewGenericHandler_CheckedRepositoryFactory(t *testing.T) {
+	logger := zap.NewNop()
+
+	mockVersionProvider := new(MockVersionProvider)
+	mockVersionProvider.On("GetVersion").Return("1.0.0")
+
+	for _, tt := range []struct {
+		name     string
+		err      error
+		expected string
+		code     int
+	}{
+		{"up", nil, ServiceUp, http.StatusOK},
+		{"down", errors.New("connection refused"), ServiceDown, http.StatusServiceUnavailable},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockHealthCheckProvider)
+			mockProvider.On("GetRepositoryFactory").Return(checkedFactory{err: tt.err})
+
+			rr := httptest.NewRecorder()
+			NewGenericHandler(mockProvider, mockVersionProvider, logger, 5).ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+			assert.Equal(t, tt.code, rr.Code)
+
+			var response GenericHealthStatus
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			assert.Equal(t, tt.expected, response.Services["database"])
+		})
+	}
+}
+
+// TestNewGenericHandler_Registry tests that the checks of a registry are added to the response
+func TestNewGenericHandler_Registry(t *testing.T) {
+	logger := zap.NewNop()
+
+	mockVersionProvider := new(MockVersionProvider)
+	mockVersionProvider.On("GetVersion").Return("1.0.0")
+
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("postgres", CheckerFunc(func(ctx context.Context) error { return nil })))
+	registry.Register(NewCheck("payments-api", CheckerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})))
+
+	// Without a provider, no database is reported
+	rr := httptest.NewRecorder()
+	NewGenericHandler(nil, mockVersionProvider, logger, 5, registry).ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	var response GenericHealthStatus
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, StatusDegraded, response.Status)
+	assert.Equal(t, map[string]string{"postgres": ServiceUp, "payments-api": ServiceDown}, response.Services)
+	assert.Equal(t, ServiceUp, response.Checks["postgres"].Status)
+	assert.Equal(t, ServiceDown, response.Checks["payments-api"].Status)
+	assert.Equal(t, "connection refused", response.Checks["payments-api"].Error)
+}
+
+// TestNewGenericHandler_NonCriticalCheck tests that a failed non-critical check does not degrade the status
+func TestNewGenericHandler_NonCriticalCheck(t *testing.T) {
+	mockVersionProvider := new(MockVersionProvider)
+	mockVersionProvider.On("GetVersion").Return("1.0.0")
+
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("search", CheckerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})).WithCritical(false))
+
+	rr := httptest.NewRecorder()
+	NewGenericHandler(nil, mockVersionProvider, zap.NewNop(), 5, registry).ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response GenericHealthStatus
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, StatusHealthy, response.Status)
+	assert.Equal(t, ServiceDown, response.Services["search"])
+	assert.False(t, response.Checks["search"].Critical)
+}
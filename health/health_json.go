@@ -0,0 +1,161 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package health provides functionality for health checking applications.
+package health
+
+import (
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Content types of health check responses
+const (
+	// ContentTypeJSON is the content type of a GenericHealthStatus response
+	ContentTypeJSON = "application/json"
+
+	// ContentTypeHealthJSON is the content type of a HealthJSONStatus response,
+	// the format of the "Health Check Response Format for HTTP APIs" draft
+	ContentTypeHealthJSON = "application/health+json"
+)
+
+// Statuses of the application/health+json format
+const (
+	// HealthJSONPass means that the application or component is healthy
+	HealthJSONPass = "pass"
+
+	// HealthJSONWarn means that the application is healthy, with concerns,
+	// such as a failed non-critical check
+	HealthJSONWarn = "warn"
+
+	// HealthJSONFail means that the application or component is unhealthy
+	HealthJSONFail = "fail"
+)
+
+// HealthJSONStatus represents a health check response in the application/health+json format.
+type HealthJSONStatus struct {
+	// Status is HealthJSONPass, HealthJSONWarn or HealthJSONFail
+	Status string `json:"status"`
+
+	// Version is the version of the application
+	Version string `json:"version,omitempty"`
+
+	// Checks holds the details of each dependency, keyed by "component:measurement",
+	// such as "postgres:responseTime", or by component name if nothing was measured
+	Checks map[string][]HealthJSONCheck `json:"checks,omitempty"`
+
+	// Output describes why the application is not healthy, or is empty if it passes
+	Output string `json:"output,omitempty"`
+}
+
+// HealthJSONCheck represents the details of a dependency in an application/health+json response.
+type HealthJSONCheck struct {
+	// ComponentID identifies the dependency, which is the name of the check
+	ComponentID string `json:"componentId,omitempty"`
+
+	// ComponentType is the kind of dependency, such as "datastore"
+	ComponentType string `json:"componentType,omitempty"`
+
+	// ObservedValue is the measured value, which is the latency of the check
+	ObservedValue any `json:"observedValue,omitempty"`
+
+	// ObservedUnit is the unit of ObservedValue, which is "ms"
+	ObservedUnit string `json:"observedUnit,omitempty"`
+
+	// Status is HealthJSONPass, HealthJSONWarn or HealthJSONFail
+	Status string `json:"status"`
+
+	// Time is when the check finished, in RFC 3339 format
+	Time string `json:"time,omitempty"`
+
+	// Output is the error of a failed check
+	Output string `json:"output,omitempty"`
+}
+
+// newHealthJSONStatus converts the outcome of a health check to the application/health+json format.
+// A failed critical dependency fails the response, and a failed non-critical check only warns.
+func newHealthJSONStatus(version string, services map[string]string, checks map[string]CheckResult) HealthJSONStatus {
+	response := HealthJSONStatus{
+		Status:  HealthJSONPass,
+		Version: version,
+		Checks:  make(map[string][]HealthJSONCheck, len(services)),
+	}
+
+	var failed []string
+	for name, status := range services {
+		detail := HealthJSONCheck{
+			ComponentID: name,
+			Status:      HealthJSONPass,
+		}
+		key := name
+
+		result, checked := checks[name]
+		if checked {
+			key = name + ":responseTime"
+			detail.ComponentType = result.ComponentType
+			detail.ObservedValue = float64(result.Latency) / float64(time.Millisecond)
+			detail.ObservedUnit = "ms"
+			detail.Time = formatTime(result.Time)
+			detail.Output = result.Error
+		}
+
+		if status != ServiceUp {
+			if status == ServiceUnknown || checked && !result.Critical {
+				detail.Status = HealthJSONWarn
+				if response.Status == HealthJSONPass {
+					response.Status = HealthJSONWarn
+				}
+			} else {
+				detail.Status = HealthJSONFail
+				response.Status = HealthJSONFail
+				failed = append(failed, name)
+			}
+		}
+
+		response.Checks[key] = append(response.Checks[key], detail)
+	}
+
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		response.Output = "failed: " + strings.Join(failed, ", ")
+	}
+
+	return response
+}
+
+// negotiateContentType returns the content type of the health check response for
+// an Accept header: ContentTypeHealthJSON if the client prefers it, and
+// ContentTypeJSON otherwise. Wildcards select ContentTypeJSON, and of two
+// types with the same quality, the one listed first is chosen.
+func negotiateContentType(accept string) string {
+	best := ContentTypeJSON
+	bestQuality := -1.0
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 || quality <= bestQuality {
+			continue
+		}
+
+		switch mediaType {
+		case ContentTypeHealthJSON:
+			best, bestQuality = ContentTypeHealthJSON, quality
+		case ContentTypeJSON, "application/*", "*/*":
+			best, bestQuality = ContentTypeJSON, quality
+		}
+	}
+
+	return best
+}
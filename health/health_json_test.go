@@ -0,0 +1,141 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ContentTypeJSON},
+		{"*/*", ContentTypeJSON},
+		{"application/json", ContentTypeJSON},
+		{"application/health+json", ContentTypeHealthJSON},
+		{"application/health+json, application/json", ContentTypeHealthJSON},
+		{"application/json, application/health+json", ContentTypeJSON},
+		{"application/json;q=0.5, application/health+json", ContentTypeHealthJSON},
+		{"application/health+json;q=0.1, */*", ContentTypeJSON},
+		{"application/health+json;q=0", ContentTypeJSON},
+		{"text/html, application/health+json;q=0.9", ContentTypeHealthJSON},
+		{"application/health+json;q=bad", ContentTypeJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.want, negotiateContentType(tt.accept))
+		})
+	}
+}
+
+func TestNewHealthJSONStatus(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	services := map[string]string{"database": ServiceUp, "postgres": ServiceUp}
+	checks := map[string]CheckResult{
+		"postgres": {Status: ServiceUp, Latency: 1500 * time.Microsecond, Critical: true, Time: now, ComponentType: ComponentTypeDatastore},
+	}
+
+	response := newHealthJSONStatus("1.0.0", services, checks)
+	assert.Equal(t, HealthJSONStatus{
+		Status:  HealthJSONPass,
+		Version: "1.0.0",
+		Checks: map[string][]HealthJSONCheck{
+			"database": {{ComponentID: "database", Status: HealthJSONPass}},
+			"postgres:responseTime": {{
+				ComponentID:   "postgres",
+				ComponentType: ComponentTypeDatastore,
+				ObservedValue: 1.5,
+				ObservedUnit:  "ms",
+				Status:        HealthJSONPass,
+				Time:          "2025-01-01T00:00:00Z",
+			}},
+		},
+	}, response)
+
+	// A dependency that was not checked warns
+	services["database"] = ServiceUnknown
+	response = newHealthJSONStatus("1.0.0", services, checks)
+	assert.Equal(t, HealthJSONWarn, response.Status)
+	assert.Equal(t, HealthJSONWarn, response.Checks["database"][0].Status)
+	services["database"] = ServiceUp
+
+	// A failed non-critical check warns
+	services["search"] = ServiceDown
+	checks["search"] = CheckResult{Status: ServiceDown, Error: "timeout", Time: now}
+	response = newHealthJSONStatus("1.0.0", services, checks)
+	assert.Equal(t, HealthJSONWarn, response.Status)
+	assert.Equal(t, HealthJSONWarn, response.Checks["search:responseTime"][0].Status)
+	assert.Equal(t, "timeout", response.Checks["search:responseTime"][0].Output)
+	assert.Empty(t, response.Output)
+
+	// A failed critical dependency fails
+	services["database"] = ServiceDown
+	response = newHealthJSONStatus("1.0.0", services, checks)
+	assert.Equal(t, HealthJSONFail, response.Status)
+	assert.Equal(t, HealthJSONFail, response.Checks["database"][0].Status)
+	assert.Equal(t, "failed: database", response.Output)
+}
+
+func TestNewGenericHandler_HealthJSON(t *testing.T) {
+	mockVersionProvider := new(MockVersionProvider)
+	mockVersionProvider.On("GetVersion").Return("1.0.0")
+
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("postgres", CheckerFunc(func(ctx context.Context) error { return nil })).
+		WithComponentType(ComponentTypeDatastore))
+	registry.Register(NewCheck("payments", CheckerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})))
+
+	handler := NewGenericHandler(nil, mockVersionProvider, zap.NewNop(), 5, registry)
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set("Accept", "application/health+json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, ContentTypeHealthJSON, rr.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+
+	var response HealthJSONStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, HealthJSONFail, response.Status)
+	assert.Equal(t, "1.0.0", response.Version)
+	assert.Equal(t, "failed: payments", response.Output)
+
+	postgres := response.Checks["postgres:responseTime"]
+	require.Len(t, postgres, 1)
+	assert.Equal(t, HealthJSONPass, postgres[0].Status)
+	assert.Equal(t, ComponentTypeDatastore, postgres[0].ComponentType)
+	assert.Equal(t, "ms", postgres[0].ObservedUnit)
+	assert.NotNil(t, postgres[0].ObservedValue)
+	assert.NotEmpty(t, postgres[0].Time)
+
+	payments := response.Checks["payments:responseTime"]
+	require.Len(t, payments, 1)
+	assert.Equal(t, HealthJSONFail, payments[0].Status)
+	assert.Equal(t, "connection refused", payments[0].Output)
+
+	// Without an Accept header, the generic format is returned
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(t, ContentTypeJSON, rr.Header().Get("Content-Type"))
+
+	var generic GenericHealthStatus
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &generic))
+	assert.Equal(t, StatusDegraded, generic.Status)
+}
@@ -13,6 +13,13 @@ type HealthCheckProvider interface {
 	RepositoryFactoryProvider
 }
 
+// ServiceStatusProvider defines the interface for reporting the status of additional services.
+// The statuses are added to the services of a health check response, keyed by service name,
+// with values ServiceUp or ServiceDown.
+type ServiceStatusProvider interface {
+	GetServiceStatuses() map[string]string
+}
+
 // VersionProvider defines the interface for getting the application version
 type VersionProvider interface {
 	GetVersion() string
@@ -32,3 +39,10 @@ type HealthConfig interface {
 	// GetTimeout returns the timeout for health checks
 	GetTimeout() int
 }
+
+// Drainer defines the interface for reporting whether the application is shutting down.
+// It is implemented by signal.GracefulShutdown, and makes readiness probes fail once
+// the shutdown begins.
+type Drainer interface {
+	IsDraining() bool
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// healthMetrics holds the OpenTelemetry instruments of a Registry.
+// Its methods do nothing on a nil receiver, so that they can be called
+// whether or not metrics are enabled.
+type healthMetrics struct {
+	// duration records how long each check took, by check and status
+	duration metric.Float64Histogram
+
+	// transitions counts status changes, by check and the statuses moved from and to
+	transitions metric.Int64Counter
+}
+
+// newHealthMetrics creates the instruments of a registry.
+//
+// Parameters:
+//   - meter: The meter used to create the instruments.
+//   - statuses: A function returning the last status of each check, observed by the status gauge.
+//
+// Returns:
+//   - *healthMetrics: The instruments.
+//   - error: An error if an instrument could not be created.
+func newHealthMetrics(meter metric.Meter, statuses func() map[string]string) (*healthMetrics, error) {
+	m := &healthMetrics{}
+
+	var err error
+	m.duration, err = meter.Float64Histogram(
+		"health.check.duration",
+		metric.WithDescription("Time taken by health checks"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create health.check.duration histogram: %w", err)
+	}
+
+	m.transitions, err = meter.Int64Counter(
+		"health.check.transitions",
+		metric.WithDescription("Number of times a health check changed status"),
+		metric.WithUnit("{transition}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create health.check.transitions counter: %w", err)
+	}
+
+	statusGauge, err := meter.Int64ObservableGauge(
+		"health.check.status",
+		metric.WithDescription("Last status of a health check: 1 for up and 0 for down"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create health.check.status gauge: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		for name, status := range statuses() {
+			var value int64
+			if status == ServiceUp {
+				value = 1
+			}
+			o.ObserveInt64(statusGauge, value, metric.WithAttributes(attribute.String("health.check", name)))
+		}
+		return nil
+	}, statusGauge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register health.check.status callback: %w", err)
+	}
+
+	return m, nil
+}
+
+// recordCheck records the duration of a check.
+func (m *healthMetrics) recordCheck(name string, result CheckResult) {
+	if m == nil {
+		return
+	}
+	m.duration.Record(context.Background(), result.Latency.Seconds(),
+		metric.WithAttributes(
+			attribute.String("health.check", name),
+			attribute.String("health.status", result.Status)))
+}
+
+// recordTransition counts a status change.
+func (m *healthMetrics) recordTransition(name string, from, to string) {
+	if m == nil {
+		return
+	}
+	m.transitions.Add(context.Background(), 1,
+		metric.WithAttributes(
+			attribute.String("health.check", name),
+			attribute.String("health.from", from),
+			attribute.String("health.to", to)))
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRegistry_Metrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	registry := NewRegistry(DefaultConfig(), DefaultOptions().WithMeter(provider.Meter("test")))
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	registry.Register(NewCheck("db", CheckerFunc(func(ctx context.Context) error {
+		if !healthy.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})))
+	registry.Register(NewCheck("cache", CheckerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})))
+
+	registry.Run(context.Background())
+	healthy.Store(false)
+	registry.Run(context.Background())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	transitions := make(map[string]int64)
+	statuses := make(map[string]int64)
+	durations := make(map[string]uint64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					check, _ := dp.Attributes.Value("health.check")
+					from, _ := dp.Attributes.Value("health.from")
+					to, _ := dp.Attributes.Value("health.to")
+					transitions[check.AsString()+" "+from.AsString()+"->"+to.AsString()] = dp.Value
+				}
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					check, _ := dp.Attributes.Value("health.check")
+					statuses[check.AsString()] = dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					check, _ := dp.Attributes.Value("health.check")
+					status, _ := dp.Attributes.Value("health.status")
+					durations[check.AsString()+" "+status.AsString()] = dp.Count
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]int64{
+		"cache Unknown->Down": 1,
+		"db Up->Down":         1,
+	}, transitions)
+	assert.Equal(t, map[string]int64{"cache": 0, "db": 0}, statuses)
+	assert.Equal(t, map[string]uint64{
+		"cache Down": 2,
+		"db Up":      1,
+		"db Down":    1,
+	}, durations)
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package health provides functionality for health checking applications.
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/abitofhelp/servicelib/logging"
+	"go.uber.org/zap"
+)
+
+// shutdownCheckName is the name under which readiness probes report a Drainer.
+const shutdownCheckName = "shutdown"
+
+// NewLivezHandler creates an HTTP handler for liveness probes, which runs the
+// checks of the registry tagged with Liveness.
+//
+// The handler responds like the /livez endpoint of the Kubernetes API server:
+// with 200 and "ok" if every critical check passed, or with 503 and a line for
+// each check if one failed. The ?verbose query parameter lists the checks on
+// success as well, and ?exclude=name leaves a check out; it may be repeated or
+// hold a comma-separated list.
+//
+// Parameters:
+//   - registry: The registry of checks.
+//
+// Returns:
+//   - http.HandlerFunc: The liveness probe handler.
+func NewLivezHandler(registry *Registry) http.HandlerFunc {
+	return newProbeHandler(registry, Liveness, nil, nil)
+}
+
+// NewReadyzHandler creates an HTTP handler for readiness probes, which runs the
+// checks of the registry tagged with Readiness, and responds like NewLivezHandler.
+//
+// If drainer is not nil, it is reported as the "shutdown" check, which fails once
+// the application begins shutting down, so that load balancers stop sending it
+// new requests while in-flight requests complete.
+//
+// Parameters:
+//   - registry: The registry of checks.
+//   - drainer: Reports whether the application is shutting down, such as a
+//     signal.GracefulShutdown, or nil.
+//
+// Returns:
+//   - http.HandlerFunc: The readiness probe handler.
+func NewReadyzHandler(registry *Registry, drainer Drainer) http.HandlerFunc {
+	return newProbeHandler(registry, Readiness, drainer, nil)
+}
+
+// NewStartupzHandler creates an HTTP handler for startup probes, which runs the
+// checks of the registry tagged with Startup, and responds like NewLivezHandler.
+// Once the checks have passed with none excluded, the application has started,
+// and the handler passes without running them again.
+//
+// Parameters:
+//   - registry: The registry of checks.
+//
+// Returns:
+//   - http.HandlerFunc: The startup probe handler.
+func NewStartupzHandler(registry *Registry) http.HandlerFunc {
+	return newProbeHandler(registry, Startup, nil, &atomic.Bool{})
+}
+
+// RegisterProbeHandlers registers the liveness, readiness and startup probe
+// handlers of a registry on a ServeMux at /livez, /readyz and /startupz.
+//
+// Parameters:
+//   - mux: The ServeMux to register the handlers on.
+//   - registry: The registry of checks.
+//   - drainer: Reports whether the application is shutting down, or nil.
+func RegisterProbeHandlers(mux *http.ServeMux, registry *Registry, drainer Drainer) {
+	mux.Handle("/livez", NewLivezHandler(registry))
+	mux.Handle("/readyz", NewReadyzHandler(registry, drainer))
+	mux.Handle("/startupz", NewStartupzHandler(registry))
+}
+
+// newProbeHandler creates the handler of a probe. If started is not nil, the
+// handler passes without running checks once they have passed with none excluded.
+func newProbeHandler(registry *Registry, probe Probe, drainer Drainer, started *atomic.Bool) http.HandlerFunc {
+	logger := logging.NewContextLogger(zap.NewNop())
+	if registry != nil {
+		logger = registry.logger
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		_, verbose := query["verbose"]
+
+		if started != nil && started.Load() {
+			writeProbeResponse(w, probe, nil, false, verbose)
+			return
+		}
+
+		exclude := excludedChecks(query["exclude"])
+
+		// Names of the checks that this probe could run, to match exclusions against
+		known := make(map[string]bool)
+		for _, check := range registry.checksFor(probe, nil) {
+			known[check.Name] = true
+		}
+		if drainer != nil {
+			known[shutdownCheckName] = true
+		}
+
+		report := registry.RunProbe(r.Context(), probe, exclude...)
+
+		var lines []string
+		failed := false
+		var failedChecks []string
+
+		names := make([]string, 0, len(report.Checks)+1)
+		for name := range report.Checks {
+			names = append(names, name)
+		}
+		if drainer != nil && !slices.Contains(exclude, shutdownCheckName) {
+			names = append(names, shutdownCheckName)
+		}
+		for _, name := range exclude {
+			if known[name] && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		slices.Sort(names)
+
+		for _, name := range names {
+			result, ran := report.Checks[name]
+			switch {
+			case slices.Contains(exclude, name):
+				lines = append(lines, fmt.Sprintf("[+]%s excluded: ok", name))
+			case !ran && name == shutdownCheckName:
+				if drainer.IsDraining() {
+					lines = append(lines, fmt.Sprintf("[-]%s failed: the application is shutting down", name))
+					failed = true
+					failedChecks = append(failedChecks, name)
+				} else {
+					lines = append(lines, fmt.Sprintf("[+]%s ok", name))
+				}
+			case result.Status == ServiceUp:
+				lines = append(lines, fmt.Sprintf("[+]%s ok", name))
+			case result.Critical:
+				lines = append(lines, fmt.Sprintf("[-]%s failed: %s", name, result.Error))
+				failed = true
+				failedChecks = append(failedChecks, name)
+			default:
+				lines = append(lines, fmt.Sprintf("[!]%s failed (non-critical): %s", name, result.Error))
+			}
+		}
+
+		var unmatched []string
+		for _, name := range exclude {
+			if !known[name] {
+				unmatched = append(unmatched, fmt.Sprintf("%q", name))
+			}
+		}
+		if len(unmatched) > 0 {
+			lines = append(lines, "warn: some health checks cannot be excluded: no matches for "+strings.Join(unmatched, ","))
+		}
+
+		if failed {
+			logger.Warn(r.Context(), "Health probe failed",
+				zap.String("probe", probe.String()),
+				zap.Strings("checks", failedChecks))
+		} else if started != nil && len(exclude) == 0 {
+			// A probe that excludes checks does not show that they passed
+			started.Store(true)
+		}
+
+		writeProbeResponse(w, probe, lines, failed, verbose)
+	}
+}
+
+// excludedChecks returns the check names of the exclude query parameters, which
+// may each hold a comma-separated list.
+func excludedChecks(values []string) []string {
+	var exclude []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				exclude = append(exclude, name)
+			}
+		}
+	}
+	return exclude
+}
+
+// writeProbeResponse writes the plain text response of a probe. A failed probe
+// always lists its checks; a passed probe lists them only if verbose is set.
+func writeProbeResponse(w http.ResponseWriter, probe Probe, lines []string, failed bool, verbose bool) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if failed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !failed && !verbose {
+		_, _ = fmt.Fprint(w, "ok")
+		return
+	}
+
+	for _, line := range lines {
+		_, _ = fmt.Fprintln(w, line)
+	}
+	if failed {
+		_, _ = fmt.Fprintf(w, "%s check failed\n", probe)
+	} else {
+		_, _ = fmt.Fprintf(w, "%s check passed\n", probe)
+	}
+}
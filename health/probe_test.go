@@ -0,0 +1,168 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// drainer is a Drainer whose state is set by the test
+type drainer struct {
+	draining atomic.Bool
+}
+
+func (d *drainer) IsDraining() bool {
+	return d.draining.Load()
+}
+
+func probe(handler http.Handler, target string) (int, string) {
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+	return rr.Code, rr.Body.String()
+}
+
+func TestNewReadyzHandler(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", passing()))
+	registry.Register(NewCheck("search", failing("timeout")).WithCritical(false))
+	registry.Register(NewCheck("deadlock", failing("stuck")).WithProbes(Liveness))
+
+	handler := NewReadyzHandler(registry, nil)
+
+	code, body := probe(handler, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body)
+
+	code, body = probe(handler, "/readyz?verbose")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[+]db ok\n[!]search failed (non-critical): timeout\nreadyz check passed\n", body)
+
+	registry.Register(NewCheck("cache", failing("connection refused")))
+
+	code, body = probe(handler, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "[-]cache failed: connection refused\n[+]db ok\n[!]search failed (non-critical): timeout\nreadyz check failed\n", body)
+}
+
+func TestNewReadyzHandler_Exclude(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", passing()))
+	registry.Register(NewCheck("cache", failing("connection refused")))
+	registry.Register(NewCheck("search", failing("timeout")))
+
+	handler := NewReadyzHandler(registry, nil)
+
+	code, body := probe(handler, "/readyz?exclude=cache&exclude=search")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body)
+
+	code, body = probe(handler, "/readyz?verbose&exclude=cache,search,etcd")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[+]cache excluded: ok\n[+]db ok\n[+]search excluded: ok\n"+
+		"warn: some health checks cannot be excluded: no matches for \"etcd\"\nreadyz check passed\n", body)
+}
+
+func TestNewReadyzHandler_Draining(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", passing()))
+
+	d := &drainer{}
+	handler := NewReadyzHandler(registry, d)
+
+	code, body := probe(handler, "/readyz?verbose")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[+]db ok\n[+]shutdown ok\nreadyz check passed\n", body)
+
+	d.draining.Store(true)
+
+	code, body = probe(handler, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "[+]db ok\n[-]shutdown failed: the application is shutting down\nreadyz check failed\n", body)
+
+	code, _ = probe(handler, "/readyz?exclude=shutdown")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Liveness is not affected by draining
+	code, _ = probe(NewLivezHandler(registry), "/livez")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestNewLivezHandler(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", failing("connection refused")))
+	registry.Register(NewCheck("deadlock", passing()).WithProbes(Liveness))
+
+	code, body := probe(NewLivezHandler(registry), "/livez?verbose")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[+]deadlock ok\nlivez check passed\n", body)
+
+	code, body = probe(NewLivezHandler(nil), "/livez")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body)
+}
+
+func TestNewStartupzHandler(t *testing.T) {
+	var ready atomic.Bool
+	calls := atomic.Int32{}
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("migrations", CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		if !ready.Load() {
+			return assert.AnError
+		}
+		return nil
+	})).WithProbes(Startup))
+
+	handler := NewStartupzHandler(registry)
+
+	code, body := probe(handler, "/startupz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "[-]migrations failed")
+
+	ready.Store(true)
+	code, _ = probe(handler, "/startupz")
+	assert.Equal(t, http.StatusOK, code)
+
+	// Once started, the checks are not run again
+	ready.Store(false)
+	code, body = probe(handler, "/startupz?verbose")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "startupz check passed\n", body)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestNewStartupzHandler_ExcludedCheckDoesNotStart(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("migrations", failing("pending")).WithProbes(Startup))
+	registry.Register(NewCheck("cache", passing()).WithProbes(Startup))
+
+	handler := NewStartupzHandler(registry)
+
+	code, _ := probe(handler, "/startupz?exclude=migrations")
+	assert.Equal(t, http.StatusOK, code)
+
+	// The excluded check has not passed, so the application has not started
+	code, body := probe(handler, "/startupz?verbose")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "[-]migrations failed")
+}
+
+func TestRegisterProbeHandlers(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", passing()))
+
+	mux := http.NewServeMux()
+	RegisterProbeHandlers(mux, registry, &drainer{})
+
+	for _, path := range []string{"/livez", "/readyz", "/startupz"} {
+		code, body := probe(mux, path)
+		assert.Equal(t, http.StatusOK, code, path)
+		assert.Equal(t, "ok", body, path)
+	}
+}
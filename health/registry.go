@@ -0,0 +1,634 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package health provides functionality for health checking applications.
+package health
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/logging"
+	"github.com/abitofhelp/servicelib/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Config contains configuration parameters for a Registry.
+type Config struct {
+	// Timeout is how long a check may run before it is reported as down,
+	// unless the check sets its own timeout
+	Timeout time.Duration
+
+	// Interval is how often checks are run in the background, unless the check
+	// sets its own interval. Probes then report the last result instead of running
+	// the check. If zero, checks are run on every probe.
+	Interval time.Duration
+
+	// StaleAfter is how old the last result of a background check may be before
+	// it is reported as down. If zero, three times the interval of the check is used.
+	StaleAfter time.Duration
+}
+
+// DefaultConfig returns a default configuration for a Registry.
+//
+// Returns:
+//   - Config: A configuration with a timeout of 5 seconds.
+func DefaultConfig() Config {
+	return Config{
+		Timeout: 5 * time.Second,
+	}
+}
+
+// WithTimeout sets how long a check may run before it is reported as down.
+// If timeout is less than or equal to zero, the default timeout is used.
+//
+// Parameters:
+//   - timeout: The timeout of each check.
+//
+// Returns:
+//   - Config: A new Config with the updated Timeout value.
+func (c Config) WithTimeout(timeout time.Duration) Config {
+	if timeout <= 0 {
+		timeout = DefaultConfig().Timeout
+	}
+	c.Timeout = timeout
+	return c
+}
+
+// WithInterval sets how often checks are run in the background, so that probes
+// report the last result instead of loading a dependency on every hit.
+// If interval is less than or equal to zero, checks are run on every probe.
+//
+// Parameters:
+//   - interval: The interval between background runs of each check.
+//
+// Returns:
+//   - Config: A new Config with the updated Interval value.
+func (c Config) WithInterval(interval time.Duration) Config {
+	if interval < 0 {
+		interval = 0
+	}
+	c.Interval = interval
+	return c
+}
+
+// WithStaleAfter sets how old the last result of a background check may be before
+// it is reported as down, as when the check has stopped running.
+// If staleAfter is less than or equal to zero, three times the interval of the check is used.
+//
+// Parameters:
+//   - staleAfter: The maximum age of a background result.
+//
+// Returns:
+//   - Config: A new Config with the updated StaleAfter value.
+func (c Config) WithStaleAfter(staleAfter time.Duration) Config {
+	if staleAfter < 0 {
+		staleAfter = 0
+	}
+	c.StaleAfter = staleAfter
+	return c
+}
+
+// Options contains additional options for a Registry.
+type Options struct {
+	// Logger is used for logging health check operations.
+	// If nil, a no-op logger will be used.
+	Logger *logging.ContextLogger
+
+	// Tracer is used for tracing health checks, with a span for each check.
+	Tracer telemetry.Tracer
+
+	// Clock is used to measure the latency of checks, time them out and schedule
+	// background checks. If nil, the system clock is used.
+	Clock clock.Clock
+
+	// Meter is used to create metrics for health checks.
+	// If nil, no metrics are recorded.
+	Meter metric.Meter
+}
+
+// DefaultOptions returns default options for a Registry.
+//
+// Returns:
+//   - Options: Options with no logger, a no-op tracer and the system clock.
+func DefaultOptions() Options {
+	return Options{
+		Logger: nil,
+		Tracer: telemetry.NewNoopTracer(),
+	}
+}
+
+// WithLogger sets the logger for the registry.
+//
+// Parameters:
+//   - logger: A ContextLogger instance for logging health check operations.
+//
+// Returns:
+//   - Options: A new Options instance with the updated Logger value.
+func (o Options) WithLogger(logger *logging.ContextLogger) Options {
+	o.Logger = logger
+	return o
+}
+
+// WithOtelTracer returns Options with an OpenTelemetry tracer.
+//
+// Parameters:
+//   - tracer: An OpenTelemetry trace.Tracer instance.
+//
+// Returns:
+//   - Options: A new Options instance with the OpenTelemetry tracer.
+func (o Options) WithOtelTracer(tracer trace.Tracer) Options {
+	o.Tracer = telemetry.NewOtelTracer(tracer)
+	return o
+}
+
+// WithClock sets the clock used to measure and time out checks.
+// Tests pass a clock.Fake so that they can time out checks without waiting.
+//
+// Parameters:
+//   - c: The clock, or nil for the system clock.
+//
+// Returns:
+//   - Options: A new Options instance with the updated Clock value.
+func (o Options) WithClock(c clock.Clock) Options {
+	o.Clock = c
+	return o
+}
+
+// WithMeter sets the OpenTelemetry meter used to record health check metrics.
+// The registry records the duration of each check, counts status transitions,
+// and observes the last status of each check as a gauge, each with a
+// health.check attribute.
+//
+// Parameters:
+//   - meter: An OpenTelemetry metric.Meter instance.
+//
+// Returns:
+//   - Options: A new Options instance with the updated Meter value.
+func (o Options) WithMeter(meter metric.Meter) Options {
+	o.Meter = meter
+	return o
+}
+
+// Report is the outcome of running the checks of a Registry.
+type Report struct {
+	// Status is StatusHealthy if every critical check passed, or StatusDegraded otherwise
+	Status string
+
+	// Checks holds the result of each check, keyed by check name
+	Checks map[string]CheckResult
+}
+
+// Registry holds named health checks and runs them concurrently, each with its own timeout.
+// A Registry can be passed to NewGenericHandler as a ServiceStatusProvider, which
+// adds the result of each check to the health check response.
+//
+// Checks with an interval run in the background, and probes report their last
+// result. Changes of a check's status are logged and counted in metrics.
+// Call Shutdown to stop the background checks.
+type Registry struct {
+	config  Config
+	logger  *logging.ContextLogger
+	tracer  telemetry.Tracer
+	clock   clock.Clock
+	metrics *healthMetrics
+
+	mutex  sync.RWMutex
+	checks map[string]Check
+	states map[string]*checkState
+	closed bool
+}
+
+// checkState is the last result of a registered check, and the means to stop
+// its background runs.
+type checkState struct {
+	result CheckResult
+	known  bool
+	cancel context.CancelFunc
+}
+
+// NewRegistry creates a new, empty Registry.
+//
+// Parameters:
+//   - config: The configuration of the registry.
+//   - options: Additional options for logging, tracing and the clock.
+//
+// Returns:
+//   - *Registry: The new registry.
+func NewRegistry(config Config, options Options) *Registry {
+	logger := options.Logger
+	if logger == nil {
+		logger = logging.NewContextLogger(zap.NewNop())
+	}
+
+	tracer := options.Tracer
+	if tracer == nil {
+		tracer = telemetry.NewNoopTracer()
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultConfig().Timeout
+	}
+
+	if config.Interval < 0 {
+		config.Interval = 0
+	}
+
+	r := &Registry{
+		config: config,
+		logger: logger,
+		tracer: tracer,
+		clock:  clock.OrReal(options.Clock),
+		checks: make(map[string]Check),
+		states: make(map[string]*checkState),
+	}
+
+	if options.Meter != nil {
+		metrics, err := newHealthMetrics(options.Meter, r.lastStatuses)
+		if err != nil {
+			logger.Warn(context.Background(), "Failed to create health check metrics, continuing without metrics",
+				zap.Error(err))
+		} else {
+			r.metrics = metrics
+		}
+	}
+
+	return r
+}
+
+// Register adds a check to the registry, replacing any check with the same name.
+// A check without a name or checker is ignored, and a check without probes runs on
+// Readiness probes. A check with an interval starts running in the background,
+// unless the registry has been shut down.
+//
+// Parameters:
+//   - check: The check to add.
+func (r *Registry) Register(check Check) {
+	if r == nil || check.Name == "" || check.Checker == nil {
+		return
+	}
+
+	if check.Probes == 0 {
+		check.Probes = Readiness
+	}
+
+	state := &checkState{}
+	interval := r.interval(check)
+
+	r.mutex.Lock()
+	if previous, ok := r.states[check.Name]; ok && previous.cancel != nil {
+		previous.cancel()
+	}
+	r.checks[check.Name] = check
+	r.states[check.Name] = state
+
+	var ctx context.Context
+	if interval > 0 && !r.closed {
+		ctx, state.cancel = context.WithCancel(context.Background())
+	}
+	r.mutex.Unlock()
+
+	if ctx != nil {
+		go r.runInBackground(ctx, check, state, interval)
+	}
+}
+
+// Unregister removes the check with the given name from the registry.
+//
+// Parameters:
+//   - name: The name of the check to remove.
+func (r *Registry) Unregister(name string) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	if state, ok := r.states[name]; ok && state.cancel != nil {
+		state.cancel()
+	}
+	delete(r.checks, name)
+	delete(r.states, name)
+	r.mutex.Unlock()
+}
+
+// Shutdown stops the background checks. Probes of checks with an interval then
+// report their last result, which becomes stale. A nil registry is ignored.
+func (r *Registry) Shutdown() {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	r.closed = true
+	for _, state := range r.states {
+		if state.cancel != nil {
+			state.cancel()
+		}
+	}
+	r.mutex.Unlock()
+}
+
+// Names returns the names of the registered checks, sorted.
+//
+// Returns:
+//   - []string: The names of the checks.
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.RLock()
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	r.mutex.RUnlock()
+
+	slices.Sort(names)
+	return names
+}
+
+// Run runs every registered check concurrently and waits for all of them to
+// finish or time out. A nil registry reports healthy with no checks.
+//
+// Parameters:
+//   - ctx: The context for the checks.
+//
+// Returns:
+//   - Report: The overall status and the result of each check.
+func (r *Registry) Run(ctx context.Context) Report {
+	return r.run(ctx, r.checksFor(0, nil))
+}
+
+// RunProbe runs the checks of a probe concurrently, like Run.
+//
+// Parameters:
+//   - ctx: The context for the checks.
+//   - probe: The probe whose checks to run.
+//   - exclude: The names of checks not to run.
+//
+// Returns:
+//   - Report: The overall status and the result of each check that was run.
+func (r *Registry) RunProbe(ctx context.Context, probe Probe, exclude ...string) Report {
+	return r.run(ctx, r.checksFor(probe, exclude))
+}
+
+// checksFor returns the registered checks that run on a probe, or all checks if
+// probe is zero, leaving out the excluded checks.
+func (r *Registry) checksFor(probe Probe, exclude []string) []Check {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	checks := make([]Check, 0, len(r.checks))
+	for _, check := range r.checks {
+		if probe != 0 && check.Probes&probe == 0 {
+			continue
+		}
+		if slices.Contains(exclude, check.Name) {
+			continue
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// run runs checks concurrently and waits for all of them to finish or time out.
+func (r *Registry) run(ctx context.Context, checks []Check) Report {
+	report := Report{
+		Status: StatusHealthy,
+		Checks: make(map[string]CheckResult),
+	}
+	if r == nil {
+		return report
+	}
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.resultFor(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for i, check := range checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != ServiceUp && check.Critical {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// GetServiceStatuses runs every registered check and returns its status, keyed by
+// check name. It implements ServiceStatusProvider; NewGenericHandler runs the checks
+// with the context of the request instead.
+//
+// Returns:
+//   - map[string]string: ServiceUp or ServiceDown for each check.
+func (r *Registry) GetServiceStatuses() map[string]string {
+	statuses := make(map[string]string)
+	for name, result := range r.Run(context.Background()).Checks {
+		statuses[name] = result.Status
+	}
+	return statuses
+}
+
+// resultFor returns the last result of a background check, or runs the check
+// and records its result.
+func (r *Registry) resultFor(ctx context.Context, check Check) CheckResult {
+	r.mutex.RLock()
+	state := r.states[check.Name]
+	var last CheckResult
+	known := false
+	if state != nil {
+		last, known = state.result, state.known
+	}
+	r.mutex.RUnlock()
+
+	interval := r.interval(check)
+	if interval > 0 && known {
+		return r.checkStaleness(last, interval)
+	}
+
+	result := r.runCheck(ctx, check)
+	r.record(check, state, result)
+	return result
+}
+
+// checkStaleness reports the last result of a background check as down if it is
+// older than the staleness threshold.
+func (r *Registry) checkStaleness(result CheckResult, interval time.Duration) CheckResult {
+	staleAfter := r.config.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = 3 * interval
+	}
+
+	if age := r.clock.Since(result.Time); age > staleAfter {
+		result.Status = ServiceDown
+		result.Error = fmt.Sprintf("health check result is stale: last checked %s ago", age.Round(time.Millisecond))
+	}
+	return result
+}
+
+// runInBackground runs a check every interval until ctx is cancelled.
+func (r *Registry) runInBackground(ctx context.Context, check Check, state *checkState, interval time.Duration) {
+	ticker := r.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		result := r.runCheck(ctx, check)
+		if ctx.Err() != nil {
+			// The check was stopped while running; its result reflects the cancellation
+			return
+		}
+		r.record(check, state, result)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// record stores the result of a check and reports a change of its status.
+// The first result of a check is only reported if the check failed.
+func (r *Registry) record(check Check, state *checkState, result CheckResult) {
+	r.metrics.recordCheck(check.Name, result)
+
+	if state == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	if r.states[check.Name] != state {
+		// The check was unregistered or replaced while running
+		r.mutex.Unlock()
+		return
+	}
+	// A check has no status before its first result
+	from := ServiceUnknown
+	if state.known {
+		from = state.result.Status
+	}
+	state.result = result
+	state.known = true
+	r.mutex.Unlock()
+
+	if from == result.Status || (from == ServiceUnknown && result.Status == ServiceUp) {
+		return
+	}
+
+	r.metrics.recordTransition(check.Name, from, result.Status)
+
+	if result.Status == ServiceUp {
+		r.logger.Info(context.Background(), "Health check recovered",
+			zap.String("check", check.Name),
+			zap.String("from", from),
+			zap.String("to", result.Status),
+			zap.Duration("latency", result.Latency))
+	} else {
+		r.logger.Warn(context.Background(), "Health check failed",
+			zap.String("check", check.Name),
+			zap.String("from", from),
+			zap.String("to", result.Status),
+			zap.Bool("critical", check.Critical),
+			zap.String("error", result.Error),
+			zap.Duration("latency", result.Latency))
+	}
+}
+
+// lastStatuses returns the status of the last result of each check, for the status gauge.
+func (r *Registry) lastStatuses() map[string]string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	statuses := make(map[string]string, len(r.states))
+	for name, state := range r.states {
+		if state.known {
+			statuses[name] = state.result.Status
+		}
+	}
+	return statuses
+}
+
+// interval returns how often a check runs in the background, or zero if it runs on every probe.
+func (r *Registry) interval(check Check) time.Duration {
+	if check.Interval > 0 {
+		return check.Interval
+	}
+	return r.config.Interval
+}
+
+// runCheck runs a single check with its timeout. The check is reported as down
+// when its timeout elapses, even if its checker does not return.
+func (r *Registry) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, span := r.tracer.Start(ctx, "health.Check")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("health.check", check.Name),
+		attribute.Bool("health.critical", check.Critical),
+	)
+
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = r.config.Timeout
+	}
+	ctx, cancel := clock.WithTimeout(ctx, r.clock, timeout)
+	defer cancel()
+
+	start := r.clock.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				r.logger.Error(ctx, "Health check panicked",
+					zap.String("check", check.Name),
+					zap.Any("panic", p))
+				done <- errors.NewInfrastructureError(errors.InternalErrorCode, fmt.Sprintf("health check panicked: %v", p), nil)
+			}
+		}()
+		done <- check.Checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errors.NewContextError("health check timed out after "+timeout.String(), ctx.Err())
+		} else {
+			err = errors.NewContextError("health check was cancelled", ctx.Err())
+		}
+	}
+
+	result := CheckResult{
+		Status:        ServiceUp,
+		Latency:       r.clock.Since(start),
+		Critical:      check.Critical,
+		Time:          r.clock.Now(),
+		ComponentType: check.componentType(),
+	}
+	if err != nil {
+		result.Status = ServiceDown
+		result.Error = err.Error()
+		span.RecordError(err)
+	}
+	span.SetAttributes(attribute.String("health.status", result.Status))
+
+	return result
+}
@@ -0,0 +1,364 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package health
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func passing() Checker {
+	return CheckerFunc(func(ctx context.Context) error { return nil })
+}
+
+func failing(message string) Checker {
+	return CheckerFunc(func(ctx context.Context) error { return errors.New(message) })
+}
+
+func TestConfig_WithTimeout(t *testing.T) {
+	assert.Equal(t, 5*time.Second, DefaultConfig().Timeout)
+	assert.Equal(t, time.Second, DefaultConfig().WithTimeout(time.Second).Timeout)
+	assert.Equal(t, 5*time.Second, DefaultConfig().WithTimeout(0).Timeout)
+}
+
+func TestConfig_WithInterval(t *testing.T) {
+	assert.Zero(t, DefaultConfig().Interval)
+	assert.Equal(t, time.Minute, DefaultConfig().WithInterval(time.Minute).Interval)
+	assert.Zero(t, DefaultConfig().WithInterval(-time.Minute).Interval)
+	assert.Equal(t, time.Minute, DefaultConfig().WithStaleAfter(time.Minute).StaleAfter)
+	assert.Zero(t, DefaultConfig().WithStaleAfter(-time.Minute).StaleAfter)
+	assert.Equal(t, time.Minute, NewCheck("db", passing()).WithInterval(time.Minute).Interval)
+	assert.Zero(t, NewCheck("db", passing()).WithInterval(-time.Minute).Interval)
+}
+
+func TestRegistry_RegisterAndUnregister(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+
+	registry.Register(NewCheck("db", passing()))
+	registry.Register(NewCheck("cache", passing()))
+	registry.Register(NewCheck("", passing()))
+	registry.Register(NewCheck("nil", nil))
+	assert.Equal(t, []string{"cache", "db"}, registry.Names())
+
+	registry.Unregister("db")
+	assert.Equal(t, []string{"cache"}, registry.Names())
+}
+
+func TestRegistry_Run(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", passing()))
+
+	report := registry.Run(context.Background())
+	assert.Equal(t, StatusHealthy, report.Status)
+	assert.Equal(t, ServiceUp, report.Checks["db"].Status)
+	assert.Empty(t, report.Checks["db"].Error)
+
+	registry.Register(NewCheck("api", failing("connection refused")))
+
+	report = registry.Run(context.Background())
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, ServiceUp, report.Checks["db"].Status)
+	assert.Equal(t, ServiceDown, report.Checks["api"].Status)
+	assert.Equal(t, "connection refused", report.Checks["api"].Error)
+}
+
+func TestRegistry_NonCritical(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", passing()))
+	registry.Register(NewCheck("search", failing("timeout")).WithCritical(false))
+
+	report := registry.Run(context.Background())
+	assert.Equal(t, StatusHealthy, report.Status)
+	assert.True(t, report.Checks["db"].Critical)
+	assert.False(t, report.Checks["search"].Critical)
+	assert.Equal(t, ServiceDown, report.Checks["search"].Status)
+}
+
+func TestRegistry_RunProbe(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("deadlock", passing()).WithProbes(Liveness))
+	registry.Register(NewCheck("db", passing()))
+	registry.Register(NewCheck("cache", passing()).WithProbes(Readiness | Startup))
+	registry.Register(Check{Name: "migrations", Checker: passing(), Critical: true})
+
+	names := func(report Report) []string {
+		var names []string
+		for name := range report.Checks {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	assert.Equal(t, []string{"deadlock"}, names(registry.RunProbe(context.Background(), Liveness)))
+	assert.Equal(t, []string{"cache", "db", "migrations"}, names(registry.RunProbe(context.Background(), Readiness)))
+	assert.Equal(t, []string{"cache"}, names(registry.RunProbe(context.Background(), Startup)))
+	assert.Equal(t, []string{"db", "migrations"}, names(registry.RunProbe(context.Background(), Readiness, "cache")))
+	assert.Len(t, registry.Run(context.Background()).Checks, 4)
+}
+
+func TestRegistry_RunsChecksConcurrently(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+
+	// Each check waits until every check has started, which only happens if they run concurrently
+	var started sync.WaitGroup
+	started.Add(3)
+	for _, name := range []string{"a", "b", "c"} {
+		registry.Register(NewCheck(name, CheckerFunc(func(ctx context.Context) error {
+			started.Done()
+			started.Wait()
+			return nil
+		})))
+	}
+
+	report := registry.Run(context.Background())
+	assert.Equal(t, StatusHealthy, report.Status)
+	assert.Len(t, report.Checks, 3)
+}
+
+func TestRegistry_Latency(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	registry := NewRegistry(DefaultConfig(), DefaultOptions().WithClock(fake))
+	registry.Register(NewCheck("db", CheckerFunc(func(ctx context.Context) error {
+		fake.Advance(20 * time.Millisecond)
+		return nil
+	})))
+
+	report := registry.Run(context.Background())
+	assert.Equal(t, 20*time.Millisecond, report.Checks["db"].Latency)
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	registry := NewRegistry(DefaultConfig().WithTimeout(time.Second), DefaultOptions().WithClock(fake))
+
+	// The slow check ignores its context, and is reported as down when its own timeout elapses
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	registry.Register(NewCheck("slow", CheckerFunc(func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})).WithTimeout(100 * time.Millisecond))
+	registry.Register(NewCheck("fast", passing()))
+
+	done := make(chan Report, 1)
+	go func() {
+		done <- registry.Run(context.Background())
+	}()
+
+	// The timer of the slow check is set before the check starts
+	<-started
+	fake.Advance(100 * time.Millisecond)
+
+	report := <-done
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, ServiceUp, report.Checks["fast"].Status)
+	assert.Equal(t, ServiceDown, report.Checks["slow"].Status)
+	assert.Contains(t, report.Checks["slow"].Error, "timed out after 100ms")
+	assert.Equal(t, 100*time.Millisecond, report.Checks["slow"].Latency)
+}
+
+func TestRegistry_Cancelled(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report := registry.Run(ctx)
+	assert.Equal(t, ServiceDown, report.Checks["db"].Status)
+	assert.NotEmpty(t, report.Checks["db"].Error)
+}
+
+func TestRegistry_Panic(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", CheckerFunc(func(ctx context.Context) error {
+		panic("boom")
+	})))
+
+	report := registry.Run(context.Background())
+	assert.Equal(t, ServiceDown, report.Checks["db"].Status)
+	assert.Contains(t, report.Checks["db"].Error, "health check panicked: boom")
+}
+
+func TestRegistry_GetServiceStatuses(t *testing.T) {
+	registry := NewRegistry(DefaultConfig(), DefaultOptions())
+	registry.Register(NewCheck("db", passing()))
+	registry.Register(NewCheck("api", failing("down")))
+
+	assert.Equal(t, map[string]string{"db": ServiceUp, "api": ServiceDown}, registry.GetServiceStatuses())
+}
+
+func TestRegistry_Span(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	registry := NewRegistry(DefaultConfig(), DefaultOptions().WithOtelTracer(provider.Tracer("test")))
+	registry.Register(NewCheck("api", failing("down")))
+	registry.Run(context.Background())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "health.Check", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("health.check", "api"))
+	assert.Contains(t, spans[0].Attributes(), attribute.String("health.status", ServiceDown))
+}
+
+func TestRegistry_Nil(t *testing.T) {
+	var registry *Registry
+	registry.Register(NewCheck("db", passing()))
+	registry.Unregister("db")
+	assert.Nil(t, registry.Names())
+
+	report := registry.Run(context.Background())
+	assert.Equal(t, StatusHealthy, report.Status)
+	assert.Empty(t, report.Checks)
+}
+
+func TestRegistry_BackgroundChecks(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	registry := NewRegistry(DefaultConfig().WithInterval(10*time.Second), DefaultOptions().WithClock(fake))
+	defer registry.Shutdown()
+
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	healthy.Store(true)
+	registry.Register(NewCheck("db", CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		if !healthy.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})))
+
+	// The check runs once in the background when it is registered
+	require.Eventually(t, func() bool { return len(registry.lastStatuses()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Probes report the last result without running the check
+	for range 3 {
+		assert.Equal(t, StatusHealthy, registry.Run(context.Background()).Status)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	// The next run happens after the interval
+	healthy.Store(false)
+	fake.Advance(10 * time.Second)
+	require.Eventually(t, func() bool {
+		return registry.Run(context.Background()).Status == StatusDegraded
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, "connection refused", registry.Run(context.Background()).Checks["db"].Error)
+}
+
+func TestRegistry_StaleResult(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	registry := NewRegistry(DefaultConfig(), DefaultOptions().WithClock(fake))
+	registry.Register(NewCheck("db", passing()).WithInterval(10 * time.Second))
+	require.Eventually(t, func() bool { return len(registry.lastStatuses()) == 1 }, time.Second, time.Millisecond)
+
+	// Once the background checks stop, the last result becomes stale after three intervals
+	registry.Shutdown()
+	fake.Advance(30 * time.Second)
+	assert.Equal(t, ServiceUp, registry.Run(context.Background()).Checks["db"].Status)
+
+	fake.Advance(time.Second)
+	result := registry.Run(context.Background()).Checks["db"]
+	assert.Equal(t, ServiceDown, result.Status)
+	assert.Equal(t, "health check result is stale: last checked 31s ago", result.Error)
+
+	// A check registered after Shutdown does not run in the background
+	var calls atomic.Int32
+	registry.Register(NewCheck("cache", CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})).WithInterval(10 * time.Second))
+	registry.Run(context.Background())
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRegistry_StaleAfter(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	registry := NewRegistry(DefaultConfig().WithStaleAfter(5*time.Second), DefaultOptions().WithClock(fake))
+	registry.Register(NewCheck("db", passing()).WithInterval(10 * time.Second))
+	require.Eventually(t, func() bool { return len(registry.lastStatuses()) == 1 }, time.Second, time.Millisecond)
+	registry.Shutdown()
+
+	fake.Advance(6 * time.Second)
+	assert.Equal(t, ServiceDown, registry.Run(context.Background()).Checks["db"].Status)
+}
+
+func TestRegistry_Transitions(t *testing.T) {
+	core, recorded := observer.New(zapcore.InfoLevel)
+	logger := logging.NewContextLogger(zap.New(core))
+	registry := NewRegistry(DefaultConfig(), DefaultOptions().WithLogger(logger))
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	registry.Register(NewCheck("db", CheckerFunc(func(ctx context.Context) error {
+		if !healthy.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})))
+
+	// The first result is not a transition unless the check failed
+	registry.Run(context.Background())
+	assert.Zero(t, recorded.Len())
+
+	healthy.Store(false)
+	registry.Run(context.Background())
+	registry.Run(context.Background())
+	healthy.Store(true)
+	registry.Run(context.Background())
+
+	entries := recorded.AllUntimed()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "Health check failed", entries[0].Message)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, "connection refused", entries[0].ContextMap()["error"])
+	assert.Equal(t, ServiceUp, entries[0].ContextMap()["from"])
+	assert.Equal(t, "Health check recovered", entries[1].Message)
+	assert.Equal(t, ServiceDown, entries[1].ContextMap()["from"])
+}
+
+func TestRegistry_ReplaceBackgroundCheck(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	registry := NewRegistry(DefaultConfig().WithInterval(time.Second), DefaultOptions().WithClock(fake))
+	defer registry.Shutdown()
+
+	registry.Register(NewCheck("db", failing("old")))
+	require.Eventually(t, func() bool { return len(registry.lastStatuses()) == 1 }, time.Second, time.Millisecond)
+
+	// The replacement starts with no result, and its own background runs
+	registry.Register(NewCheck("db", passing()))
+	require.Eventually(t, func() bool {
+		return registry.lastStatuses()["db"] == ServiceUp
+	}, time.Second, time.Millisecond)
+
+	registry.Unregister("db")
+	assert.Empty(t, registry.lastStatuses())
+	assert.Empty(t, registry.Run(context.Background()).Checks)
+}
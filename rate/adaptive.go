@@ -0,0 +1,242 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"math"
+	"time"
+)
+
+// AdaptiveStrategy identifies how the AdaptiveConcurrency algorithm adjusts its limit.
+type AdaptiveStrategy int
+
+const (
+	// AIMD raises the limit by one after each successful request while the limit
+	// is in use, and multiplies it by BackoffRatio after each failed or slow request.
+	AIMD AdaptiveStrategy = iota
+
+	// Vegas estimates how many requests are queued downstream by comparing the
+	// latency of each request with the lowest latency observed, and raises the
+	// limit while the queue is short and lowers it once it grows. It reacts to
+	// rising latency before requests start failing.
+	Vegas
+)
+
+// String returns the name of the strategy.
+func (s AdaptiveStrategy) String() string {
+	switch s {
+	case AIMD:
+		return "aimd"
+	case Vegas:
+		return "vegas"
+	default:
+		return "unknown"
+	}
+}
+
+// AdaptiveConfig contains the parameters of the AdaptiveConcurrency algorithm.
+// The limit starts at Config.MaxConcurrent and stays between MinLimit and MaxLimit.
+type AdaptiveConfig struct {
+	// Strategy selects how the limit is adjusted.
+	Strategy AdaptiveStrategy
+
+	// MinLimit is the lowest the limit can go.
+	MinLimit int
+
+	// MaxLimit is the highest the limit can go.
+	MaxLimit int
+
+	// BackoffRatio is the factor AIMD multiplies the limit by after a failed
+	// or slow request.
+	BackoffRatio float64
+
+	// LatencyThreshold is the latency above which AIMD treats a successful
+	// request as failed. Zero means that only errors lower the limit.
+	LatencyThreshold time.Duration
+}
+
+// DefaultAdaptiveConfig returns a default configuration for the AdaptiveConcurrency algorithm.
+// The default configuration includes:
+//   - Strategy: AIMD
+//   - MinLimit: 1
+//   - MaxLimit: 1000
+//   - BackoffRatio: 0.9
+//   - LatencyThreshold: 0 (only errors lower the limit)
+//
+// Returns:
+//   - An AdaptiveConfig instance with default values.
+func DefaultAdaptiveConfig() AdaptiveConfig {
+	return AdaptiveConfig{
+		Strategy:         AIMD,
+		MinLimit:         1,
+		MaxLimit:         1000,
+		BackoffRatio:     0.9,
+		LatencyThreshold: 0,
+	}
+}
+
+// WithStrategy sets how the limit is adjusted.
+// If an unknown strategy is provided, it will be set to AIMD.
+//
+// Parameters:
+//   - strategy: The strategy used to adjust the limit.
+//
+// Returns:
+//   - A new AdaptiveConfig instance with the updated Strategy value.
+func (c AdaptiveConfig) WithStrategy(strategy AdaptiveStrategy) AdaptiveConfig {
+	if strategy < AIMD || strategy > Vegas {
+		strategy = AIMD
+	}
+	c.Strategy = strategy
+	return c
+}
+
+// WithMinLimit sets the lowest the limit can go.
+// If a non-positive value is provided, it will be set to 1.
+//
+// Parameters:
+//   - minLimit: The lowest limit.
+//
+// Returns:
+//   - A new AdaptiveConfig instance with the updated MinLimit value.
+func (c AdaptiveConfig) WithMinLimit(minLimit int) AdaptiveConfig {
+	if minLimit <= 0 {
+		minLimit = 1
+	}
+	c.MinLimit = minLimit
+	return c
+}
+
+// WithMaxLimit sets the highest the limit can go.
+// If a non-positive value is provided, it will be set to 1.
+//
+// Parameters:
+//   - maxLimit: The highest limit.
+//
+// Returns:
+//   - A new AdaptiveConfig instance with the updated MaxLimit value.
+func (c AdaptiveConfig) WithMaxLimit(maxLimit int) AdaptiveConfig {
+	if maxLimit <= 0 {
+		maxLimit = 1
+	}
+	c.MaxLimit = maxLimit
+	return c
+}
+
+// WithBackoffRatio sets the factor AIMD multiplies the limit by after a failed or slow request.
+// If a value outside (0, 1) is provided, it will be set to 0.9.
+//
+// Parameters:
+//   - backoffRatio: The factor applied to the limit.
+//
+// Returns:
+//   - A new AdaptiveConfig instance with the updated BackoffRatio value.
+func (c AdaptiveConfig) WithBackoffRatio(backoffRatio float64) AdaptiveConfig {
+	if backoffRatio <= 0 || backoffRatio >= 1 {
+		backoffRatio = 0.9
+	}
+	c.BackoffRatio = backoffRatio
+	return c
+}
+
+// WithLatencyThreshold sets the latency above which AIMD treats a request as failed.
+// If a negative value is provided, it will be set to 0, which disables the threshold.
+//
+// Parameters:
+//   - latencyThreshold: The latency above which a request counts as failed.
+//
+// Returns:
+//   - A new AdaptiveConfig instance with the updated LatencyThreshold value.
+func (c AdaptiveConfig) WithLatencyThreshold(latencyThreshold time.Duration) AdaptiveConfig {
+	if latencyThreshold < 0 {
+		latencyThreshold = 0
+	}
+	c.LatencyThreshold = latencyThreshold
+	return c
+}
+
+// limitAdjuster computes a new concurrency limit from the outcome of a request.
+// It is called with the limiter's lock held.
+type limitAdjuster interface {
+	// adjust returns the new limit after a request that ran while inFlight
+	// requests, including itself, were in flight.
+	adjust(limit, inFlight int, latency time.Duration, failed bool) int
+}
+
+// newLimitAdjuster creates the adjuster of the configured strategy.
+func newLimitAdjuster(config AdaptiveConfig) limitAdjuster {
+	if config.Strategy == Vegas {
+		return &vegasAdjuster{}
+	}
+	return &aimdAdjuster{
+		backoffRatio:     config.BackoffRatio,
+		latencyThreshold: config.LatencyThreshold,
+	}
+}
+
+// aimdAdjuster increases the limit additively and decreases it multiplicatively.
+type aimdAdjuster struct {
+	backoffRatio     float64
+	latencyThreshold time.Duration
+}
+
+func (a *aimdAdjuster) adjust(limit, inFlight int, latency time.Duration, failed bool) int {
+	if failed || (a.latencyThreshold > 0 && latency > a.latencyThreshold) {
+		return int(float64(limit) * a.backoffRatio)
+	}
+
+	// Only grow while the limit is being used, so that a quiet period does not
+	// leave a limit far above what the downstream has been shown to handle
+	if inFlight*2 >= limit {
+		return limit + 1
+	}
+	return limit
+}
+
+// vegasProbeInterval is the number of requests after which Vegas measures the
+// lowest latency again, so that it follows lasting changes in the downstream.
+const vegasProbeInterval = 1000
+
+// vegasAdjuster adjusts the limit from the estimated downstream queue.
+type vegasAdjuster struct {
+	// minLatency is the lowest latency observed, taken as the latency without queueing
+	minLatency time.Duration
+
+	// samples is the number of requests since minLatency was last reset
+	samples int
+}
+
+func (v *vegasAdjuster) adjust(limit, inFlight int, latency time.Duration, failed bool) int {
+	v.samples++
+	if v.samples >= vegasProbeInterval {
+		v.minLatency = 0
+		v.samples = 0
+	}
+	if latency > 0 && (v.minLatency == 0 || latency < v.minLatency) {
+		v.minLatency = latency
+	}
+
+	current := float64(limit)
+	step := math.Max(math.Log10(current), 1)
+	if failed {
+		return int(math.Floor(current - step))
+	}
+
+	// A limit that is not being used says nothing about the downstream's capacity
+	if inFlight*2 < limit || latency <= 0 {
+		return limit
+	}
+
+	// The number of requests waiting downstream, by Little's law
+	queue := current * (1 - float64(v.minLatency)/float64(latency))
+	switch {
+	case queue <= step:
+		return int(math.Ceil(current + 6*step))
+	case queue < 3*step:
+		return int(math.Ceil(current + step))
+	case queue > 6*step:
+		return int(math.Floor(current - step))
+	default:
+		return limit
+	}
+}
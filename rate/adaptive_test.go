@@ -0,0 +1,180 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestAIMDAdjuster(t *testing.T) {
+	adjuster := newLimitAdjuster(DefaultAdaptiveConfig().WithLatencyThreshold(100 * time.Millisecond))
+
+	// The limit grows while it is in use
+	assert.Equal(t, 11, adjuster.adjust(10, 5, time.Millisecond, false))
+	assert.Equal(t, 10, adjuster.adjust(10, 4, time.Millisecond, false))
+
+	// Failed and slow requests shrink it
+	assert.Equal(t, 9, adjuster.adjust(10, 10, time.Millisecond, true))
+	assert.Equal(t, 90, adjuster.adjust(100, 100, time.Second, false))
+}
+
+func TestVegasAdjuster(t *testing.T) {
+	adjuster := newLimitAdjuster(DefaultAdaptiveConfig().WithStrategy(Vegas))
+
+	// No queue: grow quickly
+	assert.Equal(t, 16, adjuster.adjust(10, 10, 10*time.Millisecond, false))
+
+	// A short queue: grow slowly
+	assert.Equal(t, 11, adjuster.adjust(10, 10, 12*time.Millisecond, false))
+
+	// A long queue: shrink
+	assert.Equal(t, 9, adjuster.adjust(10, 10, 40*time.Millisecond, false))
+
+	// A moderate queue: hold
+	assert.Equal(t, 10, adjuster.adjust(10, 10, 15*time.Millisecond, false))
+
+	// An unused limit is left alone, but failures always shrink it
+	assert.Equal(t, 10, adjuster.adjust(10, 2, 40*time.Millisecond, false))
+	assert.Equal(t, 9, adjuster.adjust(10, 2, 10*time.Millisecond, true))
+}
+
+func TestAdaptiveConcurrency_Execute(t *testing.T) {
+	cfg := DefaultConfig().
+		WithAlgorithm(AdaptiveConcurrency).
+		WithMaxConcurrent(10).
+		WithAdaptive(DefaultAdaptiveConfig().WithMinLimit(2).WithMaxLimit(12).WithBackoffRatio(0.5))
+	rl := NewRateLimiter(cfg, DefaultOptions().WithName("adaptive"))
+	require.NotNil(t, rl)
+	assert.Equal(t, 10, rl.ConcurrencyLimit())
+
+	// Timeouts from the downstream shed load down to the minimum
+	for i := 0; i < 5; i++ {
+		_, err := Execute(context.Background(), rl, "overloaded", func(ctx context.Context) (string, error) {
+			return "", errors.New(errors.TimeoutCode, "downstream timed out")
+		})
+		require.Error(t, err)
+	}
+	assert.Equal(t, 2, rl.ConcurrencyLimit())
+
+	// Errors that say nothing about load count as successes, which raise
+	// the limit while it is in use
+	_, err := Execute(context.Background(), rl, "invalid", func(ctx context.Context) (string, error) {
+		return "", errors.New(errors.InvalidInputCode, "bad request")
+	})
+	require.Error(t, err)
+	assert.Equal(t, 3, rl.ConcurrencyLimit())
+
+	// One request at a time does not use a limit of 3, so it stays put
+	for i := 0; i < 5; i++ {
+		_, err := Execute(context.Background(), rl, "healthy", func(ctx context.Context) (string, error) {
+			return "ok", nil
+		})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, rl.ConcurrencyLimit())
+}
+
+func TestAdaptiveConcurrency_CostAboveShrunkLimit(t *testing.T) {
+	cfg := DefaultConfig().
+		WithAlgorithm(AdaptiveConcurrency).
+		WithMaxConcurrent(10).
+		WithAdaptive(DefaultAdaptiveConfig().WithMinLimit(2).WithMaxLimit(12).WithBackoffRatio(0.5))
+	rl := NewRateLimiter(cfg, DefaultOptions().WithName("adaptive"))
+	require.NotNil(t, rl)
+
+	for i := 0; i < 5; i++ {
+		_, err := Execute(context.Background(), rl, "overloaded", func(ctx context.Context) (string, error) {
+			return "", errors.New(errors.TimeoutCode, "downstream timed out")
+		})
+		require.Error(t, err)
+	}
+	require.Equal(t, 2, rl.ConcurrencyLimit())
+
+	// With nothing in flight, a cost above the shrunk limit runs alone
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, rl.WaitN(ctx, 5))
+	assert.True(t, rl.AllowN(5))
+
+	result, err := ExecuteWithWaitN(ctx, rl, "bulk", 5, func(ctx context.Context) (string, error) {
+		// Other requests wait for it to end
+		assert.False(t, rl.Allow())
+		return "done", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "done", result)
+
+	// With a request in flight, it waits for room
+	ok, _, _ := rl.inflight.tryAcquire(1)
+	require.True(t, ok)
+	assert.False(t, rl.AllowN(5))
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	assert.ErrorIs(t, rl.WaitN(short, 5), context.DeadlineExceeded)
+}
+
+func TestAdaptiveConcurrency_Metrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	cfg := DefaultConfig().WithAlgorithm(AdaptiveConcurrency).WithMaxConcurrent(5)
+	rl := NewRateLimiter(cfg, DefaultOptions().WithName("adaptive").WithMeter(provider.Meter("test")))
+
+	_, err := Execute(context.Background(), rl, "fail", func(ctx context.Context) (string, error) {
+		return "", context.DeadlineExceeded
+	})
+	require.Error(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	gauges := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			gauge := m.Data.(metricdata.Gauge[int64])
+			require.Len(t, gauge.DataPoints, 1)
+			name, _ := gauge.DataPoints[0].Attributes.Value("rate_limiter.name")
+			assert.Equal(t, "adaptive", name.AsString())
+			gauges[m.Name] = gauge.DataPoints[0].Value
+		}
+	}
+	assert.Equal(t, int64(4), gauges["rate_limiter.concurrency.limit"])
+	assert.Equal(t, int64(0), gauges["rate_limiter.concurrency.in_flight"])
+}
+
+func TestAdaptiveConfig_WithMethods(t *testing.T) {
+	cfg := DefaultAdaptiveConfig()
+	assert.Equal(t, AIMD, cfg.Strategy)
+	assert.Equal(t, "aimd", AIMD.String())
+	assert.Equal(t, "vegas", Vegas.String())
+	assert.Equal(t, "unknown", AdaptiveStrategy(99).String())
+	assert.Equal(t, "adaptive_concurrency", AdaptiveConcurrency.String())
+
+	assert.Equal(t, Vegas, cfg.WithStrategy(Vegas).Strategy)
+	assert.Equal(t, AIMD, cfg.WithStrategy(AdaptiveStrategy(99)).Strategy)
+	assert.Equal(t, 5, cfg.WithMinLimit(5).MinLimit)
+	assert.Equal(t, 1, cfg.WithMinLimit(0).MinLimit)
+	assert.Equal(t, 50, cfg.WithMaxLimit(50).MaxLimit)
+	assert.Equal(t, 1, cfg.WithMaxLimit(-1).MaxLimit)
+	assert.Equal(t, 0.5, cfg.WithBackoffRatio(0.5).BackoffRatio)
+	assert.Equal(t, 0.9, cfg.WithBackoffRatio(1.5).BackoffRatio)
+	assert.Equal(t, time.Second, cfg.WithLatencyThreshold(time.Second).LatencyThreshold)
+	assert.Equal(t, time.Duration(0), cfg.WithLatencyThreshold(-time.Second).LatencyThreshold)
+
+	assert.Equal(t, AdaptiveConcurrency, DefaultConfig().WithAlgorithm(AdaptiveConcurrency).Algorithm)
+	assert.Equal(t, Vegas, DefaultConfig().WithAdaptive(cfg.WithStrategy(Vegas)).Adaptive.Strategy)
+
+	// The initial limit is kept within the bounds
+	limiter := newAdaptiveLimiter(100, cfg.WithMinLimit(2).WithMaxLimit(10))
+	limit, _ := limiter.current()
+	assert.Equal(t, 10, limit)
+}
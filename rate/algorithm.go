@@ -0,0 +1,319 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"math"
+	"time"
+)
+
+// Algorithm identifies a rate limiting algorithm.
+type Algorithm int
+
+const (
+	// TokenBucket refills a bucket of BurstSize tokens at RequestsPerSecond and
+	// takes one token per request. It allows bursts up to BurstSize.
+	TokenBucket Algorithm = iota
+
+	// SlidingWindowLog records the time of every request and allows
+	// RequestsPerSecond × Window requests in any Window. It is exact, but keeps
+	// one entry per allowed request.
+	SlidingWindowLog
+
+	// SlidingWindowCounter counts requests in fixed windows and estimates the
+	// number in the sliding window from the current and previous counts. It
+	// approximates SlidingWindowLog with two counters per key.
+	SlidingWindowCounter
+
+	// GCRA is the generic cell rate algorithm. It spaces requests evenly at
+	// RequestsPerSecond and tolerates bursts of up to BurstSize, with a single
+	// timestamp per key.
+	GCRA
+
+	// Concurrency limits the number of requests in flight to MaxConcurrent,
+	// regardless of how fast they arrive.
+	Concurrency
+
+	// AdaptiveConcurrency limits the number of requests in flight like
+	// Concurrency, but adjusts the limit from the latency and errors of the
+	// requests it lets through, starting from MaxConcurrent. See AdaptiveConfig.
+	AdaptiveConcurrency
+)
+
+// String returns the name of the algorithm.
+func (a Algorithm) String() string {
+	switch a {
+	case TokenBucket:
+		return "token_bucket"
+	case SlidingWindowLog:
+		return "sliding_window_log"
+	case SlidingWindowCounter:
+		return "sliding_window_counter"
+	case GCRA:
+		return "gcra"
+	case Concurrency:
+		return "concurrency"
+	case AdaptiveConcurrency:
+		return "adaptive_concurrency"
+	default:
+		return "unknown"
+	}
+}
+
+// limitsInFlight reports whether the algorithm limits requests in flight rather than their rate.
+func (a Algorithm) limitsInFlight() bool {
+	return a == Concurrency || a == AdaptiveConcurrency
+}
+
+// limitState is the in-memory state of a single key for one algorithm.
+type limitState interface {
+	// take removes n requests from the state if the limit allows them.
+	take(now time.Time, limit Limit, n int) Result
+
+	// full reports whether the state is equivalent to a new state.
+	full(now time.Time, limit Limit) bool
+}
+
+// reservableState is implemented by the states of algorithms that can reserve
+// requests ahead of time by going into debt, as TokenBucket and GCRA can.
+type reservableState interface {
+	// reserve removes n requests from the state even if the limit does not allow
+	// them yet. RetryAfter is how long until it would have allowed them.
+	reserve(now time.Time, limit Limit, n int) Result
+
+	// restore gives back n requests removed by reserve.
+	restore(now time.Time, limit Limit, n int)
+}
+
+// newLimitState creates the state of a new key for the limit's algorithm.
+func newLimitState(limit Limit, now time.Time) limitState {
+	switch limit.Algorithm {
+	case SlidingWindowLog:
+		return &windowLogState{}
+	case SlidingWindowCounter:
+		return &windowCounterState{start: windowStart(now, limit.Window)}
+	case GCRA:
+		return &gcraState{tat: now}
+	default:
+		return &tokenBucketState{tokens: float64(limit.Burst), last: now}
+	}
+}
+
+// tokenBucketState is a token bucket refilled continuously.
+type tokenBucketState struct {
+	tokens float64
+	last   time.Time
+}
+
+func (s *tokenBucketState) take(now time.Time, limit Limit, n int) Result {
+	s.tokens = refill(s.tokens, now.Sub(s.last), limit)
+	s.last = now
+
+	need := float64(n)
+	if s.tokens >= need {
+		s.tokens -= need
+		return Result{Allowed: true, Remaining: int(s.tokens)}
+	}
+
+	wait := time.Duration(math.Ceil((need - s.tokens) / limit.Rate * float64(time.Second)))
+	return Result{Allowed: false, Remaining: int(s.tokens), RetryAfter: wait}
+}
+
+func (s *tokenBucketState) reserve(now time.Time, limit Limit, n int) Result {
+	s.tokens = refill(s.tokens, now.Sub(s.last), limit) - float64(n)
+	s.last = now
+
+	if s.tokens >= 0 {
+		return Result{Allowed: true, Remaining: int(s.tokens)}
+	}
+	wait := time.Duration(math.Ceil(-s.tokens / limit.Rate * float64(time.Second)))
+	return Result{Allowed: true, RetryAfter: wait}
+}
+
+func (s *tokenBucketState) restore(now time.Time, limit Limit, n int) {
+	s.tokens = refill(s.tokens, now.Sub(s.last), limit)
+	s.tokens = math.Min(s.tokens+float64(n), float64(limit.Burst))
+	s.last = now
+}
+
+func (s *tokenBucketState) full(now time.Time, limit Limit) bool {
+	return refill(s.tokens, now.Sub(s.last), limit) >= float64(limit.Burst)
+}
+
+// refill returns the tokens in a bucket after elapsed time, capped at the burst.
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	if elapsed > 0 {
+		tokens += elapsed.Seconds() * limit.Rate
+	}
+	return math.Min(tokens, float64(limit.Burst))
+}
+
+// gcraState holds the theoretical arrival time of the next request.
+type gcraState struct {
+	tat time.Time
+}
+
+func (s *gcraState) take(now time.Time, limit Limit, n int) Result {
+	interval := time.Duration(float64(time.Second) / limit.Rate)
+	tolerance := interval * time.Duration(limit.Burst)
+
+	tat := s.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTat := tat.Add(interval * time.Duration(n))
+	allowAt := newTat.Add(-tolerance)
+	if now.Before(allowAt) {
+		return Result{
+			Allowed:    false,
+			Remaining:  int(now.Sub(tat.Add(-tolerance)) / interval),
+			RetryAfter: allowAt.Sub(now),
+		}
+	}
+
+	s.tat = newTat
+	return Result{Allowed: true, Remaining: int(now.Sub(allowAt) / interval)}
+}
+
+func (s *gcraState) reserve(now time.Time, limit Limit, n int) Result {
+	interval := time.Duration(float64(time.Second) / limit.Rate)
+	tolerance := interval * time.Duration(limit.Burst)
+
+	tat := s.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	s.tat = tat.Add(interval * time.Duration(n))
+	allowAt := s.tat.Add(-tolerance)
+	if now.Before(allowAt) {
+		return Result{Allowed: true, RetryAfter: allowAt.Sub(now)}
+	}
+	return Result{Allowed: true, Remaining: int(now.Sub(allowAt) / interval)}
+}
+
+func (s *gcraState) restore(now time.Time, limit Limit, n int) {
+	interval := time.Duration(float64(time.Second) / limit.Rate)
+	s.tat = s.tat.Add(-interval * time.Duration(n))
+}
+
+func (s *gcraState) full(now time.Time, limit Limit) bool {
+	return !s.tat.After(now)
+}
+
+// windowLogState holds the times of the requests in the current window, oldest first.
+type windowLogState struct {
+	times []time.Time
+}
+
+func (s *windowLogState) take(now time.Time, limit Limit, n int) Result {
+	s.trim(now, limit)
+
+	count := len(s.times)
+	if count+n > limit.Burst {
+		// Wait until enough of the oldest requests leave the window
+		oldest := s.times[count+n-limit.Burst-1]
+		return Result{
+			Allowed:    false,
+			Remaining:  limit.Burst - count,
+			RetryAfter: oldest.Add(limit.Window).Sub(now),
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		s.times = append(s.times, now)
+	}
+	return Result{Allowed: true, Remaining: limit.Burst - count - n}
+}
+
+func (s *windowLogState) full(now time.Time, limit Limit) bool {
+	s.trim(now, limit)
+	return len(s.times) == 0
+}
+
+// trim removes the requests that have left the window.
+func (s *windowLogState) trim(now time.Time, limit Limit) {
+	cutoff := now.Add(-limit.Window)
+	i := 0
+	for i < len(s.times) && !s.times[i].After(cutoff) {
+		i++
+	}
+	s.times = s.times[i:]
+}
+
+// windowCounterState counts the requests in the current and previous fixed windows.
+type windowCounterState struct {
+	start time.Time
+	curr  int
+	prev  int
+}
+
+func (s *windowCounterState) take(now time.Time, limit Limit, n int) Result {
+	s.advance(now, limit)
+
+	burst := float64(limit.Burst)
+	estimate := s.estimate(now, limit)
+	if estimate+float64(n) > burst {
+		return Result{
+			Allowed:    false,
+			Remaining:  max(int(burst-estimate), 0),
+			RetryAfter: s.retryAfter(now, limit, n),
+		}
+	}
+
+	s.curr += n
+	return Result{Allowed: true, Remaining: int(burst - estimate - float64(n))}
+}
+
+func (s *windowCounterState) full(now time.Time, limit Limit) bool {
+	return now.Sub(s.start) >= 2*limit.Window || (s.curr == 0 && s.prev == 0)
+}
+
+// advance moves the state to the window that contains now.
+func (s *windowCounterState) advance(now time.Time, limit Limit) {
+	current := windowStart(now, limit.Window)
+	if !current.After(s.start) {
+		return
+	}
+	if current.Sub(s.start) == limit.Window {
+		s.prev = s.curr
+	} else {
+		s.prev = 0
+	}
+	s.curr = 0
+	s.start = current
+}
+
+// estimate returns the number of requests in the sliding window ending at now,
+// weighting the previous window by how much of it the sliding window overlaps.
+func (s *windowCounterState) estimate(now time.Time, limit Limit) float64 {
+	weight := float64(limit.Window-now.Sub(s.start)) / float64(limit.Window)
+	return float64(s.prev)*weight + float64(s.curr)
+}
+
+// retryAfter returns how long until the estimate leaves room for n more requests.
+func (s *windowCounterState) retryAfter(now time.Time, limit Limit, n int) time.Duration {
+	burst := float64(limit.Burst)
+	window := float64(limit.Window)
+
+	// The offset from the start of the current window at which the request fits
+	var offset float64
+	if float64(s.curr+n) <= burst {
+		// Wait for the previous window's share to shrink
+		offset = window * (1 - (burst-float64(s.curr+n))/float64(s.prev))
+	} else {
+		// Wait for the next window, where the current count becomes the previous one
+		offset = window + window*(1-(burst-float64(n))/float64(s.curr))
+	}
+
+	wait := s.start.Add(time.Duration(math.Ceil(offset))).Sub(now)
+	return max(wait, time.Millisecond)
+}
+
+// windowStart returns the start of the fixed window that contains now,
+// with windows aligned to the Unix epoch.
+func windowStart(now time.Time, window time.Duration) time.Time {
+	micros := now.UnixMicro()
+	return time.UnixMicro(micros - micros%window.Microseconds())
+}
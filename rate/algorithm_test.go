@@ -0,0 +1,131 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlgorithm_String(t *testing.T) {
+	assert.Equal(t, "token_bucket", TokenBucket.String())
+	assert.Equal(t, "sliding_window_log", SlidingWindowLog.String())
+	assert.Equal(t, "sliding_window_counter", SlidingWindowCounter.String())
+	assert.Equal(t, "gcra", GCRA.String())
+	assert.Equal(t, "concurrency", Concurrency.String())
+	assert.Equal(t, "unknown", Algorithm(99).String())
+}
+
+func TestGCRA(t *testing.T) {
+	now := time.UnixMicro(1_000_000_000)
+	limit := Limit{Algorithm: GCRA, Rate: 10, Burst: 2}
+	state := newLimitState(limit, now)
+	assert.True(t, state.full(now, limit))
+
+	// The burst is allowed at once
+	assert.True(t, state.take(now, limit, 1).Allowed)
+	assert.True(t, state.take(now, limit, 1).Allowed)
+	assert.False(t, state.full(now, limit))
+
+	// Further requests are spaced by the emission interval
+	result := state.take(now, limit, 1)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 100*time.Millisecond, result.RetryAfter)
+
+	now = now.Add(100 * time.Millisecond)
+	assert.True(t, state.take(now, limit, 1).Allowed)
+	assert.False(t, state.take(now, limit, 1).Allowed)
+
+	now = now.Add(200 * time.Millisecond)
+	assert.True(t, state.full(now, limit))
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	now := time.UnixMicro(1_000_000_000)
+	limit := Limit{Algorithm: SlidingWindowLog, Rate: 3, Burst: 3, Window: time.Second}
+	state := newLimitState(limit, now)
+
+	assert.Equal(t, 2, state.take(now, limit, 1).Remaining)
+	now = now.Add(300 * time.Millisecond)
+	assert.True(t, state.take(now, limit, 2).Allowed)
+
+	// The window is full until the first request leaves it
+	now = now.Add(300 * time.Millisecond)
+	result := state.take(now, limit, 1)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 400*time.Millisecond, result.RetryAfter)
+
+	// Two requests need the first three to leave it
+	result = state.take(now, limit, 2)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 700*time.Millisecond, result.RetryAfter)
+
+	now = now.Add(400 * time.Millisecond)
+	result = state.take(now, limit, 1)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.False(t, state.full(now, limit))
+
+	now = now.Add(time.Second)
+	assert.True(t, state.full(now, limit))
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	start := time.UnixMicro(1_000_000_000)
+	limit := Limit{Algorithm: SlidingWindowCounter, Rate: 4, Burst: 4, Window: time.Second}
+	state := newLimitState(limit, start)
+	assert.True(t, state.full(start, limit))
+
+	// Fill the first window
+	assert.True(t, state.take(start, limit, 4).Allowed)
+	result := state.take(start.Add(500*time.Millisecond), limit, 1)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 750*time.Millisecond, result.RetryAfter)
+
+	// A quarter into the next window, three quarters of the previous count remain
+	now := start.Add(1250 * time.Millisecond)
+	result = state.take(now, limit, 1)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result = state.take(now, limit, 1)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 250*time.Millisecond, result.RetryAfter)
+
+	// The retry time is exact
+	assert.True(t, state.take(now.Add(result.RetryAfter), limit, 1).Allowed)
+
+	assert.False(t, state.full(now, limit))
+	assert.True(t, state.full(start.Add(3*time.Second), limit))
+}
+
+func TestConfig_Limit(t *testing.T) {
+	cfg := DefaultConfig().WithRequestsPerSecond(10).WithBurstSize(5)
+	assert.Equal(t, Limit{Algorithm: TokenBucket, Rate: 10, Burst: 5}, cfg.limit())
+
+	// Sliding windows allow the rate over the whole window
+	cfg = cfg.WithAlgorithm(SlidingWindowLog).WithWindow(time.Minute)
+	assert.Equal(t, Limit{Algorithm: SlidingWindowLog, Rate: 10, Burst: 600, Window: time.Minute}, cfg.limit())
+
+	cfg = cfg.WithAlgorithm(SlidingWindowCounter).WithWindow(10 * time.Millisecond)
+	assert.Equal(t, 1, cfg.limit().Burst)
+}
+
+func TestMemoryBackend_ChangingAlgorithmResetsState(t *testing.T) {
+	backend := NewMemoryBackend()
+	ctx := context.Background()
+
+	limit := Limit{Algorithm: TokenBucket, Rate: 1, Burst: 1}
+	result, _ := backend.Take(ctx, "key", limit, 1)
+	assert.True(t, result.Allowed)
+	result, _ = backend.Take(ctx, "key", limit, 1)
+	assert.False(t, result.Allowed)
+
+	limit.Algorithm = GCRA
+	result, _ = backend.Take(ctx, "key", limit, 1)
+	assert.True(t, result.Allowed)
+}
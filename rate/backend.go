@@ -0,0 +1,205 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+)
+
+// Limit describes the limit enforced on a single key.
+type Limit struct {
+	// Algorithm is the rate limiting algorithm.
+	// Backends only handle the rate-based algorithms, not Concurrency.
+	Algorithm Algorithm
+
+	// Rate is the number of requests allowed per second, used by TokenBucket and GCRA
+	Rate float64
+
+	// Burst is the capacity of the bucket for TokenBucket and GCRA, and the number
+	// of requests allowed per Window for SlidingWindowLog and SlidingWindowCounter
+	Burst int
+
+	// Window is the length of the window for SlidingWindowLog and SlidingWindowCounter
+	Window time.Duration
+}
+
+// Result is the outcome of taking tokens from a bucket.
+type Result struct {
+	// Allowed reports whether the tokens were taken
+	Allowed bool
+
+	// Remaining is the number of requests that could still be allowed right now
+	Remaining int
+
+	// RetryAfter is how long until enough tokens are available, or 0 if Allowed is true
+	RetryAfter time.Duration
+}
+
+// Backend stores the state of rate limits. Implementations must take tokens
+// atomically so that a limit can be shared by every replica of a service.
+//
+// A bucket that does not exist yet is full, so backends may discard buckets
+// that have been idle long enough to refill.
+type Backend interface {
+	// Take refills the bucket for key and removes n tokens from it if enough are
+	// available. n must not exceed limit.Burst.
+	Take(ctx context.Context, key string, limit Limit, n int) (Result, error)
+
+	// Reset refills the bucket for key to its capacity.
+	Reset(ctx context.Context, key string) error
+}
+
+// IdleEvicter is implemented by backends that discard idle buckets only when asked.
+// KeyedRateLimiter calls DeleteIdle periodically so that buckets for clients that
+// have gone away do not accumulate. Backends whose buckets expire on their own,
+// such as RedisBackend, do not need to implement it.
+type IdleEvicter interface {
+	// DeleteIdle removes the buckets that have not been used for at least idle
+	// and have refilled to their capacity, and returns how many were removed.
+	DeleteIdle(idle time.Duration) int
+}
+
+// Reserver is implemented by backends that can reserve requests ahead of time.
+// RateLimiter.Reserve uses it to schedule requests instead of rejecting them.
+// Backends that do not implement it, such as RedisBackend, only grant
+// reservations that can be used immediately, and cannot cancel them.
+type Reserver interface {
+	// Reserve removes n tokens from the bucket for key. With TokenBucket and GCRA,
+	// the tokens are removed even if they are not available yet, and RetryAfter is
+	// how long until they would have been; with the other algorithms, they are
+	// only removed if they are available now. Allowed reports whether the tokens
+	// were removed. n must not exceed limit.Burst.
+	Reserve(ctx context.Context, key string, limit Limit, n int) (Result, error)
+
+	// Cancel returns n tokens removed by Reserve to the bucket for key.
+	Cancel(ctx context.Context, key string, limit Limit, n int) error
+}
+
+// bucket is the state of a single key in a MemoryBackend.
+type bucket struct {
+	state limitState
+	last  time.Time
+	limit Limit
+}
+
+// MemoryBackend is a Backend that keeps buckets in process memory.
+// It is the default backend and limits each replica independently.
+type MemoryBackend struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	clock   clock.Clock
+}
+
+// NewMemoryBackend creates a backend that keeps buckets in process memory.
+//
+// Returns:
+//   - *MemoryBackend: A new backend with no buckets.
+func NewMemoryBackend() *MemoryBackend {
+	return NewMemoryBackendWithClock(nil)
+}
+
+// NewMemoryBackendWithClock creates a backend that keeps buckets in process
+// memory and refills them by the given clock.
+//
+// Parameters:
+//   - c: The clock used to refill buckets. If nil, the system clock is used.
+//
+// Returns:
+//   - *MemoryBackend: A new backend with no buckets.
+func NewMemoryBackendWithClock(c clock.Clock) *MemoryBackend {
+	return &MemoryBackend{
+		buckets: make(map[string]*bucket),
+		clock:   clock.OrReal(c),
+	}
+}
+
+// Take refills the bucket for key and removes n tokens from it if enough are available.
+func (b *MemoryBackend) Take(ctx context.Context, key string, limit Limit, n int) (Result, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	return b.bucketFor(key, limit, now).state.take(now, limit, n), nil
+}
+
+// Reserve removes n tokens from the bucket for key, even if they are not available
+// yet when the algorithm allows it.
+func (b *MemoryBackend) Reserve(ctx context.Context, key string, limit Limit, n int) (Result, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	bk := b.bucketFor(key, limit, now)
+	if state, ok := bk.state.(reservableState); ok {
+		return state.reserve(now, limit, n), nil
+	}
+	return bk.state.take(now, limit, n), nil
+}
+
+// Cancel returns n tokens removed by Reserve to the bucket for key.
+func (b *MemoryBackend) Cancel(ctx context.Context, key string, limit Limit, n int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bk, found := b.buckets[key]
+	if !found || bk.limit.Algorithm != limit.Algorithm {
+		return nil
+	}
+	if state, ok := bk.state.(reservableState); ok {
+		state.restore(b.clock.Now(), limit, n)
+	}
+	return nil
+}
+
+// bucketFor returns the bucket for key, creating it if it does not exist or was
+// created for another algorithm, and marks it as used.
+func (b *MemoryBackend) bucketFor(key string, limit Limit, now time.Time) *bucket {
+	bk, found := b.buckets[key]
+	if !found || bk.limit.Algorithm != limit.Algorithm {
+		bk = &bucket{state: newLimitState(limit, now)}
+		b.buckets[key] = bk
+	}
+
+	bk.last = now
+	bk.limit = limit
+	return bk
+}
+
+// Reset refills the bucket for key to its capacity.
+func (b *MemoryBackend) Reset(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.buckets, key)
+	return nil
+}
+
+// DeleteIdle removes the buckets that have not been used for at least idle and
+// have refilled to their capacity. Removing a full bucket does not change any
+// later decision, because a missing bucket is full.
+func (b *MemoryBackend) DeleteIdle(idle time.Duration) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	removed := 0
+	for key, bk := range b.buckets {
+		if now.Sub(bk.last) < idle || !bk.state.full(now, bk.limit) {
+			continue
+		}
+		delete(b.buckets, key)
+		removed++
+	}
+	return removed
+}
+
+// Len returns the number of buckets held in memory.
+func (b *MemoryBackend) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.buckets)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBackend_Take(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	backend := NewMemoryBackendWithClock(fake)
+	ctx := context.Background()
+	limit := Limit{Rate: 10, Burst: 3}
+
+	for i := 2; i >= 0; i-- {
+		result, err := backend.Take(ctx, "a", limit, 1)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+		assert.Zero(t, result.RetryAfter)
+	}
+
+	result, err := backend.Take(ctx, "a", limit, 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Greater(t, result.RetryAfter, 90*time.Millisecond)
+	assert.LessOrEqual(t, result.RetryAfter, 100*time.Millisecond)
+
+	// Buckets are independent
+	result, err = backend.Take(ctx, "b", limit, 3)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Tokens are refilled over time
+	fake.Advance(120 * time.Millisecond)
+	result, err = backend.Take(ctx, "a", limit, 1)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	// Reset refills the bucket
+	require.NoError(t, backend.Reset(ctx, "a"))
+	result, err = backend.Take(ctx, "a", limit, 3)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimiter_SharedBackend(t *testing.T) {
+	backend := NewMemoryBackend()
+	cfg := DefaultConfig().WithRequestsPerSecond(1).WithBurstSize(2)
+	options := DefaultOptions().WithName("api").WithBackend(backend)
+
+	// Two replicas with the same name share a bucket
+	replica1 := NewRateLimiter(cfg, options)
+	replica2 := NewRateLimiter(cfg, options)
+	require.NotNil(t, replica1)
+	require.NotNil(t, replica2)
+
+	assert.True(t, replica1.Allow())
+	assert.True(t, replica2.Allow())
+	assert.False(t, replica1.Allow())
+	assert.False(t, replica2.Allow())
+
+	// A limiter with another name has its own bucket
+	other := NewRateLimiter(cfg, DefaultOptions().WithName("other").WithBackend(backend))
+	assert.True(t, other.Allow())
+
+	replica1.Reset()
+	assert.True(t, replica2.Allow())
+}
+
+// failingBackend is a Backend whose operations always fail.
+type failingBackend struct{}
+
+func (failingBackend) Take(ctx context.Context, key string, limit Limit, n int) (Result, error) {
+	return Result{}, errors.New(errors.ExternalServiceErrorCode, "backend unavailable")
+}
+
+func (failingBackend) Reset(ctx context.Context, key string) error {
+	return errors.New(errors.ExternalServiceErrorCode, "backend unavailable")
+}
+
+func TestRateLimiter_BackendFailureAllowsRequests(t *testing.T) {
+	cfg := DefaultConfig().WithRequestsPerSecond(1).WithBurstSize(1)
+	rl := NewRateLimiter(cfg, DefaultOptions().WithBackend(failingBackend{}))
+	require.NotNil(t, rl)
+
+	assert.True(t, rl.Allow())
+	assert.True(t, rl.Allow())
+
+	result, err := Execute(context.Background(), rl, "test-operation", func(ctx context.Context) (string, error) {
+		return "success", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "success", result)
+
+	result, err = ExecuteWithWait(context.Background(), rl, "test-operation", func(ctx context.Context) (string, error) {
+		return "success", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "success", result)
+
+	assert.NotPanics(t, rl.Reset)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// concurrencyLimiter limits the number of requests in flight.
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	inFlight int
+	limit    int
+
+	// adjuster changes the limit after each request, or is nil for a fixed limit
+	adjuster limitAdjuster
+
+	// minLimit and maxLimit bound the limit set by the adjuster
+	minLimit int
+	maxLimit int
+
+	// released is closed and replaced whenever a request ends
+	released chan struct{}
+}
+
+// newConcurrencyLimiter creates a limiter that allows limit requests in flight.
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		limit:    max(limit, 1),
+		released: make(chan struct{}),
+	}
+}
+
+// newAdaptiveLimiter creates a limiter whose limit starts at initial and is
+// adjusted after each request as configured.
+func newAdaptiveLimiter(initial int, config AdaptiveConfig) *concurrencyLimiter {
+	minLimit := max(config.MinLimit, 1)
+	maxLimit := max(config.MaxLimit, minLimit)
+	return &concurrencyLimiter{
+		limit:    min(max(initial, minLimit), maxLimit),
+		adjuster: newLimitAdjuster(config),
+		minLimit: minLimit,
+		maxLimit: maxLimit,
+		released: make(chan struct{}),
+	}
+}
+
+// tryAcquire starts a request that takes n slots if there is room for it. It
+// returns whether the request started, how many more slots are free, and a
+// channel that is closed when a request ends, which is when a rejected request
+// may be retried.
+func (c *concurrencyLimiter) tryAcquire(n int) (bool, int, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fits(n) {
+		return false, max(c.limit-c.inFlight, 0), c.released
+	}
+	c.inFlight += n
+	return true, max(c.limit-c.inFlight, 0), nil
+}
+
+// available reports whether a request that takes n slots could start now, and
+// returns a channel that is closed when a request ends if it could not.
+func (c *concurrencyLimiter) available(n int) (bool, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fits(n) {
+		return false, c.released
+	}
+	return true, nil
+}
+
+// fits reports whether a request that takes n slots fits in the limit. The
+// caller must hold the lock. A request larger than an adaptive limit that has
+// shrunk fits when nothing is in flight, because no request would end to make
+// room for it, and the limit would never grow back.
+func (c *concurrencyLimiter) fits(n int) bool {
+	return c.inFlight == 0 || c.inFlight+n <= c.limit
+}
+
+// capacity returns the most slots the limit can ever allow at once.
+func (c *concurrencyLimiter) capacity() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.adjuster != nil {
+		return c.maxLimit
+	}
+	return c.limit
+}
+
+// current returns the limit and the number of requests in flight.
+func (c *concurrencyLimiter) current() (limit, inFlight int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit, c.inFlight
+}
+
+// release ends a request that took n slots and wakes the requests waiting for
+// room. For an adaptive limiter, the request's latency and whether it failed
+// adjust the limit.
+func (c *concurrencyLimiter) release(n int, latency time.Duration, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.adjuster != nil {
+		limit := c.adjuster.adjust(c.limit, c.inFlight, latency, failed)
+		c.limit = min(max(limit, c.minLimit), c.maxLimit)
+	}
+
+	c.inFlight -= n
+	close(c.released)
+	c.released = make(chan struct{})
+}
+
+// registerConcurrencyMetrics creates gauges that observe the limit and the
+// requests in flight of a concurrency limiter.
+//
+// Parameters:
+//   - meter: The meter used to create the gauges.
+//   - name: The name of the rate limiter, recorded as the rate_limiter.name attribute.
+//   - limiter: The limiter to observe.
+//
+// Returns:
+//   - error: An error if a gauge could not be created.
+func registerConcurrencyMetrics(meter metric.Meter, name string, limiter *concurrencyLimiter) error {
+	limitGauge, err := meter.Int64ObservableGauge(
+		"rate_limiter.concurrency.limit",
+		metric.WithDescription("Number of requests the rate limiter currently allows in flight"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create rate_limiter.concurrency.limit gauge: %w", err)
+	}
+
+	inFlightGauge, err := meter.Int64ObservableGauge(
+		"rate_limiter.concurrency.in_flight",
+		metric.WithDescription("Number of requests in flight through the rate limiter"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create rate_limiter.concurrency.in_flight gauge: %w", err)
+	}
+
+	attrs := metric.WithAttributes(attribute.String("rate_limiter.name", name))
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		limit, inFlight := limiter.current()
+		o.ObserveInt64(limitGauge, int64(limit), attrs)
+		o.ObserveInt64(inFlightGauge, int64(inFlight), attrs)
+		return nil
+	}, limitGauge, inFlightGauge)
+	if err != nil {
+		return fmt.Errorf("failed to register rate_limiter.concurrency.limit callback: %w", err)
+	}
+	return nil
+}
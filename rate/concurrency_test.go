@@ -0,0 +1,117 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrency_Execute(t *testing.T) {
+	cfg := DefaultConfig().WithAlgorithm(Concurrency).WithMaxConcurrent(1)
+	rl := NewRateLimiter(cfg, DefaultOptions().WithName("test"))
+	require.NotNil(t, rl)
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = Execute(context.Background(), rl, "slow", func(ctx context.Context) (string, error) {
+			close(started)
+			<-finish
+			return "ok", nil
+		})
+	}()
+	<-started
+
+	// The only slot is taken
+	assert.False(t, rl.Allow())
+	_, err := Execute(context.Background(), rl, "rejected", func(ctx context.Context) (string, error) {
+		return "unexpected", nil
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.New(errors.ResourceExhaustedCode, "")))
+
+	// The slot is released when the request ends, even though no time has passed
+	close(finish)
+	<-done
+	assert.True(t, rl.Allow())
+	result, err := Execute(context.Background(), rl, "next", func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+}
+
+func TestConcurrency_ExecuteWithWait(t *testing.T) {
+	cfg := DefaultConfig().WithAlgorithm(Concurrency).WithMaxConcurrent(2)
+	rl := NewRateLimiter(cfg, DefaultOptions().WithName("test"))
+
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ExecuteWithWait(context.Background(), rl, "work", func(ctx context.Context) (int, error) {
+				mu.Lock()
+				inFlight++
+				peak = max(peak, inFlight)
+				mu.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+				return 0, nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, peak)
+	assert.True(t, rl.Allow())
+}
+
+func TestConcurrency_ExecuteWithWaitCanceled(t *testing.T) {
+	cfg := DefaultConfig().WithAlgorithm(Concurrency).WithMaxConcurrent(1)
+	rl := NewRateLimiter(cfg, DefaultOptions())
+
+	ok, _, _ := rl.inflight.tryAcquire(1)
+	require.True(t, ok)
+	defer rl.inflight.release(1, 0, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := ExecuteWithWait(ctx, rl, "blocked", func(ctx context.Context) (string, error) {
+		return "unexpected", nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestConfig_AlgorithmMethods(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, TokenBucket, cfg.Algorithm)
+	assert.Equal(t, time.Second, cfg.Window)
+	assert.Equal(t, 100, cfg.MaxConcurrent)
+
+	assert.Equal(t, GCRA, cfg.WithAlgorithm(GCRA).Algorithm)
+	assert.Equal(t, TokenBucket, cfg.WithAlgorithm(Algorithm(99)).Algorithm)
+
+	assert.Equal(t, time.Minute, cfg.WithWindow(time.Minute).Window)
+	assert.Equal(t, time.Millisecond, cfg.WithWindow(0).Window)
+
+	assert.Equal(t, 5, cfg.WithMaxConcurrent(5).MaxConcurrent)
+	assert.Equal(t, 1, cfg.WithMaxConcurrent(0).MaxConcurrent)
+}
@@ -2,24 +2,36 @@
 
 // Package rate provides functionality for rate limiting to protect resources.
 //
-// This package implements a token bucket rate limiter that helps protect services
+// This package implements rate limiters that help protect services
 // and resources from being overwhelmed by too many requests. Rate limiting is
 // essential for maintaining system stability, preventing resource exhaustion,
 // and ensuring fair usage of shared resources.
 //
-// The rate limiter uses a token bucket algorithm where:
+// By default, the rate limiter uses a token bucket algorithm where:
 //   - Tokens are added to the bucket at a fixed rate (RequestsPerSecond)
 //   - Each request consumes one token
 //   - If tokens are available, the request is allowed
 //   - If no tokens are available, the request is rejected or delayed
 //   - The bucket has a maximum capacity (BurstSize) to allow for bursts of traffic
 //
+// Config.Algorithm can instead select a sliding window log, a sliding window
+// counter, GCRA for evenly spaced requests, or a limit on the number of requests
+// in flight (Concurrency). AdaptiveConcurrency adjusts that limit from the latency
+// and errors of the requests, using AIMD or Vegas, so that an overloaded service
+// sheds load. All of them share the Allow, Execute and ExecuteWithWait API.
+//
 // Key features:
 //   - Configurable requests per second and burst size
 //   - Support for blocking and non-blocking rate limiting
 //   - Integration with OpenTelemetry for tracing
 //   - Comprehensive logging of rate limiting decisions
 //   - Thread-safe implementation for concurrent use
+//   - Pluggable bucket storage: in memory by default, or shared between replicas
+//     through a Redis-protocol server with RedisBackend
+//   - Per-key limits with KeyedRateLimiter, and HTTP middleware that applies them
+//   - Weighted requests with AllowN, WaitN and ExecuteWithWaitN, and reservations
+//     made ahead of time with Reserve
+//   - A clock.Clock option, so that tests can advance time instead of waiting
 //
 // Example usage:
 //
@@ -0,0 +1,236 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/logging"
+	"github.com/abitofhelp/servicelib/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// KeyedRateLimiter limits each key, such as a client IP, API key, user or tenant,
+// with its own bucket. Every bucket uses the same algorithm and limits. The
+// Concurrency and AdaptiveConcurrency algorithms are not supported per key;
+// TokenBucket is used instead.
+//
+// Buckets are stored by the Backend under the rate limiter's name and the key.
+// With a MemoryBackend, buckets that have been idle for IdleTimeout and have
+// refilled are discarded in the background; call Shutdown to stop this.
+//
+// This implementation is thread-safe and can be used concurrently from multiple
+// goroutines.
+type KeyedRateLimiter struct {
+	name        string
+	config      Config
+	logger      *logging.ContextLogger
+	tracer      telemetry.Tracer
+	backend     Backend
+	clock       clock.Clock
+	stopCleanup chan struct{}
+}
+
+// NewKeyedRateLimiter creates a new keyed rate limiter with the specified configuration and options.
+// If the rate limiter is disabled (config.Enabled is false), nil is returned and
+// all requests are allowed.
+//
+// Parameters:
+//   - config: The configuration parameters applied to the bucket of every key.
+//   - options: Additional options for the rate limiter, such as logging, tracing and the backend.
+//
+// Returns:
+//   - A new KeyedRateLimiter instance configured according to the provided parameters.
+func NewKeyedRateLimiter(config Config, options Options) *KeyedRateLimiter {
+	if !config.Enabled {
+		if options.Logger != nil {
+			options.Logger.Info(context.Background(), "Keyed rate limiter is disabled", zap.String("name", options.Name))
+		}
+		return nil
+	}
+
+	// Use the provided logger or create a no-op logger
+	logger := options.Logger
+	if logger == nil {
+		logger = logging.NewContextLogger(zap.NewNop())
+	}
+
+	// Use the provided tracer or create a no-op tracer
+	tracer := options.Tracer
+	if tracer == nil {
+		tracer = telemetry.NewNoopTracer()
+	}
+
+	// Use the provided backend or keep the buckets in memory
+	backend := options.Backend
+	if backend == nil {
+		backend = NewMemoryBackendWithClock(options.Clock)
+	}
+
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = DefaultConfig().IdleTimeout
+	}
+
+	// Requests in flight are not tracked per key
+	if config.Algorithm.limitsInFlight() {
+		logger.Warn(context.Background(), "Keyed rate limiter does not support concurrency algorithms, using token bucket",
+			zap.String("name", options.Name),
+			zap.String("algorithm", config.Algorithm.String()))
+		config.Algorithm = TokenBucket
+	}
+
+	logger.Info(context.Background(), "Initializing keyed rate limiter",
+		zap.String("name", options.Name),
+		zap.String("algorithm", config.Algorithm.String()),
+		zap.Int("requests_per_second", config.RequestsPerSecond),
+		zap.Int("burst_size", config.BurstSize),
+		zap.Duration("idle_timeout", config.IdleTimeout))
+
+	rl := &KeyedRateLimiter{
+		name:        options.Name,
+		config:      config,
+		logger:      logger,
+		tracer:      tracer,
+		backend:     backend,
+		clock:       clock.OrReal(options.Clock),
+		stopCleanup: make(chan struct{}),
+	}
+
+	if evicter, ok := backend.(IdleEvicter); ok {
+		go rl.startCleanupTimer(evicter)
+	}
+
+	return rl
+}
+
+// Allow checks if a request for key should be allowed based on the rate limit.
+// This is a non-blocking method that immediately returns whether the request
+// is allowed or not. If the rate limiter is disabled or nil, all requests are allowed.
+//
+// Parameters:
+//   - key: The key whose bucket the request is taken from.
+//
+// Returns:
+//   - true if the request is allowed (a token was available or rate limiting is disabled).
+//   - false if the request is not allowed (no tokens were available).
+func (rl *KeyedRateLimiter) Allow(key string) bool {
+	if rl == nil {
+		// If rate limiter is disabled, allow all requests
+		return true
+	}
+
+	result, _ := rl.Take(context.Background(), key)
+	return result.Allowed
+}
+
+// Take removes a token from the bucket for key and reports the state of the bucket,
+// which is useful for setting rate limit response headers.
+// If the backend fails, the error is logged and returned together with a Result
+// that allows the request.
+//
+// Parameters:
+//   - ctx: The context for the operation, which can be used for tracing and cancellation.
+//   - key: The key whose bucket the request is taken from.
+//
+// Returns:
+//   - Result: Whether the request is allowed, the remaining tokens, and when to retry.
+//   - error: An error if the backend failed.
+func (rl *KeyedRateLimiter) Take(ctx context.Context, key string) (Result, error) {
+	if rl == nil {
+		// If rate limiter is disabled, allow all requests
+		return Result{Allowed: true}, nil
+	}
+
+	// Create a span for the rate limiter operation
+	var span telemetry.Span
+	ctx, span = rl.tracer.Start(ctx, "rate.KeyedRateLimiter.Take")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("rate_limiter.name", rl.name),
+		attribute.String("rate_limiter.key", key),
+	)
+
+	result, err := takeTokens(ctx, rl.backend, rl.logger, rl.name, rl.bucketKey(key), rl.config.limit(), 1)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	if result.Allowed {
+		span.SetAttributes(attribute.String("rate_limiter.result", "allowed"))
+	} else {
+		span.SetAttributes(attribute.String("rate_limiter.result", "rejected"))
+		rl.logger.Debug(ctx, "Rate limit exceeded for key",
+			zap.String("rate_limiter", rl.name),
+			zap.String("key", key))
+	}
+	return result, err
+}
+
+// Limit returns the limit applied to every key.
+// If the rate limiter is nil, the zero Limit is returned.
+//
+// Returns:
+//   - Limit: The algorithm, rate, burst and window of each bucket.
+func (rl *KeyedRateLimiter) Limit() Limit {
+	if rl == nil {
+		return Limit{}
+	}
+	return rl.config.limit()
+}
+
+// Reset refills the bucket for key to its capacity.
+// If the rate limiter is nil, this method does nothing.
+//
+// Parameters:
+//   - key: The key whose bucket is refilled.
+func (rl *KeyedRateLimiter) Reset(key string) {
+	if rl == nil {
+		return
+	}
+
+	if err := rl.backend.Reset(context.Background(), rl.bucketKey(key)); err != nil {
+		rl.logger.Warn(context.Background(), "Failed to reset rate limiter",
+			zap.String("rate_limiter", rl.name),
+			zap.String("key", key),
+			zap.Error(err))
+	}
+}
+
+// Shutdown stops discarding idle buckets in the background.
+// It should be called once when the rate limiter is no longer needed.
+// If the rate limiter is nil, this method does nothing.
+func (rl *KeyedRateLimiter) Shutdown() {
+	if rl == nil {
+		return
+	}
+
+	close(rl.stopCleanup)
+	rl.logger.Info(context.Background(), "Keyed rate limiter shut down", zap.String("name", rl.name))
+}
+
+// bucketKey returns the backend key of the bucket for key.
+func (rl *KeyedRateLimiter) bucketKey(key string) string {
+	return rl.name + ":" + key
+}
+
+// startCleanupTimer discards idle buckets every IdleTimeout until Shutdown is called.
+func (rl *KeyedRateLimiter) startCleanupTimer(evicter IdleEvicter) {
+	ticker := rl.clock.NewTicker(rl.config.IdleTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			if removed := evicter.DeleteIdle(rl.config.IdleTimeout); removed > 0 {
+				rl.logger.Debug(context.Background(), "Discarded idle rate limiter buckets",
+					zap.String("rate_limiter", rl.name),
+					zap.Int("removed", removed))
+			}
+		case <-rl.stopCleanup:
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyedRateLimiter_Allow(t *testing.T) {
+	cfg := DefaultConfig().WithRequestsPerSecond(10).WithBurstSize(2)
+	rl := NewKeyedRateLimiter(cfg, DefaultOptions().WithName("api"))
+	require.NotNil(t, rl)
+	defer rl.Shutdown()
+
+	// Each key has its own bucket
+	assert.True(t, rl.Allow("alice"))
+	assert.True(t, rl.Allow("alice"))
+	assert.False(t, rl.Allow("alice"))
+	assert.True(t, rl.Allow("bob"))
+
+	result, err := rl.Take(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+
+	rl.Reset("alice")
+	assert.True(t, rl.Allow("alice"))
+
+	assert.Equal(t, Limit{Rate: 10, Burst: 2}, rl.Limit())
+}
+
+func TestKeyedRateLimiter_EvictsIdleBuckets(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	backend := NewMemoryBackendWithClock(fake)
+	cfg := DefaultConfig().WithRequestsPerSecond(100).WithBurstSize(1).WithIdleTimeout(20 * time.Millisecond)
+	rl := NewKeyedRateLimiter(cfg, DefaultOptions().WithBackend(backend).WithClock(fake))
+	require.NotNil(t, rl)
+	defer rl.Shutdown()
+
+	for _, key := range []string{"a", "b", "c"} {
+		assert.True(t, rl.Allow(key))
+	}
+	assert.Equal(t, 3, backend.Len())
+
+	// Wait for the cleanup ticker to start, then let the buckets go idle
+	fake.BlockUntil(1)
+	fake.Advance(20 * time.Millisecond)
+	assert.Eventually(t, func() bool { return backend.Len() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestMemoryBackend_DeleteIdleKeepsUnrefilledBuckets(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	backend := NewMemoryBackendWithClock(fake)
+	ctx := context.Background()
+
+	_, err := backend.Take(ctx, "slow", Limit{Rate: 0.1, Burst: 1}, 1)
+	require.NoError(t, err)
+	_, err = backend.Take(ctx, "fast", Limit{Rate: 1000, Burst: 1}, 1)
+	require.NoError(t, err)
+
+	fake.Advance(10 * time.Millisecond)
+
+	// The slow bucket is idle but still empty, so dropping it would allow an extra request
+	assert.Equal(t, 1, backend.DeleteIdle(5*time.Millisecond))
+	assert.Equal(t, 1, backend.Len())
+	assert.Equal(t, 0, backend.DeleteIdle(time.Hour))
+}
+
+func TestKeyedRateLimiter_Disabled(t *testing.T) {
+	rl := NewKeyedRateLimiter(DefaultConfig().WithEnabled(false), DefaultOptions())
+	assert.Nil(t, rl)
+
+	assert.NotPanics(t, func() {
+		assert.True(t, rl.Allow("alice"))
+		result, err := rl.Take(context.Background(), "alice")
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, Limit{}, rl.Limit())
+		rl.Reset("alice")
+		rl.Shutdown()
+	})
+}
+
+func TestKeyedRateLimiter_BackendFailure(t *testing.T) {
+	rl := NewKeyedRateLimiter(DefaultConfig().WithBurstSize(1), DefaultOptions().WithBackend(failingBackend{}))
+	require.NotNil(t, rl)
+	defer rl.Shutdown()
+
+	result, err := rl.Take(context.Background(), "alice")
+	assert.Error(t, err)
+	assert.True(t, result.Allowed)
+	assert.True(t, rl.Allow("alice"))
+	assert.NotPanics(t, func() { rl.Reset("alice") })
+}
+
+func TestConfig_WithIdleTimeout(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, 10*time.Minute, cfg.IdleTimeout)
+	assert.Equal(t, time.Minute, cfg.WithIdleTimeout(time.Minute).IdleTimeout)
+	assert.Equal(t, time.Second, cfg.WithIdleTimeout(0).IdleTimeout)
+}
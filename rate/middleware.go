@@ -0,0 +1,153 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	authmiddleware "github.com/abitofhelp/servicelib/auth/middleware"
+	svcctx "github.com/abitofhelp/servicelib/context" // Aliased to avoid name collision
+	"github.com/abitofhelp/servicelib/errors"
+	errhttp "github.com/abitofhelp/servicelib/errors/http"
+	"github.com/abitofhelp/servicelib/middleware"
+)
+
+// KeyFunc extracts the rate limiting key from a request.
+// It returns false if the request has no key, in which case it is not limited.
+type KeyFunc func(r *http.Request) (string, bool)
+
+// KeyByIP returns the client IP address of the request, taken from RemoteAddr.
+// Headers such as X-Forwarded-For are not trusted; behind a proxy, use KeyByHeader
+// with the header the proxy sets, or rewrite RemoteAddr before this middleware.
+func KeyByIP(r *http.Request) (string, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if host == "" {
+		return "", false
+	}
+	return "ip:" + host, true
+}
+
+// KeyByUserID returns the ID of the authenticated user, as set by the auth middleware.
+func KeyByUserID(r *http.Request) (string, bool) {
+	userID, ok := authmiddleware.GetUserID(r.Context())
+	if !ok || userID == "" {
+		return "", false
+	}
+	return "user:" + userID, true
+}
+
+// KeyByTenantID returns the tenant ID stored in the request context.
+func KeyByTenantID(r *http.Request) (string, bool) {
+	tenantID := svcctx.GetTenantID(r.Context())
+	if tenantID == "" {
+		return "", false
+	}
+	return "tenant:" + tenantID, true
+}
+
+// KeyByHeader returns a KeyFunc that uses the value of a request header, such as an API key.
+//
+// Parameters:
+//   - header: The name of the header, such as "X-API-Key".
+//
+// Returns:
+//   - KeyFunc: A function that returns the header value, or false if the header is missing.
+func KeyByHeader(header string) KeyFunc {
+	prefix := strings.ToLower(header) + ":"
+	return func(r *http.Request) (string, bool) {
+		value := r.Header.Get(header)
+		if value == "" {
+			return "", false
+		}
+		return prefix + value, true
+	}
+}
+
+// KeyByFirst returns a KeyFunc that tries each KeyFunc in order and uses the first key found.
+// For example, KeyByFirst(KeyByUserID, KeyByIP) limits authenticated users by user ID
+// and anonymous clients by IP address.
+//
+// Parameters:
+//   - keyFuncs: The functions to try, in order.
+//
+// Returns:
+//   - KeyFunc: A function that returns the first key found.
+func KeyByFirst(keyFuncs ...KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		for _, keyFunc := range keyFuncs {
+			if key, ok := keyFunc(r); ok {
+				return key, true
+			}
+		}
+		return "", false
+	}
+}
+
+// Middleware returns an HTTP middleware that limits requests per key.
+//
+// Every limited response carries the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers from the IETF RateLimit header fields draft. Requests
+// over the limit are rejected with 429 Too Many Requests, a Retry-After header
+// and a JSON error body. Requests for which keyFunc finds no key are passed through.
+//
+// Parameters:
+//   - limiter: The keyed rate limiter. If nil, requests are passed through unchanged.
+//   - keyFunc: The function that extracts the key from a request. If nil, KeyByIP is used.
+//
+// Returns:
+//   - middleware.Middleware: A middleware that applies the rate limit.
+func Middleware(limiter *KeyedRateLimiter, keyFunc KeyFunc) middleware.Middleware {
+	if keyFunc == nil {
+		keyFunc = KeyByIP
+	}
+
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := keyFunc(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Backend errors are logged by Take, which allows the request
+			result, _ := limiter.Take(r.Context(), key)
+			limit := limiter.Limit()
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+
+			if result.Allowed {
+				refill := time.Duration(float64(limit.Burst-result.Remaining) / limit.Rate * float64(time.Second))
+				header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(refill)))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			retryAfter := strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1))
+			header.Set("RateLimit-Reset", retryAfter)
+			header.Set("Retry-After", retryAfter)
+			errhttp.WriteError(w, errors.New(errors.ResourceExhaustedCode, "rate limit exceeded"))
+		})
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds.
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authmiddleware "github.com/abitofhelp/servicelib/auth/middleware"
+	svcctx "github.com/abitofhelp/servicelib/context" // Aliased to avoid name collision
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	rl := NewKeyedRateLimiter(DefaultConfig().WithRequestsPerSecond(1).WithBurstSize(2), DefaultOptions().WithName("http"))
+	require.NotNil(t, rl)
+	defer rl.Shutdown()
+
+	calls := 0
+	handler := Middleware(rl, KeyByHeader("X-API-Key"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := request("key-1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rr.Header().Get("RateLimit-Reset"))
+
+	rr = request("key-1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", rr.Header().Get("RateLimit-Reset"))
+
+	rr = request("key-1")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Contains(t, body["message"], "rate limit exceeded")
+	assert.Equal(t, "RESOURCE_EXHAUSTED", body["code"])
+
+	// Other keys are limited separately, and requests without a key are not limited
+	assert.Equal(t, http.StatusOK, request("key-2").Code)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, request("").Code)
+	}
+	assert.Equal(t, 6, calls)
+}
+
+func TestMiddleware_NilLimiter(t *testing.T) {
+	handler := Middleware(nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+}
+
+func TestKeyFuncs(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	key, ok := KeyByIP(req)
+	assert.True(t, ok)
+	assert.Equal(t, "ip:192.0.2.1", key)
+
+	req.RemoteAddr = "192.0.2.1"
+	key, ok = KeyByIP(req)
+	assert.True(t, ok)
+	assert.Equal(t, "ip:192.0.2.1", key)
+
+	req.RemoteAddr = ""
+	_, ok = KeyByIP(req)
+	assert.False(t, ok)
+
+	_, ok = KeyByUserID(req)
+	assert.False(t, ok)
+	_, ok = KeyByTenantID(req)
+	assert.False(t, ok)
+
+	ctx := authmiddleware.WithUserID(context.Background(), "user-1")
+	ctx = svcctx.WithTenantID(ctx, "tenant-1")
+	req = req.WithContext(ctx)
+
+	key, ok = KeyByUserID(req)
+	assert.True(t, ok)
+	assert.Equal(t, "user:user-1", key)
+
+	key, ok = KeyByTenantID(req)
+	assert.True(t, ok)
+	assert.Equal(t, "tenant:tenant-1", key)
+
+	// KeyByFirst falls back to later functions
+	keyFunc := KeyByFirst(KeyByHeader("X-API-Key"), KeyByTenantID)
+	key, ok = keyFunc(req)
+	assert.True(t, ok)
+	assert.Equal(t, "tenant:tenant-1", key)
+
+	req.Header.Set("X-API-Key", "secret")
+	key, ok = keyFunc(req)
+	assert.True(t, ok)
+	assert.Equal(t, "x-api-key:secret", key)
+
+	_, ok = KeyByFirst()(req)
+	assert.False(t, ok)
+}
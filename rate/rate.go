@@ -2,32 +2,43 @@
 
 // Package rate provides functionality for rate limiting to protect resources.
 //
-// This package implements a token bucket rate limiter to protect resources
-// from being overwhelmed by too many requests.
+// This package implements token bucket, sliding window, GCRA and concurrency
+// rate limiters to protect resources from being overwhelmed by too many
+// requests. Rate state is kept by a Backend, either in process memory or on a
+// shared RESP server.
 package rate
 
 import (
 	"context"
 	"fmt"
-	"sync"
+	"math"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/abitofhelp/servicelib/errors"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/abitofhelp/servicelib/telemetry"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // Config contains rate limiter configuration parameters.
 // It defines the behavior of the rate limiter, including whether it's enabled,
-// how many requests are allowed per second, and the maximum burst size.
+// the algorithm, how many requests are allowed per second, and the maximum burst size.
 type Config struct {
 	// Enabled determines if the rate limiter is enabled.
 	// If set to false, all requests will be allowed without rate limiting.
 	Enabled bool
 
+	// Algorithm selects how requests are limited.
+	// RequestsPerSecond and BurstSize apply to TokenBucket and GCRA,
+	// RequestsPerSecond and Window to the sliding window algorithms,
+	// MaxConcurrent to Concurrency, and MaxConcurrent and Adaptive to
+	// AdaptiveConcurrency.
+	Algorithm Algorithm
+
 	// RequestsPerSecond is the number of requests allowed per second.
 	// This determines the rate at which tokens are added to the bucket.
 	RequestsPerSecond int
@@ -35,21 +46,47 @@ type Config struct {
 	// BurstSize is the maximum number of requests allowed in a burst.
 	// This determines the maximum capacity of the token bucket.
 	BurstSize int
+
+	// Window is the length of the window of the sliding window algorithms,
+	// which allow RequestsPerSecond × Window requests in any Window.
+	Window time.Duration
+
+	// MaxConcurrent is the maximum number of requests in flight with the
+	// Concurrency algorithm, and the initial limit of AdaptiveConcurrency.
+	MaxConcurrent int
+
+	// Adaptive configures how AdaptiveConcurrency adjusts its limit.
+	Adaptive AdaptiveConfig
+
+	// IdleTimeout is how long a KeyedRateLimiter keeps the bucket of a key
+	// that is not used. Buckets are only discarded once they have refilled,
+	// so this never lets a key exceed its limit.
+	IdleTimeout time.Duration
 }
 
 // DefaultConfig returns a default rate limiter configuration.
 // The default configuration includes:
 //   - Enabled: true (rate limiting is enabled)
+//   - Algorithm: TokenBucket
 //   - RequestsPerSecond: 100 (100 requests allowed per second)
 //   - BurstSize: 50 (maximum of 50 requests allowed in a burst)
+//   - Window: 1 second (for the sliding window algorithms)
+//   - MaxConcurrent: 100 (for the Concurrency algorithm)
+//   - Adaptive: DefaultAdaptiveConfig() (for the AdaptiveConcurrency algorithm)
+//   - IdleTimeout: 10 minutes (idle per-key buckets are discarded after 10 minutes)
 //
 // Returns:
 //   - A Config instance with default values.
 func DefaultConfig() Config {
 	return Config{
 		Enabled:           true,
+		Algorithm:         TokenBucket,
 		RequestsPerSecond: 100,
 		BurstSize:         50,
+		Window:            time.Second,
+		MaxConcurrent:     100,
+		Adaptive:          DefaultAdaptiveConfig(),
+		IdleTimeout:       10 * time.Minute,
 	}
 }
 
@@ -66,6 +103,22 @@ func (c Config) WithEnabled(enabled bool) Config {
 	return c
 }
 
+// WithAlgorithm sets the rate limiting algorithm.
+// If an unknown algorithm is provided, it will be set to TokenBucket.
+//
+// Parameters:
+//   - algorithm: The algorithm used to limit requests.
+//
+// Returns:
+//   - A new Config instance with the updated Algorithm value.
+func (c Config) WithAlgorithm(algorithm Algorithm) Config {
+	if algorithm < TokenBucket || algorithm > AdaptiveConcurrency {
+		algorithm = TokenBucket
+	}
+	c.Algorithm = algorithm
+	return c
+}
+
 // WithRequestsPerSecond sets the number of requests allowed per second.
 // This determines the rate at which tokens are added to the bucket.
 // If a non-positive value is provided, it will be set to 1.
@@ -100,6 +153,68 @@ func (c Config) WithBurstSize(burstSize int) Config {
 	return c
 }
 
+// WithWindow sets the length of the window of the sliding window algorithms.
+// If a value shorter than 1 millisecond is provided, it will be set to 1 millisecond.
+//
+// Parameters:
+//   - window: The length of the window.
+//
+// Returns:
+//   - A new Config instance with the updated Window value.
+func (c Config) WithWindow(window time.Duration) Config {
+	if window < time.Millisecond {
+		window = time.Millisecond
+	}
+	c.Window = window
+	return c
+}
+
+// WithMaxConcurrent sets the maximum number of requests in flight with the Concurrency algorithm,
+// which is also the initial limit of the AdaptiveConcurrency algorithm.
+// If a non-positive value is provided, it will be set to 1.
+//
+// Parameters:
+//   - maxConcurrent: The maximum number of requests in flight.
+//
+// Returns:
+//   - A new Config instance with the updated MaxConcurrent value.
+func (c Config) WithMaxConcurrent(maxConcurrent int) Config {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	c.MaxConcurrent = maxConcurrent
+	return c
+}
+
+// WithAdaptive sets how the AdaptiveConcurrency algorithm adjusts its limit.
+//
+// Parameters:
+//   - adaptive: The configuration of the adaptive limit.
+//
+// Returns:
+//   - A new Config instance with the updated Adaptive value.
+func (c Config) WithAdaptive(adaptive AdaptiveConfig) Config {
+	c.Adaptive = adaptive
+	return c
+}
+
+// WithIdleTimeout sets how long a KeyedRateLimiter keeps the bucket of an unused key.
+// Idle buckets are checked at this interval, so it also sets how often the check runs.
+// If a non-positive value is provided, it will be set to 1 second.
+//
+// Parameters:
+//   - idleTimeout: How long an unused bucket is kept.
+//
+// Returns:
+//   - A new Config instance with the updated IdleTimeout value.
+func (c Config) WithIdleTimeout(idleTimeout time.Duration) Config {
+	if idleTimeout <= 0 {
+		idleTimeout = time.Second
+	}
+	c.IdleTimeout = idleTimeout
+	return c
+}
+
 // Options contains additional options for the rate limiter.
 // These options are not directly related to the rate limiting behavior itself,
 // but provide additional functionality like logging, tracing, and identification.
@@ -114,7 +229,21 @@ type Options struct {
 
 	// Name is the name of the rate limiter.
 	// This is useful for identifying the rate limiter in logs and traces.
+	// It is also the key of the rate limiter's bucket in the backend, so
+	// replicas that share a backend and a name share a limit.
 	Name string
+
+	// Backend stores the rate limiter's token bucket.
+	// If nil, a MemoryBackend is used and each replica is limited independently.
+	Backend Backend
+
+	// Meter is used to create metrics for the concurrency algorithms.
+	// If nil, no metrics are recorded.
+	Meter metric.Meter
+
+	// Clock is used to refill the buckets of the default MemoryBackend, to wait
+	// for tokens and to measure requests. If nil, the system clock will be used.
+	Clock clock.Clock
 }
 
 // DefaultOptions returns default options for rate limiter operations.
@@ -122,14 +251,16 @@ type Options struct {
 //   - No logger (a no-op logger will be used)
 //   - A no-op tracer (no OpenTelemetry integration)
 //   - Name: "default"
+//   - No backend (an in-memory backend will be used)
 //
 // Returns:
 //   - An Options instance with default values.
 func DefaultOptions() Options {
 	return Options{
-		Logger: nil,
-		Tracer: telemetry.NewNoopTracer(),
-		Name:   "default",
+		Logger:  nil,
+		Tracer:  telemetry.NewNoopTracer(),
+		Name:    "default",
+		Backend: nil,
 	}
 }
 
@@ -176,24 +307,74 @@ func (o Options) WithName(name string) Options {
 	return o
 }
 
-// RateLimiter implements a token bucket rate limiter to protect resources
+// WithBackend sets the backend that stores the rate limiter's token bucket.
+// Use a RedisBackend to enforce one limit across all replicas of a service.
+//
+// Parameters:
+//   - backend: The backend that stores the token bucket.
+//
+// Returns:
+//   - A new Options instance with the updated Backend value.
+func (o Options) WithBackend(backend Backend) Options {
+	o.Backend = backend
+	return o
+}
+
+// WithMeter sets the OpenTelemetry meter used to record rate limiter metrics.
+// With the Concurrency and AdaptiveConcurrency algorithms, the rate limiter
+// reports its current limit and the requests in flight as gauges, each with a
+// rate_limiter.name attribute.
+//
+// Parameters:
+//   - meter: An OpenTelemetry metric.Meter instance.
+//
+// Returns:
+//   - A new Options instance with the updated Meter value.
+func (o Options) WithMeter(meter metric.Meter) Options {
+	o.Meter = meter
+	return o
+}
+
+// WithClock sets the clock of the rate limiter.
+// Tests use it to pass a clock.Fake, so that buckets refill without real time passing.
+// A Backend passed with WithBackend keeps its own clock; see NewMemoryBackendWithClock.
+//
+// Parameters:
+//   - c: The clock used to refill buckets, wait for tokens and measure requests.
+//
+// Returns:
+//   - A new Options instance with the updated Clock value.
+func (o Options) WithClock(c clock.Clock) Options {
+	o.Clock = c
+	return o
+}
+
+// RateLimiter implements a rate limiter to protect resources
 // from being overwhelmed by too many requests.
 //
-// The token bucket algorithm works by maintaining a bucket of tokens that are
-// added at a fixed rate (RequestsPerSecond). Each request consumes one token.
-// If tokens are available, the request is allowed; otherwise, it is rejected
-// or delayed until tokens become available.
+// By default it uses the token bucket algorithm, which works by maintaining a
+// bucket of tokens that are added at a fixed rate (RequestsPerSecond). Each
+// request consumes one token. If tokens are available, the request is allowed;
+// otherwise, it is rejected or delayed until tokens become available. Other
+// algorithms are selected with Config.Algorithm and share the same API.
+//
+// The state of the rate-based algorithms is stored by a Backend. If the backend
+// fails, requests are allowed so that an unavailable backend does not take the
+// service down with it. The Concurrency and AdaptiveConcurrency algorithms count
+// requests in flight in this process only.
 //
 // This implementation is thread-safe and can be used concurrently from multiple
 // goroutines.
 type RateLimiter struct {
-	name           string
-	config         Config
-	logger         *logging.ContextLogger
-	tracer         telemetry.Tracer
-	tokens         int
-	lastRefillTime time.Time
-	mutex          sync.Mutex
+	name    string
+	config  Config
+	logger  *logging.ContextLogger
+	tracer  telemetry.Tracer
+	backend Backend
+	clock   clock.Clock
+
+	// inflight limits requests in flight for the concurrency algorithms, or is nil
+	inflight *concurrencyLimiter
 }
 
 // NewRateLimiter creates a new rate limiter with the specified configuration and options.
@@ -226,26 +407,51 @@ func NewRateLimiter(config Config, options Options) *RateLimiter {
 		tracer = telemetry.NewNoopTracer()
 	}
 
+	// Use the provided backend or keep the bucket in memory
+	backend := options.Backend
+	if backend == nil {
+		backend = NewMemoryBackendWithClock(options.Clock)
+	}
+
 	logger.Info(context.Background(), "Initializing rate limiter",
 		zap.String("name", options.Name),
+		zap.String("algorithm", config.Algorithm.String()),
 		zap.Int("requests_per_second", config.RequestsPerSecond),
 		zap.Int("burst_size", config.BurstSize))
 
-	return &RateLimiter{
-		name:           options.Name,
-		config:         config,
-		logger:         logger,
-		tracer:         tracer,
-		tokens:         config.BurstSize,
-		lastRefillTime: time.Now(),
-		mutex:          sync.Mutex{},
+	rl := &RateLimiter{
+		name:    options.Name,
+		config:  config,
+		logger:  logger,
+		tracer:  tracer,
+		backend: backend,
+		clock:   clock.OrReal(options.Clock),
+	}
+	switch config.Algorithm {
+	case Concurrency:
+		rl.inflight = newConcurrencyLimiter(config.MaxConcurrent)
+	case AdaptiveConcurrency:
+		rl.inflight = newAdaptiveLimiter(config.MaxConcurrent, config.Adaptive)
 	}
+
+	if rl.inflight != nil && options.Meter != nil {
+		if err := registerConcurrencyMetrics(options.Meter, options.Name, rl.inflight); err != nil {
+			logger.Warn(context.Background(), "Failed to create rate limiter metrics, continuing without metrics",
+				zap.String("name", options.Name),
+				zap.Error(err))
+		}
+	}
+	return rl
 }
 
 // Allow checks if a request should be allowed based on the rate limit.
 // This is a non-blocking method that immediately returns whether the request
 // is allowed or not. If the rate limiter is disabled or nil, all requests are allowed.
 //
+// With the concurrency algorithms, Allow only reports whether a request could start
+// now, because there is no way to signal its end; use Execute or ExecuteWithWait
+// to hold a slot while the request runs.
+//
 // The method is thread-safe and can be called concurrently from multiple goroutines.
 //
 // Returns:
@@ -257,19 +463,7 @@ func (rl *RateLimiter) Allow() bool {
 		return true
 	}
 
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
-
-	// Refill tokens based on time elapsed since last refill
-	rl.refillTokens()
-
-	// Check if we have tokens available
-	if rl.tokens > 0 {
-		rl.tokens--
-		return true
-	}
-
-	return false
+	return rl.AllowN(1)
 }
 
 // Execute executes a function with rate limiting.
@@ -307,12 +501,17 @@ func Execute[T any](ctx context.Context, rl *RateLimiter, operation string, fn f
 	span.SetAttributes(
 		attribute.String("rate_limiter.name", rl.name),
 		attribute.String("rate_limiter.operation", operation),
+		attribute.String("rate_limiter.algorithm", rl.config.Algorithm.String()),
 		attribute.Int("rate_limiter.requests_per_second", rl.config.RequestsPerSecond),
 		attribute.Int("rate_limiter.burst_size", rl.config.BurstSize),
 	)
 
 	// Check if the request is allowed
-	if !rl.Allow() {
+	taken, backendErr := rl.acquire(ctx, 1)
+	if backendErr != nil {
+		span.RecordError(backendErr)
+	}
+	if !taken.Allowed {
 		err := errors.New(errors.ResourceExhaustedCode, fmt.Sprintf("rate limit exceeded for %s", rl.name))
 		rl.logger.Warn(ctx, "Rate limit exceeded, rejecting request",
 			zap.String("rate_limiter", rl.name),
@@ -327,9 +526,9 @@ func Execute[T any](ctx context.Context, rl *RateLimiter, operation string, fn f
 	span.SetAttributes(attribute.String("rate_limiter.result", "allowed"))
 
 	// Execute the function
-	startTime := time.Now()
-	result, err := fn(ctx)
-	duration := time.Since(startTime)
+	startTime := rl.clock.Now()
+	result, err := run(ctx, taken, fn)
+	duration := rl.clock.Since(startTime)
 
 	span.SetAttributes(
 		attribute.Bool("rate_limiter.success", err == nil),
@@ -363,6 +562,29 @@ func Execute[T any](ctx context.Context, rl *RateLimiter, operation string, fn f
 //   - The result of the function execution, or the zero value of T if the context is canceled.
 //   - An error if the context is canceled or if the function returns an error.
 func ExecuteWithWait[T any](ctx context.Context, rl *RateLimiter, operation string, fn func(ctx context.Context) (T, error)) (T, error) {
+	return ExecuteWithWaitN(ctx, rl, operation, 1, fn)
+}
+
+// ExecuteWithWaitN executes a function that costs n requests with rate limiting,
+// waiting if necessary. It behaves like ExecuteWithWait, but takes n tokens, or
+// n slots with the concurrency algorithms, so that expensive operations such as
+// bulk exports count for more than cheap ones. A cost below 1 is treated as 1.
+//
+// Type Parameters:
+//   - T: The return type of the function to execute.
+//
+// Parameters:
+//   - ctx: The context for the operation. Can be used to cancel the operation.
+//   - rl: The rate limiter to use. If nil, the function is executed without rate limiting.
+//   - operation: A name for the operation being performed, used in logs and traces.
+//   - n: The cost of the operation, in requests.
+//   - fn: The function to execute when enough tokens become available.
+//
+// Returns:
+//   - The result of the function execution, or the zero value of T if the context is canceled.
+//   - An error if the context is canceled, if n exceeds what the limit can ever allow at once,
+//     or if the function returns an error.
+func ExecuteWithWaitN[T any](ctx context.Context, rl *RateLimiter, operation string, n int, fn func(ctx context.Context) (T, error)) (T, error) {
 	var zero T
 
 	if rl == nil {
@@ -375,37 +597,51 @@ func ExecuteWithWait[T any](ctx context.Context, rl *RateLimiter, operation stri
 	ctx, span = rl.tracer.Start(ctx, "rate.ExecuteWithWait")
 	defer span.End()
 
+	n = max(n, 1)
 	span.SetAttributes(
 		attribute.String("rate_limiter.name", rl.name),
 		attribute.String("rate_limiter.operation", operation),
+		attribute.String("rate_limiter.algorithm", rl.config.Algorithm.String()),
 		attribute.Int("rate_limiter.requests_per_second", rl.config.RequestsPerSecond),
 		attribute.Int("rate_limiter.burst_size", rl.config.BurstSize),
+		attribute.Int("rate_limiter.cost", n),
 	)
 
+	// A cost above the capacity would wait forever
+	if err := rl.checkCost(n); err != nil {
+		span.SetAttributes(attribute.String("rate_limiter.result", "rejected"))
+		span.RecordError(err)
+		return zero, err
+	}
+
 	// Wait until a token is available or context is canceled
-	waitStartTime := time.Now()
+	waitStartTime := rl.clock.Now()
 	for {
 		select {
 		case <-ctx.Done():
 			err := ctx.Err()
 			span.SetAttributes(
 				attribute.String("rate_limiter.result", "context_canceled"),
-				attribute.Int64("rate_limiter.wait_ms", time.Since(waitStartTime).Milliseconds()),
+				attribute.Int64("rate_limiter.wait_ms", rl.clock.Since(waitStartTime).Milliseconds()),
 			)
 			span.RecordError(err)
 			return zero, err
 		default:
-			if rl.Allow() {
-				waitDuration := time.Since(waitStartTime)
+			taken, backendErr := rl.acquire(ctx, n)
+			if backendErr != nil {
+				span.RecordError(backendErr)
+			}
+			if taken.Allowed {
+				waitDuration := rl.clock.Since(waitStartTime)
 				span.SetAttributes(
 					attribute.String("rate_limiter.result", "allowed_after_wait"),
 					attribute.Int64("rate_limiter.wait_ms", waitDuration.Milliseconds()),
 				)
 
 				// Execute the function
-				startTime := time.Now()
-				result, err := fn(ctx)
-				duration := time.Since(startTime)
+				startTime := rl.clock.Now()
+				result, err := run(ctx, taken, fn)
+				duration := rl.clock.Since(startTime)
 
 				span.SetAttributes(
 					attribute.Bool("rate_limiter.success", err == nil),
@@ -418,36 +654,146 @@ func ExecuteWithWait[T any](ctx context.Context, rl *RateLimiter, operation stri
 
 				return result, err
 			}
-			// Wait a bit before trying again
-			time.Sleep(10 * time.Millisecond)
+			taken.wait(ctx, rl.clock)
+		}
+	}
+}
+
+// permit is the outcome of asking the rate limiter to start a request.
+type permit struct {
+	Result
+
+	// release ends the request with its outcome; it must be called once the
+	// request is done if Allowed is true
+	release func(err error)
+
+	// retry is closed when retrying may succeed, for limits that do not depend on time
+	retry <-chan struct{}
+}
+
+// wait blocks until the request may be retried or the context is done.
+func (p permit) wait(ctx context.Context, c clock.Clock) {
+	if p.retry != nil {
+		select {
+		case <-ctx.Done():
+		case <-p.retry:
+		}
+		return
+	}
+
+	// Wait until the backend expects a token to be available
+	wait := p.RetryAfter
+	if wait <= 0 {
+		wait = 10 * time.Millisecond
+	}
+	select {
+	case <-ctx.Done():
+	case <-c.After(wait):
+	}
+}
+
+// acquire asks to start a request that costs n with the configured algorithm.
+// If the backend fails, the error is logged and the request is allowed.
+func (rl *RateLimiter) acquire(ctx context.Context, n int) (permit, error) {
+	if rl.inflight != nil {
+		ok, remaining, retry := rl.inflight.tryAcquire(n)
+		if !ok {
+			return permit{release: func(error) {}, retry: retry}, nil
 		}
+		start := rl.clock.Now()
+		return permit{
+			Result: Result{Allowed: true, Remaining: remaining},
+			release: func(err error) {
+				rl.inflight.release(n, rl.clock.Since(start), isOverloaded(err))
+			},
+		}, nil
 	}
+
+	result, err := rl.take(ctx, n)
+	return permit{Result: result, release: func(error) {}}, err
 }
 
-// refillTokens refills tokens based on time elapsed since last refill
-func (rl *RateLimiter) refillTokens() {
-	now := time.Now()
-	elapsed := now.Sub(rl.lastRefillTime)
-	tokensToAdd := int(elapsed.Seconds() * float64(rl.config.RequestsPerSecond))
+// run executes fn and releases the permit with its outcome, even if fn panics.
+func run[T any](ctx context.Context, p permit, fn func(ctx context.Context) (T, error)) (result T, err error) {
+	defer func() { p.release(err) }()
+	return fn(ctx)
+}
 
-	if tokensToAdd > 0 {
-		rl.tokens = min(rl.tokens+tokensToAdd, rl.config.BurstSize)
-		rl.lastRefillTime = now
+// isOverloaded reports whether an error suggests that the downstream is
+// overloaded, such as a timeout or a network error, so that the adaptive
+// limit should go down. Other errors, such as validation errors, do not.
+func isOverloaded(err error) bool {
+	return err != nil && !errors.IsCancelled(err) && errors.IsTransientError(err)
+}
+
+// take removes n tokens from the rate limiter's bucket.
+// If the backend fails, the error is logged and the request is allowed.
+func (rl *RateLimiter) take(ctx context.Context, n int) (Result, error) {
+	return takeTokens(ctx, rl.backend, rl.logger, rl.name, rl.name, rl.config.limit(), n)
+}
+
+// checkCost returns an error if a request that costs n can never be allowed,
+// because n exceeds the burst or the most requests the limit allows in flight.
+func (rl *RateLimiter) checkCost(n int) error {
+	capacity := rl.config.limit().Burst
+	if rl.inflight != nil {
+		capacity = rl.inflight.capacity()
 	}
+	if n > capacity {
+		return errors.New(errors.InvalidInputCode,
+			fmt.Sprintf("rate limiter %s cannot allow a cost of %d, the most it allows at once is %d", rl.name, n, capacity))
+	}
+	return nil
 }
 
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
+// limit returns the limit described by the configuration.
+func (c Config) limit() Limit {
+	limit := Limit{
+		Algorithm: c.Algorithm,
+		Rate:      float64(c.RequestsPerSecond),
+		Burst:     c.BurstSize,
 	}
-	return b
+	if c.Algorithm == SlidingWindowLog || c.Algorithm == SlidingWindowCounter {
+		limit.Window = c.Window
+		if limit.Window < time.Millisecond {
+			limit.Window = time.Second
+		}
+		limit.Burst = max(int(math.Round(limit.Rate*limit.Window.Seconds())), 1)
+	}
+	return limit
+}
+
+// takeTokens removes n tokens from the bucket for key.
+// If the backend fails, the error is logged and the request is allowed.
+func takeTokens(ctx context.Context, backend Backend, logger *logging.ContextLogger, name, key string, limit Limit, n int) (Result, error) {
+	result, err := backend.Take(ctx, key, limit, n)
+	if err != nil {
+		logger.Warn(ctx, "Rate limiter backend failed, allowing request",
+			zap.String("rate_limiter", name),
+			zap.Error(err))
+		return Result{Allowed: true, Remaining: limit.Burst}, err
+	}
+	return result, nil
+}
+
+// ConcurrencyLimit returns the number of requests currently allowed in flight
+// with the Concurrency and AdaptiveConcurrency algorithms.
+// If the rate limiter is nil or uses another algorithm, 0 is returned.
+//
+// Returns:
+//   - int: The current concurrency limit.
+func (rl *RateLimiter) ConcurrencyLimit() int {
+	if rl == nil || rl.inflight == nil {
+		return 0
+	}
+	limit, _ := rl.inflight.current()
+	return limit
 }
 
 // Reset resets the rate limiter to its initial state.
-// This method refills the token bucket to its maximum capacity (BurstSize)
-// and resets the last refill time to the current time. This is useful for
-// testing or when you want to clear any rate limiting history.
+// This method refills the token bucket to its maximum capacity (BurstSize).
+// This is useful for testing or when you want to clear any rate limiting history.
+// With a shared backend, the bucket is refilled for every replica.
 //
 // The method is thread-safe and can be called concurrently from multiple goroutines.
 // If the rate limiter is nil, this method does nothing.
@@ -456,8 +802,9 @@ func (rl *RateLimiter) Reset() {
 		return
 	}
 
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
-	rl.tokens = rl.config.BurstSize
-	rl.lastRefillTime = time.Now()
+	if err := rl.backend.Reset(context.Background(), rl.name); err != nil {
+		rl.logger.Warn(context.Background(), "Failed to reset rate limiter",
+			zap.String("rate_limiter", rl.name),
+			zap.Error(err))
+	}
 }
@@ -7,6 +7,7 @@
 	"testing"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/abitofhelp/servicelib/errors"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/stretchr/testify/assert"
@@ -70,9 +71,11 @@ func TestRateLimiter_Allow(t *testing.T) {
 		WithRequestsPerSecond(10).
 		WithBurstSize(5) // Small burst size for testing
 
+	fake := clock.NewFake(time.Time{})
 	options := DefaultOptions().
 		WithName("test").
-		WithLogger(logger)
+		WithLogger(logger).
+		WithClock(fake)
 
 	rl := NewRateLimiter(cfg, options)
 	require.NotNil(t, rl)
@@ -85,8 +88,8 @@ func TestRateLimiter_Allow(t *testing.T) {
 	// Next request should be denied (burst size exceeded)
 	assert.False(t, rl.Allow(), "Request 6 should be denied")
 
-	// Wait for token refill (at least 100ms for 1 token at 10 RPS)
-	time.Sleep(200 * time.Millisecond)
+	// Let a token refill (100ms for 1 token at 10 RPS)
+	fake.Advance(100 * time.Millisecond)
 
 	// Should be allowed again after refill
 	assert.True(t, rl.Allow(), "Request after wait should be allowed")
@@ -174,9 +177,12 @@ func TestExecuteWithWait(t *testing.T) {
 		WithRequestsPerSecond(10).
 		WithBurstSize(1) // Small burst size for testing
 
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
 	options := DefaultOptions().
 		WithName("test").
-		WithLogger(logger)
+		WithLogger(logger).
+		WithClock(fake)
 
 	rl := NewRateLimiter(cfg, options)
 	require.NotNil(t, rl)
@@ -186,7 +192,7 @@ func TestExecuteWithWait(t *testing.T) {
 	callCount := 0
 
 	// First request should succeed immediately
-	start := time.Now()
+	start := fake.Now()
 	result, err := ExecuteWithWait(ctx, rl, "test-operation", func(ctx context.Context) (string, error) {
 		callCount++
 		return "success", nil
@@ -194,10 +200,10 @@ func TestExecuteWithWait(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "success", result)
 	assert.Equal(t, 1, callCount)
-	assert.Less(t, time.Since(start), 50*time.Millisecond, "First request should not wait")
+	assert.Zero(t, fake.Since(start), "First request should not wait")
 
 	// Second request should wait for a token
-	start = time.Now()
+	start = fake.Now()
 	result, err = ExecuteWithWait(ctx, rl, "test-operation", func(ctx context.Context) (string, error) {
 		callCount++
 		return "success", nil
@@ -205,7 +211,7 @@ func TestExecuteWithWait(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "success", result)
 	assert.Equal(t, 2, callCount)
-	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "Second request should wait for a token")
+	assert.Equal(t, 100*time.Millisecond, fake.Since(start), "Second request should wait for a token")
 }
 
 func TestExecuteWithWait_ContextCancellation(t *testing.T) {
@@ -0,0 +1,248 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"time"
+
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/resp"
+)
+
+// The scripts below implement the rate-based algorithms on the server. They use
+// the server's clock, in microseconds, so that replicas with skewed clocks agree,
+// and they format timestamps with %.0f because Lua otherwise converts large
+// numbers to strings with only 14 significant digits. Every key expires once its
+// state is equivalent to a missing key.
+//
+// KEYS[1] is the key of the limit. ARGV is the algorithm's parameters followed by
+// the number of requests to take. Every script replies with {allowed, remaining,
+// retry after in microseconds}.
+
+// tokenBucketScriptSource refills a token bucket and takes tokens from it.
+// The state is a hash with the token count and the time of the last refill.
+// ARGV is the rate in tokens per second, the burst and n.
+const tokenBucketScriptSource = `
+if redis.replicate_commands then redis.replicate_commands() end
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+if now > ts then
+  tokens = math.min(burst, tokens + (now - ts) * rate / 1000000)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= n then
+  tokens = tokens - n
+  allowed = 1
+else
+  retry = math.ceil((n - tokens) * 1000000 / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.6f', tokens), 'ts', string.format('%.0f', now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, math.floor(tokens), retry}
+`
+
+// gcraScriptSource applies the generic cell rate algorithm.
+// The state is a string with the theoretical arrival time of the next request.
+// ARGV is the emission interval in microseconds, the burst and n.
+const gcraScriptSource = `
+if redis.replicate_commands then redis.replicate_commands() end
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then
+  tat = now
+end
+
+local tolerance = interval * burst
+local newTat = tat + n * interval
+local allowAt = newTat - tolerance
+if now < allowAt then
+  return {0, math.floor((now - (tat - tolerance)) / interval), math.ceil(allowAt - now)}
+end
+
+redis.call('SET', KEYS[1], string.format('%.0f', newTat), 'PX', math.max(1, math.ceil((newTat - now) / 1000)))
+return {1, math.floor((now - allowAt) / interval), 0}
+`
+
+// windowLogScriptSource applies the sliding window log algorithm.
+// The state is a sorted set of request times. Members are made unique by the
+// request's position in the window, which cannot repeat within a microsecond.
+// ARGV is the window in microseconds, the number of requests per window and n.
+const windowLogScriptSource = `
+if redis.replicate_commands then redis.replicate_commands() end
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', string.format('%.0f', now - window))
+local count = redis.call('ZCARD', KEYS[1])
+if count + n > limit then
+  local index = count + n - limit - 1
+  local oldest = redis.call('ZRANGE', KEYS[1], index, index, 'WITHSCORES')
+  return {0, limit - count, math.ceil(tonumber(oldest[2]) + window - now)}
+end
+
+local score = string.format('%.0f', now)
+for i = 1, n do
+  redis.call('ZADD', KEYS[1], score, score .. ':' .. (count + i))
+end
+redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+return {1, limit - count - n, 0}
+`
+
+// windowCounterScriptSource applies the sliding window counter algorithm.
+// The state is a hash with the start of the current fixed window and the counts
+// of the current and previous windows.
+// ARGV is the window in microseconds, the number of requests per window and n.
+const windowCounterScriptSource = `
+if redis.replicate_commands then redis.replicate_commands() end
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local n = tonumber(ARGV[3])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local current = now - math.fmod(now, window)
+
+local state = redis.call('HMGET', KEYS[1], 'start', 'curr', 'prev')
+local start = tonumber(state[1])
+local curr = tonumber(state[2]) or 0
+local prev = tonumber(state[3]) or 0
+if start == nil then
+  start = current
+  curr = 0
+  prev = 0
+elseif current > start then
+  if current - start == window then
+    prev = curr
+  else
+    prev = 0
+  end
+  curr = 0
+  start = current
+end
+
+local estimate = prev * (window - (now - start)) / window + curr
+if estimate + n > limit then
+  local offset
+  if curr + n <= limit then
+    offset = window * (1 - (limit - curr - n) / prev)
+  else
+    offset = window + window * (1 - (limit - n) / curr)
+  end
+  return {0, math.max(0, math.floor(limit - estimate)), math.max(1000, math.ceil(start + offset - now))}
+end
+
+curr = curr + n
+redis.call('HSET', KEYS[1], 'start', string.format('%.0f', start), 'curr', curr, 'prev', prev)
+redis.call('PEXPIRE', KEYS[1], math.ceil(2 * window / 1000))
+return {1, math.floor(limit - estimate - n), 0}
+`
+
+// scripts holds the script of each rate-based algorithm.
+var scripts = map[Algorithm]*resp.Script{
+	TokenBucket:          resp.NewScript(1, tokenBucketScriptSource),
+	GCRA:                 resp.NewScript(1, gcraScriptSource),
+	SlidingWindowLog:     resp.NewScript(1, windowLogScriptSource),
+	SlidingWindowCounter: resp.NewScript(1, windowCounterScriptSource),
+}
+
+// RedisBackend is a Backend that keeps buckets on a server that speaks the Redis
+// protocol, so that every replica of a service shares the same limit.
+//
+// The state of each key is stored under the backend's prefix, the algorithm and
+// the key, and is updated by a Lua script so that concurrent requests from
+// different replicas cannot take the same token. The Concurrency algorithm is
+// not supported.
+type RedisBackend struct {
+	client *resp.Client
+	prefix string
+}
+
+// NewRedisBackend creates a backend that keeps buckets on a RESP server.
+// The backend does not own the client; the caller is responsible for closing it.
+//
+// Parameters:
+//   - client: The client used to talk to the server.
+//   - prefix: A prefix added to every bucket key, such as "rate:".
+//
+// Returns:
+//   - *RedisBackend: A new backend.
+func NewRedisBackend(client *resp.Client, prefix string) *RedisBackend {
+	return &RedisBackend{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Take refills the bucket for key and removes n tokens from it if enough are available.
+func (b *RedisBackend) Take(ctx context.Context, key string, limit Limit, n int) (Result, error) {
+	var args []interface{}
+	switch limit.Algorithm {
+	case TokenBucket:
+		args = []interface{}{limit.Rate, limit.Burst, n}
+	case GCRA:
+		args = []interface{}{1e6 / limit.Rate, limit.Burst, n}
+	case SlidingWindowLog, SlidingWindowCounter:
+		args = []interface{}{limit.Window.Microseconds(), limit.Burst, n}
+	default:
+		return Result{}, errors.New(errors.InvalidInputCode, "rate limit algorithm "+limit.Algorithm.String()+" is not supported by the Redis backend")
+	}
+
+	keysAndArgs := append([]interface{}{b.key(limit.Algorithm, key)}, args...)
+	values, err := resp.Values(scripts[limit.Algorithm].Do(ctx, b.client, keysAndArgs...))
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 3 {
+		return Result{}, errors.New(errors.ExternalServiceErrorCode, "unexpected reply from rate limit script")
+	}
+
+	reply := make([]int64, len(values))
+	for i, value := range values {
+		if reply[i], err = resp.Int64(value, nil); err != nil {
+			return Result{}, err
+		}
+	}
+
+	return Result{
+		Allowed:    reply[0] == 1,
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Microsecond,
+	}, nil
+}
+
+// Reset refills the bucket for key to its capacity, whatever its algorithm.
+func (b *RedisBackend) Reset(ctx context.Context, key string) error {
+	args := []interface{}{"DEL"}
+	for algorithm := range scripts {
+		args = append(args, b.key(algorithm, key))
+	}
+	_, err := b.client.Do(ctx, args...)
+	return err
+}
+
+// key returns the server key that holds the state of key for an algorithm.
+func (b *RedisBackend) key(algorithm Algorithm, key string) string {
+	return b.prefix + algorithm.String() + ":" + key
+}
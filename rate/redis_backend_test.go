@@ -0,0 +1,307 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/resp"
+	"github.com/abitofhelp/servicelib/resp/resptest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emulateScript returns a Go stand-in for the script of an algorithm on the fake
+// server. It applies the in-memory implementation of the algorithm to state kept
+// beside the server's keyspace, and stores a marker key with the same expiry as
+// the real script so that Reset and expiry behave as on a real server. The Lua
+// scripts themselves are checked by TestRedisBackend_ScriptsMatchMemoryImplementation.
+func emulateScript(algorithm Algorithm) resptest.ScriptFunc {
+	states := make(map[string]limitState)
+	return func(db *resptest.DB, keys, args []string) interface{} {
+		param, _ := strconv.ParseFloat(args[0], 64)
+		burst, _ := strconv.Atoi(args[1])
+		n, _ := strconv.Atoi(args[2])
+
+		limit := Limit{Algorithm: algorithm, Burst: burst}
+		switch algorithm {
+		case TokenBucket:
+			limit.Rate = param
+		case GCRA:
+			limit.Rate = 1e6 / param
+		default:
+			limit.Window = time.Duration(param) * time.Microsecond
+		}
+
+		now := db.Time()
+		state, found := states[keys[0]]
+		if _, exists := db.Get(keys[0]); !exists || !found {
+			state = newLimitState(limit, now)
+			states[keys[0]] = state
+		}
+
+		result := state.take(now, limit, n)
+		db.Set(keys[0], "state")
+		db.PExpire(keys[0], time.Minute)
+
+		allowed := int64(0)
+		if result.Allowed {
+			allowed = 1
+		}
+		return []interface{}{allowed, int64(result.Remaining), result.RetryAfter.Microseconds()}
+	}
+}
+
+func newTestRedisBackend(t *testing.T) (*RedisBackend, *resptest.Server) {
+	t.Helper()
+
+	server, err := resptest.NewServer()
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+	server.HandleScript(tokenBucketScriptSource, emulateScript(TokenBucket))
+	server.HandleScript(gcraScriptSource, emulateScript(GCRA))
+	server.HandleScript(windowLogScriptSource, emulateScript(SlidingWindowLog))
+	server.HandleScript(windowCounterScriptSource, emulateScript(SlidingWindowCounter))
+
+	client := resp.NewClient(resp.DefaultConfig().WithAddress(server.Addr()))
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewRedisBackend(client, "rate:"), server
+}
+
+// newLuaRedisBackend creates a backend on an embedded server that runs the Lua
+// scripts, unlike the fake server, which runs emulateScript instead. The test
+// controls the server's clock.
+func newLuaRedisBackend(t *testing.T, now time.Time) (*RedisBackend, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	server.SetTime(now)
+
+	client := resp.NewClient(resp.DefaultConfig().WithAddress(server.Addr()))
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewRedisBackend(client, "rate:"), server
+}
+
+// scriptStep is a request made after some time has passed.
+type scriptStep struct {
+	advance time.Duration
+	n       int
+}
+
+// scriptCases are sequences of requests that are replayed against the Lua script
+// of an algorithm and against its in-memory implementation.
+var scriptCases = []struct {
+	name  string
+	limit Limit
+	steps []scriptStep
+}{
+	{
+		name:  "token bucket",
+		limit: Limit{Algorithm: TokenBucket, Rate: 10, Burst: 5},
+		steps: []scriptStep{
+			{0, 1}, {0, 1}, {0, 1}, {0, 1}, {0, 1},
+			// Empty: the next token arrives after 100ms
+			{0, 1},
+			{50 * time.Millisecond, 1},
+			{50 * time.Millisecond, 1},
+			{0, 3},
+			{250 * time.Millisecond, 3},
+			{time.Second, 5},
+			// More than the burst can never be allowed
+			{time.Second, 6},
+			// The key has expired, which is the same as a full bucket
+			{10 * time.Second, 4},
+			{125 * time.Millisecond, 2},
+		},
+	},
+	{
+		name:  "GCRA",
+		limit: Limit{Algorithm: GCRA, Rate: 10, Burst: 3},
+		steps: []scriptStep{
+			{0, 1}, {0, 1}, {0, 1},
+			// The next request conforms after one emission interval
+			{0, 1},
+			{50 * time.Millisecond, 1},
+			{50 * time.Millisecond, 1},
+			{0, 2},
+			{150 * time.Millisecond, 2},
+			// The key has expired, which is the same as an idle limit
+			{time.Second, 3},
+			{250 * time.Millisecond, 3},
+		},
+	},
+	{
+		name:  "sliding window log",
+		limit: Limit{Algorithm: SlidingWindowLog, Window: time.Second, Burst: 3},
+		steps: []scriptStep{
+			{0, 1},
+			{200 * time.Millisecond, 1},
+			{200 * time.Millisecond, 1},
+			// Full until the first request leaves the window
+			{0, 1},
+			{600 * time.Millisecond, 1},
+			{0, 2},
+			{400 * time.Millisecond, 2},
+			// The key has expired, which is the same as an empty log
+			{2 * time.Second, 3},
+		},
+	},
+	{
+		name:  "sliding window counter",
+		limit: Limit{Algorithm: SlidingWindowCounter, Window: time.Second, Burst: 4},
+		steps: []scriptStep{
+			{0, 2}, {0, 2},
+			// Full until the next window, when the count is weighted down
+			{0, 1},
+			{500 * time.Millisecond, 1},
+			{700 * time.Millisecond, 1},
+			{50 * time.Millisecond, 1},
+			{250 * time.Millisecond, 1},
+			// Skipping a whole window drops the previous count
+			{2 * time.Second, 3},
+			// The key has expired, which is the same as empty windows
+			{5 * time.Second, 4},
+		},
+	},
+}
+
+func TestRedisBackend_ScriptsMatchMemoryImplementation(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range scriptCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Unix(1700000000, 0)
+			backend, server := newLuaRedisBackend(t, now)
+			state := newLimitState(tc.limit, now)
+
+			for i, step := range tc.steps {
+				now = now.Add(step.advance)
+				server.SetTime(now)
+				server.FastForward(step.advance)
+
+				want := state.take(now, tc.limit, step.n)
+				got, err := backend.Take(ctx, "api", tc.limit, step.n)
+				require.NoError(t, err)
+
+				// The script works in whole microseconds
+				assert.Equal(t, want.Allowed, got.Allowed, "step %d", i)
+				assert.Equal(t, want.Remaining, got.Remaining, "step %d", i)
+				assert.InDelta(t, want.RetryAfter.Microseconds(), got.RetryAfter.Microseconds(), 1, "step %d", i)
+			}
+		})
+	}
+}
+
+func TestRedisBackend_Take(t *testing.T) {
+	backend, server := newTestRedisBackend(t)
+	ctx := context.Background()
+	limit := Limit{Rate: 10, Burst: 2}
+
+	result, err := backend.Take(ctx, "api", limit, 1)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+	assert.Equal(t, []string{"rate:token_bucket:api"}, server.Keys())
+
+	result, err = backend.Take(ctx, "api", limit, 1)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = backend.Take(ctx, "api", limit, 1)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, 90*time.Millisecond)
+	assert.LessOrEqual(t, result.RetryAfter, 100*time.Millisecond)
+
+	require.NoError(t, backend.Reset(ctx, "api"))
+	assert.Empty(t, server.Keys())
+	result, err = backend.Take(ctx, "api", limit, 2)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRedisBackend_SharedAcrossReplicas(t *testing.T) {
+	backend, server := newTestRedisBackend(t)
+	cfg := DefaultConfig().WithRequestsPerSecond(10).WithBurstSize(1)
+
+	// A second replica connects with its own client
+	client2 := resp.NewClient(resp.DefaultConfig().WithAddress(server.Addr()))
+	defer client2.Close()
+
+	replica1 := NewRateLimiter(cfg, DefaultOptions().WithName("api").WithBackend(backend))
+	replica2 := NewRateLimiter(cfg, DefaultOptions().WithName("api").WithBackend(NewRedisBackend(client2, "rate:")))
+
+	ctx := context.Background()
+	result, err := Execute(ctx, replica1, "test-operation", func(ctx context.Context) (string, error) {
+		return "success", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "success", result)
+
+	_, err = Execute(ctx, replica2, "test-operation", func(ctx context.Context) (string, error) {
+		t.Fatal("This function should not be called when rate limit is exceeded")
+		return "", nil
+	})
+	assert.Error(t, err)
+
+	// ExecuteWithWait waits for the shared bucket to refill
+	start := time.Now()
+	result, err = ExecuteWithWait(ctx, replica2, "test-operation", func(ctx context.Context) (string, error) {
+		return "success", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "success", result)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestRedisBackend_Errors(t *testing.T) {
+	backend, server := newTestRedisBackend(t)
+	ctx := context.Background()
+	limit := Limit{Rate: 10, Burst: 2}
+
+	server.HandleScript(tokenBucketScriptSource, func(db *resptest.DB, keys, args []string) interface{} {
+		return []interface{}{int64(1)}
+	})
+	_, err := backend.Take(ctx, "api", limit, 1)
+	assert.Error(t, err)
+
+	server.HandleScript(tokenBucketScriptSource, func(db *resptest.DB, keys, args []string) interface{} {
+		return []interface{}{int64(1), []byte("x"), int64(0)}
+	})
+	_, err = backend.Take(ctx, "api", limit, 1)
+	assert.Error(t, err)
+
+	_, err = backend.Take(ctx, "api", Limit{Algorithm: Concurrency}, 1)
+	assert.Error(t, err)
+
+	server.Close()
+	_, err = backend.Take(ctx, "api", limit, 1)
+	assert.Error(t, err)
+	assert.Error(t, backend.Reset(ctx, "api"))
+}
+
+func TestRedisBackend_Algorithms(t *testing.T) {
+	backend, server := newTestRedisBackend(t)
+
+	for _, algorithm := range []Algorithm{TokenBucket, GCRA, SlidingWindowLog, SlidingWindowCounter} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			cfg := DefaultConfig().WithAlgorithm(algorithm).WithRequestsPerSecond(2).WithBurstSize(2).WithWindow(time.Second)
+			rl := NewRateLimiter(cfg, DefaultOptions().WithName("api").WithBackend(backend))
+			require.NotNil(t, rl)
+
+			assert.True(t, rl.Allow())
+			assert.True(t, rl.Allow())
+			assert.False(t, rl.Allow())
+			assert.Contains(t, server.Keys(), "rate:"+algorithm.String()+":api")
+
+			rl.Reset()
+			assert.NotContains(t, server.Keys(), "rate:"+algorithm.String()+":api")
+			assert.True(t, rl.Allow())
+		})
+	}
+}
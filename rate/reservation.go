@@ -0,0 +1,218 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"go.uber.org/zap"
+)
+
+// InfDuration is the delay of a Reservation that could not be made.
+const InfDuration = time.Duration(math.MaxInt64)
+
+// Reservation holds requests reserved from a RateLimiter ahead of time.
+// The caller should wait for Delay before performing the reserved requests,
+// or call Cancel if it will not perform them.
+type Reservation struct {
+	ok        bool
+	timeToAct time.Time
+	clock     clock.Clock
+	cancel    func()
+	once      sync.Once
+}
+
+// OK reports whether the requests were reserved. If it is false, nothing was
+// reserved and the caller must not perform the requests: either they can never
+// be allowed at once, or they are not allowed now and the limiter cannot
+// reserve them ahead of time.
+// If the reservation is nil, true is returned.
+//
+// Returns:
+//   - bool: Whether the requests were reserved.
+func (r *Reservation) OK() bool {
+	return r == nil || r.ok
+}
+
+// Delay returns how long the caller must wait before performing the reserved
+// requests. Zero means they can be performed immediately. If the requests were
+// not reserved, InfDuration is returned.
+//
+// Returns:
+//   - time.Duration: The time left until the reserved requests are allowed.
+func (r *Reservation) Delay() time.Duration {
+	if r == nil {
+		return 0
+	}
+	if !r.ok {
+		return InfDuration
+	}
+	return max(r.timeToAct.Sub(clock.OrReal(r.clock).Now()), 0)
+}
+
+// Cancel gives the reserved requests back to the rate limiter, so that other
+// requests can use them. It has no effect once the delay has passed, because the
+// requests are then assumed to have been performed, or if the backend cannot
+// cancel reservations. Calling Cancel more than once has no further effect.
+func (r *Reservation) Cancel() {
+	if r == nil || !r.ok || r.cancel == nil {
+		return
+	}
+	r.once.Do(func() {
+		if clock.OrReal(r.clock).Now().Before(r.timeToAct) {
+			r.cancel()
+		}
+	})
+}
+
+// AllowN checks if a request that costs n should be allowed based on the rate limit.
+// It behaves like Allow, but takes n tokens, or checks for n free slots with the
+// concurrency algorithms. A cost below 1 is treated as 1. If n exceeds what the
+// limit can ever allow at once, false is returned.
+//
+// Parameters:
+//   - n: The cost of the request, in requests.
+//
+// Returns:
+//   - true if the request is allowed (enough tokens were available or rate limiting is disabled).
+//   - false if the request is not allowed.
+func (rl *RateLimiter) AllowN(n int) bool {
+	if rl == nil {
+		// If rate limiter is disabled, allow all requests
+		return true
+	}
+
+	n = max(n, 1)
+	if rl.checkCost(n) != nil {
+		return false
+	}
+
+	if rl.inflight != nil {
+		ok, _ := rl.inflight.available(n)
+		return ok
+	}
+
+	result, _ := rl.take(context.Background(), n)
+	return result.Allowed
+}
+
+// WaitN blocks until a request that costs n is allowed, taking n tokens.
+// With the concurrency algorithms, it only waits until n slots are free, like
+// AllowN; use ExecuteWithWaitN to hold them while the request runs.
+// A cost below 1 is treated as 1.
+//
+// Parameters:
+//   - ctx: The context for the operation. Can be used to stop waiting.
+//   - n: The cost of the request, in requests.
+//
+// Returns:
+//   - error: An error if the context is done before the request is allowed, or if n
+//     exceeds what the limit can ever allow at once.
+func (rl *RateLimiter) WaitN(ctx context.Context, n int) error {
+	if rl == nil {
+		// If rate limiter is disabled, allow all requests
+		return nil
+	}
+
+	n = max(n, 1)
+	if err := rl.checkCost(n); err != nil {
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var p permit
+		if rl.inflight != nil {
+			ok, retry := rl.inflight.available(n)
+			if ok {
+				return nil
+			}
+			p.retry = retry
+		} else {
+			result, _ := rl.take(ctx, n)
+			if result.Allowed {
+				return nil
+			}
+			p.Result = result
+		}
+		p.wait(ctx, rl.clock)
+	}
+}
+
+// Reserve reserves n requests ahead of time and returns a Reservation that tells
+// the caller how long to wait before performing them. Unlike AllowN, it does not
+// reject the requests when the limit is reached, but schedules them after the
+// requests reserved before them. A cost below 1 is treated as 1.
+//
+// Reservations ahead of time need the TokenBucket or GCRA algorithm and a backend
+// that implements Reserver, such as MemoryBackend. Otherwise the requests are taken
+// if they are allowed now, with a Delay of zero; if they are not, nothing can be
+// held for the caller, so the reservation is not OK. Reservations cannot be made
+// with the concurrency algorithms. If the backend fails,
+// the error is logged and the requests are reserved with a Delay of zero.
+//
+// Parameters:
+//   - n: The cost of the reserved requests, in requests.
+//
+// Returns:
+//   - *Reservation: The reservation. Its OK method reports whether it was made.
+func (rl *RateLimiter) Reserve(n int) *Reservation {
+	if rl == nil {
+		// If rate limiter is disabled, allow all requests
+		return &Reservation{ok: true}
+	}
+
+	ctx := context.Background()
+	n = max(n, 1)
+	if rl.inflight != nil || rl.checkCost(n) != nil {
+		return &Reservation{}
+	}
+
+	now := rl.clock.Now()
+	limit := rl.config.limit()
+	reserver, ok := rl.backend.(Reserver)
+	if !ok {
+		result, err := rl.take(ctx, n)
+		if err != nil {
+			// take has logged the failure; allow the requests as the Reserver path does
+			return &Reservation{ok: true, timeToAct: now, clock: rl.clock}
+		}
+		if !result.Allowed {
+			// Without a Reserver nothing can be reserved ahead of time
+			return &Reservation{}
+		}
+		return &Reservation{ok: true, timeToAct: now, clock: rl.clock}
+	}
+
+	result, err := reserver.Reserve(ctx, rl.name, limit, n)
+	if err != nil {
+		rl.logger.Warn(ctx, "Rate limiter backend failed, allowing request",
+			zap.String("rate_limiter", rl.name),
+			zap.Error(err))
+		return &Reservation{ok: true, timeToAct: now, clock: rl.clock}
+	}
+	if !result.Allowed {
+		// The algorithm cannot go into debt, so nothing was reserved
+		return &Reservation{}
+	}
+
+	return &Reservation{
+		ok:        true,
+		timeToAct: now.Add(result.RetryAfter),
+		clock:     rl.clock,
+		cancel: func() {
+			if err := reserver.Cancel(ctx, rl.name, limit, n); err != nil {
+				rl.logger.Warn(ctx, "Failed to cancel rate limiter reservation",
+					zap.String("rate_limiter", rl.name),
+					zap.Error(err))
+			}
+		},
+	}
+}
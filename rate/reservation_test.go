@@ -0,0 +1,174 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package rate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_AllowN(t *testing.T) {
+	rl := NewRateLimiter(DefaultConfig().WithRequestsPerSecond(1).WithBurstSize(10), DefaultOptions())
+
+	assert.True(t, rl.AllowN(6))
+	assert.False(t, rl.AllowN(5))
+	assert.True(t, rl.AllowN(4))
+	assert.False(t, rl.AllowN(0)) // treated as 1
+
+	// A cost above the burst can never be allowed
+	rl.Reset()
+	assert.False(t, rl.AllowN(11))
+	assert.True(t, rl.AllowN(10))
+
+	// With the concurrency algorithms, the cost is in slots
+	cl := NewRateLimiter(DefaultConfig().WithAlgorithm(Concurrency).WithMaxConcurrent(3), DefaultOptions())
+	assert.True(t, cl.AllowN(3))
+	assert.False(t, cl.AllowN(4))
+}
+
+func TestRateLimiter_WaitN(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+	rl := NewRateLimiter(DefaultConfig().WithRequestsPerSecond(100).WithBurstSize(5), DefaultOptions().WithClock(fake))
+	ctx := context.Background()
+
+	require.NoError(t, rl.WaitN(ctx, 5))
+
+	// Three more tokens take 30ms to refill
+	start := fake.Now()
+	require.NoError(t, rl.WaitN(ctx, 3))
+	assert.GreaterOrEqual(t, fake.Since(start), 30*time.Millisecond)
+
+	// A cost above the burst fails immediately
+	err := rl.WaitN(ctx, 6)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.New(errors.InvalidInputCode, "")))
+
+	// The context stops the wait
+	ctx, cancel := clock.WithTimeout(ctx, fake, 10*time.Millisecond)
+	defer cancel()
+	slow := NewRateLimiter(DefaultConfig().WithRequestsPerSecond(1).WithBurstSize(5), DefaultOptions().WithClock(fake))
+	require.True(t, slow.AllowN(5))
+	assert.ErrorIs(t, slow.WaitN(ctx, 5), context.DeadlineExceeded)
+
+	var nilLimiter *RateLimiter
+	assert.NoError(t, nilLimiter.WaitN(ctx, 100))
+}
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	for _, algorithm := range []Algorithm{TokenBucket, GCRA} {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			cfg := DefaultConfig().WithAlgorithm(algorithm).WithRequestsPerSecond(10).WithBurstSize(2)
+			rl := NewRateLimiter(cfg, DefaultOptions())
+
+			r := rl.Reserve(2)
+			require.True(t, r.OK())
+			assert.Equal(t, time.Duration(0), r.Delay())
+
+			// The next requests are scheduled one emission interval apart
+			r1 := rl.Reserve(1)
+			require.True(t, r1.OK())
+			assert.InDelta(t, 100*time.Millisecond, r1.Delay(), float64(10*time.Millisecond))
+
+			r2 := rl.Reserve(1)
+			require.True(t, r2.OK())
+			assert.InDelta(t, 200*time.Millisecond, r2.Delay(), float64(10*time.Millisecond))
+
+			// Cancelling gives the requests back
+			r2.Cancel()
+			r1.Cancel()
+			r1.Cancel()
+			r3 := rl.Reserve(1)
+			assert.InDelta(t, 100*time.Millisecond, r3.Delay(), float64(10*time.Millisecond))
+
+			// A cost above the burst can never be reserved
+			r4 := rl.Reserve(3)
+			assert.False(t, r4.OK())
+			assert.Equal(t, InfDuration, r4.Delay())
+			r4.Cancel()
+		})
+	}
+}
+
+func TestRateLimiter_ReserveSlidingWindow(t *testing.T) {
+	// Sliding windows cannot go into debt, so they only take what is available now
+	fake := clock.NewFake(time.Time{})
+	cfg := DefaultConfig().WithAlgorithm(SlidingWindowLog).WithRequestsPerSecond(2).WithWindow(time.Second)
+	rl := NewRateLimiter(cfg, DefaultOptions().WithClock(fake))
+	r := rl.Reserve(2)
+	require.True(t, r.OK())
+	assert.Equal(t, time.Duration(0), r.Delay())
+
+	fake.Advance(400 * time.Millisecond)
+	r = rl.Reserve(1)
+	assert.False(t, r.OK())
+	assert.Equal(t, InfDuration, r.Delay())
+
+	// Nothing was held, so the window frees up as if the reservation was never made
+	fake.Advance(600 * time.Millisecond)
+	assert.True(t, rl.AllowN(2))
+}
+
+func TestRateLimiter_ReserveWithoutReserver(t *testing.T) {
+	// A backend that does not implement Reserver only takes what is available now
+	backend, _ := newTestRedisBackend(t)
+	redisLimiter := NewRateLimiter(DefaultConfig().WithRequestsPerSecond(10).WithBurstSize(1), DefaultOptions().WithBackend(backend))
+	r := redisLimiter.Reserve(1)
+	require.True(t, r.OK())
+	assert.Equal(t, time.Duration(0), r.Delay())
+
+	r = redisLimiter.Reserve(1)
+	assert.False(t, r.OK())
+	assert.Equal(t, InfDuration, r.Delay())
+
+	// Concurrency slots cannot be reserved
+	cl := NewRateLimiter(DefaultConfig().WithAlgorithm(Concurrency), DefaultOptions())
+	assert.False(t, cl.Reserve(1).OK())
+
+	// A failing backend allows the reservation
+	fl := NewRateLimiter(DefaultConfig(), DefaultOptions().WithBackend(failingBackend{}))
+	r = fl.Reserve(1)
+	assert.True(t, r.OK())
+	assert.Equal(t, time.Duration(0), r.Delay())
+
+	var nilLimiter *RateLimiter
+	assert.True(t, nilLimiter.Reserve(1).OK())
+	var nilReservation *Reservation
+	assert.True(t, nilReservation.OK())
+	assert.Equal(t, time.Duration(0), nilReservation.Delay())
+	nilReservation.Cancel()
+}
+
+func TestExecuteWithWaitN(t *testing.T) {
+	ctx := context.Background()
+	rl := NewRateLimiter(DefaultConfig().WithRequestsPerSecond(100).WithBurstSize(4), DefaultOptions())
+
+	result, err := ExecuteWithWaitN(ctx, rl, "export", 4, func(ctx context.Context) (string, error) {
+		return "exported", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "exported", result)
+	assert.False(t, rl.Allow())
+
+	_, err = ExecuteWithWaitN(ctx, rl, "too-big", 5, func(ctx context.Context) (string, error) {
+		t.Fatal("This function should not be called when the cost exceeds the burst")
+		return "", nil
+	})
+	assert.True(t, errors.Is(err, errors.New(errors.InvalidInputCode, "")))
+
+	// With the concurrency algorithms, the cost is held while the function runs
+	cl := NewRateLimiter(DefaultConfig().WithAlgorithm(Concurrency).WithMaxConcurrent(3), DefaultOptions())
+	_, err = ExecuteWithWaitN(ctx, cl, "heavy", 2, func(ctx context.Context) (bool, error) {
+		assert.True(t, cl.Allow())
+		assert.False(t, cl.AllowN(2))
+		return true, nil
+	})
+	require.NoError(t, err)
+	assert.True(t, cl.AllowN(3))
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resilience
+
+import (
+	"fmt"
+
+	"github.com/abitofhelp/servicelib/errors"
+)
+
+// bulkhead limits the number of attempts in flight, so that a slow dependency
+// cannot tie up every goroutine of the caller.
+type bulkhead struct {
+	slots chan struct{}
+}
+
+// newBulkhead creates a bulkhead that allows maxConcurrent attempts in flight.
+func newBulkhead(maxConcurrent int) *bulkhead {
+	return &bulkhead{slots: make(chan struct{}, maxConcurrent)}
+}
+
+// acquire takes a slot and returns a function that gives it back, or an error
+// with the ResourceExhaustedCode if every slot is taken.
+func (b *bulkhead) acquire(name string) (func(), error) {
+	select {
+	case b.slots <- struct{}{}:
+		return func() { <-b.slots }, nil
+	default:
+		return nil, errors.New(errors.ResourceExhaustedCode,
+			fmt.Sprintf("bulkhead of resilience policy %s is full with %d attempts in flight", name, cap(b.slots)))
+	}
+}
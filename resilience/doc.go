@@ -0,0 +1,38 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package resilience composes the library's resilience strategies into a single
+// policy around calls to a dependency.
+//
+// Services that call a dependency usually want several strategies at once:
+// retries for transient errors, a circuit breaker, a rate limit, a timeout per
+// attempt, a bulkhead and a fallback. Nesting retry.DoWithOptions,
+// circuit.Execute and rate.Execute by hand gives each service a slightly
+// different order; a Policy applies them in one well-defined order, from the
+// outermost to the innermost:
+//
+//	Fallback(Retry(CircuitBreaker(RateLimiter(Timeout(Bulkhead(fn))))))
+//
+// Each execution records a single resilience.Execute span, the parent of the
+// spans of the strategies, and one set of metrics: executions by outcome,
+// attempts, rejections by stage, timeouts and the duration of executions.
+//
+// The resiliencetest package provides a test harness that runs policies on a
+// fake clock against scripted functions.
+//
+// Example usage:
+//
+//	cb := circuit.NewCircuitBreaker(circuit.DefaultConfig(), circuit.DefaultOptions().WithName("users"))
+//
+//	policy := resilience.NewPolicy[*User](resilience.DefaultOptions().WithName("users")).
+//	    WithRetry(retry.DefaultConfig().WithMaxRetries(3), nil).
+//	    WithCircuitBreaker(cb).
+//	    WithTimeout(500 * time.Millisecond).
+//	    WithBulkhead(20).
+//	    WithFallback(func(ctx context.Context, err error) (*User, error) {
+//	        return cache.Get(ctx, id)
+//	    })
+//
+//	user, err := policy.Execute(ctx, func(ctx context.Context) (*User, error) {
+//	    return client.GetUser(ctx, id)
+//	})
+package resilience
@@ -0,0 +1,156 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// outcome is the result of an execution of a policy.
+type outcome int
+
+const (
+	// outcomeSuccess means the function eventually returned no error
+	outcomeSuccess outcome = iota
+
+	// outcomeFailure means the execution returned an error
+	outcomeFailure
+
+	// outcomeFallback means the execution failed and the fallback returned no error
+	outcomeFallback
+)
+
+// String returns the name of the outcome, as recorded in spans and metrics.
+func (o outcome) String() string {
+	switch o {
+	case outcomeSuccess:
+		return "success"
+	case outcomeFailure:
+		return "failure"
+	case outcomeFallback:
+		return "fallback"
+	default:
+		return "unknown"
+	}
+}
+
+// stage names a strategy of a policy that can reject an attempt.
+type stage string
+
+const (
+	stageCircuitBreaker stage = "circuit_breaker"
+	stageRateLimiter    stage = "rate_limiter"
+	stageBulkhead       stage = "bulkhead"
+)
+
+// policyMetrics holds the OpenTelemetry instruments of a policy.
+// A nil *policyMetrics records nothing.
+type policyMetrics struct {
+	executions metric.Int64Counter
+	attempts   metric.Int64Counter
+	rejections metric.Int64Counter
+	timeouts   metric.Int64Counter
+	duration   metric.Float64Histogram
+	name       attribute.KeyValue
+}
+
+// newPolicyMetrics creates the instruments of a policy.
+//
+// Parameters:
+//   - meter: The meter used to create the instruments.
+//   - name: The name of the policy, recorded as the resilience.policy attribute.
+//
+// Returns:
+//   - *policyMetrics: The instruments.
+//   - error: An error if an instrument could not be created.
+func newPolicyMetrics(meter metric.Meter, name string) (*policyMetrics, error) {
+	m := &policyMetrics{name: attribute.String("resilience.policy", name)}
+
+	var err error
+	m.executions, err = meter.Int64Counter(
+		"resilience.executions",
+		metric.WithDescription("Number of executions of the resilience policy, by outcome"),
+		metric.WithUnit("{execution}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resilience.executions counter: %w", err)
+	}
+
+	m.attempts, err = meter.Int64Counter(
+		"resilience.attempts",
+		metric.WithDescription("Number of attempts made by the resilience policy, including retries"),
+		metric.WithUnit("{attempt}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resilience.attempts counter: %w", err)
+	}
+
+	m.rejections, err = meter.Int64Counter(
+		"resilience.rejections",
+		metric.WithDescription("Number of attempts rejected by the resilience policy without being executed, by stage"),
+		metric.WithUnit("{attempt}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resilience.rejections counter: %w", err)
+	}
+
+	m.timeouts, err = meter.Int64Counter(
+		"resilience.timeouts",
+		metric.WithDescription("Number of attempts that exceeded the timeout of the resilience policy"),
+		metric.WithUnit("{attempt}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resilience.timeouts counter: %w", err)
+	}
+
+	m.duration, err = meter.Float64Histogram(
+		"resilience.duration",
+		metric.WithDescription("Duration of executions of the resilience policy, including retries and fallback"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resilience.duration histogram: %w", err)
+	}
+
+	return m, nil
+}
+
+// recordExecution counts an execution and records its duration.
+func (m *policyMetrics) recordExecution(o outcome, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	attrs := metric.WithAttributes(m.name, attribute.String("resilience.outcome", o.String()))
+	m.executions.Add(context.Background(), 1, attrs)
+	m.duration.Record(context.Background(), duration.Seconds(), attrs)
+}
+
+// recordAttempt counts an attempt.
+func (m *policyMetrics) recordAttempt() {
+	if m == nil {
+		return
+	}
+	m.attempts.Add(context.Background(), 1, metric.WithAttributes(m.name))
+}
+
+// recordRejection counts an attempt rejected by a stage.
+func (m *policyMetrics) recordRejection(s stage) {
+	if m == nil {
+		return
+	}
+	m.rejections.Add(context.Background(), 1,
+		metric.WithAttributes(m.name, attribute.String("resilience.stage", string(s))))
+}
+
+// recordTimeout counts an attempt that timed out.
+func (m *policyMetrics) recordTimeout() {
+	if m == nil {
+		return
+	}
+	m.timeouts.Add(context.Background(), 1, metric.WithAttributes(m.name))
+}
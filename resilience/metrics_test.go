@@ -0,0 +1,133 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestPolicy_Metrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+
+	policy := NewPolicy[string](DefaultOptions().
+		WithName("metered").
+		WithMeter(provider.Meter("test")).
+		WithClock(fake)).
+		WithRetry(retry.DefaultConfig().WithMaxRetries(1).WithJitterFactor(0), nil).
+		WithTimeout(100 * time.Millisecond).
+		WithBulkhead(1)
+
+	// One execution that times out once and then succeeds
+	calls := 0
+	_, err := policy.Execute(context.Background(), func(ctx context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			fake.Advance(time.Second)
+			return "", ctx.Err()
+		}
+		return "ok", nil
+	})
+	require.NoError(t, err)
+
+	// One execution that fails
+	_, err = policy.Execute(context.Background(), func(ctx context.Context) (string, error) {
+		return "", errors.NewValidationError("invalid", "name", nil)
+	})
+	require.Error(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	sums := make(map[string]int64)
+	histograms := make(map[string]uint64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					name, _ := dp.Attributes.Value("resilience.policy")
+					assert.Equal(t, "metered", name.AsString())
+					outcome, _ := dp.Attributes.Value("resilience.outcome")
+					sums[m.Name+" "+outcome.AsString()] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					outcome, _ := dp.Attributes.Value("resilience.outcome")
+					histograms[m.Name+" "+outcome.AsString()] += dp.Count
+				}
+			}
+		}
+	}
+
+	assert.Equal(t, int64(1), sums["resilience.executions success"])
+	assert.Equal(t, int64(1), sums["resilience.executions failure"])
+	assert.Equal(t, int64(3), sums["resilience.attempts "])
+	assert.Equal(t, int64(1), sums["resilience.timeouts "])
+	assert.Equal(t, uint64(1), histograms["resilience.duration success"])
+	assert.Equal(t, uint64(1), histograms["resilience.duration failure"])
+}
+
+func TestPolicy_MetricsRejections(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	policy := NewPolicy[string](DefaultOptions().WithName("metered").WithMeter(provider.Meter("test"))).
+		WithBulkhead(1)
+
+	// Hold the only slot while a second execution is rejected
+	_, err := policy.Execute(context.Background(), func(ctx context.Context) (string, error) {
+		return policy.Execute(ctx, func(ctx context.Context) (string, error) {
+			return "ok", nil
+		})
+	})
+	require.Error(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	rejections := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if data, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "resilience.rejections" {
+				for _, dp := range data.DataPoints {
+					stage, _ := dp.Attributes.Value("resilience.stage")
+					rejections[stage.AsString()] += dp.Value
+				}
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{"bulkhead": 1}, rejections)
+}
+
+func TestPolicyMetrics_Nil(t *testing.T) {
+	var m *policyMetrics
+	assert.NotPanics(t, func() {
+		m.recordExecution(outcomeSuccess, time.Second)
+		m.recordAttempt()
+		m.recordRejection(stageBulkhead)
+		m.recordTimeout()
+	})
+}
+
+func TestOutcome_String(t *testing.T) {
+	assert.Equal(t, "success", outcomeSuccess.String())
+	assert.Equal(t, "failure", outcomeFailure.String())
+	assert.Equal(t, "fallback", outcomeFallback.String())
+	assert.Equal(t, "unknown", outcome(42).String())
+}
@@ -0,0 +1,477 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/abitofhelp/servicelib/circuit"
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/logging"
+	"github.com/abitofhelp/servicelib/rate"
+	"github.com/abitofhelp/servicelib/retry"
+	"github.com/abitofhelp/servicelib/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Options contains additional options for a resilience policy.
+// These options are not directly related to the behavior of the policy,
+// but provide additional functionality like logging, tracing and metrics.
+type Options struct {
+	// Logger is used for logging policy executions.
+	// If nil, a no-op logger will be used.
+	Logger *logging.ContextLogger
+
+	// Tracer is used for tracing policy executions, including the retry
+	// attempts made by the policy.
+	// It provides integration with OpenTelemetry for distributed tracing.
+	Tracer telemetry.Tracer
+
+	// Meter is used to create metrics for policy executions.
+	// If nil, no metrics are recorded.
+	Meter metric.Meter
+
+	// Clock is used to measure executions, time out attempts and wait between
+	// retries. If nil, the system clock will be used.
+	Clock clock.Clock
+
+	// Name is the name of the policy.
+	// It identifies the policy in logs, traces and metrics.
+	Name string
+}
+
+// DefaultOptions returns default options for resilience policies.
+// The default options include:
+//   - No logger (a no-op logger will be used)
+//   - A no-op tracer (no OpenTelemetry integration)
+//   - No meter (no metrics are recorded)
+//   - The system clock
+//   - Name: "default"
+//
+// Returns:
+//   - An Options instance with default values.
+func DefaultOptions() Options {
+	return Options{
+		Logger: nil,
+		Tracer: telemetry.NewNoopTracer(),
+		Name:   "default",
+	}
+}
+
+// WithLogger sets the logger for the policy.
+//
+// Parameters:
+//   - logger: A ContextLogger instance for logging policy executions.
+//
+// Returns:
+//   - A new Options instance with the updated Logger value.
+func (o Options) WithLogger(logger *logging.ContextLogger) Options {
+	o.Logger = logger
+	return o
+}
+
+// WithOtelTracer returns Options with an OpenTelemetry tracer.
+// This allows users to opt-in to OpenTelemetry tracing if they need it.
+//
+// Parameters:
+//   - tracer: An OpenTelemetry trace.Tracer instance.
+//
+// Returns:
+//   - A new Options instance with the provided OpenTelemetry tracer.
+func (o Options) WithOtelTracer(tracer trace.Tracer) Options {
+	o.Tracer = telemetry.NewOtelTracer(tracer)
+	return o
+}
+
+// WithMeter sets the OpenTelemetry meter used to record policy metrics.
+// The policy counts executions by outcome, attempts, rejections by stage and
+// timeouts, and records the duration of executions, each with a
+// resilience.policy attribute.
+//
+// Parameters:
+//   - meter: An OpenTelemetry metric.Meter instance.
+//
+// Returns:
+//   - A new Options instance with the updated Meter value.
+func (o Options) WithMeter(meter metric.Meter) Options {
+	o.Meter = meter
+	return o
+}
+
+// WithClock sets the clock of the policy.
+// Tests use it to pass a clock.Fake, so that timeouts and backoffs do not take
+// real time.
+//
+// Parameters:
+//   - c: The clock used to measure executions and wait between retries.
+//
+// Returns:
+//   - A new Options instance with the updated Clock value.
+func (o Options) WithClock(c clock.Clock) Options {
+	o.Clock = c
+	return o
+}
+
+// WithName sets the name of the policy.
+//
+// Parameters:
+//   - name: A string identifier for the policy.
+//
+// Returns:
+//   - A new Options instance with the updated Name value.
+func (o Options) WithName(name string) Options {
+	o.Name = name
+	return o
+}
+
+// FallbackFunc produces the result of an execution that failed, from the error
+// that it failed with.
+type FallbackFunc[T any] func(ctx context.Context, err error) (T, error)
+
+// Policy composes retry, circuit breaking, rate limiting, timeouts, a bulkhead
+// and a fallback around a function. Each strategy is optional and is added with
+// one of the With methods; a policy without any simply calls the function.
+//
+// The strategies are always applied in the same order, from the outermost to
+// the innermost:
+//
+//	Fallback(Retry(CircuitBreaker(RateLimiter(Timeout(Bulkhead(fn))))))
+//
+// so that every retry attempt passes through the circuit breaker and the rate
+// limiter and counts in their statistics, the timeout bounds a single attempt,
+// and the fallback sees the final error once retries are exhausted.
+//
+// A Policy is immutable: the With methods return a modified copy. Copies share
+// the circuit breaker, rate limiter and bulkhead they were given, so a policy is
+// built once and used concurrently from multiple goroutines.
+type Policy[T any] struct {
+	name    string
+	logger  *logging.ContextLogger
+	tracer  telemetry.Tracer
+	clock   clock.Clock
+	metrics *policyMetrics
+
+	retry       *retry.Config
+	isRetryable retry.IsRetryableError
+	breaker     *circuit.CircuitBreaker
+	limiter     *rate.RateLimiter
+	timeout     time.Duration
+	bulkhead    *bulkhead
+	fallback    FallbackFunc[T]
+}
+
+// NewPolicy creates a policy that calls functions without any resilience
+// strategy, to be added with the With methods.
+//
+// Type Parameters:
+//   - T: The result type of the functions executed by the policy.
+//
+// Parameters:
+//   - options: Additional options for the policy, such as logging, tracing and metrics.
+//
+// Returns:
+//   - Policy[T]: A new policy.
+func NewPolicy[T any](options Options) Policy[T] {
+	// Use the provided logger or create a no-op logger
+	logger := options.Logger
+	if logger == nil {
+		logger = logging.NewContextLogger(zap.NewNop())
+	}
+
+	// Use the provided tracer or create a no-op tracer
+	tracer := options.Tracer
+	if tracer == nil {
+		tracer = telemetry.NewNoopTracer()
+	}
+
+	p := Policy[T]{
+		name:   options.Name,
+		logger: logger,
+		tracer: tracer,
+		clock:  clock.OrReal(options.Clock),
+	}
+
+	if options.Meter != nil {
+		metrics, err := newPolicyMetrics(options.Meter, options.Name)
+		if err != nil {
+			logger.Warn(context.Background(), "Failed to create resilience policy metrics, continuing without metrics",
+				zap.String("policy", options.Name),
+				zap.Error(err))
+		} else {
+			p.metrics = metrics
+		}
+	}
+
+	return p
+}
+
+// WithRetry makes the policy retry failed attempts with the given configuration.
+// The retries share the policy's logger, tracer and clock.
+//
+// Parameters:
+//   - config: The retry configuration, such as the number of retries and the backoff.
+//   - isRetryable: A function that determines if an error is retryable. If nil,
+//     IsRetryable is used.
+//
+// Returns:
+//   - Policy[T]: A new policy that retries failed attempts.
+func (p Policy[T]) WithRetry(config retry.Config, isRetryable retry.IsRetryableError) Policy[T] {
+	if isRetryable == nil {
+		isRetryable = IsRetryable
+	}
+	p.retry = &config
+	p.isRetryable = isRetryable
+	return p
+}
+
+// WithCircuitBreaker makes each attempt pass through the given circuit breaker.
+//
+// Parameters:
+//   - cb: The circuit breaker. If nil, attempts are not circuit broken.
+//
+// Returns:
+//   - Policy[T]: A new policy that circuit breaks attempts.
+func (p Policy[T]) WithCircuitBreaker(cb *circuit.CircuitBreaker) Policy[T] {
+	p.breaker = cb
+	return p
+}
+
+// WithRateLimiter makes each attempt pass through the given rate limiter, which
+// rejects the attempt when the limit is reached.
+//
+// Parameters:
+//   - rl: The rate limiter. If nil, attempts are not rate limited.
+//
+// Returns:
+//   - Policy[T]: A new policy that rate limits attempts.
+func (p Policy[T]) WithRateLimiter(rl *rate.RateLimiter) Policy[T] {
+	p.limiter = rl
+	return p
+}
+
+// WithTimeout bounds the duration of each attempt. The function is called with
+// a context that is cancelled when the timeout elapses, and must return when it
+// is done; the attempt then fails with an error with the TimeoutCode.
+// A timeout of zero or less disables the timeout.
+//
+// Parameters:
+//   - timeout: The maximum duration of an attempt.
+//
+// Returns:
+//   - Policy[T]: A new policy that times out attempts.
+func (p Policy[T]) WithTimeout(timeout time.Duration) Policy[T] {
+	p.timeout = max(timeout, 0)
+	return p
+}
+
+// WithBulkhead limits the number of attempts in flight through the policy.
+// Attempts beyond the limit are rejected at once with an error with the
+// ResourceExhaustedCode, rather than queued. A limit of zero or less disables
+// the bulkhead.
+//
+// Parameters:
+//   - maxConcurrent: The maximum number of attempts in flight.
+//
+// Returns:
+//   - Policy[T]: A new policy that limits concurrent attempts.
+func (p Policy[T]) WithBulkhead(maxConcurrent int) Policy[T] {
+	p.bulkhead = nil
+	if maxConcurrent > 0 {
+		p.bulkhead = newBulkhead(maxConcurrent)
+	}
+	return p
+}
+
+// WithFallback makes the policy call fallback with the final error when an
+// execution fails, and return its result instead.
+//
+// Parameters:
+//   - fallback: The function that produces the result of a failed execution.
+//
+// Returns:
+//   - Policy[T]: A new policy with a fallback.
+func (p Policy[T]) WithFallback(fallback FallbackFunc[T]) Policy[T] {
+	p.fallback = fallback
+	return p
+}
+
+// Execute calls fn through the strategies of the policy, in the order described
+// on Policy. It records a single resilience.Execute span, which is the parent of
+// the spans of the retry attempts, circuit breaker and rate limiter, and records
+// the policy's metrics once per execution.
+//
+// Parameters:
+//   - ctx: The context for the operation. Can be used to cancel the execution.
+//   - fn: The function to execute.
+//
+// Returns:
+//   - T: The result of the function, or of the fallback if it was used.
+//   - error: The final error of the execution, or of the fallback if it was used.
+func (p Policy[T]) Execute(ctx context.Context, fn func(ctx context.Context) (T, error)) (T, error) {
+	var span telemetry.Span
+	ctx, span = p.tracer.Start(ctx, "resilience.Execute")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("resilience.policy", p.name),
+		attribute.Bool("resilience.retry", p.retry != nil),
+		attribute.Bool("resilience.circuit_breaker", p.breaker != nil),
+		attribute.Bool("resilience.rate_limiter", p.limiter != nil),
+		attribute.Int64("resilience.timeout_ms", p.timeout.Milliseconds()),
+		attribute.Bool("resilience.bulkhead", p.bulkhead != nil),
+		attribute.Bool("resilience.fallback", p.fallback != nil),
+	)
+
+	startTime := p.clock.Now()
+	attempts := 0
+	attempt := func(ctx context.Context) (T, error) {
+		attempts++
+		p.metrics.recordAttempt()
+		return p.attempt(ctx, fn)
+	}
+
+	result, err := p.retried(ctx, attempt)
+
+	out := outcomeSuccess
+	if err != nil {
+		out = outcomeFailure
+		span.RecordError(err)
+
+		if p.fallback != nil {
+			p.logger.Warn(ctx, "Resilience policy failed, using fallback",
+				zap.String("policy", p.name),
+				zap.Int("attempts", attempts),
+				zap.Error(err))
+
+			out = outcomeFallback
+			result, err = p.fallback(ctx, err)
+			if err != nil {
+				out = outcomeFailure
+				span.RecordError(err)
+			}
+		}
+	}
+
+	duration := p.clock.Since(startTime)
+	span.SetAttributes(
+		attribute.String("resilience.result", out.String()),
+		attribute.Int("resilience.attempts", attempts),
+		attribute.Int64("resilience.duration_ms", duration.Milliseconds()),
+	)
+	p.metrics.recordExecution(out, duration)
+
+	return result, err
+}
+
+// retried calls attempt once, or with retries if the policy retries.
+func (p Policy[T]) retried(ctx context.Context, attempt func(ctx context.Context) (T, error)) (T, error) {
+	if p.retry == nil {
+		return attempt(ctx)
+	}
+
+	var result T
+	options := retry.Options{Logger: p.logger, Tracer: p.tracer, Clock: p.clock}
+	err := retry.DoWithOptions(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = attempt(ctx)
+		return err
+	}, *p.retry, p.isRetryable, options)
+	return result, err
+}
+
+// attempt calls fn once through the circuit breaker, rate limiter, timeout and
+// bulkhead. Each strategy wraps the ones inside it, so they are wrapped from the
+// innermost, the bulkhead, to the outermost, the circuit breaker.
+func (p Policy[T]) attempt(ctx context.Context, fn func(ctx context.Context) (T, error)) (T, error) {
+	if p.bulkhead != nil {
+		next := fn
+		fn = func(ctx context.Context) (T, error) {
+			release, err := p.bulkhead.acquire(p.name)
+			if err != nil {
+				p.metrics.recordRejection(stageBulkhead)
+				var zero T
+				return zero, err
+			}
+			defer release()
+			return next(ctx)
+		}
+	}
+
+	if p.timeout > 0 {
+		next := fn
+		fn = func(ctx context.Context) (T, error) {
+			return p.withTimeout(ctx, next)
+		}
+	}
+
+	if p.limiter != nil {
+		next := fn
+		fn = func(ctx context.Context) (T, error) {
+			called := false
+			result, err := rate.Execute(ctx, p.limiter, p.name, func(ctx context.Context) (T, error) {
+				called = true
+				return next(ctx)
+			})
+			if err != nil && !called {
+				p.metrics.recordRejection(stageRateLimiter)
+			}
+			return result, err
+		}
+	}
+
+	if p.breaker != nil {
+		next := fn
+		fn = func(ctx context.Context) (T, error) {
+			called := false
+			result, err := circuit.Execute(ctx, p.breaker, p.name, func(ctx context.Context) (T, error) {
+				called = true
+				return next(ctx)
+			})
+			if err != nil && !called {
+				p.metrics.recordRejection(stageCircuitBreaker)
+			}
+			return result, err
+		}
+	}
+
+	return fn(ctx)
+}
+
+// withTimeout calls fn with a context that is cancelled when the policy's
+// timeout elapses, and turns the error of an attempt that timed out into an
+// error with the TimeoutCode.
+func (p Policy[T]) withTimeout(ctx context.Context, fn func(ctx context.Context) (T, error)) (T, error) {
+	attemptCtx, cancel := clock.WithTimeout(ctx, p.clock, p.timeout)
+	defer cancel()
+
+	result, err := fn(attemptCtx)
+	if err != nil && attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		p.metrics.recordTimeout()
+		p.logger.Warn(ctx, "Resilience policy attempt timed out",
+			zap.String("policy", p.name),
+			zap.Duration("timeout", p.timeout))
+		return result, errors.Wrap(err, errors.TimeoutCode,
+			fmt.Sprintf("resilience policy %s timed out after %s", p.name, p.timeout))
+	}
+	return result, err
+}
+
+// IsRetryable is the retry condition of a policy that is not given one. It
+// retries transient errors, such as network errors, timeouts and rate limit
+// rejections, but not rejections by an open circuit breaker, which would only
+// be rejected again until the circuit's sleep window has passed.
+//
+// Parameters:
+//   - err: The error of a failed attempt.
+//
+// Returns:
+//   - bool: Whether the attempt should be retried.
+func IsRetryable(err error) bool {
+	return errors.IsTransientError(err) && !errors.IsCircuitBreakerOpenError(err)
+}
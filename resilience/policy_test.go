@@ -0,0 +1,290 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resilience_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/circuit"
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/rate"
+	"github.com/abitofhelp/servicelib/resilience"
+	"github.com/abitofhelp/servicelib/resilience/resiliencetest"
+	"github.com/abitofhelp/servicelib/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// transientErr is an error that IsRetryable retries.
+var transientErr = errors.NewNetworkError("connection refused", "localhost", "5432", nil)
+
+// retryConfig returns a retry configuration without jitter, so that the
+// backoffs can be asserted.
+func retryConfig(maxRetries int) retry.Config {
+	return retry.DefaultConfig().
+		WithMaxRetries(maxRetries).
+		WithInitialBackoff(100 * time.Millisecond).
+		WithBackoffFactor(2).
+		WithMaxBackoff(time.Second).
+		WithJitterFactor(0)
+}
+
+func TestPolicy_NoStrategies(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	policy := resilience.NewPolicy[string](h.Options("plain"))
+
+	result, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Latency: 50 * time.Millisecond, Result: "ok"}))
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Len(t, h.Calls(), 1)
+	assert.Equal(t, 50*time.Millisecond, h.Elapsed())
+}
+
+func TestPolicy_Retry(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	policy := resilience.NewPolicy[string](h.Options("retry")).
+		WithRetry(retryConfig(3), nil)
+
+	result, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Err: transientErr},
+		resiliencetest.Step[string]{Err: transientErr},
+		resiliencetest.Step[string]{Result: "ok"},
+	))
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Equal(t, []time.Duration{0, 100 * time.Millisecond, 300 * time.Millisecond}, h.Calls())
+}
+
+func TestPolicy_RetryNonRetryableError(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	policy := resilience.NewPolicy[string](h.Options("retry")).
+		WithRetry(retryConfig(3), nil)
+
+	invalid := errors.NewValidationError("invalid name", "name", nil)
+	_, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Err: invalid}))
+
+	assert.ErrorIs(t, err, invalid)
+	assert.Len(t, h.Calls(), 1)
+}
+
+func TestPolicy_RetryExhausted(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	policy := resilience.NewPolicy[string](h.Options("retry")).
+		WithRetry(retryConfig(2), nil)
+
+	_, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Err: transientErr}))
+
+	assert.True(t, errors.IsRetryError(err))
+	assert.Len(t, h.Calls(), 3)
+}
+
+func TestPolicy_Timeout(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	policy := resilience.NewPolicy[string](h.Options("timeout")).
+		WithTimeout(100 * time.Millisecond)
+
+	_, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Latency: time.Second, Result: "late"}))
+
+	require.Error(t, err)
+	assert.True(t, errors.IsTimeout(err))
+	assert.Equal(t, 100*time.Millisecond, h.Elapsed())
+}
+
+func TestPolicy_TimeoutBoundsEachAttempt(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	policy := resilience.NewPolicy[string](h.Options("timeout")).
+		WithRetry(retryConfig(2), nil).
+		WithTimeout(100 * time.Millisecond)
+
+	result, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Latency: time.Second},
+		resiliencetest.Step[string]{Latency: 80 * time.Millisecond, Result: "ok"},
+	))
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+
+	// The first attempt times out at 100ms and is retried after a 100ms backoff
+	assert.Equal(t, []time.Duration{0, 200 * time.Millisecond}, h.Calls())
+	assert.Equal(t, 280*time.Millisecond, h.Elapsed())
+}
+
+func TestPolicy_CircuitBreakerStopsRetries(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	cb := circuit.NewCircuitBreaker(
+		circuit.DefaultConfig().WithConsecutiveFailures(2).WithSleepWindow(time.Minute),
+		circuit.DefaultOptions().WithName("db"))
+	require.NotNil(t, cb)
+
+	policy := resilience.NewPolicy[string](h.Options("breaker")).
+		WithRetry(retryConfig(5), nil).
+		WithCircuitBreaker(cb)
+
+	_, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Err: transientErr}))
+
+	// The circuit opens after two failures, and its rejection is not retried
+	assert.True(t, errors.IsCircuitBreakerOpenError(err))
+	assert.Len(t, h.Calls(), 2)
+	assert.Equal(t, circuit.Open, cb.GetState())
+}
+
+func TestPolicy_RateLimiter(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	rl := rate.NewRateLimiter(
+		rate.DefaultConfig().WithRequestsPerSecond(1).WithBurstSize(1),
+		rate.DefaultOptions().WithName("api"))
+	require.NotNil(t, rl)
+
+	policy := resilience.NewPolicy[string](h.Options("limited")).WithRateLimiter(rl)
+	fn := resiliencetest.Script(h, resiliencetest.Step[string]{Result: "ok"})
+
+	result, err := policy.Execute(context.Background(), fn)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+
+	_, err = policy.Execute(context.Background(), fn)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.New(errors.ResourceExhaustedCode, "")))
+	assert.Len(t, h.Calls(), 1)
+}
+
+func TestPolicy_Bulkhead(t *testing.T) {
+	policy := resilience.NewPolicy[string](resilience.DefaultOptions().WithName("bulkhead")).
+		WithBulkhead(1)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = policy.Execute(context.Background(), func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			return "ok", nil
+		})
+	}()
+	<-started
+
+	called := false
+	_, err := policy.Execute(context.Background(), func(ctx context.Context) (string, error) {
+		called = true
+		return "ok", nil
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.New(errors.ResourceExhaustedCode, "")))
+	assert.False(t, called)
+
+	close(release)
+	wg.Wait()
+
+	result, err := policy.Execute(context.Background(), func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+}
+
+func TestPolicy_Fallback(t *testing.T) {
+	h := resiliencetest.NewHarness()
+
+	var fallbackErr error
+	policy := resilience.NewPolicy[string](h.Options("fallback")).
+		WithRetry(retryConfig(1), nil).
+		WithFallback(func(ctx context.Context, err error) (string, error) {
+			fallbackErr = err
+			return "cached", nil
+		})
+
+	result, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Err: transientErr}))
+
+	require.NoError(t, err)
+	assert.Equal(t, "cached", result)
+
+	// The fallback sees the final error, once retries are exhausted
+	assert.True(t, errors.IsRetryError(fallbackErr))
+	assert.Len(t, h.Calls(), 2)
+}
+
+func TestPolicy_FallbackError(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	fallbackFailed := errors.New(errors.InternalErrorCode, "no cached value")
+	policy := resilience.NewPolicy[string](h.Options("fallback")).
+		WithFallback(func(ctx context.Context, err error) (string, error) {
+			return "", fallbackFailed
+		})
+
+	_, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Err: transientErr}))
+
+	assert.Equal(t, fallbackFailed, err)
+}
+
+func TestPolicy_IsImmutable(t *testing.T) {
+	h := resiliencetest.NewHarness()
+	base := resilience.NewPolicy[string](h.Options("base"))
+	_ = base.WithTimeout(10 * time.Millisecond)
+
+	// The base policy has no timeout, so the slow call succeeds
+	result, err := base.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Latency: time.Second, Result: "ok"}))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+}
+
+func TestPolicy_Span(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	h := resiliencetest.NewHarness()
+	policy := resilience.NewPolicy[string](h.Options("traced").WithOtelTracer(provider.Tracer("test"))).
+		WithRetry(retryConfig(1), nil)
+
+	_, err := policy.Execute(context.Background(), resiliencetest.Script(h,
+		resiliencetest.Step[string]{Err: transientErr},
+		resiliencetest.Step[string]{Result: "ok"},
+	))
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	byName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		byName[span.Name()] = append(byName[span.Name()], span)
+	}
+	require.Len(t, byName["resilience.Execute"], 1)
+	require.Len(t, byName["retry.Do"], 1)
+	require.Len(t, byName["retry.Attempt"], 2)
+
+	root := byName["resilience.Execute"][0]
+	assert.Equal(t, root.SpanContext().SpanID(), byName["retry.Do"][0].Parent().SpanID())
+
+	attrs := make(map[string]any)
+	for _, kv := range root.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	assert.Equal(t, "traced", attrs["resilience.policy"])
+	assert.Equal(t, "success", attrs["resilience.result"])
+	assert.Equal(t, int64(2), attrs["resilience.attempts"])
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, resilience.IsRetryable(transientErr))
+	assert.True(t, resilience.IsRetryable(errors.New(errors.TimeoutCode, "timed out")))
+	assert.False(t, resilience.IsRetryable(errors.NewCircuitBreakerOpenError("db")))
+	assert.False(t, resilience.IsRetryable(errors.NewValidationError("invalid", "name", nil)))
+	assert.False(t, resilience.IsRetryable(nil))
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package resiliencetest provides a test harness for resilience policies.
+//
+// The harness runs a policy on a fake clock against a scripted function, whose
+// calls take a given latency and return given results, so that tests of
+// timeouts, retries and backoffs run at once and with predictable timing.
+//
+// Example usage:
+//
+//	h := resiliencetest.NewHarness()
+//	policy := resilience.NewPolicy[string](h.Options("example")).
+//	    WithTimeout(100 * time.Millisecond).
+//	    WithRetry(retry.DefaultConfig().WithMaxRetries(2), nil)
+//
+//	fn := resiliencetest.Script(h,
+//	    resiliencetest.Step[string]{Latency: time.Second},             // times out
+//	    resiliencetest.Step[string]{Latency: 10 * time.Millisecond, Result: "ok"},
+//	)
+//
+//	result, err := policy.Execute(context.Background(), fn)
+//	// result == "ok", err == nil, len(h.Calls()) == 2
+package resiliencetest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/resilience"
+)
+
+// Step is the behavior of one call of a scripted function.
+type Step[T any] struct {
+	// Latency is how long the call takes on the harness's clock. If the
+	// deadline of the call's context comes first, the call returns the
+	// context's error at the deadline.
+	Latency time.Duration
+
+	// Result is the result of the call.
+	Result T
+
+	// Err is the error of the call.
+	Err error
+}
+
+// Harness runs resilience policies on a fake clock that advances automatically
+// whenever the policy or a scripted function waits, so that an execution runs to
+// completion in the test's goroutine without taking real time.
+//
+// A Harness is meant for executions that run one at a time. Tests of concurrent
+// executions should use a clock.Fake without auto-advance instead.
+type Harness struct {
+	// Clock is the fake clock of the harness.
+	Clock *clock.Fake
+
+	start time.Time
+	mu    sync.Mutex
+	calls []time.Duration
+}
+
+// NewHarness creates a harness with a fake clock that advances automatically.
+//
+// Returns:
+//   - *Harness: A new Harness.
+func NewHarness() *Harness {
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+	return &Harness{Clock: fake, start: fake.Now()}
+}
+
+// Options returns policy options that use the harness's clock.
+//
+// Parameters:
+//   - name: The name of the policy.
+//
+// Returns:
+//   - resilience.Options: Default options with the given name and the fake clock.
+func (h *Harness) Options(name string) resilience.Options {
+	return resilience.DefaultOptions().WithName(name).WithClock(h.Clock)
+}
+
+// Elapsed returns the time that has passed on the harness's clock since the
+// harness was created.
+//
+// Returns:
+//   - time.Duration: The elapsed fake time.
+func (h *Harness) Elapsed() time.Duration {
+	return h.Clock.Since(h.start)
+}
+
+// Calls returns the times at which the scripted functions of the harness were
+// called, as offsets from the creation of the harness.
+//
+// Returns:
+//   - []time.Duration: The time of each call, in order.
+func (h *Harness) Calls() []time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]time.Duration(nil), h.calls...)
+}
+
+// Script returns a function that behaves as the given steps, one per call. Once
+// the steps are used up, every further call behaves as the last step. Without
+// steps, the function returns the zero value of T at once.
+//
+// Type Parameters:
+//   - T: The result type of the function.
+//
+// Parameters:
+//   - h: The harness whose clock the calls take their latency on.
+//   - steps: The behavior of each call, in order.
+//
+// Returns:
+//   - func(ctx context.Context) (T, error): The scripted function, to be executed by a policy.
+func Script[T any](h *Harness, steps ...Step[T]) func(ctx context.Context) (T, error) {
+	var mu sync.Mutex
+	next := 0
+
+	return func(ctx context.Context) (T, error) {
+		h.mu.Lock()
+		h.calls = append(h.calls, h.Elapsed())
+		h.mu.Unlock()
+
+		var step Step[T]
+		mu.Lock()
+		if len(steps) > 0 {
+			step = steps[min(next, len(steps)-1)]
+			next++
+		}
+		mu.Unlock()
+
+		// Let the latency pass, or only until the deadline of the call if that
+		// comes first, as a function that honors its context would return then
+		end := h.Clock.Now().Add(step.Latency)
+		if deadline, ok := ctx.Deadline(); ok && deadline.Before(end) {
+			end = deadline
+		}
+		h.Clock.Set(end)
+		if err := ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+		return step.Result, step.Err
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resiliencetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScript(t *testing.T) {
+	h := NewHarness()
+	failed := errors.New("failed")
+	fn := Script(h,
+		Step[int]{Latency: time.Second, Err: failed},
+		Step[int]{Latency: 2 * time.Second, Result: 42},
+	)
+
+	_, err := fn(context.Background())
+	assert.Equal(t, failed, err)
+
+	result, err := fn(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 42, result)
+
+	// The last step repeats
+	result, err = fn(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 42, result)
+
+	assert.Equal(t, []time.Duration{0, time.Second, 3 * time.Second}, h.Calls())
+	assert.Equal(t, 5*time.Second, h.Elapsed())
+}
+
+func TestScript_Deadline(t *testing.T) {
+	h := NewHarness()
+	fn := Script(h, Step[string]{Latency: time.Minute, Result: "late"})
+
+	ctx, cancel := clock.WithTimeout(context.Background(), h.Clock, time.Second)
+	defer cancel()
+
+	_, err := fn(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, time.Second, h.Elapsed())
+}
+
+func TestScript_NoSteps(t *testing.T) {
+	h := NewHarness()
+
+	result, err := Script[string](h)(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestHarness_Options(t *testing.T) {
+	h := NewHarness()
+
+	options := h.Options("example")
+	assert.Equal(t, "example", options.Name)
+	assert.Same(t, h.Clock, options.Clock)
+}
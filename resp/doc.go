@@ -0,0 +1,32 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package resp provides a minimal client for servers that speak the Redis
+// serialization protocol (RESP), such as Redis, Valkey, KeyDB, and Dragonfly.
+//
+// The client implements the wire protocol and a small connection pool, and
+// nothing more. It exists so that components such as the shared cache store
+// can talk to a RESP server without pulling a full-featured client library
+// into every service.
+//
+// Key features:
+//   - Pooled, lazily dialed connections safe for concurrent use
+//   - Context deadlines applied to every command
+//   - Optional AUTH and SELECT on connect
+//   - Helpers for converting replies to common Go types
+//   - Lua scripts run with EVALSHA, falling back to EVAL
+//   - An in-process fake server in the resptest package for tests
+//
+// Example usage:
+//
+//	client := resp.NewClient(resp.DefaultConfig().WithAddress("redis:6379"))
+//	defer client.Close()
+//
+//	// Store a value with a 30 second TTL
+//	_, err := client.Do(ctx, "SET", "greeting", "hello", "PX", 30000)
+//
+//	// Read it back
+//	value, err := resp.String(client.Do(ctx, "GET", "greeting"))
+//	if errors.Is(err, resp.ErrNil) {
+//	    // The key does not exist
+//	}
+package resp
@@ -0,0 +1,143 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/abitofhelp/servicelib/errors"
+)
+
+// ErrNil is returned by the reply helpers when the server replied with a null value,
+// for example when GET is called for a key that does not exist.
+var ErrNil = errors.New(errors.NotFoundCode, "resp: nil reply")
+
+// Bytes converts a reply to a byte slice.
+// It is intended to wrap a call to Do, for example: resp.Bytes(client.Do(ctx, "GET", key)).
+//
+// Parameters:
+//   - reply: The reply returned by Do.
+//   - err: The error returned by Do.
+//
+// Returns:
+//   - []byte: The reply as a byte slice.
+//   - error: The error from Do, ErrNil for a null reply, or an error if the reply
+//     cannot be converted.
+func Bytes(reply interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case int64:
+		return strconv.AppendInt(nil, v, 10), nil
+	case nil:
+		return nil, ErrNil
+	default:
+		return nil, fmt.Errorf("resp: unexpected reply type %T for bytes", reply)
+	}
+}
+
+// String converts a reply to a string.
+//
+// Parameters:
+//   - reply: The reply returned by Do.
+//   - err: The error returned by Do.
+//
+// Returns:
+//   - string: The reply as a string.
+//   - error: The error from Do, ErrNil for a null reply, or an error if the reply
+//     cannot be converted.
+func String(reply interface{}, err error) (string, error) {
+	b, err := Bytes(reply, err)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Int64 converts a reply to an int64.
+//
+// Parameters:
+//   - reply: The reply returned by Do.
+//   - err: The error returned by Do.
+//
+// Returns:
+//   - int64: The reply as an integer.
+//   - error: The error from Do, ErrNil for a null reply, or an error if the reply
+//     cannot be converted.
+func Int64(reply interface{}, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case nil:
+		return 0, ErrNil
+	default:
+		return 0, fmt.Errorf("resp: unexpected reply type %T for int64", reply)
+	}
+}
+
+// Values converts a reply to a slice of replies.
+//
+// Parameters:
+//   - reply: The reply returned by Do.
+//   - err: The error returned by Do.
+//
+// Returns:
+//   - []interface{}: The elements of an array reply.
+//   - error: The error from Do, ErrNil for a null reply, or an error if the reply
+//     is not an array.
+func Values(reply interface{}, err error) ([]interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	switch v := reply.(type) {
+	case []interface{}:
+		return v, nil
+	case nil:
+		return nil, ErrNil
+	default:
+		return nil, fmt.Errorf("resp: unexpected reply type %T for array", reply)
+	}
+}
+
+// Strings converts an array reply to a slice of strings.
+// Null elements are converted to empty strings.
+//
+// Parameters:
+//   - reply: The reply returned by Do.
+//   - err: The error returned by Do.
+//
+// Returns:
+//   - []string: The elements of the array reply as strings.
+//   - error: The error from Do, ErrNil for a null reply, or an error if the reply
+//     cannot be converted.
+func Strings(reply interface{}, err error) ([]string, error) {
+	values, err := Values(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(values))
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		s, err := String(value, nil)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = s
+	}
+	return result, nil
+}
@@ -0,0 +1,605 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package resp provides a minimal client for servers that speak the Redis
+// serialization protocol (RESP).
+//
+// This package implements just enough of the protocol to back shared caches.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abitofhelp/servicelib/errors"
+)
+
+const (
+	// maxBulkLength is the longest bulk string that ReadReply accepts, the
+	// default proto-max-bulk-len of Redis. A longer one is a corrupt reply.
+	maxBulkLength int64 = 512 * 1024 * 1024
+
+	// maxArrayLength is the most elements of an array that ReadReply accepts,
+	// the most that a Redis aggregate can hold.
+	maxArrayLength int64 = 1<<32 - 1
+
+	// maxArrayPrealloc bounds the elements allocated before they are read, so
+	// that the length of a corrupt reply cannot allocate memory by itself.
+	maxArrayPrealloc = 1024
+)
+
+// Config contains client configuration parameters.
+// It defines how the client connects to the server and how long it waits
+// for network operations to complete.
+type Config struct {
+	// Address is the host:port of the server.
+	Address string
+
+	// Password is sent with AUTH after connecting, if not empty.
+	Password string
+
+	// DB is the database index selected with SELECT after connecting.
+	DB int
+
+	// DialTimeout is the maximum time allowed to establish a connection.
+	DialTimeout time.Duration
+
+	// ReadTimeout is the maximum time allowed to read a reply.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the maximum time allowed to write a command.
+	WriteTimeout time.Duration
+
+	// PoolSize is the maximum number of idle connections kept for reuse.
+	PoolSize int
+}
+
+// DefaultConfig returns a default client configuration with reasonable values.
+// The default configuration includes:
+//   - Address: "localhost:6379"
+//   - DB: 0
+//   - DialTimeout: 5 seconds
+//   - ReadTimeout: 3 seconds
+//   - WriteTimeout: 3 seconds
+//   - PoolSize: 10
+//
+// Returns:
+//   - A Config instance with default values.
+func DefaultConfig() Config {
+	return Config{
+		Address:      "localhost:6379",
+		DB:           0,
+		DialTimeout:  5 * time.Second,
+		ReadTimeout:  3 * time.Second,
+		WriteTimeout: 3 * time.Second,
+		PoolSize:     10,
+	}
+}
+
+// WithAddress sets the host:port of the server.
+//
+// Parameters:
+//   - address: The address of the server.
+//
+// Returns:
+//   - A new Config instance with the updated Address value.
+func (c Config) WithAddress(address string) Config {
+	c.Address = address
+	return c
+}
+
+// WithPassword sets the password sent with AUTH after connecting.
+//
+// Parameters:
+//   - password: The server password.
+//
+// Returns:
+//   - A new Config instance with the updated Password value.
+func (c Config) WithPassword(password string) Config {
+	c.Password = password
+	return c
+}
+
+// WithDB sets the database index selected after connecting.
+// If a negative value is provided, it will be set to 0.
+//
+// Parameters:
+//   - db: The database index.
+//
+// Returns:
+//   - A new Config instance with the updated DB value.
+func (c Config) WithDB(db int) Config {
+	if db < 0 {
+		db = 0
+	}
+	c.DB = db
+	return c
+}
+
+// WithDialTimeout sets the maximum time allowed to establish a connection.
+// If a non-positive value is provided, it will be set to 1 second.
+//
+// Parameters:
+//   - timeout: The dial timeout.
+//
+// Returns:
+//   - A new Config instance with the updated DialTimeout value.
+func (c Config) WithDialTimeout(timeout time.Duration) Config {
+	if timeout <= 0 {
+		timeout = 1 * time.Second
+	}
+	c.DialTimeout = timeout
+	return c
+}
+
+// WithReadTimeout sets the maximum time allowed to read a reply.
+// If a non-positive value is provided, it will be set to 1 second.
+//
+// Parameters:
+//   - timeout: The read timeout.
+//
+// Returns:
+//   - A new Config instance with the updated ReadTimeout value.
+func (c Config) WithReadTimeout(timeout time.Duration) Config {
+	if timeout <= 0 {
+		timeout = 1 * time.Second
+	}
+	c.ReadTimeout = timeout
+	return c
+}
+
+// WithWriteTimeout sets the maximum time allowed to write a command.
+// If a non-positive value is provided, it will be set to 1 second.
+//
+// Parameters:
+//   - timeout: The write timeout.
+//
+// Returns:
+//   - A new Config instance with the updated WriteTimeout value.
+func (c Config) WithWriteTimeout(timeout time.Duration) Config {
+	if timeout <= 0 {
+		timeout = 1 * time.Second
+	}
+	c.WriteTimeout = timeout
+	return c
+}
+
+// WithPoolSize sets the maximum number of idle connections kept for reuse.
+// If a non-positive value is provided, it will be set to 1.
+//
+// Parameters:
+//   - poolSize: The maximum number of idle connections.
+//
+// Returns:
+//   - A new Config instance with the updated PoolSize value.
+func (c Config) WithPoolSize(poolSize int) Config {
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	c.PoolSize = poolSize
+	return c
+}
+
+// Error is an error reply returned by the server, such as "ERR unknown command"
+// or "NOSCRIPT No matching script".
+type Error string
+
+// Error returns the error message sent by the server.
+func (e Error) Error() string {
+	return string(e)
+}
+
+// conn is a single connection to the server with buffered I/O.
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+}
+
+// Client is a RESP client with a pool of reusable connections.
+// It is safe for concurrent use by multiple goroutines.
+type Client struct {
+	config Config
+	idle   chan *conn
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewClient creates a new client with the given configuration.
+// Connections are established lazily when the first command is sent.
+//
+// Parameters:
+//   - config: The configuration parameters for the client.
+//
+// Returns:
+//   - *Client: A new client instance.
+func NewClient(config Config) *Client {
+	poolSize := config.PoolSize
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	return &Client{
+		config: config,
+		idle:   make(chan *conn, poolSize),
+	}
+}
+
+// Do sends a command to the server and returns its reply.
+// The reply is one of: string (simple string), []byte (bulk string), int64 (integer),
+// []interface{} (array), or nil (null bulk string or null array). Error replies are
+// returned as an Error value in the error result.
+//
+// Parameters:
+//   - ctx: The context for the operation. Its deadline bounds the network I/O.
+//   - args: The command name followed by its arguments.
+//
+// Returns:
+//   - interface{}: The decoded reply.
+//   - error: An Error if the server returned an error reply, or another error if the
+//     command could not be sent or the reply could not be read.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError("context cancelled or timed out before command was sent", err)
+	}
+
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.roundTrip(ctx, cn, args)
+	if err != nil {
+		if _, ok := err.(Error); !ok {
+			// The connection state is unknown after an I/O or protocol error
+			_ = cn.netConn.Close()
+			return nil, err
+		}
+	}
+
+	c.put(cn)
+	return reply, err
+}
+
+// Close closes all idle connections. Commands sent after Close return an error.
+//
+// Returns:
+//   - error: Always nil; provided for io.Closer compatibility.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	for {
+		select {
+		case cn := <-c.idle:
+			_ = cn.netConn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// get returns an idle connection or dials a new one.
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, errors.New(errors.InternalErrorCode, "resp client is closed")
+	}
+
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	return c.dial(ctx)
+}
+
+// put returns a connection to the idle pool, closing it if the pool is full.
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		_ = cn.netConn.Close()
+		return
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		_ = cn.netConn.Close()
+	}
+}
+
+// dial establishes and initializes a new connection.
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := net.Dialer{Timeout: c.config.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.config.Address)
+	if err != nil {
+		host, port, _ := net.SplitHostPort(c.config.Address)
+		return nil, errors.NewNetworkError("failed to connect to resp server", host, port, err)
+	}
+
+	cn := &conn{
+		netConn: netConn,
+		reader:  bufio.NewReader(netConn),
+		writer:  bufio.NewWriter(netConn),
+	}
+
+	if c.config.Password != "" {
+		if _, err := c.roundTrip(ctx, cn, []interface{}{"AUTH", c.config.Password}); err != nil {
+			_ = netConn.Close()
+			return nil, errors.Wrap(err, errors.UnauthorizedCode, "failed to authenticate with resp server")
+		}
+	}
+
+	if c.config.DB != 0 {
+		if _, err := c.roundTrip(ctx, cn, []interface{}{"SELECT", c.config.DB}); err != nil {
+			_ = netConn.Close()
+			return nil, errors.Wrap(err, errors.ConfigurationErrorCode, "failed to select resp database")
+		}
+	}
+
+	return cn, nil
+}
+
+// roundTrip writes a command and reads its reply on the given connection.
+func (c *Client) roundTrip(ctx context.Context, cn *conn, args []interface{}) (interface{}, error) {
+	writeDeadline := time.Now().Add(c.config.WriteTimeout)
+	readDeadline := time.Now().Add(c.config.ReadTimeout)
+	if deadline, ok := ctx.Deadline(); ok {
+		if c.config.WriteTimeout <= 0 || deadline.Before(writeDeadline) {
+			writeDeadline = deadline
+		}
+		if c.config.ReadTimeout <= 0 || deadline.Before(readDeadline) {
+			readDeadline = deadline
+		}
+	} else {
+		if c.config.WriteTimeout <= 0 {
+			writeDeadline = time.Time{}
+		}
+		if c.config.ReadTimeout <= 0 {
+			readDeadline = time.Time{}
+		}
+	}
+
+	if err := cn.netConn.SetWriteDeadline(writeDeadline); err != nil {
+		return nil, errors.NewNetworkError("failed to set write deadline", "", "", err)
+	}
+	if err := WriteCommand(cn.writer, args...); err != nil {
+		return nil, errors.NewNetworkError("failed to write command", "", "", err)
+	}
+	if err := cn.writer.Flush(); err != nil {
+		return nil, errors.NewNetworkError("failed to write command", "", "", err)
+	}
+
+	if err := cn.netConn.SetReadDeadline(readDeadline); err != nil {
+		return nil, errors.NewNetworkError("failed to set read deadline", "", "", err)
+	}
+	reply, err := ReadReply(cn.reader)
+	if err != nil {
+		if _, ok := err.(Error); ok {
+			return nil, err
+		}
+		return nil, errors.NewNetworkError("failed to read reply", "", "", err)
+	}
+	return reply, nil
+}
+
+// WriteCommand encodes a command as a RESP array of bulk strings.
+// Arguments may be strings, byte slices, integers, floats, or booleans;
+// any other value is formatted with fmt.Sprint.
+//
+// Parameters:
+//   - w: The writer to encode the command to.
+//   - args: The command name followed by its arguments.
+//
+// Returns:
+//   - error: An error if writing fails.
+func WriteCommand(w *bufio.Writer, args ...interface{}) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := writeBulk(w, argBytes(arg)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteReply encodes a reply value using the same type mapping that Do returns:
+// string as a simple string, []byte as a bulk string, int64 or int as an integer,
+// []interface{} as an array, nil as a null bulk string, and Error as an error reply.
+//
+// Parameters:
+//   - w: The writer to encode the reply to.
+//   - reply: The reply value.
+//
+// Returns:
+//   - error: An error if writing fails or the value has an unsupported type.
+func WriteReply(w *bufio.Writer, reply interface{}) error {
+	var err error
+	switch v := reply.(type) {
+	case nil:
+		_, err = w.WriteString("$-1\r\n")
+	case string:
+		_, err = fmt.Fprintf(w, "+%s\r\n", v)
+	case Error:
+		_, err = fmt.Fprintf(w, "-%s\r\n", string(v))
+	case []byte:
+		err = writeBulk(w, v)
+	case int64:
+		_, err = fmt.Fprintf(w, ":%d\r\n", v)
+	case int:
+		_, err = fmt.Fprintf(w, ":%d\r\n", v)
+	case []interface{}:
+		if _, err = fmt.Fprintf(w, "*%d\r\n", len(v)); err != nil {
+			return err
+		}
+		for _, elem := range v {
+			if err = WriteReply(w, elem); err != nil {
+				return err
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported reply type %T", reply)
+	}
+	return err
+}
+
+// ReadReply decodes a single reply. Error replies are returned as an Error
+// value in the error result.
+//
+// Parameters:
+//   - r: The reader to decode the reply from.
+//
+// Returns:
+//   - interface{}: The decoded reply.
+//   - error: An Error for error replies, or another error if the reply is malformed.
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("empty reply line")
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := readLength(line, maxBulkLength)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := readFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := readLength(line, maxArrayLength)
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		values := make([]interface{}, 0, min(n, maxArrayPrealloc))
+		for i := 0; i < n; i++ {
+			value, err := ReadReply(r)
+			if err != nil {
+				if _, ok := err.(Error); !ok {
+					return nil, err
+				}
+				value = err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply type %q", line[0])
+	}
+}
+
+// readLength parses the length of a bulk string or an array from its header
+// line, which is -1 for a null reply.
+//
+// Parameters:
+//   - line: The header line, including its type byte.
+//   - limit: The largest length accepted.
+//
+// Returns:
+//   - int: The length, or -1 for a null reply.
+//   - error: An error if the length is not a number, or is below -1 or above limit.
+func readLength(line []byte, limit int64) (int, error) {
+	n, err := strconv.ParseInt(string(line[1:]), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < -1 || n > limit || n > math.MaxInt {
+		return 0, fmt.Errorf("%d is out of range", n)
+	}
+	return int(n), nil
+}
+
+// readLine reads a CRLF-terminated line without the terminator.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// readFull reads exactly len(buf) bytes.
+func readFull(r *bufio.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := r.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// writeBulk writes a single bulk string.
+func writeBulk(w *bufio.Writer, b []byte) error {
+	if _, err := fmt.Fprintf(w, "$%d\r\n", len(b)); err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	_, err := w.WriteString("\r\n")
+	return err
+}
+
+// argBytes converts a command argument to its wire representation.
+func argBytes(arg interface{}) []byte {
+	switch v := arg.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(nil, v, 10)
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64)
+	case bool:
+		if v {
+			return []byte("1")
+		}
+		return []byte("0")
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
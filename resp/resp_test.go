@@ -0,0 +1,189 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resp_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/resp"
+	"github.com/abitofhelp/servicelib/resp/resptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T) (*resp.Client, *resptest.Server) {
+	t.Helper()
+
+	server, err := resptest.NewServer()
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	client := resp.NewClient(resp.DefaultConfig().WithAddress(server.Addr()))
+	t.Cleanup(func() { _ = client.Close() })
+	return client, server
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := resp.DefaultConfig()
+	assert.Equal(t, "localhost:6379", cfg.Address)
+	assert.Equal(t, 10, cfg.PoolSize)
+
+	cfg = cfg.WithDB(-1).WithPoolSize(0).WithDialTimeout(0).WithReadTimeout(0).WithWriteTimeout(0)
+	assert.Equal(t, 0, cfg.DB)
+	assert.Equal(t, 1, cfg.PoolSize)
+	assert.Equal(t, time.Second, cfg.DialTimeout)
+	assert.Equal(t, time.Second, cfg.ReadTimeout)
+	assert.Equal(t, time.Second, cfg.WriteTimeout)
+}
+
+func TestClient_Do(t *testing.T) {
+	client, _ := newTestClient(t)
+	ctx := context.Background()
+
+	pong, err := resp.String(client.Do(ctx, "PING"))
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", pong)
+
+	_, err = client.Do(ctx, "SET", "key1", []byte("value1"), "PX", 60000)
+	require.NoError(t, err)
+
+	value, err := resp.Bytes(client.Do(ctx, "GET", "key1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+
+	_, err = resp.Bytes(client.Do(ctx, "GET", "missing"))
+	assert.True(t, errors.Is(err, resp.ErrNil))
+
+	n, err := resp.Int64(client.Do(ctx, "INCRBY", "counter", 5))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	keys, err := resp.Strings(client.Do(ctx, "KEYS", "*"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"counter", "key1"}, keys)
+}
+
+func TestClient_ErrorReply(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	_, err := client.Do(context.Background(), "NOPE")
+	require.Error(t, err)
+	var replyErr resp.Error
+	assert.True(t, errors.As(err, &replyErr))
+	assert.Contains(t, replyErr.Error(), "unknown command")
+
+	// The connection remains usable after an error reply
+	pong, err := resp.String(client.Do(context.Background(), "PING"))
+	require.NoError(t, err)
+	assert.Equal(t, "PONG", pong)
+}
+
+func TestClient_ReusesConnections(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, err := client.Do(ctx, "PING")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 5, server.CommandCount())
+}
+
+func TestClient_DialError(t *testing.T) {
+	client := resp.NewClient(resp.DefaultConfig().WithAddress("127.0.0.1:1").WithDialTimeout(100 * time.Millisecond))
+	defer client.Close()
+
+	_, err := client.Do(context.Background(), "PING")
+	require.Error(t, err)
+	assert.True(t, errors.IsNetworkError(err))
+}
+
+func TestClient_ContextCanceled(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.Do(ctx, "PING")
+	require.Error(t, err)
+	assert.True(t, errors.IsContextError(err))
+}
+
+func TestClient_Closed(t *testing.T) {
+	client, _ := newTestClient(t)
+	require.NoError(t, client.Close())
+
+	_, err := client.Do(context.Background(), "PING")
+	assert.Error(t, err)
+}
+
+func TestWriteAndReadReply(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+
+	reply := []interface{}{"OK", []byte("bulk"), int64(42), nil, []interface{}{[]byte("nested")}}
+	require.NoError(t, resp.WriteReply(w, reply))
+	require.NoError(t, resp.WriteReply(w, resp.Error("ERR boom")))
+	require.NoError(t, w.Flush())
+
+	r := bufio.NewReader(&buf)
+	decoded, err := resp.ReadReply(r)
+	require.NoError(t, err)
+	assert.Equal(t, reply, decoded)
+
+	_, err = resp.ReadReply(r)
+	assert.Equal(t, resp.Error("ERR boom"), err)
+
+	assert.Error(t, resp.WriteReply(w, struct{}{}))
+}
+
+func TestReadReply_InvalidLengths(t *testing.T) {
+	for _, reply := range []string{
+		"$9223372036854775807\r\n",
+		"$536870913\r\n",
+		"$-2\r\n",
+		"$x\r\n",
+		"*9223372036854775807\r\n",
+		"*-2\r\n",
+	} {
+		_, err := resp.ReadReply(bufio.NewReader(strings.NewReader(reply)))
+		assert.Error(t, err, reply)
+	}
+
+	// A long array is not allocated before its elements are read
+	_, err := resp.ReadReply(bufio.NewReader(strings.NewReader("*4294967295\r\n:1\r\n")))
+	assert.Error(t, err)
+
+	// Null replies are still accepted
+	for _, reply := range []string{"$-1\r\n", "*-1\r\n"} {
+		value, err := resp.ReadReply(bufio.NewReader(strings.NewReader(reply)))
+		require.NoError(t, err, reply)
+		assert.Nil(t, value, reply)
+	}
+}
+
+func TestReplyHelpers(t *testing.T) {
+	b, err := resp.Bytes("simple", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("simple"), b)
+
+	n, err := resp.Int64([]byte("12"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), n)
+
+	_, err = resp.Int64(nil, nil)
+	assert.Equal(t, resp.ErrNil, err)
+
+	_, err = resp.Values(int64(1), nil)
+	assert.Error(t, err)
+
+	strs, err := resp.Strings([]interface{}{[]byte("a"), nil}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", ""}, strs)
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resptest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abitofhelp/servicelib/resp"
+)
+
+// ScriptFunc emulates a Lua script in Go. It receives the script's KEYS and ARGV
+// and returns the reply the script would return. The server holds its lock while
+// the function runs, so the function is atomic like a real script.
+type ScriptFunc func(db *DB, keys, args []string) interface{}
+
+// DB gives a ScriptFunc access to the server's keyspace.
+// It is only valid while the ScriptFunc is running.
+type DB struct {
+	s *Server
+}
+
+// HandleScript registers fn as the implementation of the Lua script src.
+// The fake server cannot run Lua, so EVAL and EVALSHA reply with an error for
+// scripts that have no handler.
+//
+// Parameters:
+//   - src: The Lua source of the script, exactly as sent by the client.
+//   - fn: The Go emulation of the script.
+func (s *Server) HandleScript(src string, fn ScriptFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[src] = fn
+}
+
+// Time returns the server time, as returned by the TIME command.
+func (db *DB) Time() time.Time {
+	return time.Now()
+}
+
+// Call runs a command against the keyspace and returns its reply, like
+// redis.call in a Lua script.
+func (db *DB) Call(args ...string) interface{} {
+	return db.s.dispatch(args)
+}
+
+// Get returns the string value of key.
+func (db *DB) Get(key string) (string, bool) {
+	e := db.s.lookup(key)
+	if e == nil || e.value == nil {
+		return "", false
+	}
+	return string(e.value), true
+}
+
+// Set stores a string value under key, removing any expiry.
+func (db *DB) Set(key, value string) {
+	db.s.data[key] = &entry{value: []byte(value)}
+}
+
+// Del removes key.
+func (db *DB) Del(key string) {
+	delete(db.s.data, key)
+}
+
+// HGet returns a field of the hash stored under key.
+func (db *DB) HGet(key, field string) (string, bool) {
+	e := db.s.lookup(key)
+	if e == nil || e.hash == nil {
+		return "", false
+	}
+	value, found := e.hash[field]
+	return value, found
+}
+
+// HSet sets a field of the hash stored under key, creating the hash if needed.
+func (db *DB) HSet(key, field, value string) {
+	e := db.s.lookup(key)
+	if e == nil || e.hash == nil {
+		e = &entry{hash: make(map[string]string)}
+		db.s.data[key] = e
+	}
+	e.hash[field] = value
+}
+
+// PExpire sets the time to live of key.
+func (db *DB) PExpire(key string, ttl time.Duration) {
+	if e := db.s.lookup(key); e != nil {
+		e.expireAt = time.Now().Add(ttl)
+	}
+}
+
+// script implements SCRIPT LOAD and SCRIPT FLUSH.
+func (s *Server) script(args []string) interface{} {
+	if len(args) < 1 {
+		return wrongArgs("SCRIPT")
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "LOAD":
+		if len(args) != 2 {
+			return wrongArgs("SCRIPT|LOAD")
+		}
+		hash := scriptHash(args[1])
+		s.scripts[hash] = args[1]
+		return hash
+	case "FLUSH":
+		s.scripts = make(map[string]string)
+		return "OK"
+	default:
+		return resp.Error("ERR unknown subcommand '" + args[0] + "'")
+	}
+}
+
+// eval implements EVAL script numkeys [key ...] [arg ...] and
+// EVALSHA sha1 numkeys [key ...] [arg ...].
+func (s *Server) eval(name string, args []string) interface{} {
+	if len(args) < 2 {
+		return wrongArgs(name)
+	}
+
+	src := args[0]
+	if name == "EVALSHA" {
+		var found bool
+		src, found = s.scripts[strings.ToLower(args[0])]
+		if !found {
+			return resp.Error("NOSCRIPT No matching script. Please use EVAL.")
+		}
+	} else {
+		s.scripts[scriptHash(src)] = src
+	}
+
+	numKeys, err := strconv.Atoi(args[1])
+	if err != nil || numKeys < 0 {
+		return notInteger()
+	}
+	if numKeys > len(args)-2 {
+		return resp.Error("ERR Number of keys can't be greater than number of args")
+	}
+
+	fn, found := s.handlers[src]
+	if !found {
+		return resp.Error("ERR resptest: no handler registered for script")
+	}
+	return fn(&DB{s: s}, args[2:2+numKeys], args[2+numKeys:])
+}
+
+// scriptHash returns the SHA1 digest that identifies a script.
+func scriptHash(src string) string {
+	sum := sha1.Sum([]byte(src))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,470 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+// Package resptest provides an in-process fake RESP server for tests.
+//
+// The server implements the subset of commands used by this library's
+// RESP-backed components. Data is kept in memory and discarded on Close.
+package resptest
+
+import (
+	"bufio"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abitofhelp/servicelib/resp"
+)
+
+// entry is a single key in the fake server's keyspace.
+type entry struct {
+	value    []byte
+	set      map[string]struct{}
+	hash     map[string]string
+	expireAt time.Time
+}
+
+// Server is an in-process fake RESP server listening on a loopback address.
+// It is safe for concurrent use by multiple clients.
+type Server struct {
+	listener net.Listener
+	mu       sync.Mutex
+	data     map[string]*entry
+	commands int
+	wg       sync.WaitGroup
+	conns    map[net.Conn]struct{}
+	closed   bool
+	scripts  map[string]string
+	handlers map[string]ScriptFunc
+}
+
+// NewServer starts a fake server on a random loopback port.
+// The caller should call Close when finished, typically with t.Cleanup.
+//
+// Returns:
+//   - *Server: The running server.
+//   - error: An error if the listener could not be created.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		data:     make(map[string]*entry),
+		conns:    make(map[net.Conn]struct{}),
+		scripts:  make(map[string]string),
+		handlers: make(map[string]ScriptFunc),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the host:port the server is listening on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server and closes all client connections.
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+// CommandCount returns the number of commands the server has processed.
+func (s *Server) CommandCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commands
+}
+
+// Keys returns all non-expired keys in sorted order.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		if s.lookup(k) != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// serve accepts connections until the listener is closed.
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+// handle processes commands from a single connection.
+func (s *Server) handle(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		_ = c.Close()
+	}()
+
+	reader := bufio.NewReader(c)
+	writer := bufio.NewWriter(c)
+	for {
+		request, err := resp.ReadReply(reader)
+		if err != nil {
+			return
+		}
+		args, ok := request.([]interface{})
+		if !ok || len(args) == 0 {
+			return
+		}
+
+		strArgs := make([]string, len(args))
+		for i, arg := range args {
+			b, _ := arg.([]byte)
+			strArgs[i] = string(b)
+		}
+
+		reply := s.execute(strArgs)
+		if err := resp.WriteReply(writer, reply); err != nil {
+			return
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// execute runs a single command and returns its reply.
+func (s *Server) execute(args []string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands++
+	return s.dispatch(args)
+}
+
+// dispatch runs a single command. The caller must hold the server's lock.
+func (s *Server) dispatch(args []string) interface{} {
+	name := strings.ToUpper(args[0])
+	args = args[1:]
+
+	switch name {
+	case "PING":
+		return "PONG"
+	case "AUTH", "SELECT":
+		return "OK"
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		e := s.lookup(args[0])
+		if e == nil {
+			return nil
+		}
+		if e.set != nil || e.hash != nil {
+			return wrongType()
+		}
+		return e.value
+	case "SET":
+		return s.set(args)
+	case "DEL":
+		var n int64
+		for _, key := range args {
+			if s.lookup(key) != nil {
+				delete(s.data, key)
+				n++
+			}
+		}
+		return n
+	case "EXISTS":
+		var n int64
+		for _, key := range args {
+			if s.lookup(key) != nil {
+				n++
+			}
+		}
+		return n
+	case "PTTL":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		e := s.lookup(args[0])
+		if e == nil {
+			return int64(-2)
+		}
+		if e.expireAt.IsZero() {
+			return int64(-1)
+		}
+		return time.Until(e.expireAt).Milliseconds()
+	case "PEXPIRE":
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		ms, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return notInteger()
+		}
+		e := s.lookup(args[0])
+		if e == nil {
+			return int64(0)
+		}
+		e.expireAt = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return int64(1)
+	case "INCRBY":
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		delta, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return notInteger()
+		}
+		e := s.lookup(args[0])
+		if e == nil {
+			e = &entry{value: []byte("0")}
+			s.data[args[0]] = e
+		}
+		current, err := strconv.ParseInt(string(e.value), 10, 64)
+		if err != nil {
+			return notInteger()
+		}
+		current += delta
+		e.value = []byte(strconv.FormatInt(current, 10))
+		return current
+	case "DBSIZE":
+		var n int64
+		for k := range s.data {
+			if s.lookup(k) != nil {
+				n++
+			}
+		}
+		return n
+	case "FLUSHDB", "FLUSHALL":
+		s.data = make(map[string]*entry)
+		return "OK"
+	case "KEYS":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		return s.match(args[0])
+	case "SCAN":
+		return s.scan(args)
+	case "SCRIPT":
+		return s.script(args)
+	case "EVAL", "EVALSHA":
+		return s.eval(name, args)
+	case "SADD":
+		if len(args) < 2 {
+			return wrongArgs(name)
+		}
+		e := s.lookup(args[0])
+		if e == nil {
+			e = &entry{set: make(map[string]struct{})}
+			s.data[args[0]] = e
+		}
+		if e.set == nil {
+			return wrongType()
+		}
+		var n int64
+		for _, member := range args[1:] {
+			if _, found := e.set[member]; !found {
+				e.set[member] = struct{}{}
+				n++
+			}
+		}
+		return n
+	case "SREM":
+		if len(args) < 2 {
+			return wrongArgs(name)
+		}
+		e := s.lookup(args[0])
+		if e == nil {
+			return int64(0)
+		}
+		if e.set == nil {
+			return wrongType()
+		}
+		var n int64
+		for _, member := range args[1:] {
+			if _, found := e.set[member]; found {
+				delete(e.set, member)
+				n++
+			}
+		}
+		if len(e.set) == 0 {
+			delete(s.data, args[0])
+		}
+		return n
+	case "SMEMBERS":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		e := s.lookup(args[0])
+		if e == nil {
+			return []interface{}{}
+		}
+		if e.set == nil {
+			return wrongType()
+		}
+		members := make([]string, 0, len(e.set))
+		for member := range e.set {
+			members = append(members, member)
+		}
+		sort.Strings(members)
+		return toReplies(members)
+	default:
+		return resp.Error("ERR unknown command '" + name + "'")
+	}
+}
+
+// set implements SET key value [EX seconds|PX milliseconds] [NX|XX].
+func (s *Server) set(args []string) interface{} {
+	if len(args) < 2 {
+		return wrongArgs("SET")
+	}
+
+	key, value := args[0], args[1]
+	var ttl time.Duration
+	var nx, xx bool
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return resp.Error("ERR syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return resp.Error("ERR invalid expire time in 'set' command")
+			}
+			if strings.ToUpper(args[i]) == "EX" {
+				ttl = time.Duration(n) * time.Second
+			} else {
+				ttl = time.Duration(n) * time.Millisecond
+			}
+			i++
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		default:
+			return resp.Error("ERR syntax error")
+		}
+	}
+
+	exists := s.lookup(key) != nil
+	if (nx && exists) || (xx && !exists) {
+		return nil
+	}
+
+	e := &entry{value: []byte(value)}
+	if ttl > 0 {
+		e.expireAt = time.Now().Add(ttl)
+	}
+	s.data[key] = e
+	return "OK"
+}
+
+// scan implements SCAN cursor [MATCH pattern] [COUNT count].
+// The fake server returns all matching keys in a single page.
+func (s *Server) scan(args []string) interface{} {
+	if len(args) < 1 {
+		return wrongArgs("SCAN")
+	}
+
+	pattern := "*"
+	for i := 1; i+1 < len(args); i += 2 {
+		if strings.ToUpper(args[i]) == "MATCH" {
+			pattern = args[i+1]
+		}
+	}
+
+	if args[0] != "0" {
+		return []interface{}{[]byte("0"), []interface{}{}}
+	}
+	return []interface{}{[]byte("0"), s.match(pattern)}
+}
+
+// match returns all non-expired keys matching a glob pattern.
+func (s *Server) match(pattern string) []interface{} {
+	keys := make([]string, 0)
+	for k := range s.data {
+		if s.lookup(k) == nil {
+			continue
+		}
+		if ok, _ := path.Match(pattern, k); ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return toReplies(keys)
+}
+
+// lookup returns the entry for a key, removing it if it has expired.
+// The caller must hold the lock.
+func (s *Server) lookup(key string) *entry {
+	e, found := s.data[key]
+	if !found {
+		return nil
+	}
+	if !e.expireAt.IsZero() && !time.Now().Before(e.expireAt) {
+		delete(s.data, key)
+		return nil
+	}
+	return e
+}
+
+func toReplies(values []string) []interface{} {
+	replies := make([]interface{}, len(values))
+	for i, v := range values {
+		replies[i] = []byte(v)
+	}
+	return replies
+}
+
+func wrongArgs(name string) resp.Error {
+	return resp.Error("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+}
+
+func wrongType() resp.Error {
+	return resp.Error("WRONGTYPE Operation against a key holding the wrong kind of value")
+}
+
+func notInteger() resp.Error {
+	return resp.Error("ERR value is not an integer or out of range")
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resp
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+)
+
+// Script is a Lua script run atomically on the server with EVALSHA.
+// The server caches scripts by their SHA1 digest, so after the first call only
+// the digest is sent. Script is safe for concurrent use.
+type Script struct {
+	keyCount int
+	src      string
+	hash     string
+}
+
+// NewScript creates a script with a fixed number of keys.
+//
+// Parameters:
+//   - keyCount: The number of leading arguments passed as KEYS; the rest are passed as ARGV.
+//   - src: The Lua source of the script.
+//
+// Returns:
+//   - *Script: A new script.
+func NewScript(keyCount int, src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{
+		keyCount: keyCount,
+		src:      src,
+		hash:     hex.EncodeToString(sum[:]),
+	}
+}
+
+// Hash returns the SHA1 digest the server uses to identify the script.
+func (s *Script) Hash() string {
+	return s.hash
+}
+
+// Do runs the script with EVALSHA, and with EVAL if the server has not cached it yet.
+//
+// Parameters:
+//   - ctx: The context for the command.
+//   - client: The client used to send the command.
+//   - keysAndArgs: The script's keys followed by its arguments.
+//
+// Returns:
+//   - interface{}: The reply returned by the script.
+//   - error: An error if the script failed or the command could not be sent.
+func (s *Script) Do(ctx context.Context, client *Client, keysAndArgs ...interface{}) (interface{}, error) {
+	args := make([]interface{}, 0, len(keysAndArgs)+3)
+	args = append(args, "EVALSHA", s.hash, s.keyCount)
+	args = append(args, keysAndArgs...)
+
+	reply, err := client.Do(ctx, args...)
+	if e, ok := err.(Error); ok && strings.HasPrefix(string(e), "NOSCRIPT") {
+		// EVAL runs the script and caches it for later EVALSHA calls
+		args[0], args[1] = "EVAL", s.src
+		reply, err = client.Do(ctx, args...)
+	}
+	return reply, err
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package resp_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/abitofhelp/servicelib/resp"
+	"github.com/abitofhelp/servicelib/resp/resptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const incrScript = `return redis.call('INCRBY', KEYS[1], ARGV[1])`
+
+func TestScript(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	server.HandleScript(incrScript, func(db *resptest.DB, keys, args []string) interface{} {
+		value, _ := db.Get(keys[0])
+		current, _ := strconv.ParseInt(value, 10, 64)
+		delta, _ := strconv.ParseInt(args[0], 10, 64)
+		current += delta
+		db.Set(keys[0], strconv.FormatInt(current, 10))
+		return current
+	})
+
+	script := resp.NewScript(1, incrScript)
+	assert.Len(t, script.Hash(), 40)
+
+	// The first call falls back to EVAL, which caches the script
+	n, err := resp.Int64(script.Do(ctx, client, "counter", 2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	commands := server.CommandCount()
+	n, err = resp.Int64(script.Do(ctx, client, "counter", 2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), n)
+	assert.Equal(t, commands+1, server.CommandCount(), "cached script should be run with a single EVALSHA")
+
+	// After a flush, the script is sent again
+	_, err = client.Do(ctx, "SCRIPT", "FLUSH")
+	require.NoError(t, err)
+	n, err = resp.Int64(script.Do(ctx, client, "counter", 2))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), n)
+}
+
+func TestScript_Errors(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	// A script without a handler fails on the fake server
+	_, err := resp.NewScript(0, "return 1").Do(ctx, client)
+	assert.Error(t, err)
+
+	hash, err := resp.String(client.Do(ctx, "SCRIPT", "LOAD", "return 1"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.NewScript(0, "return 1").Hash(), hash)
+
+	server.HandleScript("return 1", func(db *resptest.DB, keys, args []string) interface{} {
+		return int64(1)
+	})
+	_, err = client.Do(ctx, "EVALSHA", hash, 2, "only-one-key")
+	assert.Error(t, err)
+	_, err = client.Do(ctx, "EVALSHA", hash, "x")
+	assert.Error(t, err)
+	n, err := resp.Int64(client.Do(ctx, "EVALSHA", hash, 0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
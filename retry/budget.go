@@ -0,0 +1,183 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package retry
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+)
+
+// BudgetConfig contains retry budget configuration parameters.
+// A retry budget caps the retries made across all calls that share it to a
+// ratio of the calls, so that retries cannot multiply the load on a dependency
+// that is already failing.
+type BudgetConfig struct {
+	// Ratio is the number of retries allowed per call.
+	// For example, a Ratio of 0.1 allows one retry for every ten calls.
+	Ratio float64
+
+	// MinRetriesPerSecond is the number of retries allowed per second regardless
+	// of the number of calls, so that callers with little traffic can still retry.
+	MinRetriesPerSecond float64
+
+	// Burst is the maximum number of retries that the budget saves up while
+	// calls succeed. The budget starts full.
+	Burst int
+}
+
+// DefaultBudgetConfig returns a default retry budget configuration.
+// The default configuration includes:
+//   - 0.1 ratio (one retry for every ten calls)
+//   - 1 retry per second regardless of the number of calls
+//   - A burst of 10 retries
+//
+// Returns:
+//   - A BudgetConfig instance with default values.
+func DefaultBudgetConfig() BudgetConfig {
+	return BudgetConfig{
+		Ratio:               0.1,
+		MinRetriesPerSecond: 1,
+		Burst:               10,
+	}
+}
+
+// WithRatio sets the number of retries allowed per call.
+// If a negative value is provided, it will be set to 0.
+//
+// Parameters:
+//   - ratio: The number of retries allowed per call.
+//
+// Returns:
+//   - A new BudgetConfig instance with the updated Ratio value.
+func (c BudgetConfig) WithRatio(ratio float64) BudgetConfig {
+	c.Ratio = max(ratio, 0)
+	return c
+}
+
+// WithMinRetriesPerSecond sets the number of retries allowed per second
+// regardless of the number of calls.
+// If a negative value is provided, it will be set to 0.
+//
+// Parameters:
+//   - minRetriesPerSecond: The number of retries allowed per second.
+//
+// Returns:
+//   - A new BudgetConfig instance with the updated MinRetriesPerSecond value.
+func (c BudgetConfig) WithMinRetriesPerSecond(minRetriesPerSecond float64) BudgetConfig {
+	c.MinRetriesPerSecond = max(minRetriesPerSecond, 0)
+	return c
+}
+
+// WithBurst sets the maximum number of retries that the budget saves up.
+// If a value less than 1 is provided, it will be set to 1.
+//
+// Parameters:
+//   - burst: The maximum number of saved up retries.
+//
+// Returns:
+//   - A new BudgetConfig instance with the updated Burst value.
+func (c BudgetConfig) WithBurst(burst int) BudgetConfig {
+	c.Burst = max(burst, 1)
+	return c
+}
+
+// Budget is a token bucket of retries shared by the calls to a dependency.
+// Every call deposits Ratio tokens, tokens also accrue at MinRetriesPerSecond,
+// and every retry or hedge withdraws a token; when the bucket is empty, failed
+// attempts are not retried. DoWithOptions uses the budget of its Config, and
+// DoHedged the budget of its Hedger.
+//
+// A nil *Budget allows every retry.
+//
+// This implementation is thread-safe and can be used concurrently from multiple
+// goroutines.
+type Budget struct {
+	config BudgetConfig
+	clock  clock.Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewBudget creates a full retry budget.
+//
+// Parameters:
+//   - config: The configuration of the budget.
+//   - options: Additional options. Only the Clock is used, to accrue
+//     MinRetriesPerSecond.
+//
+// Returns:
+//   - *Budget: A new Budget instance.
+func NewBudget(config BudgetConfig, options Options) *Budget {
+	config.Burst = max(config.Burst, 1)
+	c := clock.OrReal(options.Clock)
+	return &Budget{
+		config: config,
+		clock:  c,
+		tokens: float64(config.Burst),
+		last:   c.Now(),
+	}
+}
+
+// Deposit credits the budget for a call. DoWithOptions and DoHedged deposit once
+// per call; callers only need it to credit calls made without them.
+func (b *Budget) Deposit() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens = min(b.tokens+b.config.Ratio, float64(b.config.Burst))
+}
+
+// Withdraw takes a retry from the budget.
+//
+// Returns:
+//   - bool: Whether the retry is allowed. If false, the budget is exhausted and
+//     nothing was taken.
+func (b *Budget) Withdraw() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Available returns the number of retries the budget allows now.
+// If the budget is nil, math.MaxInt is returned.
+//
+// Returns:
+//   - int: The number of whole tokens in the budget.
+func (b *Budget) Available() int {
+	if b == nil {
+		return math.MaxInt
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	return int(b.tokens)
+}
+
+// refill accrues the tokens of MinRetriesPerSecond since the last refill.
+// The caller must hold the lock.
+func (b *Budget) refill() {
+	now := b.clock.Now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.config.MinRetriesPerSecond, float64(b.config.Burst))
+	}
+	b.last = now
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetConfig_With(t *testing.T) {
+	config := DefaultBudgetConfig()
+	assert.Equal(t, 0.1, config.Ratio)
+	assert.Equal(t, 1.0, config.MinRetriesPerSecond)
+	assert.Equal(t, 10, config.Burst)
+
+	assert.Equal(t, 0.0, config.WithRatio(-1).Ratio)
+	assert.Equal(t, 0.5, config.WithRatio(0.5).Ratio)
+	assert.Equal(t, 0.0, config.WithMinRetriesPerSecond(-1).MinRetriesPerSecond)
+	assert.Equal(t, 1, config.WithBurst(0).Burst)
+	assert.Equal(t, 5, config.WithBurst(5).Burst)
+}
+
+func TestBudget_WithdrawAndDeposit(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	budget := NewBudget(
+		DefaultBudgetConfig().WithRatio(0.5).WithMinRetriesPerSecond(0).WithBurst(2),
+		DefaultOptions().WithClock(fake))
+
+	// The budget starts full
+	assert.Equal(t, 2, budget.Available())
+	assert.True(t, budget.Withdraw())
+	assert.True(t, budget.Withdraw())
+	assert.False(t, budget.Withdraw())
+
+	// Two calls earn one retry
+	budget.Deposit()
+	assert.False(t, budget.Withdraw())
+	budget.Deposit()
+	assert.True(t, budget.Withdraw())
+
+	// Deposits never exceed the burst
+	for range 10 {
+		budget.Deposit()
+	}
+	assert.Equal(t, 2, budget.Available())
+}
+
+func TestBudget_MinRetriesPerSecond(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	budget := NewBudget(
+		DefaultBudgetConfig().WithRatio(0).WithMinRetriesPerSecond(2).WithBurst(1),
+		DefaultOptions().WithClock(fake))
+
+	assert.True(t, budget.Withdraw())
+	assert.False(t, budget.Withdraw())
+
+	fake.Advance(250 * time.Millisecond)
+	assert.False(t, budget.Withdraw())
+
+	fake.Advance(250 * time.Millisecond)
+	assert.True(t, budget.Withdraw())
+}
+
+func TestBudget_Nil(t *testing.T) {
+	var budget *Budget
+	budget.Deposit()
+	assert.True(t, budget.Withdraw())
+	assert.Equal(t, math.MaxInt, budget.Available())
+}
+
+func TestDoWithOptions_Budget(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+	options := DefaultOptions().WithClock(fake)
+
+	budget := NewBudget(
+		DefaultBudgetConfig().WithRatio(0).WithMinRetriesPerSecond(0).WithBurst(3),
+		options)
+	config := DefaultConfig().WithMaxRetries(5).WithBudget(budget)
+
+	failure := errors.New("unavailable")
+	attempts := 0
+	fn := func(ctx context.Context) error {
+		attempts++
+		return failure
+	}
+
+	// The first call spends the budget on three retries and returns the last error
+	err := DoWithOptions(context.Background(), fn, config, nil, options)
+	assert.Equal(t, failure, err)
+	assert.Equal(t, 4, attempts)
+
+	// The second call is not retried at all
+	attempts = 0
+	err = DoWithOptions(context.Background(), fn, config, nil, options)
+	assert.Equal(t, failure, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestDoWithOptions_BudgetNotSpentOnSuccess(t *testing.T) {
+	budget := NewBudget(DefaultBudgetConfig().WithRatio(0).WithMinRetriesPerSecond(0).WithBurst(1), DefaultOptions())
+	config := DefaultConfig().WithBudget(budget)
+
+	err := DoWithOptions(context.Background(), func(ctx context.Context) error { return nil }, config, nil, DefaultOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, budget.Available())
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/errors/wrappers"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorClass names a kind of failure, so that retries can be tuned per kind,
+// for example with a longer backoff for rate limiting than for network errors.
+type ErrorClass string
+
+const (
+	// ClassUnknown is an error that could not be classified
+	ClassUnknown ErrorClass = "unknown"
+
+	// ClassTransient is a temporary failure that is likely to succeed on retry
+	ClassTransient ErrorClass = "transient"
+
+	// ClassNetwork is a network failure, such as a refused or reset connection
+	ClassNetwork ErrorClass = "network"
+
+	// ClassTimeout is an operation that timed out
+	ClassTimeout ErrorClass = "timeout"
+
+	// ClassRateLimited is a request rejected because a rate limit or quota was reached
+	ClassRateLimited ErrorClass = "rate_limited"
+
+	// ClassUnavailable is a dependency that is temporarily unavailable
+	ClassUnavailable ErrorClass = "unavailable"
+
+	// ClassCircuitOpen is a request rejected by an open circuit breaker
+	ClassCircuitOpen ErrorClass = "circuit_open"
+
+	// ClassExhausted is an operation whose own retries were exhausted, which is
+	// not retried again so that nested retries do not multiply
+	ClassExhausted ErrorClass = "exhausted"
+
+	// ClassCancelled is an operation whose context was cancelled
+	ClassCancelled ErrorClass = "cancelled"
+
+	// ClassPermanent is a failure that will not succeed on retry, such as
+	// invalid input or a missing resource
+	ClassPermanent ErrorClass = "permanent"
+)
+
+// Decision is the retry decision for an error.
+type Decision struct {
+	// Retryable is true if the operation should be retried
+	Retryable bool
+
+	// Class is the kind of failure, which selects the backoff in Config.ClassBackoff
+	Class ErrorClass
+
+	// RetryAfter is how long the server asked the client to wait before
+	// retrying, or zero if it gave no hint. The wait before the next attempt is
+	// at least RetryAfter, but no longer than the maximum backoff of the class.
+	RetryAfter time.Duration
+}
+
+// Classifier makes the retry decision for an error.
+type Classifier func(err error) Decision
+
+// Classify is the default Classifier. It recognizes, in order:
+//   - Cancelled contexts, which are not retried
+//   - errors.RetryError, whose retries were already exhausted, which is not retried
+//   - Rejections by an open circuit breaker, which are not retried
+//   - HTTPError, by status code, with the delay of its Retry-After header
+//   - gRPC status errors, by code, with the delay of their RetryInfo detail
+//   - wrappers.RetryableError, with the delay of its RetryAfter
+//   - Errors of this library, by error code and with errors.IsTransientError
+//
+// Errors it does not recognize are of ClassUnknown and are not retryable.
+//
+// Parameters:
+//   - err: The error of a failed attempt.
+//
+// Returns:
+//   - Decision: The retry decision for the error.
+func Classify(err error) Decision {
+	switch {
+	case err == nil:
+		return Decision{}
+	case stderrors.Is(err, context.Canceled) || errors.IsCancelled(err):
+		return Decision{Class: ClassCancelled}
+	case errors.IsRetryError(err):
+		return Decision{Class: ClassExhausted}
+	case errors.IsCircuitBreakerOpenError(err):
+		return Decision{Class: ClassCircuitOpen}
+	}
+
+	var httpErr *HTTPError
+	if stderrors.As(err, &httpErr) {
+		return classifyHTTPStatus(httpErr.StatusCode, httpErr.RetryAfter)
+	}
+
+	if s, ok := status.FromError(err); ok && s.Code() != codes.OK && s.Code() != codes.Unknown {
+		return classifyGRPCStatus(s)
+	}
+
+	var retryableErr *wrappers.RetryableError
+	if stderrors.As(err, &retryableErr) {
+		return Decision{
+			Retryable:  retryableErr.IsRetryable(),
+			Class:      ClassTransient,
+			RetryAfter: time.Duration(retryableErr.RetryAfter) * time.Millisecond,
+		}
+	}
+
+	var coded interface{ GetCode() errors.ErrorCode }
+	if stderrors.As(err, &coded) {
+		switch coded.GetCode() {
+		case errors.ResourceExhaustedCode:
+			return Decision{Retryable: true, Class: ClassRateLimited}
+		case errors.ServiceUnavailableCode:
+			return Decision{Retryable: true, Class: ClassUnavailable}
+		case errors.InvalidInputCode, errors.ValidationErrorCode, errors.NotFoundCode,
+			errors.AlreadyExistsCode, errors.UnauthorizedCode, errors.ForbiddenCode,
+			errors.BusinessRuleViolationCode, errors.ConfigurationErrorCode, errors.DataCorruptionCode:
+			return Decision{Class: ClassPermanent}
+		}
+	}
+
+	switch {
+	case errors.IsTimeout(err):
+		return Decision{Retryable: true, Class: ClassTimeout}
+	case errors.IsNetworkError(err):
+		return Decision{Retryable: true, Class: ClassNetwork}
+	case errors.IsTransientError(err):
+		return Decision{Retryable: true, Class: ClassTransient}
+	case errors.IsDomainError(err) || errors.IsAuthenticationError(err) || errors.IsAuthorizationError(err):
+		return Decision{Class: ClassPermanent}
+	}
+
+	return Decision{Class: ClassUnknown}
+}
+
+// IsRetryable reports whether Classify considers an error retryable.
+// It can be passed to DoWithOptions as the isRetryable function.
+//
+// Parameters:
+//   - err: The error of a failed attempt.
+//
+// Returns:
+//   - bool: Whether the operation should be retried.
+func IsRetryable(err error) bool {
+	return Classify(err).Retryable
+}
+
+// classifyHTTPStatus makes the retry decision for an HTTP status code.
+func classifyHTTPStatus(code int, retryAfter time.Duration) Decision {
+	switch code {
+	case http.StatusTooManyRequests:
+		return Decision{Retryable: true, Class: ClassRateLimited, RetryAfter: retryAfter}
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return Decision{Retryable: true, Class: ClassUnavailable, RetryAfter: retryAfter}
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return Decision{Retryable: true, Class: ClassTimeout, RetryAfter: retryAfter}
+	case http.StatusInternalServerError, http.StatusTooEarly:
+		return Decision{Retryable: true, Class: ClassTransient, RetryAfter: retryAfter}
+	default:
+		return Decision{Class: ClassPermanent}
+	}
+}
+
+// classifyGRPCStatus makes the retry decision for a gRPC status.
+func classifyGRPCStatus(s *status.Status) Decision {
+	var retryAfter time.Duration
+	for _, detail := range s.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			retryAfter = info.GetRetryDelay().AsDuration()
+		}
+	}
+
+	switch s.Code() {
+	case codes.Unavailable:
+		return Decision{Retryable: true, Class: ClassUnavailable, RetryAfter: retryAfter}
+	case codes.ResourceExhausted:
+		return Decision{Retryable: true, Class: ClassRateLimited, RetryAfter: retryAfter}
+	case codes.DeadlineExceeded:
+		return Decision{Retryable: true, Class: ClassTimeout, RetryAfter: retryAfter}
+	case codes.Aborted:
+		return Decision{Retryable: true, Class: ClassTransient, RetryAfter: retryAfter}
+	case codes.Canceled:
+		return Decision{Class: ClassCancelled}
+	default:
+		return Decision{Class: ClassPermanent}
+	}
+}
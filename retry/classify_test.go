@@ -0,0 +1,70 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package retry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	serviceErrors "github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/errors/wrappers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Decision
+	}{
+		{"nil", nil, Decision{}},
+		{"context canceled", fmt.Errorf("call: %w", context.Canceled), Decision{Class: ClassCancelled}},
+		{"retry error", serviceErrors.NewRetryError("exhausted", nil, 3, 3), Decision{Class: ClassExhausted}},
+		{"circuit open", serviceErrors.NewCircuitBreakerOpenError("db"), Decision{Class: ClassCircuitOpen}},
+		{"http 429", &HTTPError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Second},
+			Decision{Retryable: true, Class: ClassRateLimited, RetryAfter: 2 * time.Second}},
+		{"http 503", &HTTPError{StatusCode: http.StatusServiceUnavailable}, Decision{Retryable: true, Class: ClassUnavailable}},
+		{"http 504", &HTTPError{StatusCode: http.StatusGatewayTimeout}, Decision{Retryable: true, Class: ClassTimeout}},
+		{"http 404", &HTTPError{StatusCode: http.StatusNotFound}, Decision{Class: ClassPermanent}},
+		{"grpc unavailable", status.Error(codes.Unavailable, "down"), Decision{Retryable: true, Class: ClassUnavailable}},
+		{"grpc invalid argument", status.Error(codes.InvalidArgument, "bad"), Decision{Class: ClassPermanent}},
+		{"grpc canceled", status.Error(codes.Canceled, "canceled"), Decision{Class: ClassCancelled}},
+		{"retryable wrapper", wrappers.NewRetryableError(fmt.Errorf("busy"), 1, 3, 500),
+			Decision{Retryable: true, Class: ClassTransient, RetryAfter: 500 * time.Millisecond}},
+		{"resource exhausted", serviceErrors.NewInfrastructureError(serviceErrors.ResourceExhaustedCode, "quota", nil),
+			Decision{Retryable: true, Class: ClassRateLimited}},
+		{"service unavailable", serviceErrors.NewInfrastructureError(serviceErrors.ServiceUnavailableCode, "down", nil),
+			Decision{Retryable: true, Class: ClassUnavailable}},
+		{"timeout", context.DeadlineExceeded, Decision{Retryable: true, Class: ClassTimeout}},
+		{"network", serviceErrors.NewNetworkError("refused", "db", "5432", nil), Decision{Retryable: true, Class: ClassNetwork}},
+		{"transient", fmt.Errorf("rate limit exceeded"), Decision{Retryable: true, Class: ClassTransient}},
+		{"not found", serviceErrors.NewNotFoundError("user", "42", nil), Decision{Class: ClassPermanent}},
+		{"validation", serviceErrors.NewValidationError("invalid", "name", nil), Decision{Class: ClassPermanent}},
+		{"unknown", fmt.Errorf("something went wrong"), Decision{Class: ClassUnknown}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Classify(tt.err))
+			assert.Equal(t, tt.want.Retryable, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestClassify_GRPCRetryInfo(t *testing.T) {
+	s, err := status.New(codes.ResourceExhausted, "quota exceeded").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(3 * time.Second),
+	})
+	require.NoError(t, err)
+
+	decision := Classify(fmt.Errorf("call: %w", s.Err()))
+	assert.Equal(t, Decision{Retryable: true, Class: ClassRateLimited, RetryAfter: 3 * time.Second}, decision)
+}
@@ -10,6 +10,13 @@
 //   - Customizable retry conditions
 //   - Integration with OpenTelemetry for tracing
 //   - Comprehensive logging of retry attempts
+//   - A configurable clock, so that tests can pass a clock.Fake
+//   - Retry budgets that cap retries across calls as a ratio of the calls
+//   - Hedging, which sends a second request after a latency percentile and
+//     cancels the loser
+//   - Error classification that honors transient errors, HTTP Retry-After
+//     headers and gRPC status codes, with per-class backoff overrides
+//   - Decorrelated jitter, and the history of every attempt in the final RetryError
 //
 // The package distinguishes between two types of errors related to retries:
 //   - RetryError: Used internally by this package to indicate that all retry attempts have been exhausted.
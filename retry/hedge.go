@@ -0,0 +1,428 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package retry
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/abitofhelp/servicelib/errors"
+	"github.com/abitofhelp/servicelib/logging"
+	"github.com/abitofhelp/servicelib/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// HedgeConfig contains hedging configuration parameters.
+// Hedging sends a second request when the first has not returned within the
+// latency that most requests take, and uses whichever returns first, which cuts
+// the tail latency of calls to a dependency with uneven response times.
+type HedgeConfig struct {
+	// Percentile is the latency percentile after which a hedged request is sent.
+	// For example, a Percentile of 0.95 hedges the requests that are slower than
+	// 95% of recent successful requests.
+	Percentile float64
+
+	// MaxHedges is the maximum number of hedged requests sent in addition to the
+	// first request. Each is sent one delay after the previous one.
+	MaxHedges int
+
+	// InitialDelay is the delay before a hedged request is sent while fewer than
+	// MinSamples latencies have been observed.
+	InitialDelay time.Duration
+
+	// MinDelay is the shortest delay before a hedged request is sent, however
+	// fast recent requests were.
+	MinDelay time.Duration
+
+	// Window is the number of recent successful latencies the percentile is
+	// computed from.
+	Window int
+
+	// MinSamples is the number of latencies that must be observed before the
+	// percentile is used instead of InitialDelay.
+	MinSamples int
+
+	// Budget caps the hedged requests, which add load to the dependency like
+	// retries do. Each call deposits into the budget and each hedged request
+	// withdraws from it. If nil, hedged requests are only limited by MaxHedges.
+	Budget *Budget
+}
+
+// DefaultHedgeConfig returns a default hedging configuration.
+// The default configuration includes:
+//   - The 95th percentile
+//   - 1 hedged request
+//   - 100ms initial delay
+//   - 1ms minimum delay
+//   - A window of 100 latencies, of which at least 20 must be observed
+//
+// Returns:
+//   - A HedgeConfig instance with default values.
+func DefaultHedgeConfig() HedgeConfig {
+	return HedgeConfig{
+		Percentile:   0.95,
+		MaxHedges:    1,
+		InitialDelay: 100 * time.Millisecond,
+		MinDelay:     time.Millisecond,
+		Window:       100,
+		MinSamples:   20,
+	}
+}
+
+// WithPercentile sets the latency percentile after which a hedged request is sent.
+// If a value outside the range (0, 1] is provided, it will be clamped to the valid range.
+//
+// Parameters:
+//   - percentile: The latency percentile, between 0 and 1.
+//
+// Returns:
+//   - A new HedgeConfig instance with the updated Percentile value.
+func (c HedgeConfig) WithPercentile(percentile float64) HedgeConfig {
+	c.Percentile = min(max(percentile, 0.01), 1)
+	return c
+}
+
+// WithMaxHedges sets the maximum number of hedged requests.
+// If a negative value is provided, it will be set to 0 (no hedging).
+//
+// Parameters:
+//   - maxHedges: The maximum number of hedged requests per call.
+//
+// Returns:
+//   - A new HedgeConfig instance with the updated MaxHedges value.
+func (c HedgeConfig) WithMaxHedges(maxHedges int) HedgeConfig {
+	c.MaxHedges = max(maxHedges, 0)
+	return c
+}
+
+// WithInitialDelay sets the delay used until MinSamples latencies have been observed.
+// If a non-positive value is provided, it will be set to MinDelay.
+//
+// Parameters:
+//   - initialDelay: The delay before a hedged request is sent.
+//
+// Returns:
+//   - A new HedgeConfig instance with the updated InitialDelay value.
+func (c HedgeConfig) WithInitialDelay(initialDelay time.Duration) HedgeConfig {
+	if initialDelay <= 0 {
+		initialDelay = c.MinDelay
+	}
+	c.InitialDelay = initialDelay
+	return c
+}
+
+// WithMinDelay sets the shortest delay before a hedged request is sent.
+// If a negative value is provided, it will be set to 0.
+//
+// Parameters:
+//   - minDelay: The shortest delay.
+//
+// Returns:
+//   - A new HedgeConfig instance with the updated MinDelay value.
+func (c HedgeConfig) WithMinDelay(minDelay time.Duration) HedgeConfig {
+	c.MinDelay = max(minDelay, 0)
+	return c
+}
+
+// WithWindow sets the number of recent latencies the percentile is computed from.
+// If a value less than 1 is provided, it will be set to 1.
+//
+// Parameters:
+//   - window: The number of latencies kept.
+//
+// Returns:
+//   - A new HedgeConfig instance with the updated Window value.
+func (c HedgeConfig) WithWindow(window int) HedgeConfig {
+	c.Window = max(window, 1)
+	return c
+}
+
+// WithMinSamples sets the number of latencies that must be observed before the
+// percentile is used. If a value less than 1 is provided, it will be set to 1.
+//
+// Parameters:
+//   - minSamples: The number of latencies needed.
+//
+// Returns:
+//   - A new HedgeConfig instance with the updated MinSamples value.
+func (c HedgeConfig) WithMinSamples(minSamples int) HedgeConfig {
+	c.MinSamples = max(minSamples, 1)
+	return c
+}
+
+// WithBudget sets the retry budget that caps hedged requests.
+//
+// Parameters:
+//   - budget: The shared retry budget, or nil for no budget.
+//
+// Returns:
+//   - A new HedgeConfig instance with the updated Budget value.
+func (c HedgeConfig) WithBudget(budget *Budget) HedgeConfig {
+	c.Budget = budget
+	return c
+}
+
+// Hedger sends hedged requests for the calls made through DoHedged, and tracks
+// the latencies of their successful requests to decide when to hedge.
+// A Hedger is shared by the calls to one dependency.
+//
+// This implementation is thread-safe and can be used concurrently from multiple
+// goroutines.
+type Hedger struct {
+	config HedgeConfig
+	logger *logging.ContextLogger
+	tracer telemetry.Tracer
+	clock  clock.Clock
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+// NewHedger creates a new hedger with the specified configuration and options.
+//
+// Parameters:
+//   - config: The hedging configuration.
+//   - options: Additional options, such as logging, tracing and the clock.
+//
+// Returns:
+//   - *Hedger: A new Hedger instance.
+func NewHedger(config HedgeConfig, options Options) *Hedger {
+	// Use the provided logger or create a no-op logger
+	logger := options.Logger
+	if logger == nil {
+		logger = logging.NewContextLogger(zap.NewNop())
+	}
+
+	// Use the provided tracer or create a no-op tracer
+	tracer := options.Tracer
+	if tracer == nil {
+		tracer = telemetry.NewNoopTracer()
+	}
+
+	config.Window = max(config.Window, 1)
+	config.MinSamples = min(max(config.MinSamples, 1), config.Window)
+
+	return &Hedger{
+		config:    config,
+		logger:    logger,
+		tracer:    tracer,
+		clock:     clock.OrReal(options.Clock),
+		latencies: make([]time.Duration, 0, config.Window),
+	}
+}
+
+// Delay returns how long DoHedged currently waits before sending a hedged
+// request: the configured percentile of the recent successful latencies, or
+// InitialDelay until MinSamples latencies have been observed, and never less
+// than MinDelay.
+//
+// Returns:
+//   - time.Duration: The current hedging delay.
+func (h *Hedger) Delay() time.Duration {
+	if h == nil {
+		return 0
+	}
+
+	h.mu.Lock()
+	if len(h.latencies) < h.config.MinSamples {
+		h.mu.Unlock()
+		return max(h.config.InitialDelay, h.config.MinDelay)
+	}
+	sorted := slices.Clone(h.latencies)
+	h.mu.Unlock()
+
+	slices.Sort(sorted)
+	index := int(math.Ceil(h.config.Percentile*float64(len(sorted)))) - 1
+	index = min(max(index, 0), len(sorted)-1)
+	return max(sorted[index], h.config.MinDelay)
+}
+
+// record adds the latency of a successful request to the window.
+func (h *Hedger) record(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < h.config.Window {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % h.config.Window
+}
+
+// hedgeResult is the outcome of one request of a hedged call.
+type hedgeResult[T any] struct {
+	value   T
+	err     error
+	attempt int
+	latency time.Duration
+}
+
+// DoHedged calls fn and, if it has not returned after the hedger's Delay, calls
+// it again concurrently, up to MaxHedges times, one delay apart. The first
+// request to succeed wins, and the context of every other request is cancelled.
+// If every request sent fails, the error of the last one to fail is returned;
+// hedging reduces latency and does not retry errors, which DoWithOptions does.
+//
+// Because the requests run concurrently, fn must be safe to call concurrently
+// and should be idempotent.
+//
+// Type Parameters:
+//   - T: The result type of the function.
+//
+// Parameters:
+//   - ctx: The context for the operation. Can be used to cancel every request.
+//   - h: The hedger. If nil, fn is called once without hedging.
+//   - fn: The function to execute.
+//
+// Returns:
+//   - T: The result of the request that won.
+//   - error: The error of the last request to fail if none succeeded, or an error
+//     if the context is done first.
+func DoHedged[T any](ctx context.Context, h *Hedger, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if h == nil {
+		return fn(ctx)
+	}
+
+	var span telemetry.Span
+	ctx, span = h.tracer.Start(ctx, "retry.Hedge")
+	defer span.End()
+
+	delay := h.Delay()
+	span.SetAttributes(
+		attribute.Int64("retry.hedge_delay_ms", delay.Milliseconds()),
+		attribute.Int("retry.max_hedges", h.config.MaxHedges),
+	)
+
+	h.config.Budget.Deposit()
+
+	// The channel holds a result for every request, so that the losers never block
+	results := make(chan hedgeResult[T], h.config.MaxHedges+1)
+	var cancels []context.CancelFunc
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	send := func(attempt int) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+
+		go func() {
+			var attemptSpan telemetry.Span
+			attemptCtx, attemptSpan = h.tracer.Start(attemptCtx, "retry.Attempt")
+			attemptSpan.SetAttributes(
+				attribute.Int("retry.attempt", attempt+1),
+				attribute.Bool("retry.hedge", attempt > 0),
+			)
+
+			startTime := h.clock.Now()
+			value, err := fn(attemptCtx)
+			latency := h.clock.Since(startTime)
+
+			attemptSpan.SetAttributes(
+				attribute.Bool("retry.success", err == nil),
+				attribute.Int64("retry.duration_ms", latency.Milliseconds()),
+			)
+			if err != nil {
+				attemptSpan.RecordError(err)
+			}
+			attemptSpan.End()
+
+			results <- hedgeResult[T]{value: value, err: err, attempt: attempt, latency: latency}
+		}()
+	}
+
+	send(0)
+	sent, inFlight := 1, 1
+
+	// cancelled reports a call whose context is done before any request succeeded
+	cancelled := func() error {
+		ctxErr := errors.NewContextError("context cancelled or timed out during hedged request", ctx.Err())
+		span.SetAttributes(
+			attribute.String("retry.result", "cancelled"),
+			attribute.Int("retry.attempts", sent),
+		)
+		span.RecordError(ctxErr)
+		return ctxErr
+	}
+	hedging := h.config.MaxHedges > 0
+
+	timer := h.clock.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		var hedge <-chan time.Time
+		if hedging {
+			hedge = timer.C()
+		}
+
+		select {
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				h.record(r.latency)
+				if r.attempt > 0 {
+					h.logger.Debug(ctx, "Hedged request won",
+						zap.Int("attempt", r.attempt+1),
+						zap.Duration("latency", r.latency))
+				}
+
+				span.SetAttributes(
+					attribute.String("retry.result", "success"),
+					attribute.Int("retry.attempts", sent),
+					attribute.Int("retry.winner", r.attempt+1),
+				)
+				return r.value, nil
+			}
+
+			if ctx.Err() != nil {
+				return zero, cancelled()
+			}
+			if inFlight == 0 {
+				h.logger.Debug(ctx, "Every hedged request failed",
+					zap.Error(r.err),
+					zap.Int("attempts", sent))
+
+				span.SetAttributes(
+					attribute.String("retry.result", "failure"),
+					attribute.Int("retry.attempts", sent),
+				)
+				span.RecordError(r.err)
+				return zero, r.err
+			}
+
+		case <-hedge:
+			if !h.config.Budget.Withdraw() {
+				h.logger.Debug(ctx, "Retry budget exhausted, not hedging",
+					zap.Int("attempts", sent))
+				hedging = false
+				continue
+			}
+
+			h.logger.Debug(ctx, "Sending hedged request",
+				zap.Int("attempt", sent+1),
+				zap.Duration("delay", delay))
+
+			send(sent)
+			sent++
+			inFlight++
+			if sent > h.config.MaxHedges {
+				hedging = false
+			} else {
+				timer.Reset(delay)
+			}
+
+		case <-ctx.Done():
+			return zero, cancelled()
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	serviceErrors "github.com/abitofhelp/servicelib/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedgeConfig_With(t *testing.T) {
+	config := DefaultHedgeConfig()
+	assert.Equal(t, 0.95, config.Percentile)
+	assert.Equal(t, 1, config.MaxHedges)
+
+	assert.Equal(t, 1.0, config.WithPercentile(2).Percentile)
+	assert.Equal(t, 0.01, config.WithPercentile(0).Percentile)
+	assert.Equal(t, 0, config.WithMaxHedges(-1).MaxHedges)
+	assert.Equal(t, config.MinDelay, config.WithInitialDelay(0).InitialDelay)
+	assert.Equal(t, time.Duration(0), config.WithMinDelay(-time.Second).MinDelay)
+	assert.Equal(t, 1, config.WithWindow(0).Window)
+	assert.Equal(t, 1, config.WithMinSamples(0).MinSamples)
+
+	budget := NewBudget(DefaultBudgetConfig(), DefaultOptions())
+	assert.Same(t, budget, config.WithBudget(budget).Budget)
+}
+
+func TestHedger_Delay(t *testing.T) {
+	h := NewHedger(DefaultHedgeConfig().
+		WithPercentile(0.9).
+		WithInitialDelay(50*time.Millisecond).
+		WithMinDelay(5*time.Millisecond).
+		WithWindow(10).
+		WithMinSamples(5), DefaultOptions())
+
+	// The initial delay is used until enough latencies are observed
+	for i := 1; i <= 4; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, h.Delay())
+
+	for i := 5; i <= 10; i++ {
+		h.record(time.Duration(i) * 10 * time.Millisecond)
+	}
+	// Latencies 1-4ms and 50-100ms: the 90th percentile is the 9th of 10
+	assert.Equal(t, 90*time.Millisecond, h.Delay())
+
+	// The window keeps the latest latencies, and the delay is at least MinDelay
+	for range 10 {
+		h.record(time.Millisecond)
+	}
+	assert.Equal(t, 5*time.Millisecond, h.Delay())
+
+	var nilHedger *Hedger
+	assert.Equal(t, time.Duration(0), nilHedger.Delay())
+}
+
+func TestDoHedged_NoHedgeWhenFast(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	h := NewHedger(DefaultHedgeConfig(), DefaultOptions().WithClock(fake))
+
+	var calls atomic.Int32
+	result, err := DoHedged(context.Background(), h, func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		return "ok", nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDoHedged_HedgeWinsAndCancelsLoser(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	h := NewHedger(DefaultHedgeConfig().WithInitialDelay(100*time.Millisecond), DefaultOptions().WithClock(fake))
+
+	var calls atomic.Int32
+	loserCancelled := make(chan struct{})
+	done := make(chan struct{})
+	var result string
+	var err error
+	go func() {
+		defer close(done)
+		result, err = DoHedged(context.Background(), h, func(ctx context.Context) (string, error) {
+			if calls.Add(1) == 1 {
+				// The first request hangs until it is cancelled
+				<-ctx.Done()
+				close(loserCancelled)
+				return "", ctx.Err()
+			}
+			return "hedged", nil
+		})
+	}()
+
+	// Wait for the hedge timer, then let the delay pass
+	fake.BlockUntil(1)
+	fake.Advance(100 * time.Millisecond)
+	<-done
+
+	require.NoError(t, err)
+	assert.Equal(t, "hedged", result)
+	assert.Equal(t, int32(2), calls.Load())
+
+	select {
+	case <-loserCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the losing request was not cancelled")
+	}
+}
+
+func TestDoHedged_AllFail(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	h := NewHedger(DefaultHedgeConfig().WithInitialDelay(100*time.Millisecond), DefaultOptions().WithClock(fake))
+
+	failure := errors.New("unavailable")
+	_, err := DoHedged(context.Background(), h, func(ctx context.Context) (string, error) {
+		return "", failure
+	})
+
+	// A failure is returned at once, without hedging
+	assert.Equal(t, failure, err)
+	assert.Equal(t, 0, fake.Waiters())
+}
+
+func TestDoHedged_Budget(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	options := DefaultOptions().WithClock(fake)
+	budget := NewBudget(DefaultBudgetConfig().WithRatio(0).WithMinRetriesPerSecond(0).WithBurst(1), options)
+	require.True(t, budget.Withdraw())
+
+	h := NewHedger(DefaultHedgeConfig().WithInitialDelay(100*time.Millisecond).WithBudget(budget), options)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = DoHedged(context.Background(), h, func(ctx context.Context) (string, error) {
+			calls.Add(1)
+			<-release
+			return "ok", nil
+		})
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	close(release)
+	<-done
+
+	// The exhausted budget prevented the hedge
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDoHedged_ContextCancelled(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	h := NewHedger(DefaultHedgeConfig(), DefaultOptions().WithClock(fake))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := DoHedged(ctx, h, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	require.Error(t, err)
+	assert.True(t, serviceErrors.IsContextError(err))
+}
+
+func TestDoHedged_NilHedger(t *testing.T) {
+	result, err := DoHedged(context.Background(), nil, func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 42, result)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package retry
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+)
+
+// HTTPError is an HTTP response that failed with an error status. Classify
+// retries it by its status code, and waits at least as long as its Retry-After
+// header asks.
+type HTTPError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Status is the HTTP status line of the response, such as "503 Service Unavailable"
+	Status string
+
+	// RetryAfter is the delay of the Retry-After header, or zero if there was none
+	RetryAfter time.Duration
+}
+
+// NewHTTPError returns an HTTPError for a response with an error status, which
+// can be returned from a RetryableFunc. The response body is not read or closed.
+//
+// Parameters:
+//   - resp: The HTTP response.
+//   - c: The clock used to convert a Retry-After date to a delay, normally the
+//     Clock of the retry Options. If nil, the system clock is used.
+//
+// Returns:
+//   - error: An *HTTPError if the status code is 400 or above, or nil otherwise.
+func NewHTTPError(resp *http.Response, c clock.Clock) error {
+	if resp == nil || resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+
+	retryAfter, _ := ParseRetryAfter(resp.Header.Get("Retry-After"), clock.OrReal(c).Now())
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: retryAfter,
+	}
+}
+
+// Error returns a string representation of the error.
+func (e *HTTPError) Error() string {
+	status := e.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return "http request failed: " + status
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+//
+// Parameters:
+//   - value: The value of the header.
+//   - now: The current time, to convert an HTTP date to a delay.
+//
+// Returns:
+//   - time.Duration: The delay, which is zero for a date in the past.
+//   - bool: Whether the value could be parsed.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
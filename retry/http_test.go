@@ -0,0 +1,64 @@
+// Copyright (c) 2025 A Bit of Help, Inc.
+
+package retry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/abitofhelp/servicelib/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPError(t *testing.T) {
+	assert.Nil(t, NewHTTPError(nil, nil))
+	assert.Nil(t, NewHTTPError(&http.Response{StatusCode: http.StatusOK}, nil))
+
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Status:     "429 Too Many Requests",
+		Header:     http.Header{"Retry-After": []string{"7"}},
+	}
+	err := NewHTTPError(resp, nil)
+	httpErr, ok := err.(*HTTPError)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusTooManyRequests, httpErr.StatusCode)
+	assert.Equal(t, 7*time.Second, httpErr.RetryAfter)
+	assert.Equal(t, "http request failed: 429 Too Many Requests", err.Error())
+
+	// An HTTP date is converted to a delay with the clock
+	fake := clock.NewFake(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	resp.Header.Set("Retry-After", "Wed, 01 Jan 2025 00:00:30 GMT")
+	httpErr, ok = NewHTTPError(resp, fake).(*HTTPError)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, httpErr.RetryAfter)
+
+	assert.Equal(t, "http request failed: 502 Bad Gateway", (&HTTPError{StatusCode: http.StatusBadGateway}).Error())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"empty", "", 0, false},
+		{"seconds", "120", 2 * time.Minute, true},
+		{"negative seconds", "-1", 0, false},
+		{"http date", now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{"past http date", now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"invalid", "soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
@@ -3,8 +3,12 @@
 package retry
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -67,3 +71,33 @@ func TestWithConfigEdgeCases(t *testing.T) {
 		assert.Equal(t, 1.0, newConfig.JitterFactor)
 	})
 }
+
+func TestWithClock(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+	start := fake.Now()
+
+	options := DefaultOptions().WithClock(fake)
+	assert.Same(t, fake, options.Clock)
+
+	config := DefaultConfig().
+		WithMaxRetries(2).
+		WithInitialBackoff(time.Second).
+		WithBackoffFactor(2).
+		WithMaxBackoff(time.Minute).
+		WithJitterFactor(0)
+
+	attempts := 0
+	err := DoWithOptions(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	}, config, nil, options)
+
+	// The backoffs of 1s and 2s pass on the fake clock, not in real time
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 3*time.Second, fake.Since(start))
+}
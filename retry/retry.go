@@ -16,6 +16,7 @@
 	"sync"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/abitofhelp/servicelib/errors"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/abitofhelp/servicelib/telemetry"
@@ -71,8 +72,51 @@ type Config struct {
 	// RetryableErrors is a list of specific errors that should be considered retryable.
 	// If an error matches one of these errors (using errors.Is), it will be retried.
 	RetryableErrors []error
+
+	// Budget caps the retries made across all calls that share it.
+	// If nil, retries are only limited by MaxRetries.
+	Budget *Budget
+
+	// Classifier decides whether an error is retryable, its class and how long
+	// the server asked to wait. If nil, Classify is used.
+	Classifier Classifier
+
+	// ClassBackoff overrides the backoff for errors of a class. Errors of other
+	// classes use InitialBackoff, MaxBackoff and BackoffFactor.
+	ClassBackoff map[ErrorClass]Backoff
+
+	// Jitter is the strategy for randomizing the backoff.
+	// The default, ProportionalJitter, uses JitterFactor.
+	Jitter JitterStrategy
 }
 
+// Backoff is the backoff for errors of a class.
+type Backoff struct {
+	// Initial is the duration to wait before the first retry after an error of the class
+	Initial time.Duration
+
+	// Max is the maximum duration to wait between retries after errors of the class
+	Max time.Duration
+
+	// Factor is the factor by which the backoff increases after each retry
+	Factor float64
+}
+
+// JitterStrategy is a strategy for randomizing the backoff between attempts.
+type JitterStrategy int
+
+const (
+	// ProportionalJitter multiplies the exponential backoff by a random factor
+	// between 1-JitterFactor and 1+JitterFactor.
+	ProportionalJitter JitterStrategy = iota
+
+	// DecorrelatedJitter waits a random duration between the initial backoff and
+	// three times the previous wait, capped at the maximum backoff. It spreads
+	// out the retries of many clients better than ProportionalJitter, and
+	// ignores BackoffFactor and JitterFactor.
+	DecorrelatedJitter
+)
+
 // DefaultConfig returns a default retry configuration with reasonable values.
 // The default configuration includes:
 //   - 3 maximum retry attempts (4 total attempts including the initial one)
@@ -194,6 +238,102 @@ func (c Config) WithRetryableErrors(retryableErrors []error) Config {
 	return c
 }
 
+// WithBudget sets the retry budget shared by the calls made with the configuration.
+// Each call deposits into the budget, and each retry withdraws from it; once the
+// budget is exhausted, failed attempts are not retried.
+//
+// Parameters:
+//   - budget: The shared retry budget, or nil for no budget.
+//
+// Returns:
+//   - A new Config instance with the updated Budget value.
+func (c Config) WithBudget(budget *Budget) Config {
+	c.Budget = budget
+	return c
+}
+
+// WithClassifier sets the function that classifies the errors of failed attempts.
+//
+// Parameters:
+//   - classifier: The classifier, or nil to use Classify.
+//
+// Returns:
+//   - A new Config instance with the updated Classifier value.
+func (c Config) WithClassifier(classifier Classifier) Config {
+	c.Classifier = classifier
+	return c
+}
+
+// WithClassBackoff sets the backoff for errors of a class, for example a longer
+// backoff for rate limiting than for network errors.
+// A non-positive initial backoff is set to 1ms, a maximum backoff below the
+// initial backoff is set to the initial backoff, and a factor below 1.0 is set
+// to 1.0.
+//
+// Parameters:
+//   - class: The class of errors.
+//   - backoff: The backoff for errors of the class.
+//
+// Returns:
+//   - A new Config instance with the updated ClassBackoff value.
+func (c Config) WithClassBackoff(class ErrorClass, backoff Backoff) Config {
+	if backoff.Initial <= 0 {
+		backoff.Initial = 1 * time.Millisecond
+	}
+	if backoff.Max < backoff.Initial {
+		backoff.Max = backoff.Initial
+	}
+	if backoff.Factor < 1.0 {
+		backoff.Factor = 1.0
+	}
+
+	// Copy the map, so that configurations derived from the same Config do not share it
+	classBackoff := make(map[ErrorClass]Backoff, len(c.ClassBackoff)+1)
+	for k, v := range c.ClassBackoff {
+		classBackoff[k] = v
+	}
+	classBackoff[class] = backoff
+	c.ClassBackoff = classBackoff
+	return c
+}
+
+// WithJitter sets the strategy for randomizing the backoff.
+//
+// Parameters:
+//   - jitter: The jitter strategy.
+//
+// Returns:
+//   - A new Config instance with the updated Jitter value.
+func (c Config) WithJitter(jitter JitterStrategy) Config {
+	c.Jitter = jitter
+	return c
+}
+
+// backoffFor returns the backoff for errors of a class.
+func (c Config) backoffFor(class ErrorClass) Backoff {
+	if backoff, ok := c.ClassBackoff[class]; ok {
+		return backoff
+	}
+	return Backoff{Initial: c.InitialBackoff, Max: c.MaxBackoff, Factor: c.BackoffFactor}
+}
+
+// decide returns the retry decision for the error of a failed attempt.
+// Errors that match RetryableErrors are always retryable.
+func (c Config) decide(err error) Decision {
+	classify := c.Classifier
+	if classify == nil {
+		classify = Classify
+	}
+	decision := classify(err)
+	for _, retryableErr := range c.RetryableErrors {
+		if errors.Is(err, retryableErr) {
+			decision.Retryable = true
+			break
+		}
+	}
+	return decision
+}
+
 // Options contains additional options for the retry operation.
 // These options are not directly related to the retry behavior itself,
 // but provide additional functionality like logging and tracing.
@@ -205,6 +345,10 @@ type Options struct {
 	// Tracer is used for tracing retry operations.
 	// It provides integration with OpenTelemetry for distributed tracing.
 	Tracer telemetry.Tracer
+
+	// Clock is used to measure attempts and wait between them.
+	// If nil, the system clock will be used.
+	Clock clock.Clock
 }
 
 // DefaultOptions returns default options for retry operations.
@@ -235,6 +379,19 @@ func (o Options) WithOtelTracer(tracer trace.Tracer) Options {
 	return o
 }
 
+// WithClock returns Options with the specified clock.
+// Tests use it to pass a clock.Fake, so that backoffs do not take real time.
+//
+// Parameters:
+//   - c: The clock used to measure attempts and wait between them.
+//
+// Returns:
+//   - A new Options instance with the specified clock.
+func (o Options) WithClock(c clock.Clock) Options {
+	o.Clock = c
+	return o
+}
+
 // Do executes the given function with retry logic using default options.
 // This is a convenience wrapper around DoWithOptions that uses DefaultOptions().
 //
@@ -285,8 +442,12 @@ func DoWithOptions(ctx context.Context, fn RetryableFunc, config Config, isRetry
 		logger = logging.NewContextLogger(zap.NewNop())
 	}
 
+	clk := clock.OrReal(options.Clock)
+	config.Budget.Deposit()
+
 	var err error
-	backoff := config.InitialBackoff
+	var history []errors.RetryAttempt
+	backoffs := make(map[ErrorClass]time.Duration)
 
 	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
 		// Check if context is done before each attempt
@@ -320,9 +481,9 @@ func DoWithOptions(ctx context.Context, fn RetryableFunc, config Config, isRetry
 		currentCtx := attemptCtx
 
 		// Execute the function
-		startTime := time.Now()
+		startTime := clk.Now()
 		err = fn(currentCtx)
-		duration := time.Since(startTime)
+		duration := clk.Since(startTime)
 
 		// End the attempt span
 		attemptSpan.SetAttributes(
@@ -354,9 +515,18 @@ func DoWithOptions(ctx context.Context, fn RetryableFunc, config Config, isRetry
 			zap.Int("max_attempts", config.MaxRetries+1),
 			zap.Duration("duration", duration))
 
+		// Classify the error, to decide whether and how long to wait before retrying
+		decision := config.decide(err)
+		history = append(history, errors.RetryAttempt{
+			Attempt:  attempt + 1,
+			Err:      err,
+			Class:    string(decision.Class),
+			Duration: duration,
+		})
+
 		// Check if we've reached the maximum number of retries
 		if attempt == config.MaxRetries {
-			retryErr := errors.NewRetryError("maximum retry attempts reached", err, attempt, config.MaxRetries)
+			retryErr := errors.NewRetryError("maximum retry attempts reached", err, attempt, config.MaxRetries).WithHistory(history)
 			logger.Error(ctx, "Maximum retry attempts reached",
 				zap.Error(retryErr),
 				zap.Int("attempts", attempt+1),
@@ -370,33 +540,52 @@ func DoWithOptions(ctx context.Context, fn RetryableFunc, config Config, isRetry
 			return retryErr
 		}
 
-		// Check if the error is retryable
-		if isRetryable != nil && !isRetryable(err) {
+		// Check if the error is retryable. Without an isRetryable function, errors
+		// that the classifier does not recognize are retried, as they always were.
+		retryable := decision.Retryable || decision.Class == ClassUnknown
+		if isRetryable != nil {
+			retryable = isRetryable(err)
+		}
+		if !retryable {
 			logger.Error(ctx, "Non-retryable error encountered",
 				zap.Error(err),
+				zap.String("error_class", string(decision.Class)),
 				zap.Int("attempt", attempt+1))
 
 			span.SetAttributes(
 				attribute.String("retry.result", "non_retryable_error"),
+				attribute.String("retry.error_class", string(decision.Class)),
 				attribute.Int("retry.attempts", attempt+1),
 			)
 
 			return err // Non-retryable error, return immediately
 		}
 
-		// Calculate jitter factor between (1-jitterFactor) and (1+jitterFactor)
-		// This ensures backoff is always positive and properly distributed
-		// Use mutex to protect access to the random number generator
-		rngMutex.Lock()
-		jitterValue := rng.Float64()
-		rngMutex.Unlock()
-		jitterMultiplier := 1.0 + (jitterValue*2.0-1.0)*config.JitterFactor
+		// Check if the retry budget allows another attempt
+		if !config.Budget.Withdraw() {
+			logger.Warn(ctx, "Retry budget exhausted, not retrying",
+				zap.Error(err),
+				zap.Int("attempt", attempt+1))
+
+			span.SetAttributes(
+				attribute.String("retry.result", "budget_exhausted"),
+				attribute.Int("retry.attempts", attempt+1),
+			)
+
+			return err // Retrying would amplify the load on a failing dependency
+		}
 
-		// Apply jitter by multiplying the backoff by the jitter factor
-		backoff = time.Duration(float64(backoff) * jitterMultiplier)
+		// Wait at least as long as the server asked, but never longer than the
+		// maximum backoff, which bounds the wait whatever the server sends
+		backoff := nextBackoff(config, decision.Class, backoffs)
+		if decision.RetryAfter > backoff {
+			backoff = min(decision.RetryAfter, max(config.backoffFor(decision.Class).Max, backoff))
+		}
+		history[len(history)-1].Backoff = backoff
 
 		logger.Debug(ctx, "Waiting before next retry attempt",
 			zap.Duration("backoff", backoff),
+			zap.String("error_class", string(decision.Class)),
 			zap.Int("attempt", attempt+1),
 			zap.Int("next_attempt", attempt+2))
 
@@ -415,15 +604,9 @@ func DoWithOptions(ctx context.Context, fn RetryableFunc, config Config, isRetry
 			)
 
 			return ctxErr
-		case <-time.After(backoff):
+		case <-clk.After(backoff):
 			// Continue with next attempt
 		}
-
-		// Increase backoff for next attempt
-		backoff = time.Duration(float64(backoff) * config.BackoffFactor)
-		if backoff > config.MaxBackoff {
-			backoff = config.MaxBackoff
-		}
 	}
 
 	// This should never happen due to the return in the loop, but just in case
@@ -435,6 +618,39 @@ func DoWithOptions(ctx context.Context, fn RetryableFunc, config Config, isRetry
 	return unexpectedErr
 }
 
+// nextBackoff returns the wait before the next retry after an error of a class,
+// and advances the backoff of the class in backoffs.
+func nextBackoff(config Config, class ErrorClass, backoffs map[ErrorClass]time.Duration) time.Duration {
+	policy := config.backoffFor(class)
+	current, ok := backoffs[class]
+	if !ok {
+		current = policy.Initial
+	}
+
+	// Use mutex to protect access to the random number generator
+	rngMutex.Lock()
+	jitterValue := rng.Float64()
+	rngMutex.Unlock()
+
+	if config.Jitter == DecorrelatedJitter {
+		// Wait between the initial backoff and three times the previous wait
+		upper := max(current*3, policy.Initial)
+		wait := policy.Initial + time.Duration(jitterValue*float64(upper-policy.Initial))
+		wait = min(wait, policy.Max)
+		backoffs[class] = wait
+		return wait
+	}
+
+	// Calculate jitter factor between (1-jitterFactor) and (1+jitterFactor)
+	// This ensures backoff is always positive and properly distributed
+	jitterMultiplier := 1.0 + (jitterValue*2.0-1.0)*config.JitterFactor
+	wait := time.Duration(float64(current) * jitterMultiplier)
+
+	// Increase backoff for next attempt
+	backoffs[class] = min(time.Duration(float64(wait)*policy.Factor), policy.Max)
+	return wait
+}
+
 // IsNetworkError checks if an error is a network-related error.
 //
 // Deprecated: Use errors.IsNetworkError instead.
@@ -9,14 +9,18 @@
 	"testing"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestJitterFactorExtremes(t *testing.T) {
 	// Test with jitter factor = 0 (no jitter)
 	t.Run("jitter factor = 0", func(t *testing.T) {
+		fake := clock.NewFake(time.Time{})
+		fake.SetAutoAdvance(true)
 		options := Options{
 			Tracer: &SimpleMockTracer{},
+			Clock:  fake,
 		}
 
 		config := DefaultConfig().WithJitterFactor(0)
@@ -44,8 +48,11 @@ func TestJitterFactorExtremes(t *testing.T) {
 
 	// Test with jitter factor = 1 (maximum jitter)
 	t.Run("jitter factor = 1", func(t *testing.T) {
+		fake := clock.NewFake(time.Time{})
+		fake.SetAutoAdvance(true)
 		options := Options{
 			Tracer: &SimpleMockTracer{},
+			Clock:  fake,
 		}
 
 		config := DefaultConfig().WithJitterFactor(1)
@@ -75,8 +82,11 @@ func TestJitterFactorExtremes(t *testing.T) {
 func TestBackoffFactorExtremes(t *testing.T) {
 	// Test with very small backoff factor
 	t.Run("small backoff factor", func(t *testing.T) {
+		fake := clock.NewFake(time.Time{})
+		fake.SetAutoAdvance(true)
 		options := Options{
 			Tracer: &SimpleMockTracer{},
+			Clock:  fake,
 		}
 
 		config := DefaultConfig().
@@ -108,8 +118,11 @@ func TestBackoffFactorExtremes(t *testing.T) {
 
 	// Test with very large backoff factor
 	t.Run("large backoff factor", func(t *testing.T) {
+		fake := clock.NewFake(time.Time{})
+		fake.SetAutoAdvance(true)
 		options := Options{
 			Tracer: &SimpleMockTracer{},
+			Clock:  fake,
 		}
 
 		config := DefaultConfig().
@@ -143,8 +156,11 @@ func TestBackoffFactorExtremes(t *testing.T) {
 func TestConcurrentUsage(t *testing.T) {
 	// Test concurrent usage
 	t.Run("concurrent usage", func(t *testing.T) {
+		fake := clock.NewFake(time.Time{})
+		fake.SetAutoAdvance(true)
 		options := Options{
 			Tracer: &SimpleMockTracer{},
+			Clock:  fake,
 		}
 
 		config := DefaultConfig().
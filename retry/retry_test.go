@@ -5,9 +5,11 @@
 import (
 	"context"
 	"errors"
+	"net/http"
 	"testing"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	serviceErrors "github.com/abitofhelp/servicelib/errors"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/abitofhelp/servicelib/telemetry"
@@ -69,6 +71,8 @@ func TestDoSuccessFirstAttempt(t *testing.T) {
 
 func TestDoSuccessAfterRetries(t *testing.T) {
 	ctx := context.Background()
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
 	config := DefaultConfig()
 
 	attempts := 0
@@ -88,13 +92,15 @@ func TestDoSuccessAfterRetries(t *testing.T) {
 		return true
 	}
 
-	err := Do(ctx, fn, config, isRetryable)
+	err := DoWithOptions(ctx, fn, config, isRetryable, DefaultOptions().WithClock(fake))
 	assert.NoError(t, err)
 	assert.Equal(t, maxAttempts+1, attempts)
 }
 
 func TestDoMaxRetriesExceeded(t *testing.T) {
 	ctx := context.Background()
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
 	config := DefaultConfig()
 
 	// Function that always fails
@@ -107,7 +113,7 @@ func TestDoMaxRetriesExceeded(t *testing.T) {
 		return true
 	}
 
-	err := Do(ctx, fn, config, isRetryable)
+	err := DoWithOptions(ctx, fn, config, isRetryable, DefaultOptions().WithClock(fake))
 	assert.Error(t, err)
 	assert.True(t, serviceErrors.IsRetryError(err))
 }
@@ -150,15 +156,20 @@ func TestDoContextCancellation(t *testing.T) {
 }
 
 func TestDoContextTimeout(t *testing.T) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+	ctx, cancel := clock.WithTimeout(context.Background(), fake, 10*time.Millisecond)
 	defer cancel()
 
 	config := DefaultConfig().
 		WithInitialBackoff(50 * time.Millisecond).
 		WithMaxRetries(5)
 
+	attempts := 0
+
 	// Function that always fails
 	fn := func(ctx context.Context) error {
+		attempts++
 		return errors.New("error")
 	}
 
@@ -167,16 +178,17 @@ func TestDoContextTimeout(t *testing.T) {
 		return true
 	}
 
-	// Sleep to ensure context times out
-	time.Sleep(20 * time.Millisecond)
-
-	err := Do(ctx, fn, config, isRetryable)
+	// The context times out during the first backoff
+	err := DoWithOptions(ctx, fn, config, isRetryable, DefaultOptions().WithClock(fake))
 	assert.Error(t, err)
 	assert.True(t, serviceErrors.IsContextError(err))
+	assert.Equal(t, 1, attempts)
 }
 
 func TestDoBackoffAndJitter(t *testing.T) {
 	ctx := context.Background()
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
 	config := DefaultConfig().
 		WithMaxRetries(3).
 		WithInitialBackoff(10 * time.Millisecond).
@@ -189,7 +201,7 @@ func TestDoBackoffAndJitter(t *testing.T) {
 
 	// Function that records attempt times and always fails
 	fn := func(ctx context.Context) error {
-		startTimes = append(startTimes, time.Now())
+		startTimes = append(startTimes, fake.Now())
 		attempts++
 		return errors.New("error")
 	}
@@ -199,7 +211,7 @@ func TestDoBackoffAndJitter(t *testing.T) {
 		return true
 	}
 
-	err := Do(ctx, fn, config, isRetryable)
+	err := DoWithOptions(ctx, fn, config, isRetryable, DefaultOptions().WithClock(fake))
 	assert.Error(t, err)
 	assert.Equal(t, config.MaxRetries+1, attempts)
 
@@ -390,10 +402,13 @@ func TestDoWithOptionsCustomLoggerAndTracer(t *testing.T) {
 	// Create a custom tracer (using no-op tracer for testing)
 	tracer := telemetry.NewNoopTracer()
 
-	// Create options with custom logger and tracer
+	// Create options with custom logger and tracer, and a fake clock for the backoffs
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
 	options := Options{
 		Logger: logger,
 		Tracer: tracer,
+		Clock:  fake,
 	}
 
 	// Function that succeeds on first attempt
@@ -431,3 +446,160 @@ func TestDoWithOptionsCustomLoggerAndTracer(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, maxAttempts+1, attempts)
 }
+
+func TestConfig_WithClassBackoff(t *testing.T) {
+	config := DefaultConfig().WithClassBackoff(ClassRateLimited, Backoff{Initial: -1, Max: 0, Factor: 0.5})
+	assert.Equal(t, Backoff{Initial: time.Millisecond, Max: time.Millisecond, Factor: 1.0}, config.ClassBackoff[ClassRateLimited])
+
+	// Derived configurations do not share the map
+	derived := config.WithClassBackoff(ClassNetwork, Backoff{Initial: time.Second, Max: time.Minute, Factor: 2})
+	assert.Len(t, derived.ClassBackoff, 2)
+	assert.Len(t, config.ClassBackoff, 1)
+
+	assert.Equal(t, Backoff{Initial: time.Second, Max: time.Minute, Factor: 2}, derived.backoffFor(ClassNetwork))
+	assert.Equal(t, Backoff{Initial: 100 * time.Millisecond, Max: 2 * time.Second, Factor: 2}, derived.backoffFor(ClassTimeout))
+}
+
+func TestDoWithOptions_ClassBackoffAndRetryAfter(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+	start := fake.Now()
+
+	config := DefaultConfig().
+		WithMaxRetries(2).
+		WithInitialBackoff(time.Second).
+		WithMaxBackoff(10*time.Second).
+		WithJitterFactor(0).
+		WithClassBackoff(ClassRateLimited, Backoff{Initial: 10 * time.Second, Max: time.Minute, Factor: 2})
+
+	attempts := 0
+	err := DoWithOptions(context.Background(), func(ctx context.Context) error {
+		attempts++
+		switch attempts {
+		case 1:
+			// The server asks for longer than the class backoff
+			return &HTTPError{StatusCode: http.StatusServiceUnavailable, RetryAfter: 5 * time.Second}
+		case 2:
+			return &HTTPError{StatusCode: http.StatusTooManyRequests}
+		}
+		return nil
+	}, config, nil, DefaultOptions().WithClock(fake))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 15*time.Second, fake.Since(start))
+}
+
+func TestDoWithOptions_RetryAfterCappedAtMaxBackoff(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+	start := fake.Now()
+
+	config := DefaultConfig().
+		WithMaxRetries(1).
+		WithInitialBackoff(100 * time.Millisecond).
+		WithMaxBackoff(2 * time.Second).
+		WithJitterFactor(0)
+
+	attempts := 0
+	err := DoWithOptions(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return &HTTPError{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Hour}
+		}
+		return nil
+	}, config, nil, DefaultOptions().WithClock(fake))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 2*time.Second, fake.Since(start))
+}
+
+func TestDoWithOptions_DecorrelatedJitter(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+
+	config := DefaultConfig().
+		WithMaxRetries(5).
+		WithInitialBackoff(100 * time.Millisecond).
+		WithMaxBackoff(time.Second).
+		WithJitter(DecorrelatedJitter)
+
+	err := DoWithOptions(context.Background(), func(ctx context.Context) error {
+		return serviceErrors.NewNetworkError("connection refused", "db", "5432", nil)
+	}, config, nil, DefaultOptions().WithClock(fake))
+
+	var retryErr *serviceErrors.RetryError
+	assert.True(t, errors.As(err, &retryErr))
+	assert.Len(t, retryErr.History, 6)
+
+	previous := 100 * time.Millisecond
+	for _, attempt := range retryErr.History[:5] {
+		assert.GreaterOrEqual(t, attempt.Backoff, 100*time.Millisecond)
+		assert.LessOrEqual(t, attempt.Backoff, min(3*previous, time.Second))
+		previous = attempt.Backoff
+	}
+}
+
+func TestDoWithOptions_History(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+
+	config := DefaultConfig().WithMaxRetries(2).WithInitialBackoff(time.Second).WithJitterFactor(0)
+	errs := []error{
+		serviceErrors.NewNetworkError("connection refused", "db", "5432", nil),
+		context.DeadlineExceeded,
+		&HTTPError{StatusCode: http.StatusBadGateway},
+	}
+
+	attempts := 0
+	err := DoWithOptions(context.Background(), func(ctx context.Context) error {
+		fake.Advance(10 * time.Millisecond)
+		attempts++
+		return errs[attempts-1]
+	}, config, nil, DefaultOptions().WithClock(fake))
+
+	var retryErr *serviceErrors.RetryError
+	assert.True(t, errors.As(err, &retryErr))
+	assert.Equal(t, errs, retryErr.Errors())
+	assert.Equal(t, []serviceErrors.RetryAttempt{
+		{Attempt: 1, Err: errs[0], Class: "network", Duration: 10 * time.Millisecond, Backoff: time.Second},
+		{Attempt: 2, Err: errs[1], Class: "timeout", Duration: 10 * time.Millisecond, Backoff: time.Second},
+		{Attempt: 3, Err: errs[2], Class: "unavailable", Duration: 10 * time.Millisecond},
+	}, retryErr.History)
+}
+
+func TestDoWithOptions_ClassifiedErrors(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	fake.SetAutoAdvance(true)
+	options := DefaultOptions().WithClock(fake)
+	errNotReady := errors.New("not ready")
+
+	tests := []struct {
+		name     string
+		err      error
+		config   Config
+		attempts int
+	}{
+		{"permanent errors are not retried", serviceErrors.NewNotFoundError("user", "42", nil), DefaultConfig(), 1},
+		{"exhausted retries are not retried", serviceErrors.NewRetryError("inner", nil, 3, 3), DefaultConfig(), 1},
+		{"unknown errors are retried", errNotReady, DefaultConfig(), 4},
+		{"retryable errors override the classifier", errNotReady,
+			DefaultConfig().
+				WithClassifier(func(err error) Decision { return Decision{Class: ClassPermanent} }).
+				WithRetryableErrors([]error{errNotReady}), 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := DoWithOptions(context.Background(), func(ctx context.Context) error {
+				attempts++
+				return tt.err
+			}, tt.config, nil, options)
+
+			assert.Error(t, err)
+			assert.Equal(t, tt.attempts, attempts)
+		})
+	}
+}
@@ -14,6 +14,8 @@
 //   - Concurrent execution of shutdown callbacks
 //   - Proper handling of multiple signals (force exit on second signal)
 //   - Context-based notification system for shutdown events
+//   - A draining state, which readiness probes report as not ready during shutdown,
+//     and a drain delay that gives load balancers time to notice before it proceeds
 //
 // The package is designed around the GracefulShutdown type, which manages the shutdown
 // process and provides methods for registering callbacks to be executed during shutdown.
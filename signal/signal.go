@@ -11,6 +11,7 @@
 	"syscall"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/abitofhelp/servicelib/logging"
 	"go.uber.org/zap"
 )
@@ -35,6 +36,10 @@ type GracefulShutdown struct {
 	callbacks  []ShutdownCallback     // Registered shutdown callbacks
 	callbackMu sync.Mutex             // Mutex for thread-safe callback registration
 	signals    []os.Signal            // OS signals to handle
+	draining   chan struct{}          // Closed when the shutdown begins
+	drainDelay time.Duration          // Time between draining and canceling the context
+	clock      clock.Clock            // Clock that times the drain delay, or nil for the system clock
+	drainMu    sync.Mutex             // Mutex for draining, drainDelay and clock
 }
 
 // NewGracefulShutdown creates a new graceful shutdown handler.
@@ -82,11 +87,92 @@ func (gs *GracefulShutdown) RegisterCallback(callback ShutdownCallback) {
 	gs.callbacks = append(gs.callbacks, callback)
 }
 
+// SetDrainDelay sets how long HandleShutdown waits after marking the application
+// as draining before it cancels its context and runs the shutdown callbacks. The
+// delay gives load balancers time to see the failing readiness probe and stop
+// sending requests before the application stops accepting them; it should be at
+// least the period of the readiness probe. The default of zero does not wait.
+//
+// Parameters:
+//   - delay: The drain delay. If non-positive, the shutdown does not wait.
+func (gs *GracefulShutdown) SetDrainDelay(delay time.Duration) {
+	gs.drainMu.Lock()
+	defer gs.drainMu.Unlock()
+	gs.drainDelay = delay
+}
+
+// SetClock sets the clock that times the drain delay. Tests use it to pass a
+// clock.Fake, so that the delay does not take real time.
+//
+// Parameters:
+//   - c: The clock. If nil, the system clock is used.
+func (gs *GracefulShutdown) SetClock(c clock.Clock) {
+	gs.drainMu.Lock()
+	defer gs.drainMu.Unlock()
+	gs.clock = c
+}
+
+// StartDraining marks the application as draining, which readiness checks such as
+// the health package's readyz handler report as not ready, so that load balancers stop
+// sending new requests while the shutdown is in progress.
+//
+// HandleShutdown calls StartDraining when a shutdown signal is received or its cancel
+// function is called. Calling it more than once has no further effect.
+func (gs *GracefulShutdown) StartDraining() {
+	gs.drainMu.Lock()
+	draining := gs.drainingLocked()
+	select {
+	case <-draining:
+		gs.drainMu.Unlock()
+		return
+	default:
+		close(draining)
+	}
+	gs.drainMu.Unlock()
+
+	if gs.logger != nil {
+		gs.logger.Info(context.Background(), "Draining before shutdown")
+	}
+}
+
+// IsDraining reports whether the shutdown has begun.
+//
+// Returns:
+//   - bool: True once StartDraining has been called.
+func (gs *GracefulShutdown) IsDraining() bool {
+	select {
+	case <-gs.Draining():
+		return true
+	default:
+		return false
+	}
+}
+
+// Draining returns a channel that is closed when the shutdown begins.
+//
+// Returns:
+//   - <-chan struct{}: A channel that is closed by StartDraining.
+func (gs *GracefulShutdown) Draining() <-chan struct{} {
+	gs.drainMu.Lock()
+	defer gs.drainMu.Unlock()
+	return gs.drainingLocked()
+}
+
+// drainingLocked returns the draining channel, creating it if needed.
+// The caller must hold drainMu.
+func (gs *GracefulShutdown) drainingLocked() chan struct{} {
+	if gs.draining == nil {
+		gs.draining = make(chan struct{})
+	}
+	return gs.draining
+}
+
 // HandleShutdown sets up signal handling for graceful shutdown.
 //
 // This method starts a goroutine that listens for OS signals and initiates
 // the shutdown process when a signal is received. The shutdown process includes:
-//  1. Canceling the returned context to notify the application
+//  1. Marking the application as draining, waiting for the drain delay set by
+//     SetDrainDelay, and canceling the returned context to notify the application
 //  2. Creating a timeout context for shutdown callbacks
 //  3. Executing all registered callbacks concurrently
 //  4. Waiting for callbacks to complete or timeout
@@ -101,9 +187,22 @@ func (gs *GracefulShutdown) RegisterCallback(callback ShutdownCallback) {
 //     The application should monitor this context and begin its shutdown procedure when canceled.
 //   - context.CancelFunc: A function that can be called to manually trigger the shutdown process
 //     without waiting for an OS signal. This is useful for testing or for implementing
-//     application-specific shutdown triggers.
+//     application-specific shutdown triggers. It marks the application as draining at once
+//     and cancels the context once the drain delay has passed, without blocking.
 func (gs *GracefulShutdown) HandleShutdown() (context.Context, context.CancelFunc) {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	cancel := func() {
+		gs.StartDraining()
+
+		gs.drainMu.Lock()
+		delay, c := gs.drainDelay, clock.OrReal(gs.clock)
+		gs.drainMu.Unlock()
+		if delay <= 0 {
+			cancelCtx()
+			return
+		}
+		c.AfterFunc(delay, cancelCtx)
+	}
 
 	go func() {
 		// Create a channel to receive OS signals
@@ -114,8 +213,16 @@ func (gs *GracefulShutdown) HandleShutdown() (context.Context, context.CancelFun
 		sig := <-sigCh
 		gs.logger.Info(ctx, "Received shutdown signal", zap.String("signal", sig.String()))
 
-		// Cancel the context to notify all services to shut down
+		// Stop reporting ready, and once load balancers have had time to notice,
+		// cancel the context to notify all services to shut down
 		cancel()
+		select {
+		case <-ctx.Done():
+		case sig := <-sigCh:
+			gs.logger.Warn(ctx, "Received second signal while draining, forcing exit",
+				zap.String("signal", sig.String()))
+			os.Exit(0)
+		}
 
 		// Create a timeout context for shutdown
 		timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), gs.timeout)
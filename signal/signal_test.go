@@ -11,6 +11,7 @@
 	"testing"
 	"time"
 
+	"github.com/abitofhelp/servicelib/clock"
 	"github.com/abitofhelp/servicelib/logging"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -33,6 +34,71 @@ func TestNewGracefulShutdown(t *testing.T) {
 	assert.Len(t, gs.signals, 4) // SIGINT, SIGTERM, SIGHUP, SIGQUIT
 }
 
+// TestStartDraining tests that StartDraining marks the application as draining once
+func TestStartDraining(t *testing.T) {
+	logger := logging.NewContextLogger(zaptest.NewLogger(t))
+	gs := NewGracefulShutdown(time.Second, logger)
+
+	assert.False(t, gs.IsDraining())
+	select {
+	case <-gs.Draining():
+		t.Fatal("Draining should not be closed yet")
+	default:
+	}
+
+	gs.StartDraining()
+	gs.StartDraining()
+
+	assert.True(t, gs.IsDraining())
+	select {
+	case <-gs.Draining():
+	default:
+		t.Fatal("Draining should be closed")
+	}
+}
+
+// TestHandleShutdownCancelStartsDraining tests that the cancel function of HandleShutdown marks the application as draining
+func TestHandleShutdownCancelStartsDraining(t *testing.T) {
+	// A GracefulShutdown that was not created by NewGracefulShutdown can drain too
+	gs := &GracefulShutdown{
+		timeout: time.Second,
+		logger:  logging.NewContextLogger(zaptest.NewLogger(t)),
+		signals: []os.Signal{syscall.SIGUSR2},
+	}
+
+	ctx, cancel := gs.HandleShutdown()
+	assert.False(t, gs.IsDraining())
+
+	cancel()
+	assert.True(t, gs.IsDraining())
+	assert.Error(t, ctx.Err())
+}
+
+// TestHandleShutdownDrainDelay tests that the context is canceled once the drain delay has passed
+func TestHandleShutdownDrainDelay(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	gs := &GracefulShutdown{
+		timeout: time.Second,
+		logger:  logging.NewContextLogger(zaptest.NewLogger(t)),
+		signals: []os.Signal{syscall.SIGUSR2},
+	}
+	gs.SetClock(fake)
+	gs.SetDrainDelay(5 * time.Second)
+
+	ctx, cancel := gs.HandleShutdown()
+	cancel()
+
+	// Readiness fails at once, while the application keeps serving
+	assert.True(t, gs.IsDraining())
+	assert.NoError(t, ctx.Err())
+
+	fake.Advance(5*time.Second - time.Nanosecond)
+	assert.NoError(t, ctx.Err())
+
+	fake.Advance(time.Nanosecond)
+	assert.Error(t, ctx.Err())
+}
+
 func TestRegisterCallback(t *testing.T) {
 	// Create a test logger
 	logger := logging.NewContextLogger(zaptest.NewLogger(t))
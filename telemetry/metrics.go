@@ -14,6 +14,7 @@
 	"context"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/knadh/koanf/v2"
@@ -22,6 +23,7 @@
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -391,7 +393,8 @@ func RecordDBOperation(ctx context.Context, operation, database, collection stri
 	}
 }
 
-// UpdateDBConnections updates the open database connections counter
+// UpdateDBConnections updates the open database connections counter.
+// The init functions of the db package call it for pools they instrument.
 //
 // Parameters:
 //   - ctx: The context for the operation
@@ -435,7 +438,52 @@ func IsMetricsEnabled(k *koanf.Koanf) bool {
 	return k.Bool("telemetry.metrics.enabled")
 }
 
+// Prometheus bridge for OpenTelemetry instruments
+var (
+	// prometheusBridgeRegistry holds the collector of the OpenTelemetry Prometheus exporter.
+	// It is gathered by every handler created with CreatePrometheusHandler.
+	prometheusBridgeRegistry = prometheus.NewRegistry()
+
+	// prometheusBridgeOnce guards the creation of the bridge meter provider
+	prometheusBridgeOnce sync.Once
+
+	// prometheusBridgeProvider is the meter provider backed by the Prometheus exporter
+	prometheusBridgeProvider *sdkmetric.MeterProvider
+
+	// prometheusBridgeErr is the error returned when the bridge could not be created
+	prometheusBridgeErr error
+)
+
+// PrometheusMeter returns an OpenTelemetry meter whose instruments are exposed
+// by every handler created with CreatePrometheusHandler. Components that accept
+// a metric.Meter, such as cache.Options, can use it to publish their metrics on
+// the /metrics endpoint without an OTLP collector.
+//
+// Parameters:
+//   - name: The instrumentation scope name, such as "github.com/abitofhelp/servicelib/cache"
+//
+// Returns:
+//   - metric.Meter: The meter
+//   - error: An error if the Prometheus exporter could not be created
+func PrometheusMeter(name string) (metric.Meter, error) {
+	prometheusBridgeOnce.Do(func() {
+		exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(prometheusBridgeRegistry))
+		if err != nil {
+			prometheusBridgeErr = fmt.Errorf("failed to create Prometheus exporter: %w", err)
+			return
+		}
+		prometheusBridgeProvider = sdkmetric.NewMeterProvider(sdkmetric.WithReader(exporter))
+	})
+	if prometheusBridgeErr != nil {
+		return nil, prometheusBridgeErr
+	}
+
+	return prometheusBridgeProvider.Meter(name), nil
+}
+
 // CreatePrometheusHandler creates a handler for the /metrics endpoint
+// The handler exposes Go runtime and process metrics, together with the
+// instruments created from meters returned by PrometheusMeter.
 //
 // Returns:
 //   - http.Handler: The Prometheus metrics handler
@@ -449,8 +497,8 @@ func CreatePrometheusHandler() http.Handler {
 	// Register the process collector which collects process metrics
 	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
 
-	// Create a handler for the registry
-	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+	// Create a handler for the registry and the OpenTelemetry bridge
+	return promhttp.HandlerFor(prometheus.Gatherers{registry, prometheusBridgeRegistry}, promhttp.HandlerOpts{
 		EnableOpenMetrics: true,
 	})
 }
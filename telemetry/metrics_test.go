@@ -37,6 +37,27 @@ func TestCreatePrometheusHandler(t *testing.T) {
 	assert.Contains(t, rr.Header().Get("Content-Type"), "text/plain")
 }
 
+func TestPrometheusMeter(t *testing.T) {
+	meter, err := PrometheusMeter("github.com/abitofhelp/servicelib/telemetry/test")
+	require.NoError(t, err)
+	require.NotNil(t, meter)
+
+	counter, err := meter.Int64Counter("telemetry.test.events", metric.WithDescription("Test events"))
+	require.NoError(t, err)
+	counter.Add(context.Background(), 3)
+
+	// Instruments are exposed by every Prometheus handler
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		rr := httptest.NewRecorder()
+		CreatePrometheusHandler().ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "telemetry_test_events_total")
+		assert.Contains(t, rr.Body.String(), "go_goroutines")
+	}
+}
+
 func TestRecordHTTPRequest(t *testing.T) {
 	// Create a context
 	ctx := context.Background()